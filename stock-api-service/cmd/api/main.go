@@ -3,64 +3,318 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/alerts"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/algorithm"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/api"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/api/middlewares"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/auth"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/cache"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/config"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/database"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/freshness"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/graphqlapi"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/grpcapi"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/metrics"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/openapi"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/ratelimit"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/recommendation"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/snapshot"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/stream"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/tracing"
 	"github.com/joho/godotenv"
 )
 
 func main() {
 	// Cargar variables de entorno
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Nota: No se pudo cargar el archivo .env: %v", err)
-	}
+	envErr := godotenv.Load()
 
 	// Inicializar configuración
 	cfg := config.NewConfig()
 
+	// Configurar logs estructurados
+	if _, err := logging.Setup(os.Stdout, cfg.LogLevel, cfg.LogFormat); err != nil {
+		slog.Error("Configuración de logs inválida", "error", err)
+		os.Exit(1)
+	}
+	if envErr != nil {
+		slog.Info("No se pudo cargar el archivo .env", "error", envErr)
+	}
+
+	// Configurar trazas de OpenTelemetry
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName:  api.ServiceName,
+		Exporter:     cfg.TracingExporter,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		SampleRatio:  cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		fatal("Error al configurar las trazas", err)
+	}
+
+	// Validar la política CORS antes de arrancar
+	corsConfig := middlewares.CORSConfig{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}
+	if err := corsConfig.Validate(); err != nil {
+		fatal("Configuración de CORS inválida", err)
+	}
+
 	// Conectar a la base de datos
-	db, err := database.Connect(cfg.GetDBConnectionString())
+	db, err := database.Connect(cfg.GetDBConnectionString(), cfg.DBPool())
 	if err != nil {
-		log.Fatalf("Error al conectar a la base de datos: %v", err)
+		fatal("Error al conectar a la base de datos", err)
 	}
 	defer db.Close()
 
-	// Crear repositorio de stocks
-	repo := repository.NewStockRepository(db)
+	// Publicar las estadísticas del pool de conexiones
+	metrics.RegisterDB(db, "stockdb")
+
+	// Crear repositorio de stocks, con un pool propio para los listados y las
+	// recomendaciones si hay una cadena de conexión de solo lectura
+	repo := repository.NewCockroachStockRepository(db).WithFollowerReads(cfg.DBFollowerReads)
+	if cfg.DBReadDSN != "" {
+		readDB, err := database.Connect(cfg.DBReadDSN, cfg.DBReadPool())
+		if err != nil {
+			fatal("Error al conectar a la base de datos de solo lectura", err)
+		}
+		defer readDB.Close()
+
+		metrics.RegisterDB(readDB, "stockdb_read")
+		repo = repo.WithReadPool(readDB)
+	}
+	if cfg.DBFollowerReads {
+		slog.Info("Lecturas de réplicas seguidoras activadas para los listados y las recomendaciones")
+	}
+
+	// Crear repositorio de snapshots e inicializar sus tablas
+	snapshots := repository.NewSnapshotRepository(db)
+	initCtx, initCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := snapshots.InitDB(initCtx); err != nil {
+		initCancel()
+		fatal("Error al inicializar la base de datos", err)
+	}
+
+	// Crear repositorio de listas de seguimiento e inicializar sus tablas
+	watchlists := repository.NewWatchlistRepository(db)
+	if err := watchlists.InitDB(initCtx); err != nil {
+		initCancel()
+		fatal("Error al inicializar la base de datos", err)
+	}
+
+	// Crear repositorio de alertas e inicializar sus tablas
+	alertRepo := repository.NewAlertRepository(db)
+	if err := alertRepo.InitDB(initCtx); err != nil {
+		initCancel()
+		fatal("Error al inicializar la base de datos", err)
+	}
+
+	// Crear almacén de API keys e inicializar su tabla
+	apiKeys := auth.NewKeyStore(db)
+	if err := apiKeys.InitDB(initCtx); err != nil {
+		initCancel()
+		fatal("Error al inicializar la base de datos", err)
+	}
+	initCancel()
+
+	// Configurar autenticación
+	jwtVerifier, err := auth.NewJWTVerifier(auth.JWTConfig{
+		HS256Secret: cfg.JWTHS256Secret,
+		JWKSFile:    cfg.JWTJWKSFile,
+		Issuer:      cfg.JWTIssuer,
+		Audience:    cfg.JWTAudience,
+	})
+	if err != nil {
+		fatal("Error al configurar la autenticación JWT", err)
+	}
+	if !cfg.AuthEnabled {
+		slog.Warn("La autenticación está desactivada (AUTH_ENABLED=false)")
+	}
+	authenticator := auth.NewAuthenticator(cfg.AuthEnabled, apiKeys, jwtVerifier, cfg.AuthBootstrapKey)
+
+	// Contexto de las tareas en segundo plano, cancelado al apagar el servidor
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Recomendador con la configuración predeterminada del servicio
+	recommender := algorithm.NewStockRecommender().WithMaxPerSector(cfg.RecommendationMaxPerSector)
+
+	// Guardar el snapshot diario de recomendaciones en segundo plano
+	snapshotService := snapshot.NewService(repo, snapshots, recommender)
+	go snapshotService.RunDaily(backgroundCtx, cfg.SnapshotDailyHour, time.Hour)
+
+	// Caché de recomendaciones, invalidada cuando stock-data-service termina una sincronización
+	recommendationCache := cache.NewRecommendationCache(cfg.RecommendationCacheTTL, 1000)
+	syncWatcher := cache.NewSyncWatcher(repo, cfg.SyncPollInterval, recommendationCache)
+
+	// Recomendaciones de REST, GraphQL y gRPC, que comparten la caché
+	recommendationService := recommendation.NewService(repo, recommender, recommendationCache, cfg.RecommendationWatchlistBoost)
+
+	// Evaluar las reglas de alerta sobre los eventos nuevos tras cada sincronización
+	alertEngine := alerts.NewEngine(repo, alertRepo, recommender, cfg.AlertEvaluationInterval, cfg.AlertLookback)
+	syncWatcher.OnSync(alertEngine.Notify)
+	go alertEngine.Run(backgroundCtx)
+	go syncWatcher.Run(backgroundCtx)
+
+	// Vigilar la frescura de los datos para detectar si la ingesta se detuvo
+	freshnessMonitor := freshness.NewMonitor(repo, freshness.Thresholds{
+		MaxSyncAge: cfg.FreshnessMaxSyncAge,
+		MaxDataAge: cfg.FreshnessMaxDataAge,
+	}, cfg.FreshnessCheckInterval)
+	go freshnessMonitor.Run(backgroundCtx)
+
+	// Seguir los eventos y snapshots nuevos para el stream de eventos
+	streamHub := stream.NewHub(repo, snapshots, stream.Config{
+		PollInterval: cfg.StreamPollInterval,
+		BufferSize:   cfg.StreamBufferSize,
+		Lookback:     cfg.StreamLookback,
+	})
+	go streamHub.Run(backgroundCtx)
+
+	// Consultas GraphQL, con el servicio de recomendaciones de la API REST
+	graphqlService, err := graphqlapi.NewService(repo, recommendationService, graphqlapi.Limits{
+		MaxDepth:      cfg.GraphQLMaxDepth,
+		MaxComplexity: cfg.GraphQLMaxComplexity,
+	})
+	if err != nil {
+		fatal("Error al configurar GraphQL", err)
+	}
+
+	// Documento OpenAPI con el que se validan las solicitudes
+	spec, err := openapi.Load()
+	if err != nil {
+		fatal("Error al cargar el documento OpenAPI", err)
+	}
+
+	// Límites de solicitudes por cliente
+	var rateLimiter *ratelimit.Limiter
+	if cfg.RateLimitEnabled {
+		var store ratelimit.Store
+		switch cfg.RateLimitStore {
+		case "cockroach":
+			sqlStore := ratelimit.NewSQLStore(db)
+			initCtx, initCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			err := sqlStore.InitDB(initCtx)
+			initCancel()
+			if err != nil {
+				fatal("Error al inicializar la base de datos", err)
+			}
+			go sqlStore.RunPruner(backgroundCtx, time.Hour, 24*time.Hour)
+			store = sqlStore
+		case "memory":
+			store = ratelimit.NewMemoryStore()
+		default:
+			fatal("Configuración de límites de solicitudes inválida", fmt.Errorf("RATE_LIMIT_STORE inválido: %q (use memory o cockroach)", cfg.RateLimitStore))
+		}
+
+		rateLimiter = ratelimit.NewLimiter(store, map[string]ratelimit.Policy{
+			ratelimit.GroupRead:            {PerMinute: cfg.RateLimitReadPerMinute, Burst: cfg.RateLimitReadBurst},
+			ratelimit.GroupRecommendations: {PerMinute: cfg.RateLimitRecommendationsPerMinute, Burst: cfg.RateLimitRecommendationsBurst},
+			ratelimit.GroupWrite:           {PerMinute: cfg.RateLimitWritePerMinute, Burst: cfg.RateLimitWriteBurst},
+			ratelimit.GroupIP:              {PerMinute: cfg.RateLimitIPPerMinute, Burst: cfg.RateLimitIPBurst},
+		}, cfg.RateLimitDailyQuota)
+	}
 
 	// Configurar servidor HTTP con Gin
-	router := api.NewRouter(repo)
-	server := router.SetupServer(cfg.ServerPort)
+	router := api.NewRouter(api.Dependencies{
+		Stocks:          repo,
+		Snapshots:       snapshots,
+		Watchlists:      watchlists,
+		Alerts:          alertRepo,
+		SnapshotService: snapshotService,
+		Recommendations: recommendationService,
+		Freshness:       freshnessMonitor,
+		Stream:          streamHub,
+		StreamHeartbeat: cfg.StreamHeartbeatInterval,
+		GraphQL:         graphqlService,
+		OpenAPI:         spec,
+		Authenticator:   authenticator,
+		APIKeys:         apiKeys,
+		RateLimiter:     rateLimiter,
+		TrustedProxies:  cfg.TrustedProxies,
+		CORS:            corsConfig,
+	})
+	server, err := router.SetupServer(cfg.ServerPort)
+	if err != nil {
+		fatal("Configuración de proxies de confianza inválida", err)
+	}
 
 	// Arrancar servidor en una goroutine
 	go func() {
-		log.Printf("Servidor iniciado en el puerto %s", cfg.ServerPort)
+		slog.Info("Servidor iniciado", "port", cfg.ServerPort)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("Error al iniciar el servidor: %v", err)
+			slog.Error("Error al iniciar el servidor", "error", err)
 		}
 	}()
 
+	// Servidor gRPC para los servicios internos, con los mismos componentes que la API REST
+	var grpcServer *grpcapi.Server
+	if cfg.GRPCPort != "" {
+		grpcServer = grpcapi.NewServer(grpcapi.Dependencies{
+			Stocks:          repo,
+			Recommendations: recommendationService,
+			Stream:          streamHub,
+			Authenticator:   authenticator,
+			RateLimiter:     rateLimiter,
+		})
+		go func() {
+			slog.Info("Servidor gRPC iniciado", "port", cfg.GRPCPort)
+			if err := grpcServer.Serve(cfg.GRPCPort); err != nil {
+				slog.Error("Error al iniciar el servidor gRPC", "error", err)
+			}
+		}()
+	}
+
 	// Configurar graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Apagando servidor...")
+	slog.Info("Apagando servidor...")
+	stopBackground()
 
 	// Cerrar con timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// Ambos servidores se cierran a la vez y comparten el plazo
+	grpcDone := make(chan error, 1)
+	if grpcServer != nil {
+		go func() { grpcDone <- grpcServer.Shutdown(ctx) }()
+	} else {
+		grpcDone <- nil
+	}
+
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Error al cerrar el servidor: %v", err)
+		fatal("Error al cerrar el servidor", err)
 	}
+	if err := <-grpcDone; err != nil {
+		slog.Error("Error al cerrar el servidor gRPC", "error", err)
+	}
+
+	// Enviar las trazas pendientes
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Error al cerrar las trazas", "error", err)
+	}
+
+	slog.Info("Servidor apagado correctamente")
+}
 
-	log.Println("Servidor apagado correctamente")
+// fatal registra un error irrecuperable y termina el proceso.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,92 @@
+// Paquete main ejecuta un backtest del algoritmo de recomendación desde la línea de comandos.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/backtest"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/config"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/database"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	startFlag := flag.String("start", "", "Primera fecha de evaluación (YYYY-MM-DD)")
+	endFlag := flag.String("end", time.Now().Format("2006-01-02"), "Última fecha de evaluación (YYYY-MM-DD)")
+	step := flag.Int("step", 7, "Días entre fechas de evaluación")
+	top := flag.Int("top", 10, "Cantidad de recomendaciones por fecha")
+	horizon := flag.Int("horizon", 30, "Días de evaluación posteriores a cada fecha")
+	lookback := flag.Int("lookback", 30, "Días de historial entregados al recomendador")
+	strategies := flag.String("strategies", "", "Estrategias separadas por coma (todas si se omite)")
+	pricesFile := flag.String("prices", "", "Archivo CSV opcional con columnas ticker,date,close")
+	flag.Parse()
+
+	// Cargar variables de entorno
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Nota: No se pudo cargar el archivo .env: %v", err)
+	}
+
+	cfg := backtest.Config{
+		StepDays:     *step,
+		TopN:         *top,
+		HorizonDays:  *horizon,
+		LookbackDays: *lookback,
+	}
+
+	var err error
+	if cfg.Start, err = backtest.ParseDate(*startFlag); err != nil {
+		log.Fatalf("Fecha de inicio inválida %q: %v", *startFlag, err)
+	}
+	if cfg.End, err = backtest.ParseDate(*endFlag); err != nil {
+		log.Fatalf("Fecha de fin inválida %q: %v", *endFlag, err)
+	}
+	if *strategies != "" {
+		for _, name := range strings.Split(*strategies, ",") {
+			cfg.Strategies = append(cfg.Strategies, strings.TrimSpace(name))
+		}
+	}
+
+	var prices backtest.PriceSeries
+	if *pricesFile != "" {
+		file, err := os.Open(*pricesFile)
+		if err != nil {
+			log.Fatalf("Error al abrir la serie de precios: %v", err)
+		}
+		prices, err = backtest.LoadPriceSeriesCSV(file)
+		file.Close()
+		if err != nil {
+			log.Fatalf("Error al cargar la serie de precios: %v", err)
+		}
+	}
+
+	// Conectar a la base de datos
+	appConfig := config.NewConfig()
+	db, err := database.Connect(appConfig.GetDBConnectionString(), appConfig.DBPool())
+	if err != nil {
+		log.Fatalf("Error al conectar a la base de datos: %v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewCockroachStockRepository(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	report, err := backtest.Run(ctx, repo, cfg, prices)
+	if err != nil {
+		log.Fatalf("Error al ejecutar el backtest: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("Error al escribir el reporte: %v", err)
+	}
+}
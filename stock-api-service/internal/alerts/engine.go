@@ -0,0 +1,294 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/metrics"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// Límites de lectura del historial.
+const (
+	// Eventos nuevos leídos por consulta
+	pageSize = 500
+	// Eventos de cada ticker leídos para contar las ventanas de una página
+	windowLimit = 1000
+)
+
+// EventSource lee el historial de calificaciones.
+type EventSource interface {
+	GetStockEventsAfter(ctx context.Context, afterID int64, limit int) ([]models.StockEvent, error)
+	GetLatestStockEventID(ctx context.Context) (int64, error)
+	GetTickersEventsBetween(ctx context.Context, tickers []string, startDate, endDate time.Time, perTicker int) ([]models.StockEvent, error)
+}
+
+// Store guarda las reglas, las alertas disparadas y la posición del motor.
+type Store interface {
+	ListEnabledAlertRules(ctx context.Context) ([]models.AlertRule, error)
+	SaveAlerts(ctx context.Context, alerts []models.Alert) (int, error)
+	GetAlertCursor(ctx context.Context) (int64, bool, error)
+	SaveAlertCursor(ctx context.Context, eventID int64) error
+}
+
+// Engine evalúa las reglas activas sobre los eventos de stock_events posteriores
+// a la última posición guardada. Se despierta con Notify, que se llama cada vez
+// que termina una sincronización, y además cada cierto intervalo por si se
+// perdió algún aviso.
+//
+// Los identificadores de stock_events los genera unique_rowid() al insertar, no
+// al confirmar, de modo que un evento puede aparecer después de otro con un
+// identificador mayor. Por eso la posición guardada solo avanza hasta el mayor
+// evento leído hace más de lookback, y cada evaluación vuelve a leer los
+// eventos posteriores. Las alertas ya guardadas se ignoran al volver a
+// evaluarlos, y por el mismo motivo un reinicio o varias réplicas que evalúan
+// los mismos eventos no duplican alertas.
+type Engine struct {
+	events   EventSource
+	store    Store
+	ratings  RatingScale
+	interval time.Duration
+	lookback time.Duration
+	// Para reemplazar el reloj en las pruebas
+	now func() time.Time
+
+	// Lecturas cuyos eventos todavía pueden completarse; solo las usa Evaluate
+	marks []mark
+
+	// Aviso pendiente de evaluación; tiene capacidad 1 para no acumular avisos
+	wake chan struct{}
+	// Evita evaluaciones simultáneas en la misma réplica
+	mu sync.Mutex
+}
+
+// mark registra el mayor identificador leído en una evaluación. Todos los
+// eventos con un identificador menor o igual se confirman antes de lookback.
+type mark struct {
+	at time.Time
+	id int64
+}
+
+// NewEngine crea un motor de alertas. lookback es el tiempo máximo entre la
+// asignación del identificador de un evento y su confirmación.
+func NewEngine(events EventSource, store Store, ratings RatingScale, interval, lookback time.Duration) *Engine {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	if lookback <= 0 {
+		lookback = 30 * time.Second
+	}
+	return &Engine{
+		events:   events,
+		store:    store,
+		ratings:  ratings,
+		interval: interval,
+		lookback: lookback,
+		now:      time.Now,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Notify pide una evaluación sin esperar a que termine.
+func (e *Engine) Notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run evalúa las reglas al arrancar, con cada aviso y cada intervalo, hasta que
+// el contexto se cancela.
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := e.Evaluate(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Error al evaluar las reglas de alerta", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-e.wake:
+		}
+	}
+}
+
+// Evaluate evalúa las reglas activas sobre los eventos posteriores a la
+// posición guardada y devuelve la cantidad de alertas nuevas. La primera vez
+// empieza desde el evento más reciente, sin evaluar el historial existente.
+func (e *Engine) Evaluate(ctx context.Context) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cursor, ok, err := e.store.GetAlertCursor(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		latest, err := e.events.GetLatestStockEventID(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return 0, e.store.SaveAlertCursor(ctx, latest)
+	}
+
+	// Avanza la posición hasta las lecturas que ya no pueden completarse
+	settled := cursor
+	n := 0
+	for n < len(e.marks) && !e.marks[n].at.After(e.now().Add(-e.lookback)) {
+		settled = max(settled, e.marks[n].id)
+		n++
+	}
+	e.marks = e.marks[n:]
+	if settled > cursor {
+		if err := e.store.SaveAlertCursor(ctx, settled); err != nil {
+			return 0, err
+		}
+		cursor = settled
+	}
+
+	stored, err := e.store.ListEnabledAlertRules(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rules := make([]*Rule, 0, len(stored))
+	for _, rule := range stored {
+		compiled, err := Compile(rule)
+		if err != nil {
+			// Las reglas se validan al guardarse; una inválida no detiene a las demás
+			slog.WarnContext(ctx, "Regla de alerta inválida, se omite", "rule_id", rule.ID, "error", err)
+			continue
+		}
+		rules = append(rules, compiled)
+	}
+
+	triggered := 0
+	after := cursor
+	for {
+		events, err := e.events.GetStockEventsAfter(ctx, after, pageSize)
+		if err != nil {
+			return triggered, err
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		alerts, err := e.evaluate(ctx, rules, events)
+		if err != nil {
+			return triggered, err
+		}
+
+		saved, err := e.store.SaveAlerts(ctx, alerts)
+		if err != nil {
+			return triggered, err
+		}
+		triggered += saved
+		metrics.AddAlertsTriggered(saved)
+
+		after = events[len(events)-1].ID
+		if len(events) < pageSize {
+			break
+		}
+	}
+
+	if after > cursor && (len(e.marks) == 0 || e.marks[len(e.marks)-1].id < after) {
+		e.marks = append(e.marks, mark{at: e.now(), id: after})
+	}
+	if triggered > 0 {
+		slog.InfoContext(ctx, "Alertas disparadas", "alerts", triggered, "last_event_id", after)
+	}
+	return triggered, nil
+}
+
+// candidate es un evento que cumple las condiciones de una regla.
+type candidate struct {
+	rule   *Rule
+	event  models.StockEvent
+	values map[string]float64
+}
+
+// evaluate aplica las reglas a una página de eventos. Si una regla tiene
+// ventana, cuenta las calificaciones del mismo ticker que cumplen las
+// condiciones en el periodo que termina en la fecha del evento, incluido el
+// propio evento. El historial de todas las ventanas se lee con una sola
+// consulta por página.
+func (e *Engine) evaluate(ctx context.Context, rules []*Rule, events []models.StockEvent) ([]models.Alert, error) {
+	var candidates []candidate
+	var tickers []string
+	var start, end time.Time
+	seen := map[string]bool{}
+	for _, event := range events {
+		for _, rule := range rules {
+			values, ok := rule.Match(event.Stock, e.ratings)
+			if !ok {
+				continue
+			}
+			candidates = append(candidates, candidate{rule: rule, event: event, values: values})
+
+			if window, _ := rule.Window(); window > 0 {
+				if !seen[event.Ticker] {
+					seen[event.Ticker] = true
+					tickers = append(tickers, event.Ticker)
+				}
+				if from := event.Time.Add(-window); start.IsZero() || from.Before(start) {
+					start = from
+				}
+				if event.Time.After(end) {
+					end = event.Time
+				}
+			}
+		}
+	}
+
+	history := map[string][]models.StockEvent{}
+	if len(tickers) > 0 {
+		past, err := e.events.GetTickersEventsBetween(ctx, tickers, start, end, windowLimit)
+		if err != nil {
+			return nil, fmt.Errorf("error al contar las ventanas de las reglas: %w", err)
+		}
+		for _, event := range past {
+			history[event.Ticker] = append(history[event.Ticker], event)
+		}
+	}
+
+	var alerts []models.Alert
+	for _, c := range candidates {
+		if window, minCount := c.rule.Window(); window > 0 {
+			count := countInWindow(c.rule, history[c.event.Ticker], c.event.Time.Add(-window), c.event.Time, e.ratings)
+			c.values[ValueCountInWindow] = float64(count)
+			if count < minCount {
+				continue
+			}
+		}
+		alerts = append(alerts, models.Alert{
+			RuleID:   c.rule.ID,
+			RuleName: c.rule.Name,
+			Owner:    c.rule.Owner,
+			EventID:  c.event.ID,
+			Stock:    c.event.Stock,
+			Values:   c.values,
+		})
+	}
+	return alerts, nil
+}
+
+// countInWindow cuenta las calificaciones del historial entre dos fechas,
+// ambas incluidas, que cumplen las condiciones de la regla.
+func countInWindow(rule *Rule, history []models.StockEvent, start, end time.Time, ratings RatingScale) int {
+	count := 0
+	for _, past := range history {
+		if past.Time.Before(start) || past.Time.After(end) {
+			continue
+		}
+		if _, ok := rule.Match(past.Stock, ratings); ok {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,238 @@
+package alerts
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+)
+
+// countingSource cuenta las consultas del historial de las ventanas.
+type countingSource struct {
+	*repository.MemoryStockRepository
+	windowQueries int
+}
+
+func (s *countingSource) GetTickersEventsBetween(ctx context.Context, tickers []string, startDate, endDate time.Time, perTicker int) ([]models.StockEvent, error) {
+	s.windowQueries++
+	return s.MemoryStockRepository.GetTickersEventsBetween(ctx, tickers, startDate, endDate, perTicker)
+}
+
+// memoryStore guarda las reglas, las alertas y la posición en memoria. Como la
+// base de datos, ignora las alertas repetidas de una regla y un evento.
+type memoryStore struct {
+	rules  []models.AlertRule
+	alerts []models.Alert
+	cursor *int64
+}
+
+func (s *memoryStore) ListEnabledAlertRules(context.Context) ([]models.AlertRule, error) {
+	return s.rules, nil
+}
+
+func (s *memoryStore) SaveAlerts(_ context.Context, alerts []models.Alert) (int, error) {
+	saved := 0
+	for _, alert := range alerts {
+		if !slices.ContainsFunc(s.alerts, func(a models.Alert) bool {
+			return a.RuleID == alert.RuleID && a.EventID == alert.EventID
+		}) {
+			s.alerts = append(s.alerts, alert)
+			saved++
+		}
+	}
+	return saved, nil
+}
+
+func (s *memoryStore) GetAlertCursor(context.Context) (int64, bool, error) {
+	if s.cursor == nil {
+		return 0, false, nil
+	}
+	return *s.cursor, true, nil
+}
+
+func (s *memoryStore) SaveAlertCursor(_ context.Context, eventID int64) error {
+	s.cursor = &eventID
+	return nil
+}
+
+// newTestEngine crea un motor con un reloj controlado por la prueba.
+func newTestEngine(source EventSource, store Store) (*Engine, *time.Time) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	engine := NewEngine(source, store, ratings, time.Minute, 30*time.Second)
+	engine.now = func() time.Time { return now }
+	return engine, &now
+}
+
+// rating crea una calificación de prueba del día indicado de marzo de 2024.
+func rating(ticker, action string, day int) models.Stock {
+	return models.Stock{
+		Ticker:     ticker,
+		Action:     action,
+		Brokerage:  "Barclays",
+		RatingFrom: "Hold",
+		RatingTo:   "Buy",
+		Time:       time.Date(2024, 3, day, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+// triggered resume las alertas como ticker@día=count_in_window, ordenadas.
+func triggered(alerts []models.Alert) []string {
+	var out []string
+	for _, alert := range alerts {
+		label := alert.Stock.Ticker + "@" + alert.Stock.Time.Format("02")
+		if count, ok := alert.Values[ValueCountInWindow]; ok {
+			label += "=" + strconv.FormatFloat(count, 'f', -1, 64)
+		}
+		out = append(out, label)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestEngineCountsWindowsWithOneQueryPerPage(t *testing.T) {
+	repo := repository.NewMemoryStockRepository()
+	source := &countingSource{MemoryStockRepository: repo}
+	store := &memoryStore{rules: []models.AlertRule{
+		{
+			ID:         1,
+			Name:       "mejoras repetidas",
+			Conditions: []models.AlertCondition{cond("action", "eq", "upgraded by")},
+			Window:     &models.AlertWindow{Duration: "7d", MinCount: 2},
+		},
+		{
+			ID:         2,
+			Name:       "cualquier rebaja",
+			Conditions: []models.AlertCondition{cond("action", "eq", "downgraded by")},
+		},
+		{
+			ID:         3,
+			Name:       "inválida",
+			Conditions: []models.AlertCondition{cond("price", "eq", 1.0)},
+		},
+	}}
+	engine, now := newTestEngine(source, store)
+	ctx := context.Background()
+
+	// Una mejora anterior al motor cuenta para la ventana pero no se evalúa
+	repo.SaveStocks([]models.Stock{rating("NVDA", "upgraded by", 1)})
+	if n, err := engine.Evaluate(ctx); err != nil || n != 0 {
+		t.Fatalf("Evaluate() inicial = %d, %v; want 0", n, err)
+	}
+
+	repo.SaveStocks([]models.Stock{
+		rating("NVDA", "upgraded by", 3),
+		rating("NVDA", "upgraded by", 20),
+		rating("AAPL", "upgraded by", 4),
+		rating("AAPL", "upgraded by", 5),
+		rating("TSLA", "upgraded by", 5),
+		rating("MSFT", "downgraded by", 6),
+	})
+	n, err := engine.Evaluate(ctx)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+
+	// La mejora de NVDA del día 20 queda fuera de la ventana de la del día 3
+	want := []string{"AAPL@05=2", "MSFT@06", "NVDA@03=2"}
+	if got := triggered(store.alerts); n != len(want) || !reflect.DeepEqual(got, want) {
+		t.Errorf("alertas = %v (%d), want %v", got, n, want)
+	}
+	if source.windowQueries != 1 {
+		t.Errorf("consultas de ventanas = %d, want 1 por página", source.windowQueries)
+	}
+
+	// Sin eventos nuevos no se consulta el historial una vez asentada la lectura
+	*now = now.Add(time.Minute)
+	if n, err := engine.Evaluate(ctx); err != nil || n != 0 || source.windowQueries != 1 {
+		t.Errorf("Evaluate() sin eventos = %d, %v, %d consultas; want 0 y sin consultas nuevas", n, err, source.windowQueries)
+	}
+}
+
+func TestEngineSkipsHistoryWithoutWindows(t *testing.T) {
+	repo := repository.NewMemoryStockRepository()
+	source := &countingSource{MemoryStockRepository: repo}
+	store := &memoryStore{rules: []models.AlertRule{{
+		ID:         1,
+		Conditions: []models.AlertCondition{cond("rating_delta", "gt", 0.0)},
+	}}}
+	engine, _ := newTestEngine(source, store)
+	ctx := context.Background()
+
+	if _, err := engine.Evaluate(ctx); err != nil {
+		t.Fatalf("Evaluate() inicial error = %v", err)
+	}
+	repo.SaveStocks([]models.Stock{rating("NVDA", "upgraded by", 1), rating("AAPL", "upgraded by", 2)})
+	if n, err := engine.Evaluate(ctx); err != nil || n != 2 {
+		t.Fatalf("Evaluate() = %d, %v; want 2", n, err)
+	}
+	if source.windowQueries != 0 {
+		t.Errorf("consultas de ventanas = %d, want 0", source.windowQueries)
+	}
+	if _, ok := store.alerts[0].Values[ValueCountInWindow]; ok {
+		t.Errorf("Values = %v, want sin count_in_window", store.alerts[0].Values)
+	}
+}
+
+// lateSource oculta los eventos aún no confirmados.
+type lateSource struct {
+	*repository.MemoryStockRepository
+	pending map[int64]bool
+}
+
+func (s *lateSource) GetStockEventsAfter(ctx context.Context, afterID int64, limit int) ([]models.StockEvent, error) {
+	events, err := s.MemoryStockRepository.GetStockEventsAfter(ctx, afterID, limit)
+	return slices.DeleteFunc(events, func(event models.StockEvent) bool { return s.pending[event.ID] }), err
+}
+
+func TestEngineEvaluatesEventsCommittedOutOfOrder(t *testing.T) {
+	repo := repository.NewMemoryStockRepository()
+	source := &lateSource{MemoryStockRepository: repo, pending: map[int64]bool{}}
+	store := &memoryStore{rules: []models.AlertRule{{
+		ID:         1,
+		Conditions: []models.AlertCondition{cond("action", "eq", "upgraded by")},
+	}}}
+	engine, now := newTestEngine(source, store)
+	ctx := context.Background()
+
+	if _, err := engine.Evaluate(ctx); err != nil {
+		t.Fatalf("Evaluate() inicial error = %v", err)
+	}
+	start := *store.cursor
+
+	// El evento del día 2 recibe un identificador menor pero se confirma después
+	repo.SaveStocks([]models.Stock{rating("NVDA", "upgraded by", 2), rating("AAPL", "upgraded by", 3)})
+	events, _ := repo.GetStockEventsAfter(ctx, start, 10)
+	source.pending[events[0].ID] = true
+
+	if n, err := engine.Evaluate(ctx); err != nil || n != 1 {
+		t.Fatalf("Evaluate() = %d, %v; want 1", n, err)
+	}
+	if *store.cursor != start {
+		t.Errorf("posición = %d, want %d dentro de la ventana", *store.cursor, start)
+	}
+
+	// La siguiente evaluación vuelve a leer la ventana e incluye el evento tardío
+	*now = now.Add(10 * time.Second)
+	delete(source.pending, events[0].ID)
+	if n, err := engine.Evaluate(ctx); err != nil || n != 1 {
+		t.Fatalf("Evaluate() tras confirmar = %d, %v; want 1", n, err)
+	}
+	if got, want := triggered(store.alerts), []string{"AAPL@03", "NVDA@02"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("alertas = %v, want %v", got, want)
+	}
+
+	// Pasada la ventana, la posición avanza hasta el último evento leído
+	*now = now.Add(time.Minute)
+	if n, err := engine.Evaluate(ctx); err != nil || n != 0 {
+		t.Fatalf("Evaluate() sin eventos = %d, %v; want 0", n, err)
+	}
+	if last := events[len(events)-1].ID; *store.cursor != last {
+		t.Errorf("posición = %d, want %d", *store.cursor, last)
+	}
+}
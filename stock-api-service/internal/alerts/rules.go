@@ -0,0 +1,302 @@
+// Paquete alerts evalúa las reglas de alerta de los usuarios sobre las
+// calificaciones nuevas y guarda las alertas que se disparan.
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/algorithm"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// Campos derivados y valores calculados al evaluar una regla.
+const (
+	// Variación porcentual entre el precio objetivo anterior y el actual
+	FieldTargetChangePct = "target_change_pct"
+	// Diferencia entre el valor de la calificación actual y el de la anterior
+	FieldRatingDelta = "rating_delta"
+	// Calificaciones del ticker que cumplen la regla dentro del periodo
+	ValueCountInWindow = "count_in_window"
+)
+
+// Límites de una regla.
+const (
+	// Cantidad máxima de condiciones
+	maxConditions = 20
+	// Cantidad máxima de valores de un operador in
+	maxInValues = 100
+	// Periodo máximo de una ventana
+	maxWindow = 90 * 24 * time.Hour
+	// Cantidad mínima máxima de una ventana
+	maxMinCount = 100
+)
+
+// ErrInvalidRule indica que la definición de una regla no es válida.
+var ErrInvalidRule = errors.New("regla de alerta inválida")
+
+// RatingScale asigna un valor numérico a cada calificación.
+type RatingScale interface {
+	RatingValue(rating string) (float64, bool)
+}
+
+// textFields son los campos de texto de models.Stock que admite una regla.
+var textFields = map[string]func(models.Stock) string{
+	"ticker":            func(s models.Stock) string { return s.Ticker },
+	"company":           func(s models.Stock) string { return s.Company },
+	"action":            func(s models.Stock) string { return s.Action },
+	"brokerage":         func(s models.Stock) string { return s.Brokerage },
+	"rating_from":       func(s models.Stock) string { return s.RatingFrom },
+	"rating_to":         func(s models.Stock) string { return s.RatingTo },
+	"sector":            func(s models.Stock) string { return s.Sector },
+	"industry":          func(s models.Stock) string { return s.Industry },
+	"exchange":          func(s models.Stock) string { return s.Exchange },
+	"market_cap_bucket": func(s models.Stock) string { return s.MarketCapBucket },
+}
+
+// numberFields son los campos numéricos que admite una regla, incluidos los derivados.
+var numberFields = map[string]bool{
+	"target_from":        true,
+	"target_to":          true,
+	FieldTargetChangePct: true,
+	FieldRatingDelta:     true,
+}
+
+// Operadores admitidos por tipo de campo.
+var (
+	textOps   = map[string]bool{"eq": true, "ne": true, "in": true, "contains": true}
+	numberOps = map[string]bool{"eq": true, "ne": true, "gt": true, "gte": true, "lt": true, "lte": true}
+)
+
+// condition es una condición ya validada.
+type condition struct {
+	field string
+	op    string
+	// Valor para campos de texto, en minúsculas
+	text string
+	// Valores del operador in, en minúsculas
+	texts []string
+	// Valor para campos numéricos
+	number float64
+}
+
+// Rule es una regla validada, lista para evaluarse.
+type Rule struct {
+	// Regla original
+	models.AlertRule
+
+	conditions []condition
+	window     time.Duration
+	minCount   int
+}
+
+// Compile valida una regla y la prepara para evaluarse. Los errores envuelven
+// ErrInvalidRule e indican qué parte de la regla es inválida.
+func Compile(rule models.AlertRule) (*Rule, error) {
+	if len(rule.Conditions) == 0 {
+		return nil, fmt.Errorf("%w: debe tener al menos una condición", ErrInvalidRule)
+	}
+	if len(rule.Conditions) > maxConditions {
+		return nil, fmt.Errorf("%w: admite como máximo %d condiciones", ErrInvalidRule, maxConditions)
+	}
+
+	compiled := &Rule{AlertRule: rule}
+	for i, c := range rule.Conditions {
+		cond, err := compileCondition(c)
+		if err != nil {
+			return nil, fmt.Errorf("%w: condición %d: %v", ErrInvalidRule, i+1, err)
+		}
+		compiled.conditions = append(compiled.conditions, cond)
+	}
+
+	if rule.Window != nil {
+		window, err := ParseWindow(rule.Window.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("%w: window.duration: %v", ErrInvalidRule, err)
+		}
+		if rule.Window.MinCount < 1 || rule.Window.MinCount > maxMinCount {
+			return nil, fmt.Errorf("%w: window.min_count debe estar entre 1 y %d", ErrInvalidRule, maxMinCount)
+		}
+		compiled.window = window
+		compiled.minCount = rule.Window.MinCount
+	}
+
+	return compiled, nil
+}
+
+// compileCondition valida el campo, el operador y el tipo del valor de una condición.
+func compileCondition(c models.AlertCondition) (condition, error) {
+	cond := condition{field: strings.ToLower(strings.TrimSpace(c.Field)), op: strings.ToLower(strings.TrimSpace(c.Op))}
+
+	if _, ok := textFields[cond.field]; ok {
+		if !textOps[cond.op] {
+			return cond, fmt.Errorf("operador %q no admitido para %s (use eq, ne, in o contains)", c.Op, cond.field)
+		}
+		if cond.op == "in" {
+			values, ok := c.Value.([]any)
+			if !ok || len(values) == 0 || len(values) > maxInValues {
+				return cond, fmt.Errorf("in requiere una lista de 1 a %d textos", maxInValues)
+			}
+			for _, value := range values {
+				text, ok := value.(string)
+				if !ok {
+					return cond, fmt.Errorf("in requiere una lista de textos")
+				}
+				cond.texts = append(cond.texts, strings.ToLower(text))
+			}
+			return cond, nil
+		}
+		text, ok := c.Value.(string)
+		if !ok || text == "" {
+			return cond, fmt.Errorf("%s requiere un texto no vacío", cond.field)
+		}
+		cond.text = strings.ToLower(text)
+		return cond, nil
+	}
+
+	if numberFields[cond.field] {
+		if !numberOps[cond.op] {
+			return cond, fmt.Errorf("operador %q no admitido para %s (use eq, ne, gt, gte, lt o lte)", c.Op, cond.field)
+		}
+		number, ok := c.Value.(float64)
+		if !ok {
+			return cond, fmt.Errorf("%s requiere un número", cond.field)
+		}
+		cond.number = number
+		return cond, nil
+	}
+
+	return cond, fmt.Errorf("campo desconocido %q (use %s)", c.Field, strings.Join(Fields(), ", "))
+}
+
+// Fields devuelve los campos admitidos por las reglas en orden alfabético.
+func Fields() []string {
+	fields := make([]string, 0, len(textFields)+len(numberFields))
+	for field := range textFields {
+		fields = append(fields, field)
+	}
+	for field := range numberFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// ParseWindow interpreta la duración de una ventana. Admite días (7d) y el
+// formato de time.ParseDuration (36h, 90m).
+func ParseWindow(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	var window time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("duración inválida %q", value)
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("duración inválida %q", value)
+		}
+		window = d
+	}
+
+	if window < time.Minute || window > maxWindow {
+		return 0, fmt.Errorf("la duración debe estar entre 1m y 90d")
+	}
+	return window, nil
+}
+
+// Window devuelve el periodo de la regla y la cantidad mínima de calificaciones,
+// o 0 si la regla no tiene ventana.
+func (r *Rule) Window() (time.Duration, int) {
+	return r.window, r.minCount
+}
+
+// Match indica si una calificación cumple todas las condiciones de la regla y
+// devuelve los valores derivados que se pudieron calcular. Una condición sobre
+// un valor derivado desconocido, como el cambio de precio objetivo sin precio
+// anterior, no se cumple.
+func (r *Rule) Match(stock models.Stock, ratings RatingScale) (map[string]float64, bool) {
+	numbers := Derive(stock, ratings)
+	for _, cond := range r.conditions {
+		if !cond.matches(stock, numbers) {
+			return numbers, false
+		}
+	}
+	return numbers, true
+}
+
+// Derive calcula los campos numéricos de una calificación. Omite los que no se
+// pueden calcular.
+func Derive(stock models.Stock, ratings RatingScale) map[string]float64 {
+	numbers := map[string]float64{}
+
+	from := algorithm.ExtractPrice(stock.TargetFrom)
+	to := algorithm.ExtractPrice(stock.TargetTo)
+	if from > 0 {
+		numbers["target_from"] = from
+	}
+	if to > 0 {
+		numbers["target_to"] = to
+	}
+	if from > 0 && to > 0 {
+		numbers[FieldTargetChangePct] = (to - from) / from * 100
+	}
+
+	if ratings != nil {
+		ratingFrom, okFrom := ratings.RatingValue(stock.RatingFrom)
+		ratingTo, okTo := ratings.RatingValue(stock.RatingTo)
+		if okFrom && okTo {
+			numbers[FieldRatingDelta] = ratingTo - ratingFrom
+		}
+	}
+
+	return numbers
+}
+
+// matches evalúa la condición sobre una calificación y sus campos numéricos.
+func (c condition) matches(stock models.Stock, numbers map[string]float64) bool {
+	if field, ok := textFields[c.field]; ok {
+		value := strings.ToLower(field(stock))
+		switch c.op {
+		case "eq":
+			return value == c.text
+		case "ne":
+			return value != c.text
+		case "contains":
+			return strings.Contains(value, c.text)
+		case "in":
+			for _, text := range c.texts {
+				if value == text {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	value, ok := numbers[c.field]
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "eq":
+		return value == c.number
+	case "ne":
+		return value != c.number
+	case "gt":
+		return value > c.number
+	case "gte":
+		return value >= c.number
+	case "lt":
+		return value < c.number
+	case "lte":
+		return value <= c.number
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package alerts
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// testScale asigna un valor a las calificaciones de las pruebas.
+type testScale map[string]float64
+
+func (s testScale) RatingValue(rating string) (float64, bool) {
+	value, ok := s[rating]
+	return value, ok
+}
+
+var ratings = testScale{"Sell": 1, "Hold": 2, "Buy": 3}
+
+// cond crea una condición de prueba.
+func cond(field, op string, value any) models.AlertCondition {
+	return models.AlertCondition{Field: field, Op: op, Value: value}
+}
+
+func TestCompileErrors(t *testing.T) {
+	many := make([]models.AlertCondition, maxConditions+1)
+	for i := range many {
+		many[i] = cond("ticker", "eq", "NVDA")
+	}
+	tooManyValues := make([]any, maxInValues+1)
+	for i := range tooManyValues {
+		tooManyValues[i] = "NVDA"
+	}
+
+	tests := []struct {
+		name    string
+		rule    models.AlertRule
+		wantErr string
+	}{
+		{"sin condiciones", models.AlertRule{}, "al menos una condición"},
+		{"demasiadas condiciones", models.AlertRule{Conditions: many}, "como máximo"},
+		{"campo desconocido", models.AlertRule{Conditions: []models.AlertCondition{cond("price", "eq", 1.0)}}, `condición 1: campo desconocido "price"`},
+		{"operador numérico en texto", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "gt", "NVDA")}}, `operador "gt" no admitido para ticker`},
+		{"operador de texto en número", models.AlertRule{Conditions: []models.AlertCondition{cond("target_to", "contains", 1.0)}}, `operador "contains" no admitido para target_to`},
+		{"texto vacío", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "eq", "")}}, "requiere un texto no vacío"},
+		{"número en campo de texto", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "eq", 1.0)}}, "requiere un texto no vacío"},
+		{"texto en campo numérico", models.AlertRule{Conditions: []models.AlertCondition{cond("target_change_pct", "gte", "10")}}, "requiere un número"},
+		{"in sin lista", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "in", "NVDA")}}, "in requiere una lista"},
+		{"in vacío", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "in", []any{})}}, "in requiere una lista"},
+		{"in con números", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "in", []any{"NVDA", 1.0})}}, "lista de textos"},
+		{"in demasiado largo", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "in", tooManyValues)}}, "in requiere una lista"},
+		{"condición posterior", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "eq", "NVDA"), cond("rating_delta", "gt", true)}}, "condición 2"},
+		{"duración inválida", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "eq", "NVDA")}, Window: &models.AlertWindow{Duration: "una semana", MinCount: 2}}, "window.duration"},
+		{"ventana demasiado corta", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "eq", "NVDA")}, Window: &models.AlertWindow{Duration: "30s", MinCount: 2}}, "entre 1m y 90d"},
+		{"ventana demasiado larga", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "eq", "NVDA")}, Window: &models.AlertWindow{Duration: "91d", MinCount: 2}}, "entre 1m y 90d"},
+		{"cantidad mínima cero", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "eq", "NVDA")}, Window: &models.AlertWindow{Duration: "7d"}}, "window.min_count"},
+		{"cantidad mínima excesiva", models.AlertRule{Conditions: []models.AlertCondition{cond("ticker", "eq", "NVDA")}, Window: &models.AlertWindow{Duration: "7d", MinCount: maxMinCount + 1}}, "window.min_count"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.rule)
+			if !errors.Is(err, ErrInvalidRule) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Compile() error = %v, want ErrInvalidRule con %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileWindow(t *testing.T) {
+	tests := []struct {
+		duration string
+		want     time.Duration
+	}{
+		{"7d", 7 * 24 * time.Hour},
+		{" 36h ", 36 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"90d", 90 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		rule, err := Compile(models.AlertRule{
+			Conditions: []models.AlertCondition{cond("ticker", "eq", "NVDA")},
+			Window:     &models.AlertWindow{Duration: tt.duration, MinCount: 3},
+		})
+		if err != nil {
+			t.Fatalf("Compile(%q) error = %v", tt.duration, err)
+		}
+		if window, minCount := rule.Window(); window != tt.want || minCount != 3 {
+			t.Errorf("Window() con %q = %v, %d; want %v, 3", tt.duration, window, minCount, tt.want)
+		}
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	stock := models.Stock{
+		Ticker:     "NVDA",
+		Company:    "NVIDIA Corporation",
+		Action:     "upgraded by",
+		Brokerage:  "The Goldman Sachs Group",
+		RatingFrom: "Hold",
+		RatingTo:   "Buy",
+		TargetFrom: "$140.00",
+		TargetTo:   "$161.00",
+		Sector:     "Technology",
+	}
+	noTarget := stock
+	noTarget.TargetFrom = ""
+	unknownRating := stock
+	unknownRating.RatingFrom = "Market Perform"
+
+	tests := []struct {
+		name       string
+		conditions []models.AlertCondition
+		stock      models.Stock
+		want       bool
+	}{
+		{"eq sin mayúsculas", []models.AlertCondition{cond("ticker", "eq", "nvda")}, stock, true},
+		{"eq distinto", []models.AlertCondition{cond("ticker", "eq", "AAPL")}, stock, false},
+		{"ne", []models.AlertCondition{cond("sector", "ne", "Energy")}, stock, true},
+		{"contains", []models.AlertCondition{cond("brokerage", "contains", "GOLDMAN")}, stock, true},
+		{"in", []models.AlertCondition{cond("action", "in", []any{"downgraded by", "Upgraded By"})}, stock, true},
+		{"in sin coincidencia", []models.AlertCondition{cond("action", "in", []any{"downgraded by"})}, stock, false},
+		{"campo de texto vacío", []models.AlertCondition{cond("industry", "eq", "Semiconductors")}, stock, false},
+		{"target_to gt", []models.AlertCondition{cond("target_to", "gt", 160.0)}, stock, true},
+		{"cambio de precio objetivo gte", []models.AlertCondition{cond("target_change_pct", "gte", 15.0)}, stock, true},
+		{"cambio de precio objetivo lt", []models.AlertCondition{cond("target_change_pct", "lt", 15.0)}, stock, false},
+		{"cambio sin precio anterior", []models.AlertCondition{cond("target_change_pct", "gte", 0.0)}, noTarget, false},
+		{"rating_delta eq", []models.AlertCondition{cond("rating_delta", "eq", 1.0)}, stock, true},
+		{"rating_delta con calificación desconocida", []models.AlertCondition{cond("rating_delta", "gte", 0.0)}, unknownRating, false},
+		{"todas las condiciones", []models.AlertCondition{cond("ticker", "eq", "NVDA"), cond("rating_delta", "gt", 0.0), cond("target_to", "lte", 161.0)}, stock, true},
+		{"una condición falla", []models.AlertCondition{cond("ticker", "eq", "NVDA"), cond("rating_delta", "lt", 0.0)}, stock, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Compile(models.AlertRule{Conditions: tt.conditions})
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if _, got := rule.Match(tt.stock, ratings); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDerive(t *testing.T) {
+	values := Derive(models.Stock{TargetFrom: "$100.00", TargetTo: "$125.00", RatingFrom: "Buy", RatingTo: "Sell"}, ratings)
+	want := map[string]float64{"target_from": 100, "target_to": 125, FieldTargetChangePct: 25, FieldRatingDelta: -2}
+	for field, value := range want {
+		if values[field] != value {
+			t.Errorf("Derive()[%s] = %v, want %v", field, values[field], value)
+		}
+	}
+
+	// Sin escala no se calcula rating_delta
+	if _, ok := Derive(models.Stock{RatingFrom: "Hold", RatingTo: "Buy"}, nil)[FieldRatingDelta]; ok {
+		t.Error("Derive() sin escala calculó rating_delta")
+	}
+}
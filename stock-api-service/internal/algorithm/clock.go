@@ -0,0 +1,24 @@
+package algorithm
+
+import "time"
+
+// Clock proporciona el instante que el recomendador usa como referencia de antigüedad.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock es un reloj que devuelve la hora actual del sistema.
+type SystemClock struct{}
+
+// Now devuelve la hora actual.
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock es un reloj detenido en un instante concreto.
+type FixedClock time.Time
+
+// Now devuelve el instante fijo del reloj.
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
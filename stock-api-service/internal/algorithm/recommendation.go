@@ -11,13 +11,59 @@ import (
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
 )
 
+// Strategy define los pesos que el recomendador asigna a cada componente del score.
+type Strategy struct {
+	// Nombre de la estrategia
+	Name string `json:"name"`
+	// Peso del cambio de calificación
+	RatingWeight float64 `json:"rating_weight"`
+	// Peso del cambio en el precio objetivo
+	TargetWeight float64 `json:"target_weight"`
+	// Peso de lo reciente que es la actualización
+	RecencyWeight float64 `json:"recency_weight"`
+}
+
+// DefaultStrategy es la estrategia utilizada por el endpoint de recomendaciones.
+var DefaultStrategy = Strategy{Name: "balanced", RatingWeight: 0.4, TargetWeight: 0.4, RecencyWeight: 0.2}
+
+// Strategies devuelve las estrategias disponibles para generar recomendaciones.
+func Strategies() []Strategy {
+	return []Strategy{
+		DefaultStrategy,
+		{Name: "rating_focus", RatingWeight: 0.7, TargetWeight: 0.2, RecencyWeight: 0.1},
+		{Name: "target_focus", RatingWeight: 0.2, TargetWeight: 0.7, RecencyWeight: 0.1},
+		{Name: "momentum", RatingWeight: 0.3, TargetWeight: 0.3, RecencyWeight: 0.4},
+	}
+}
+
+// StrategyByName busca una estrategia por su nombre.
+func StrategyByName(name string) (Strategy, bool) {
+	for _, strategy := range Strategies() {
+		if strategy.Name == name {
+			return strategy, true
+		}
+	}
+	return Strategy{}, false
+}
+
 // StockRecommender implementa el algoritmo de recomendación de stocks.
 type StockRecommender struct {
 	ratingValues map[string]float64
+	strategy     Strategy
+	clock        Clock
+	maxPerSector int
+	// Tickers cuya puntuación se multiplica por boostFactor
+	boosted     map[string]bool
+	boostFactor float64
 }
 
 // NewStockRecommender crea una nueva instancia del recomendador de stocks.
 func NewStockRecommender() *StockRecommender {
+	return NewStockRecommenderWithStrategy(DefaultStrategy)
+}
+
+// NewStockRecommenderWithStrategy crea un recomendador que pondera el score según la estrategia indicada.
+func NewStockRecommenderWithStrategy(strategy Strategy) *StockRecommender {
 	return &StockRecommender{
 		ratingValues: map[string]float64{
 			"Strong Buy":     5.0,
@@ -33,15 +79,78 @@ func NewStockRecommender() *StockRecommender {
 			"Sell":           1.0,
 			"Strong Sell":    0.5,
 		},
+		strategy: strategy,
+		clock:    SystemClock{},
 	}
 }
 
+// WithClock devuelve una copia del recomendador que usa el reloj indicado como
+// referencia para calcular la antigüedad de las actualizaciones.
+func (r *StockRecommender) WithClock(clock Clock) *StockRecommender {
+	clone := *r
+	clone.clock = clock
+	return &clone
+}
+
+// WithMaxPerSector devuelve una copia del recomendador que limita cuántas
+// recomendaciones de un mismo sector se incluyen. Cero desactiva el límite.
+// Los stocks sin sector conocido no se limitan.
+func (r *StockRecommender) WithMaxPerSector(maxPerSector int) *StockRecommender {
+	clone := *r
+	clone.maxPerSector = maxPerSector
+	return &clone
+}
+
+// WithBoost devuelve una copia del recomendador que multiplica por factor la
+// puntuación de los tickers indicados, por ejemplo los de una lista de seguimiento.
+func (r *StockRecommender) WithBoost(tickers []string, factor float64) *StockRecommender {
+	clone := *r
+	clone.boosted = make(map[string]bool, len(tickers))
+	for _, ticker := range tickers {
+		clone.boosted[ticker] = true
+	}
+	clone.boostFactor = factor
+	return &clone
+}
+
+// MaxPerSector devuelve el límite de recomendaciones por sector (cero si no hay límite).
+func (r *StockRecommender) MaxPerSector() int {
+	return r.maxPerSector
+}
+
+// Now devuelve el instante de referencia del recomendador.
+func (r *StockRecommender) Now() time.Time {
+	return r.clock.Now()
+}
+
+// Strategy devuelve la estrategia utilizada por el recomendador.
+func (r *StockRecommender) Strategy() Strategy {
+	return r.strategy
+}
+
+// RatingValue devuelve el valor numérico asociado a una calificación.
+func (r *StockRecommender) RatingValue(rating string) (float64, bool) {
+	value, exists := r.ratingValues[rating]
+	return value, exists
+}
+
 // GenerateRecommendations genera recomendaciones basadas en los stocks más recientes.
 func (r *StockRecommender) GenerateRecommendations(stocks []models.Stock, limit int) []models.RecommendationResult {
+	return r.GenerateRecommendationsAt(stocks, limit, r.clock.Now())
+}
+
+// GenerateRecommendationsAt genera recomendaciones tal como se habrían calculado en el
+// instante asOf. Los stocks posteriores a asOf se ignoran y la antigüedad de cada
+// actualización se mide respecto a ese instante.
+func (r *StockRecommender) GenerateRecommendationsAt(stocks []models.Stock, limit int, asOf time.Time) []models.RecommendationResult {
 	// Paso 1: Agrupar stocks por ticker y quedarnos con la actualización más reciente
 	latestStocks := make(map[string]models.Stock)
 
 	for _, stock := range stocks {
+		if stock.Time.After(asOf) {
+			continue
+		}
+
 		existing, exists := latestStocks[stock.Ticker]
 		if !exists || stock.Time.After(existing.Time) {
 			latestStocks[stock.Ticker] = stock
@@ -85,10 +194,15 @@ func (r *StockRecommender) GenerateRecommendations(stocks []models.Stock, limit
 		}
 
 		// Calcular score (máximo para actualizaciones del último día)
-		daysAgo := time.Since(stock.Time).Hours() / 24
+		daysAgo := asOf.Sub(stock.Time).Hours() / 24
 		recencyScore := 100 * math.Exp(-daysAgo/7) // Decaimiento exponencial (una semana -> 36%)
 
-		finalScore := (ratingScore * 0.4) + (priceScore * 0.4) + (recencyScore * 0.2)
+		finalScore := (ratingScore * r.strategy.RatingWeight) +
+			(priceScore * r.strategy.TargetWeight) +
+			(recencyScore * r.strategy.RecencyWeight)
+		if r.boosted[stock.Ticker] {
+			finalScore *= r.boostFactor
+		}
 
 		// Solo incluir stocks con mejoras positivas
 		if ratingChange > 0 || (toPrice > fromPrice && fromPrice > 0) {
@@ -102,12 +216,22 @@ func (r *StockRecommender) GenerateRecommendations(stocks []models.Stock, limit
 		}
 	}
 
-	// Paso 3: Ordenar resultados por puntuación
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
+	// Paso 3: Ordenar resultados por puntuación. Los stocks salen de un mapa en
+	// orden aleatorio, así que los empates se resuelven por ticker para que el
+	// ranking sea el mismo en cada cálculo
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Stock.Ticker < results[j].Stock.Ticker
 	})
 
-	// Paso 4: Limitar resultados
+	// Paso 4: Diversificar por sector
+	if r.maxPerSector > 0 {
+		results = r.diversify(results)
+	}
+
+	// Paso 5: Limitar resultados
 	if limit > 0 && len(results) > limit {
 		results = results[:limit]
 	}
@@ -115,6 +239,26 @@ func (r *StockRecommender) GenerateRecommendations(stocks []models.Stock, limit
 	return results
 }
 
+// diversify descarta, en orden de puntuación, las recomendaciones que superan el
+// límite de su sector.
+func (r *StockRecommender) diversify(results []models.RecommendationResult) []models.RecommendationResult {
+	perSector := make(map[string]int)
+	diversified := results[:0]
+
+	for _, result := range results {
+		sector := result.Stock.Sector
+		if sector != "" {
+			if perSector[sector] >= r.maxPerSector {
+				continue
+			}
+			perSector[sector]++
+		}
+		diversified = append(diversified, result)
+	}
+
+	return diversified
+}
+
 // generateRationale genera una explicación de la recomendación.
 func (r *StockRecommender) generateRationale(stock models.Stock, ratingChange, fromPrice, toPrice, daysAgo float64) string {
 	var reasons []string
@@ -185,6 +329,12 @@ func (r *StockRecommender) calculatePotentialReturn(fromPrice, toPrice float64)
 
 // extractPrice extrae el valor numérico de una cadena de precio.
 func (r *StockRecommender) extractPrice(priceStr string) float64 {
+	return ExtractPrice(priceStr)
+}
+
+// ExtractPrice extrae el valor numérico de una cadena de precio con formato "$123.45".
+// Devuelve 0 si la cadena no tiene un formato válido.
+func ExtractPrice(priceStr string) float64 {
 	priceStr = strings.TrimSpace(priceStr)
 
 	if len(priceStr) > 0 && priceStr[0] == '$' {
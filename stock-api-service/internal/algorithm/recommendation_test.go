@@ -0,0 +1,40 @@
+package algorithm
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+func TestGenerateRecommendationsBreaksTiesByTicker(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	recommender := NewStockRecommender().WithClock(FixedClock(now))
+
+	// Mismo cambio de calificación, precio objetivo y fecha: todos empatan
+	tickers := []string{"MSFT", "AAPL", "NVDA", "AMZN", "GOOG", "META"}
+	var stocks []models.Stock
+	for _, ticker := range tickers {
+		stocks = append(stocks, models.Stock{
+			Ticker:     ticker,
+			RatingFrom: "Hold",
+			RatingTo:   "Buy",
+			TargetFrom: "$100.00",
+			TargetTo:   "$120.00",
+			Time:       now.Add(-time.Hour),
+		})
+	}
+
+	want := slices.Clone(tickers)
+	slices.Sort(want)
+	for range 20 {
+		var got []string
+		for _, result := range recommender.GenerateRecommendations(stocks, 0) {
+			got = append(got, result.Stock.Ticker)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("ranking = %v, want %v", got, want)
+		}
+	}
+}
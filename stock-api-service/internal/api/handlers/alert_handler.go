@@ -0,0 +1,255 @@
+// Paquete handlers contiene los manejadores de solicitudes HTTP.
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/alerts"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/auth"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// AlertRuleRequest representa la solicitud para crear o reemplazar una regla de alerta.
+type AlertRuleRequest struct {
+	// Nombre de la regla, único por principal
+	Name string `json:"name" binding:"required"`
+	// Indica si la regla se evalúa; por defecto true
+	Enabled *bool `json:"enabled"`
+	// Condiciones que deben cumplirse todas
+	Conditions []models.AlertCondition `json:"conditions" binding:"required"`
+	// Periodo y cantidad mínima de calificaciones, opcional
+	Window *models.AlertWindow `json:"window"`
+}
+
+// AlertHandler maneja las reglas de alerta y las alertas del principal autenticado.
+type AlertHandler struct {
+	alerts *repository.AlertRepository
+}
+
+// NewAlertHandler crea una nueva instancia de AlertHandler.
+func NewAlertHandler(alerts *repository.AlertRepository) *AlertHandler {
+	return &AlertHandler{
+		alerts: alerts,
+	}
+}
+
+// CreateAlertRule crea una regla de alerta. La regla se evalúa sobre las
+// calificaciones que lleguen a partir de ese momento.
+func (h *AlertHandler) CreateAlertRule(c *gin.Context) {
+	rule, ok := bindAlertRule(c)
+	if !ok {
+		return
+	}
+
+	if err := h.alerts.CreateAlertRule(c.Request.Context(), &rule); err != nil {
+		respondAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// ListAlertRules lista las reglas de alerta del principal.
+func (h *AlertHandler) ListAlertRules(c *gin.Context) {
+	rules, err := h.alerts.ListAlertRules(c.Request.Context(), alertOwner(c))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+		"count": len(rules),
+	})
+}
+
+// GetAlertRule devuelve una regla de alerta del principal.
+func (h *AlertHandler) GetAlertRule(c *gin.Context) {
+	id, ok := parseAlertID(c, "regla")
+	if !ok {
+		return
+	}
+
+	rule, err := h.alerts.GetAlertRule(c.Request.Context(), alertOwner(c), id)
+	if err != nil {
+		respondAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// UpdateAlertRule reemplaza una regla de alerta.
+func (h *AlertHandler) UpdateAlertRule(c *gin.Context) {
+	id, ok := parseAlertID(c, "regla")
+	if !ok {
+		return
+	}
+	rule, ok := bindAlertRule(c)
+	if !ok {
+		return
+	}
+	rule.ID = id
+
+	if err := h.alerts.UpdateAlertRule(c.Request.Context(), &rule); err != nil {
+		respondAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteAlertRule elimina una regla de alerta junto con sus alertas.
+func (h *AlertHandler) DeleteAlertRule(c *gin.Context) {
+	id, ok := parseAlertID(c, "regla")
+	if !ok {
+		return
+	}
+
+	if err := h.alerts.DeleteAlertRule(c.Request.Context(), alertOwner(c), id); err != nil {
+		respondAlertError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListAlerts lista las alertas del principal, de la más reciente a la más
+// antigua. Admite los parámetros status (open, acknowledged o all, por defecto
+// all), rule_id, before_id para paginar y limit (1 a 500, por defecto 50).
+func (h *AlertHandler) ListAlerts(c *gin.Context) {
+	filter := models.AlertFilter{Limit: 50}
+
+	switch status := c.DefaultQuery("status", "all"); status {
+	case "open":
+		acknowledged := false
+		filter.Acknowledged = &acknowledged
+	case "acknowledged":
+		acknowledged := true
+		filter.Acknowledged = &acknowledged
+	case "all":
+	default:
+		problem.Abort(c, problem.BadRequest("status debe ser open, acknowledged o all: "+status))
+		return
+	}
+
+	for name, dest := range map[string]*int64{"rule_id": &filter.RuleID, "before_id": &filter.BeforeID} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			problem.Abort(c, problem.BadRequest(fmt.Sprintf("%s debe ser un identificador válido: %s", name, value)))
+			return
+		}
+		*dest = id
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 || l > 500 {
+			problem.Abort(c, problem.BadRequest("limit debe ser un entero entre 1 y 500: "+limitStr))
+			return
+		}
+		filter.Limit = l
+	}
+
+	list, err := h.alerts.ListAlerts(c.Request.Context(), alertOwner(c), filter)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	response := gin.H{
+		"alerts": list,
+		"count":  len(list),
+	}
+	if len(list) == filter.Limit {
+		response["next_before_id"] = list[len(list)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AcknowledgeAlert marca una alerta como reconocida.
+func (h *AlertHandler) AcknowledgeAlert(c *gin.Context) {
+	id, ok := parseAlertID(c, "alerta")
+	if !ok {
+		return
+	}
+
+	alert, err := h.alerts.AcknowledgeAlert(c.Request.Context(), alertOwner(c), id)
+	if err != nil {
+		respondAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// bindAlertRule lee y valida el cuerpo de una solicitud de creación o reemplazo.
+func bindAlertRule(c *gin.Context) (models.AlertRule, bool) {
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.BadRequest("Solicitud inválida: "+err.Error()))
+		return models.AlertRule{}, false
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		problem.Abort(c, problem.BadRequest("El nombre de la regla debe tener entre 1 y 100 caracteres"))
+		return models.AlertRule{}, false
+	}
+
+	rule := models.AlertRule{
+		Owner:      alertOwner(c),
+		Name:       name,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+		Conditions: req.Conditions,
+		Window:     req.Window,
+	}
+	if _, err := alerts.Compile(rule); err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
+		return models.AlertRule{}, false
+	}
+
+	return rule, true
+}
+
+// alertOwner devuelve el propietario de las reglas y alertas: el principal autenticado.
+func alertOwner(c *gin.Context) string {
+	principal, _ := auth.PrincipalFromContext(c)
+	return principal.ID
+}
+
+// parseAlertID lee el identificador de la ruta y responde 400 si no es válido.
+func parseAlertID(c *gin.Context, kind string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest(fmt.Sprintf("Identificador de %s inválido: %s", kind, c.Param("id"))))
+		return 0, false
+	}
+	return id, true
+}
+
+// respondAlertError registra el error del repositorio para que el middleware de errores
+// responda según su categoría. Superar el máximo de reglas se responde 400, como las
+// demás restricciones de la solicitud.
+func respondAlertError(c *gin.Context, err error) {
+	if errors.Is(err, repository.ErrAlertRuleLimit) {
+		err = &problem.Error{
+			Status: http.StatusBadRequest,
+			Code:   problem.CodeInvalidRequest,
+			Detail: fmt.Sprintf("%s (%d)", repository.ErrAlertRuleLimit.Error(), repository.MaxAlertRules),
+			Err:    err,
+		}
+	}
+	problem.Abort(c, err)
+}
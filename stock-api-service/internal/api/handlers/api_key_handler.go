@@ -0,0 +1,99 @@
+// Paquete handlers contiene los manejadores de solicitudes HTTP.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/auth"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKeyRequest representa la solicitud para crear una API key.
+type CreateAPIKeyRequest struct {
+	// Nombre descriptivo de la key
+	Name string `json:"name" binding:"required"`
+	// Rol asignado (reader, operator o admin)
+	Role string `json:"role" binding:"required"`
+}
+
+// CreateAPIKeyResponse incluye el valor en claro de la key recién creada.
+type CreateAPIKeyResponse struct {
+	auth.APIKey
+	// Valor en claro; solo se devuelve al crear la key
+	Key string `json:"key"`
+}
+
+// APIKeyHandler maneja la administración de API keys.
+type APIKeyHandler struct {
+	keys *auth.KeyStore
+}
+
+// NewAPIKeyHandler crea una nueva instancia de APIKeyHandler.
+func NewAPIKeyHandler(keys *auth.KeyStore) *APIKeyHandler {
+	return &APIKeyHandler{
+		keys: keys,
+	}
+}
+
+// CreateAPIKey crea una API key y devuelve su valor en claro una única vez.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.BadRequest("Solicitud inválida: "+err.Error()))
+		return
+	}
+
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
+		return
+	}
+
+	key, plaintext, err := h.keys.Create(c.Request.Context(), req.Name, role)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{
+		APIKey: key,
+		Key:    plaintext,
+	})
+}
+
+// ListAPIKeys lista las API keys registradas sin sus valores.
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.keys.List(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": keys,
+		"count":    len(keys),
+	})
+}
+
+// RevokeAPIKey revoca una API key.
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("Identificador de API key inválido: "+c.Param("id")))
+		return
+	}
+
+	if err := h.keys.Revoke(c.Request.Context(), id); err != nil {
+		if errors.Is(err, auth.ErrInvalidKey) {
+			problem.Abort(c, problem.NotFound("API key no encontrada o ya revocada: "+c.Param("id")))
+			return
+		}
+		problem.Abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
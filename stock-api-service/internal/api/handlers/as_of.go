@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// parseAsOf extrae el parámetro as_of de la solicitud con el formato de
+// models.ParseAsOf. Devuelve nil si el parámetro no está presente.
+func parseAsOf(c *gin.Context) (*time.Time, error) {
+	return models.ParseAsOf(c.Query("as_of"))
+}
@@ -0,0 +1,111 @@
+// Paquete handlers contiene los manejadores de solicitudes HTTP.
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/backtest"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// BacktestRequest representa la solicitud para iniciar un backtest.
+type BacktestRequest struct {
+	// Primera fecha de evaluación (YYYY-MM-DD o RFC3339)
+	Start string `json:"start"`
+	// Última fecha de evaluación (YYYY-MM-DD o RFC3339)
+	End string `json:"end"`
+	// Días entre fechas de evaluación
+	StepDays int `json:"step_days"`
+	// Cantidad de recomendaciones por fecha
+	TopN int `json:"top_n"`
+	// Días de evaluación posteriores a cada fecha
+	HorizonDays int `json:"horizon_days"`
+	// Días de historial entregados al recomendador
+	LookbackDays int `json:"lookback_days"`
+	// Estrategias a evaluar (todas si se omite)
+	Strategies []string `json:"strategies"`
+	// Serie de precios opcional para evaluar el retorno real
+	Prices []backtest.PricePoint `json:"prices"`
+}
+
+// BacktestHandler maneja las solicitudes relacionadas con backtests del recomendador.
+type BacktestHandler struct {
+	jobs *backtest.JobManager
+}
+
+// NewBacktestHandler crea una nueva instancia de BacktestHandler.
+func NewBacktestHandler(jobs *backtest.JobManager) *BacktestHandler {
+	return &BacktestHandler{
+		jobs: jobs,
+	}
+}
+
+// CreateBacktest inicia un backtest asíncrono y devuelve el trabajo creado.
+func (h *BacktestHandler) CreateBacktest(c *gin.Context) {
+	var req BacktestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.BadRequest("Solicitud inválida: "+err.Error()))
+		return
+	}
+
+	start, err := backtest.ParseDate(req.Start)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("Fecha de inicio inválida: "+req.Start))
+		return
+	}
+
+	end, err := backtest.ParseDate(req.End)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("Fecha de fin inválida: "+req.End))
+		return
+	}
+
+	if end.Sub(start) > backtest.MaxRangeDays*24*time.Hour {
+		problem.Abort(c, problem.BadRequest(fmt.Sprintf("El rango del backtest no puede superar %d días", backtest.MaxRangeDays)))
+		return
+	}
+
+	cfg := backtest.Config{
+		Start:        start,
+		End:          end,
+		StepDays:     req.StepDays,
+		TopN:         req.TopN,
+		HorizonDays:  req.HorizonDays,
+		LookbackDays: req.LookbackDays,
+		Strategies:   req.Strategies,
+	}
+
+	var prices backtest.PriceSeries
+	if len(req.Prices) > 0 {
+		prices = backtest.NewPriceSeries(req.Prices)
+	}
+
+	job, err := h.jobs.Submit(c.Request.Context(), cfg, prices)
+	if errors.Is(err, backtest.ErrTooManyJobs) {
+		c.Header("Retry-After", "60")
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, problem.CodeUnavailable, "Hay demasiados backtests en ejecución, intente más tarde"))
+		return
+	}
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("Configuración de backtest inválida: "+err.Error()))
+		return
+	}
+
+	c.Header("Location", "/api/v1/backtests/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetBacktest devuelve el estado y, si terminó, el reporte de un backtest.
+func (h *BacktestHandler) GetBacktest(c *gin.Context) {
+	job, ok := h.jobs.Get(c.Param("id"))
+	if !ok {
+		problem.Abort(c, problem.NotFound("Backtest no encontrado: "+c.Param("id")))
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
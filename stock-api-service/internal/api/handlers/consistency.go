@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// ConsistencyStrong es el valor del parámetro consistency que pide leer el
+// último estado confirmado en lugar de una réplica seguidora.
+const ConsistencyStrong = "strong"
+
+// parseConsistency extrae el parámetro consistency de la solicitud. Con
+// consistency=strong marca el contexto de la solicitud con
+// repository.WithStrongConsistency y devuelve true.
+func parseConsistency(c *gin.Context) (bool, error) {
+	switch value := c.Query("consistency"); value {
+	case "":
+		return false, nil
+	case ConsistencyStrong:
+		c.Request = c.Request.WithContext(repository.WithStrongConsistency(c.Request.Context()))
+		return true, nil
+	default:
+		return false, fmt.Errorf("consistency debe ser strong: %s", value)
+	}
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// Formatos de respuesta.
+const (
+	// JSON, el formato predeterminado
+	FormatJSON = "json"
+	// CSV separado por comas, con encabezado
+	FormatCSV = "csv"
+	// CSV con BOM UTF-8 y fin de línea CRLF, que Excel abre con los acentos
+	// correctos, y con los textos que parecen fórmulas neutralizados
+	FormatExcel = "excel"
+	// Un objeto JSON por línea
+	FormatNDJSON = "ndjson"
+)
+
+// Trailers que informan el resultado de una exportación, ya que el código de
+// estado se envía antes de leer la primera fila.
+const (
+	// complete si se exportaron todas las filas, error si la exportación se interrumpió
+	exportStatusTrailer = "X-Export-Status"
+	// Cantidad de filas exportadas
+	exportRowsTrailer = "X-Export-Rows"
+)
+
+// exportWriteTimeout es el tiempo máximo para escribir cada bloque de filas.
+const exportWriteTimeout = 30 * time.Second
+
+// exportFlushRows es la cantidad de filas entre cada envío al cliente.
+const exportFlushRows = 500
+
+// negotiateFormat determina el formato de la respuesta a partir del parámetro
+// format o, si no se indica, de la cabecera Accept. Responde 400 si format no es
+// válido. Un Accept sin tipos conocidos responde JSON.
+func negotiateFormat(c *gin.Context) (string, bool) {
+	c.Header("Vary", "Accept")
+
+	if format := strings.ToLower(c.Query("format")); format != "" {
+		switch format {
+		case FormatJSON, FormatCSV, FormatExcel, FormatNDJSON:
+			return format, true
+		}
+		problem.Abort(c, problem.BadRequest("format debe ser json, csv, excel o ndjson: "+format))
+		return "", false
+	}
+
+	for _, part := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json":
+			return FormatJSON, true
+		case "text/csv":
+			return FormatCSV, true
+		case "application/x-ndjson", "application/ndjson":
+			return FormatNDJSON, true
+		}
+	}
+	return FormatJSON, true
+}
+
+// exportWriter escribe filas en CSV o NDJSON directamente en la respuesta.
+type exportWriter struct {
+	c    *gin.Context
+	rc   *http.ResponseController
+	csv  *csv.Writer
+	json *json.Encoder
+	rows int
+	// Neutraliza las fórmulas, solo en el formato para hojas de cálculo
+	spreadsheet bool
+}
+
+// startExport envía las cabeceras de una exportación con el nombre de archivo
+// indicado, sin extensión, y el encabezado de las columnas en CSV. Los errores
+// de escritura se informan al escribir las filas o en Finish.
+func startExport(c *gin.Context, format, filename string, columns []string) *exportWriter {
+	w := &exportWriter{
+		c:  c,
+		rc: http.NewResponseController(c.Writer),
+	}
+
+	extension, contentType := "csv", "text/csv; charset=utf-8"
+	if format == FormatNDJSON {
+		extension, contentType = "ndjson", "application/x-ndjson"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": filename + "." + extension,
+	}))
+	c.Header("Cache-Control", "no-store")
+	c.Header("Trailer", exportStatusTrailer+", "+exportRowsTrailer)
+	c.Status(http.StatusOK)
+
+	w.extendDeadline()
+	if format == FormatNDJSON {
+		w.json = json.NewEncoder(c.Writer)
+		return w
+	}
+
+	w.csv = csv.NewWriter(c.Writer)
+	if format == FormatExcel {
+		// El BOM se escribe antes del encabezado, que el CSV retiene en su búfer
+		w.csv.UseCRLF = true
+		w.spreadsheet = true
+		c.Writer.WriteString("\ufeff")
+	}
+	w.csv.Write(columns)
+	return w
+}
+
+// Write escribe una fila: record en CSV o value en NDJSON.
+func (w *exportWriter) Write(record []string, value any) error {
+	var err error
+	switch {
+	case w.json != nil:
+		err = w.json.Encode(value)
+	case w.spreadsheet:
+		neutralized := make([]string, len(record))
+		for i, field := range record {
+			neutralized[i] = spreadsheetText(field)
+		}
+		err = w.csv.Write(neutralized)
+	default:
+		err = w.csv.Write(record)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.rows++
+	if w.rows%exportFlushRows == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+// Finish envía las filas pendientes y los trailers con el resultado. Si err no
+// es nil, lo registra y marca la exportación como interrumpida: el código de
+// estado ya se envió y el cliente solo puede detectarlo por el trailer o porque
+// el archivo termina antes de tiempo.
+func (w *exportWriter) Finish(err error) {
+	if flushErr := w.flush(); err == nil {
+		err = flushErr
+	}
+
+	status := "complete"
+	if err != nil {
+		status = "error"
+		if w.c.Request.Context().Err() == nil {
+			slog.ErrorContext(w.c.Request.Context(), "Error durante la exportación", "rows", w.rows, "error", err)
+		}
+	}
+	w.c.Writer.Header().Set(exportStatusTrailer, status)
+	w.c.Writer.Header().Set(exportRowsTrailer, strconv.Itoa(w.rows))
+}
+
+// flush envía al cliente lo escrito hasta el momento y extiende el plazo de escritura.
+func (w *exportWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	w.extendDeadline()
+	return w.rc.Flush()
+}
+
+// extendDeadline extiende el plazo de escritura, que de otro modo sería el
+// WriteTimeout del servidor y cortaría las exportaciones largas.
+func (w *exportWriter) extendDeadline() {
+	w.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+}
+
+// exportFilename construye el nombre de un archivo exportado con la fecha actual.
+func exportFilename(parts ...string) string {
+	return strings.Join(append(parts, time.Now().UTC().Format("20060102T150405Z")), "-")
+}
+
+// stockExportColumns son las columnas exportadas de un stock.
+var stockExportColumns = []string{
+	"ticker", "company", "target_from", "target_to", "action", "brokerage",
+	"rating_from", "rating_to", "time", "sector", "industry", "exchange", "market_cap_bucket",
+}
+
+// stockRecord convierte un stock en una fila con las columnas de stockExportColumns.
+func stockRecord(stock models.Stock) []string {
+	return []string{
+		stock.Ticker,
+		stock.Company,
+		stock.TargetFrom,
+		stock.TargetTo,
+		stock.Action,
+		stock.Brokerage,
+		stock.RatingFrom,
+		stock.RatingTo,
+		stock.Time.UTC().Format(time.RFC3339),
+		stock.Sector,
+		stock.Industry,
+		stock.Exchange,
+		stock.MarketCapBucket,
+	}
+}
+
+// spreadsheetText neutraliza los textos que una hoja de cálculo interpretaría
+// como fórmula anteponiendo un apóstrofo: los que empiezan con = o @, y los que
+// empiezan con + o - seguido de un carácter de fórmula. Los números con signo,
+// como -5.00 o +12.5%, se conservan.
+func spreadsheetText(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '@':
+		return "'" + value
+	case '+', '-':
+		if len(value) > 1 && isFormulaChar(value[1]) {
+			return "'" + value
+		}
+	}
+	return value
+}
+
+// isFormulaChar indica si un carácter inicia una fórmula después de + o -: un
+// operador, un paréntesis o una letra, como en -SUM(A1) o +cmd|.
+func isFormulaChar(c byte) bool {
+	return strings.IndexByte("=@+-(", c) >= 0 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/graphqlapi"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// maxGraphQLBody es el tamaño máximo del cuerpo de una consulta GraphQL.
+const maxGraphQLBody = 1 << 20
+
+// GraphQLRequest representa una consulta GraphQL.
+type GraphQLRequest struct {
+	// Documento con la consulta
+	Query string `json:"query"`
+	// Operación a ejecutar si el documento tiene varias
+	OperationName string `json:"operationName"`
+	// Valores de las variables de la consulta
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// GraphQLHandler maneja las consultas GraphQL.
+type GraphQLHandler struct {
+	service *graphqlapi.Service
+}
+
+// NewGraphQLHandler crea una nueva instancia de GraphQLHandler.
+func NewGraphQLHandler(service *graphqlapi.Service) *GraphQLHandler {
+	return &GraphQLHandler{
+		service: service,
+	}
+}
+
+// Query ejecuta una consulta enviada en el cuerpo JSON de un POST o en los
+// parámetros query, operationName y variables de un GET. Responde 400 si la
+// consulta es inválida o excede los límites de profundidad o complejidad; los
+// errores de ejecución se informan en el campo errors con código 200.
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req GraphQLRequest
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				problem.Abort(c, problem.BadRequest("variables debe ser un objeto JSON: "+err.Error()))
+				return
+			}
+		}
+	} else {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxGraphQLBody)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.BadRequest("Solicitud inválida: "+err.Error()))
+			return
+		}
+	}
+
+	if req.Query == "" {
+		problem.Abort(c, problem.BadRequest("El parámetro query es requerido"))
+		return
+	}
+
+	result, executed := h.service.Execute(c.Request.Context(), req.Query, req.OperationName, req.Variables)
+	status := http.StatusOK
+	if !executed {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, result)
+}
+
+// Schema devuelve el esquema en el lenguaje de definición de GraphQL. El
+// esquema también puede consultarse por introspección en /graphql.
+func (h *GraphQLHandler) Schema(c *gin.Context) {
+	c.String(http.StatusOK, h.service.SDL())
+}
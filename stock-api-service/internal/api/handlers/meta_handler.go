@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/freshness"
+	"github.com/gin-gonic/gin"
+)
+
+// MetaHandler maneja las solicitudes sobre el estado de los datos.
+type MetaHandler struct {
+	freshness *freshness.Monitor
+}
+
+// NewMetaHandler crea una nueva instancia de MetaHandler.
+func NewMetaHandler(monitor *freshness.Monitor) *MetaHandler {
+	return &MetaHandler{
+		freshness: monitor,
+	}
+}
+
+// GetFreshness devuelve la última sincronización exitosa, el evento más reciente
+// y si superan los umbrales de frescura.
+func (h *MetaHandler) GetFreshness(c *gin.Context) {
+	c.JSON(http.StatusOK, h.freshness.Report())
+}
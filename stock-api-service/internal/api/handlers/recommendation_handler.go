@@ -4,63 +4,132 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"time"
+	"strconv"
 
-	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/algorithm"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/recommendation"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
 // RecommendationHandler maneja las solicitudes relacionadas con recomendaciones de stocks.
 type RecommendationHandler struct {
-	repo        *repository.StockRepository
-	recommender *algorithm.StockRecommender
+	recommendations *recommendation.Service
+	watchlists      *repository.WatchlistRepository
 }
 
 // NewRecommendationHandler crea una nueva instancia de RecommendationHandler.
-func NewRecommendationHandler(repo *repository.StockRepository) *RecommendationHandler {
+func NewRecommendationHandler(recommendations *recommendation.Service, watchlists *repository.WatchlistRepository) *RecommendationHandler {
 	return &RecommendationHandler{
-		repo:        repo,
-		recommender: algorithm.NewStockRecommender(),
+		recommendations: recommendations,
+		watchlists:      watchlists,
 	}
 }
 
-// GetRecommendations genera recomendaciones de stocks.
+// GetRecommendations genera recomendaciones de stocks. Con format=csv, excel o
+// ndjson, o la cabecera Accept equivalente, devuelve una fila por recomendación.
 func (h *RecommendationHandler) GetRecommendations(c *gin.Context) {
-	// Obtener stocks recientes para análisis (últimos 30 días)
-	endDate := time.Now()
-	startDate := endDate.AddDate(0, -1, 0)
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return
+	}
+
+	asOf, err := parseAsOf(c)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
+		return
+	}
 
-	stocks, err := h.repo.GetStocksByDateRange(c.Request.Context(), startDate, endDate)
+	// Con consistencia fuerte se recalcula con el último estado confirmado y se
+	// actualiza la caché, que pudo calcularse con una réplica seguidora
+	strong, err := parseConsistency(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error al obtener stocks para recomendaciones: " + err.Error(),
-		})
+		problem.Abort(c, problem.BadRequest(err.Error()))
 		return
 	}
 
-	// Generar recomendaciones
-	recommendationResults := h.recommender.GenerateRecommendations(stocks, 10)
+	req := recommendation.Request{AsOf: asOf, Strong: strong}
 
-	// Crear respuesta
-	response := models.RecommendationResponse{
-		Recommendations: recommendationResults,
-		GeneratedAt:     time.Now(),
-		Count:           len(recommendationResults),
-		Message:         h.generateResponseMessage(len(recommendationResults)),
+	// Cantidad de recomendaciones
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > recommendation.MaxLimit {
+			problem.Abort(c, problem.BadRequest(fmt.Sprintf("limit debe ser un entero entre 1 y %d: %s", recommendation.MaxLimit, limitStr)))
+			return
+		}
+		req.Limit = limit
 	}
 
-	c.JSON(http.StatusOK, response)
-}
+	// Límite de recomendaciones por sector
+	if maxStr := c.Query("max_per_sector"); maxStr != "" {
+		maxPerSector, err := strconv.Atoi(maxStr)
+		if err != nil || maxPerSector < 0 {
+			problem.Abort(c, problem.BadRequest("max_per_sector debe ser un entero mayor o igual a cero: "+maxStr))
+			return
+		}
+		req.MaxPerSector = &maxPerSector
+	}
+
+	// Lista de seguimiento del principal, que restringe o prioriza sus tickers
+	if idStr := c.Query("watchlist"); idStr != "" {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			problem.Abort(c, problem.BadRequest("Identificador de lista inválido: "+idStr))
+			return
+		}
+
+		req.WatchlistMode = c.DefaultQuery("watchlist_mode", recommendation.WatchlistModeRestrict)
+		if req.WatchlistMode != recommendation.WatchlistModeRestrict && req.WatchlistMode != recommendation.WatchlistModeBoost {
+			problem.Abort(c, problem.BadRequest("watchlist_mode debe ser restrict o boost: "+req.WatchlistMode))
+			return
+		}
+
+		found, err := h.watchlists.GetWatchlist(c.Request.Context(), watchlistOwner(c), id)
+		if err != nil {
+			respondWatchlistError(c, err)
+			return
+		}
+		req.Watchlist = &found
+	}
 
-// generateResponseMessage genera un mensaje para la respuesta.
-func (h *RecommendationHandler) generateResponseMessage(count int) string {
-	if count == 0 {
-		return "No se encontraron recomendaciones para hoy. Intente más tarde cuando haya nuevas actualizaciones."
-	} else if count == 1 {
-		return "Se encontró 1 recomendación de inversión para hoy."
+	response, hit, err := h.recommendations.Recommend(c.Request.Context(), req)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	if hit {
+		c.Header("X-Cache", "HIT")
 	} else {
-		return fmt.Sprintf("Se encontraron %d recomendaciones de inversión para hoy.", count)
+		c.Header("X-Cache", "MISS")
+	}
+
+	respondRecommendations(c, format, response)
+}
+
+// recommendationExportColumns son las columnas exportadas de una recomendación.
+var recommendationExportColumns = append([]string{"rank", "score", "potential_return", "rationale"}, stockExportColumns...)
+
+// respondRecommendations responde las recomendaciones en el formato indicado.
+func respondRecommendations(c *gin.Context, format string, response models.RecommendationResponse) {
+	if format == FormatJSON {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	w := startExport(c, format, exportFilename("recommendations"), recommendationExportColumns)
+	var err error
+	for i, result := range response.Recommendations {
+		ranked := models.RankedRecommendation{Rank: i + 1, RecommendationResult: result}
+		record := append([]string{
+			strconv.Itoa(ranked.Rank),
+			strconv.FormatFloat(result.Score, 'f', -1, 64),
+			result.PotentialReturn,
+			result.Rationale,
+		}, stockRecord(result.Stock)...)
+		if err = w.Write(record, ranked); err != nil {
+			break
+		}
 	}
+	w.Finish(err)
 }
@@ -0,0 +1,66 @@
+// Paquete handlers contiene los manejadores de solicitudes HTTP.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// SectorHandler maneja las solicitudes relacionadas con la vista por sectores.
+type SectorHandler struct {
+	repo repository.StockRepository
+}
+
+// NewSectorHandler crea una nueva instancia de SectorHandler.
+func NewSectorHandler(repo repository.StockRepository) *SectorHandler {
+	return &SectorHandler{
+		repo: repo,
+	}
+}
+
+// ListSectors resume por sector las mejoras y rebajas de los analistas en los últimos días.
+func (h *SectorHandler) ListSectors(c *gin.Context) {
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d <= 0 || d > 365 {
+			problem.Abort(c, problem.BadRequest("days debe ser un entero entre 1 y 365: "+daysStr))
+			return
+		}
+		days = d
+	}
+
+	asOf, err := parseAsOf(c)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
+		return
+	}
+
+	endDate := time.Now()
+	if asOf != nil {
+		endDate = *asOf
+	}
+	startDate := endDate.AddDate(0, 0, -days)
+
+	summaries, err := h.repo.GetSectorSummaries(c.Request.Context(), startDate, endDate)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	if summaries == nil {
+		summaries = []models.SectorSummary{}
+	}
+
+	c.JSON(http.StatusOK, models.SectorSummaryResponse{
+		Sectors: summaries,
+		From:    startDate,
+		To:      endDate,
+		Count:   len(summaries),
+	})
+}
@@ -0,0 +1,139 @@
+// Paquete handlers contiene los manejadores de solicitudes HTTP.
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/snapshot"
+	"github.com/gin-gonic/gin"
+)
+
+// SnapshotHandler maneja las solicitudes relacionadas con snapshots de recomendaciones.
+type SnapshotHandler struct {
+	repo    *repository.SnapshotRepository
+	service *snapshot.Service
+}
+
+// NewSnapshotHandler crea una nueva instancia de SnapshotHandler.
+func NewSnapshotHandler(repo *repository.SnapshotRepository, service *snapshot.Service) *SnapshotHandler {
+	return &SnapshotHandler{
+		repo:    repo,
+		service: service,
+	}
+}
+
+// CreateSnapshot guarda un snapshot bajo demanda de las recomendaciones actuales.
+func (h *SnapshotHandler) CreateSnapshot(c *gin.Context) {
+	result, _, err := h.service.Take(c.Request.Context(), repository.SnapshotKindOnDemand)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// ListSnapshots lista los snapshots más recientes, opcionalmente filtrados por fecha.
+func (h *SnapshotHandler) ListSnapshots(c *gin.Context) {
+	limit := 30
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 || l > 365 {
+			problem.Abort(c, problem.BadRequest("limit debe ser un entero entre 1 y 365: "+limitStr))
+			return
+		}
+		limit = l
+	}
+
+	var date *time.Time
+	if dateStr := c.Query("date"); dateStr != "" {
+		d, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			problem.Abort(c, problem.BadRequest("La fecha debe tener formato YYYY-MM-DD: "+dateStr))
+			return
+		}
+		date = &d
+	}
+
+	snapshots, err := h.repo.ListSnapshots(c.Request.Context(), date, limit)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	if snapshots == nil {
+		snapshots = []models.RecommendationSnapshot{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshots": snapshots,
+		"count":     len(snapshots),
+	})
+}
+
+// GetSnapshot devuelve un snapshot por su identificador, o el más reciente con "latest".
+func (h *SnapshotHandler) GetSnapshot(c *gin.Context) {
+	result, err := h.resolve(c.Request.Context(), c.Param("id"), c.Query("kind"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DiffSnapshots compara dos snapshots. Por defecto compara el último snapshot
+// diario con el anterior ("qué cambió desde ayer").
+func (h *SnapshotHandler) DiffSnapshots(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	kind := c.DefaultQuery("kind", repository.SnapshotKindDaily)
+
+	toID := c.DefaultQuery("to", "latest")
+	to, err := h.resolve(ctx, toID, kind)
+	if err != nil {
+		problem.Abort(c, snapshotError(err, "Snapshot de destino no encontrado"))
+		return
+	}
+
+	var from models.RecommendationSnapshot
+	if fromID := c.Query("from"); fromID != "" {
+		from, err = h.resolve(ctx, fromID, kind)
+	} else {
+		from, err = h.repo.GetPreviousSnapshot(ctx, to, kind)
+	}
+	if err != nil {
+		problem.Abort(c, snapshotError(err, "Snapshot de origen no encontrado"))
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot.Diff(from, to))
+}
+
+// resolve obtiene un snapshot a partir de su identificador o del alias "latest".
+func (h *SnapshotHandler) resolve(ctx context.Context, id, kind string) (models.RecommendationSnapshot, error) {
+	if id == "latest" {
+		return h.repo.GetLatestSnapshot(ctx, kind)
+	}
+
+	snapshotID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return models.RecommendationSnapshot{}, problem.BadRequest("Identificador de snapshot inválido: " + id)
+	}
+	return h.repo.GetSnapshot(ctx, snapshotID)
+}
+
+// snapshotError reemplaza el mensaje de un snapshot no encontrado para indicar
+// cuál de los dos de la comparación falta. Los demás errores no cambian.
+func snapshotError(err error, message string) error {
+	if errors.Is(err, repository.ErrSnapshotNotFound) {
+		return &problem.Error{Status: http.StatusNotFound, Code: problem.CodeNotFound, Detail: message, Err: err}
+	}
+	return err
+}
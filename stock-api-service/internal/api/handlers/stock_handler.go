@@ -2,134 +2,88 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
 // StockHandler maneja las solicitudes relacionadas con stocks.
 type StockHandler struct {
-	repo *repository.StockRepository
+	repo repository.StockRepository
 }
 
 // NewStockHandler crea una nueva instancia de StockHandler.
-func NewStockHandler(repo *repository.StockRepository) *StockHandler {
+func NewStockHandler(repo repository.StockRepository) *StockHandler {
 	return &StockHandler{
 		repo: repo,
 	}
 }
 
 // ListStocks maneja la solicitud para listar stocks con filtros y paginación.
+// Con format=csv, excel o ndjson, o la cabecera Accept equivalente, exporta
+// todos los stocks que cumplen el filtro sin paginación.
 func (h *StockHandler) ListStocks(c *gin.Context) {
-	// Extraer parámetros de filtrado
-	brokerage := c.Query("brokerage")
-	ticker := c.Query("ticker")
-	rating := c.Query("rating")
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return
+	}
 
 	// Parsear parámetros de paginación
-	pagination := h.parsePagination(c)
+	pagination, err := parsePagination(c)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
+		return
+	}
 
-	// Extraer parámetros de ordenamiento
-	orderBy := c.Query("order_by")
-	sortOrder := c.Query("sort")
+	// Extraer instante de referencia
+	asOf, err := parseAsOf(c)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
+		return
+	}
 
-	// Validar y establecer valores predeterminados para ordenamiento
-	if orderBy == "" {
-		orderBy = "time"
-	} else {
-		// Validar que el campo de ordenamiento sea válido
-		validFields := map[string]bool{
-			"ticker": true, "company": true, "brokerage": true,
-			"rating_from": true, "rating_to": true, "time": true,
-		}
-		if !validFields[orderBy] {
-			orderBy = "time"
-		}
+	// Consistencia fuerte a pedido, en lugar de leer de una réplica seguidora
+	if _, err := parseConsistency(c); err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
+		return
 	}
 
-	if sortOrder == "" {
-		sortOrder = "DESC"
-	} else {
-		sortOrder = strings.ToUpper(sortOrder)
-		if sortOrder != "ASC" && sortOrder != "DESC" {
-			sortOrder = "DESC"
-		}
+	// Extraer parámetros de filtrado y ordenamiento; el repositorio rechaza un
+	// ordenamiento desconocido
+	filter := parseStockFilter(c)
+	filter.OrderBy = c.Query("order_by")
+	filter.SortOrder = c.Query("sort")
+	filter.AsOf = asOf
+	if err := repository.ValidateStockFilter(filter); err != nil {
+		problem.Abort(c, err)
+		return
 	}
 
-	var stocks []models.Stock
-	var totalStocks int
-	var err error
+	if format != FormatJSON {
+		w := startExport(c, format, exportFilename("stocks"), stockExportColumns)
+		w.Finish(h.repo.StreamStocks(c.Request.Context(), filter, func(stock models.Stock) error {
+			return w.Write(stockRecord(stock), stock)
+		}))
+		return
+	}
 
 	// Obtener stocks según los filtros
-	if ticker != "" {
-		stocks, err = h.repo.GetStocksByTickerPattern(c.Request.Context(), ticker, pagination.Offset, pagination.Limit)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Error al obtener stocks por ticker: " + err.Error(),
-			})
-			return
-		}
-
-		totalStocks, err = h.repo.CountStocksByTickerPattern(c.Request.Context(), ticker)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Error al contar stocks por ticker: " + err.Error(),
-			})
-			return
-		}
-	} else if brokerage != "" {
-		stocks, err = h.repo.GetStocksByBrokerage(c.Request.Context(), brokerage, pagination.Offset, pagination.Limit)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Error al obtener stocks por brokerage: " + err.Error(),
-			})
-			return
-		}
-
-		totalStocks, err = h.repo.CountStocksByBrokerage(c.Request.Context(), brokerage)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Error al contar stocks por brokerage: " + err.Error(),
-			})
-			return
-		}
-	} else if rating != "" {
-		stocks, err = h.repo.GetStocksByRating(c.Request.Context(), rating, pagination.Offset, pagination.Limit)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Error al obtener stocks por rating: " + err.Error(),
-			})
-			return
-		}
-
-		totalStocks, err = h.repo.CountStocksByRating(c.Request.Context(), rating)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Error al contar stocks por rating: " + err.Error(),
-			})
-			return
-		}
-	} else {
-		// Sin filtros, obtener todos los stocks
-		stocks, err = h.repo.GetStocks(c.Request.Context(), orderBy, sortOrder, pagination.Offset, pagination.Limit)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Error al obtener stocks: " + err.Error(),
-			})
-			return
-		}
+	stocks, err := h.repo.GetStocks(c.Request.Context(), filter, pagination.Offset, pagination.Limit)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
 
-		totalStocks, err = h.repo.CountStocks(c.Request.Context())
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Error al contar stocks: " + err.Error(),
-			})
-			return
-		}
+	totalStocks, err := h.repo.CountStocks(c.Request.Context(), filter)
+	if err != nil {
+		problem.Abort(c, err)
+		return
 	}
 
 	// Calcular el total de páginas
@@ -152,42 +106,115 @@ func (h *StockHandler) GetStockDetails(c *gin.Context) {
 	ticker := c.Param("ticker")
 
 	if ticker == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Se requiere especificar un ticker",
-		})
+		problem.Abort(c, problem.BadRequest("Se requiere especificar un ticker"))
+		return
+	}
+
+	asOf, err := parseAsOf(c)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
 		return
 	}
 
 	// Obtener stock por ticker exacto
-	stock, err := h.repo.GetStockByTicker(c.Request.Context(), ticker)
+	var stock models.Stock
+	if asOf != nil {
+		stock, err = h.repo.GetStockByTickerAsOf(c.Request.Context(), ticker, *asOf)
+	} else {
+		stock, err = h.repo.GetStockByTicker(c.Request.Context(), ticker)
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Stock no encontrado: " + err.Error(),
-		})
+		problem.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, stock)
 }
 
-// parsePagination extrae y valida los parámetros de paginación de la solicitud.
-func (h *StockHandler) parsePagination(c *gin.Context) models.Pagination {
+// GetTickerHistory devuelve el historial de calificaciones de un ticker, de la
+// más reciente a la más antigua, con la paginación de ListStocks. Con
+// format=csv, excel o ndjson, o la cabecera Accept equivalente, exporta el
+// historial completo.
+func (h *StockHandler) GetTickerHistory(c *gin.Context) {
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return
+	}
+
+	ticker := strings.ToUpper(c.Param("ticker"))
+	ctx := c.Request.Context()
+
+	if format != FormatJSON {
+		w := startExport(c, format, exportFilename(ticker, "history"), append([]string{"id"}, stockExportColumns...))
+		w.Finish(h.repo.StreamTickerHistory(ctx, ticker, func(event models.StockEvent) error {
+			return w.Write(append([]string{strconv.FormatInt(event.ID, 10)}, stockRecord(event.Stock)...), event)
+		}))
+		return
+	}
+
+	pagination, err := parsePagination(c)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
+		return
+	}
+
+	events, err := h.repo.GetTickerHistory(ctx, ticker, pagination.Offset, pagination.Limit)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	totalEvents, err := h.repo.CountTickerHistory(ctx, ticker)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	if totalEvents == 0 {
+		problem.Abort(c, problem.NotFound("No hay calificaciones para el ticker: "+ticker))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TickerHistoryResponse{
+		Ticker:       ticker,
+		Events:       events,
+		TotalEvents:  totalEvents,
+		TotalPages:   (totalEvents + pagination.Limit - 1) / pagination.Limit,
+		CurrentPage:  pagination.Page,
+		ItemsPerPage: pagination.Limit,
+	})
+}
+
+// parseStockFilter extrae los parámetros de filtrado de stocks de la solicitud.
+func parseStockFilter(c *gin.Context) models.StockFilter {
+	return models.StockFilter{
+		Brokerage: c.Query("brokerage"),
+		Ticker:    c.Query("ticker"),
+		Rating:    c.Query("rating"),
+		Sector:    c.Query("sector"),
+		Industry:  c.Query("industry"),
+	}
+}
+
+// parsePagination extrae los parámetros de paginación de la solicitud. Los
+// valores fuera de rango se rechazan en lugar de reemplazarse.
+func parsePagination(c *gin.Context) (models.Pagination, error) {
 	page := 1
 	pageSize := 10
 
 	if pageStr := c.Query("page"); pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
+		p, err := strconv.Atoi(pageStr)
+		if err != nil || p < 1 {
+			return models.Pagination{}, fmt.Errorf("page debe ser un entero mayor o igual a 1: %s", pageStr)
 		}
+		page = p
 	}
 
 	if sizeStr := c.Query("page_size"); sizeStr != "" {
-		if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 {
-			if s > 100 {
-				s = 100
-			}
-			pageSize = s
+		s, err := strconv.Atoi(sizeStr)
+		if err != nil || s < 1 || s > 100 {
+			return models.Pagination{}, fmt.Errorf("page_size debe ser un entero entre 1 y 100: %s", sizeStr)
 		}
+		pageSize = s
 	}
 
 	// Calcular offset para la consulta a la BD
@@ -197,5 +224,5 @@ func (h *StockHandler) parsePagination(c *gin.Context) models.Pagination {
 		Page:   page,
 		Limit:  pageSize,
 		Offset: offset,
-	}
+	}, nil
 }
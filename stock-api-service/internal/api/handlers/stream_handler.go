@@ -0,0 +1,131 @@
+// Paquete handlers contiene los manejadores de solicitudes HTTP.
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/metrics"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/stream"
+	"github.com/gin-gonic/gin"
+)
+
+// streamWriteTimeout es el tiempo máximo para escribir cada mensaje del stream.
+const streamWriteTimeout = 30 * time.Second
+
+// StreamHandler envía los eventos nuevos por Server-Sent Events.
+type StreamHandler struct {
+	hub       *stream.Hub
+	heartbeat time.Duration
+}
+
+// NewStreamHandler crea una nueva instancia de StreamHandler.
+func NewStreamHandler(hub *stream.Hub, heartbeat time.Duration) *StreamHandler {
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	return &StreamHandler{
+		hub:       hub,
+		heartbeat: heartbeat,
+	}
+}
+
+// StreamEvents envía las calificaciones nuevas y los snapshots de recomendaciones
+// a medida que se guardan. Admite los mismos filtros que /api/v1/stocks, que se
+// aplican a las calificaciones, y reanuda desde la cabecera Last-Event-ID o el
+// parámetro last_event_id.
+func (h *StreamHandler) StreamEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+	filter := parseStockFilter(c)
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	var cursor stream.Cursor
+	var err error
+	if lastEventID != "" {
+		cursor, err = stream.ParseCursor(lastEventID)
+		if err != nil {
+			problem.Abort(c, problem.BadRequest(err.Error()))
+			return
+		}
+	} else {
+		cursor, err = h.hub.Head(ctx)
+		if err != nil {
+			problem.Abort(c, err)
+			return
+		}
+	}
+
+	metrics.AddStreamClients(1)
+	defer metrics.AddStreamClients(-1)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Evita que un proxy intermedio acumule la respuesta
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Cada escritura extiende el plazo, que de otro modo sería el WriteTimeout del servidor
+	rc := http.NewResponseController(c.Writer)
+	write := func(format string, args ...any) bool {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprintf(c.Writer, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	// El cursor inicial permite reanudar aunque no llegue ningún mensaje
+	if !write("retry: 5000\nid: %s\n: conectado\n\n", cursor) {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		messages, next, wait, err := h.hub.Next(ctx, cursor, filter)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Error al leer eventos del stream", "error", err)
+			}
+			return
+		}
+		cursor = next
+
+		for _, message := range messages {
+			data, err := json.Marshal(message.Data)
+			if err != nil {
+				slog.ErrorContext(ctx, "Error al serializar un evento del stream", "error", err)
+				continue
+			}
+			if !write("id: %s\nevent: %s\ndata: %s\n\n", message.Cursor, message.Type, data) {
+				return
+			}
+		}
+		if len(messages) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.hub.Done():
+			return
+		case <-heartbeat.C:
+			// Incluye el cursor para que la reanudación omita los eventos descartados por el filtro
+			if !write("id: %s\n: heartbeat %s\n\n", cursor, time.Now().UTC().Format(time.RFC3339)) {
+				return
+			}
+		case <-wait:
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// Paquete handlers contiene los manejadores de solicitudes HTTP.
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/auth"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// tickerPattern define los tickers aceptados en una lista de seguimiento.
+var tickerPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9.\-]{0,9}$`)
+
+// WatchlistRequest representa la solicitud para crear o reemplazar una lista de seguimiento.
+type WatchlistRequest struct {
+	// Nombre de la lista, único por principal
+	Name string `json:"name" binding:"required"`
+	// Tickers de la lista
+	Tickers []string `json:"tickers"`
+}
+
+// WatchlistTickersRequest representa la solicitud para agregar tickers a una lista.
+type WatchlistTickersRequest struct {
+	// Tickers a agregar
+	Tickers []string `json:"tickers" binding:"required"`
+}
+
+// WatchlistHandler maneja las listas de seguimiento del principal autenticado.
+type WatchlistHandler struct {
+	watchlists *repository.WatchlistRepository
+	stocks     repository.StockRepository
+}
+
+// NewWatchlistHandler crea una nueva instancia de WatchlistHandler.
+func NewWatchlistHandler(watchlists *repository.WatchlistRepository, stocks repository.StockRepository) *WatchlistHandler {
+	return &WatchlistHandler{
+		watchlists: watchlists,
+		stocks:     stocks,
+	}
+}
+
+// CreateWatchlist crea una lista de seguimiento.
+func (h *WatchlistHandler) CreateWatchlist(c *gin.Context) {
+	watchlist, ok := bindWatchlist(c)
+	if !ok {
+		return
+	}
+
+	if err := h.watchlists.CreateWatchlist(c.Request.Context(), &watchlist); err != nil {
+		respondWatchlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, watchlist)
+}
+
+// ListWatchlists lista las listas de seguimiento del principal.
+func (h *WatchlistHandler) ListWatchlists(c *gin.Context) {
+	watchlists, err := h.watchlists.ListWatchlists(c.Request.Context(), watchlistOwner(c))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"watchlists": watchlists,
+		"count":      len(watchlists),
+	})
+}
+
+// GetWatchlist devuelve una lista de seguimiento del principal.
+func (h *WatchlistHandler) GetWatchlist(c *gin.Context) {
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return
+	}
+
+	watchlist, err := h.watchlists.GetWatchlist(c.Request.Context(), watchlistOwner(c), id)
+	if err != nil {
+		respondWatchlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, watchlist)
+}
+
+// UpdateWatchlist reemplaza el nombre y los tickers de una lista de seguimiento.
+func (h *WatchlistHandler) UpdateWatchlist(c *gin.Context) {
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return
+	}
+	watchlist, ok := bindWatchlist(c)
+	if !ok {
+		return
+	}
+	watchlist.ID = id
+
+	if err := h.watchlists.UpdateWatchlist(c.Request.Context(), &watchlist); err != nil {
+		respondWatchlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, watchlist)
+}
+
+// DeleteWatchlist elimina una lista de seguimiento.
+func (h *WatchlistHandler) DeleteWatchlist(c *gin.Context) {
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.watchlists.DeleteWatchlist(c.Request.Context(), watchlistOwner(c), id); err != nil {
+		respondWatchlistError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AddTickers agrega tickers a una lista de seguimiento y devuelve la lista actualizada.
+func (h *WatchlistHandler) AddTickers(c *gin.Context) {
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return
+	}
+
+	var req WatchlistTickersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.BadRequest("Solicitud inválida: "+err.Error()))
+		return
+	}
+	tickers, err := normalizeTickers(req.Tickers)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	owner := watchlistOwner(c)
+	if err := h.watchlists.AddTickers(ctx, owner, id, tickers); err != nil {
+		respondWatchlistError(c, err)
+		return
+	}
+
+	watchlist, err := h.watchlists.GetWatchlist(ctx, owner, id)
+	if err != nil {
+		respondWatchlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, watchlist)
+}
+
+// RemoveTicker quita un ticker de una lista de seguimiento.
+func (h *WatchlistHandler) RemoveTicker(c *gin.Context) {
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return
+	}
+
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	if err := h.watchlists.RemoveTicker(c.Request.Context(), watchlistOwner(c), id, ticker); err != nil {
+		respondWatchlistError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetFeed devuelve las actualizaciones recientes de los tickers de una lista.
+// Admite los parámetros days (1 a 365, por defecto 30) y limit (1 a 500, por defecto 50).
+func (h *WatchlistHandler) GetFeed(c *gin.Context) {
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return
+	}
+
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d < 1 || d > 365 {
+			problem.Abort(c, problem.BadRequest("days debe ser un entero entre 1 y 365: "+daysStr))
+			return
+		}
+		days = d
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 || l > 500 {
+			problem.Abort(c, problem.BadRequest("limit debe ser un entero entre 1 y 500: "+limitStr))
+			return
+		}
+		limit = l
+	}
+
+	ctx := c.Request.Context()
+	watchlist, err := h.watchlists.GetWatchlist(ctx, watchlistOwner(c), id)
+	if err != nil {
+		respondWatchlistError(c, err)
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	events := []models.StockEvent{}
+	if len(watchlist.Tickers) > 0 {
+		events, err = h.stocks.GetStockEventsForTickers(ctx, watchlist.Tickers, since, limit)
+		if err != nil {
+			problem.Abort(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.WatchlistFeed{
+		Watchlist: watchlist,
+		Events:    events,
+		Since:     since,
+		Count:     len(events),
+	})
+}
+
+// bindWatchlist lee y valida el cuerpo de una solicitud de creación o reemplazo.
+func bindWatchlist(c *gin.Context) (models.Watchlist, bool) {
+	var req WatchlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.BadRequest("Solicitud inválida: "+err.Error()))
+		return models.Watchlist{}, false
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		problem.Abort(c, problem.BadRequest("El nombre de la lista debe tener entre 1 y 100 caracteres"))
+		return models.Watchlist{}, false
+	}
+
+	tickers, err := normalizeTickers(req.Tickers)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
+		return models.Watchlist{}, false
+	}
+
+	return models.Watchlist{
+		Owner:   watchlistOwner(c),
+		Name:    name,
+		Tickers: tickers,
+	}, true
+}
+
+// normalizeTickers pasa los tickers a mayúsculas, descarta duplicados, los
+// ordena y verifica su formato y cantidad.
+func normalizeTickers(tickers []string) ([]string, error) {
+	seen := make(map[string]bool, len(tickers))
+	normalized := []string{}
+	for _, ticker := range tickers {
+		ticker = strings.ToUpper(strings.TrimSpace(ticker))
+		if !tickerPattern.MatchString(ticker) {
+			return nil, fmt.Errorf("ticker inválido: %q", ticker)
+		}
+		if !seen[ticker] {
+			seen[ticker] = true
+			normalized = append(normalized, ticker)
+		}
+	}
+
+	if len(normalized) > repository.MaxWatchlistTickers {
+		return nil, fmt.Errorf("una lista de seguimiento admite como máximo %d tickers", repository.MaxWatchlistTickers)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// watchlistOwner devuelve el propietario de las listas: el principal autenticado.
+func watchlistOwner(c *gin.Context) string {
+	principal, _ := auth.PrincipalFromContext(c)
+	return principal.ID
+}
+
+// parseWatchlistID lee el identificador de la lista de la ruta y responde 400 si no es válido.
+func parseWatchlistID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("Identificador de lista inválido: "+c.Param("id")))
+		return 0, false
+	}
+	return id, true
+}
+
+// respondWatchlistError registra el error del repositorio para que el middleware de errores
+// responda según su categoría. Superar el máximo de tickers se responde 400, como las
+// demás restricciones de la solicitud.
+func respondWatchlistError(c *gin.Context, err error) {
+	if errors.Is(err, repository.ErrWatchlistFull) {
+		err = &problem.Error{
+			Status: http.StatusBadRequest,
+			Code:   problem.CodeInvalidRequest,
+			Detail: fmt.Sprintf("%s (%d)", repository.ErrWatchlistFull.Error(), repository.MaxWatchlistTickers),
+			Err:    err,
+		}
+	}
+	problem.Abort(c, err)
+}
@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader es la cabecera que transporta el identificador de solicitud.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength es la longitud máxima aceptada para un identificador recibido.
+const maxRequestIDLength = 128
+
+// RequestID es un middleware que asigna un identificador a cada solicitud. Respeta
+// el recibido en X-Request-ID si es válido, lo devuelve en la respuesta y lo guarda
+// en el contexto de la solicitud para los logs y las llamadas posteriores.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewRequestID()
+		}
+
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+
+		c.Next()
+	}
+}
+
+// validRequestID acepta identificadores cortos formados por caracteres seguros
+// para evitar inyectar contenido arbitrario en los logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
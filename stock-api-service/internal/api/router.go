@@ -3,61 +3,255 @@ package api
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/api/handlers"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/api/middlewares"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/auth"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/backtest"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/freshness"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/graphqlapi"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/health"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/metrics"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/openapi"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/ratelimit"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/recommendation"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/snapshot"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/stream"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
+// ServiceName es el nombre del servicio en las trazas.
+const ServiceName = "stock-api-service"
+
+// Dependencies agrupa los componentes compartidos que necesitan los handlers.
+type Dependencies struct {
+	// Repositorio de stocks
+	Stocks repository.StockRepository
+	// Repositorio de snapshots de recomendaciones
+	Snapshots *repository.SnapshotRepository
+	// Repositorio de listas de seguimiento
+	Watchlists *repository.WatchlistRepository
+	// Repositorio de reglas de alerta y alertas
+	Alerts *repository.AlertRepository
+	// Servicio que genera los snapshots de recomendaciones
+	SnapshotService *snapshot.Service
+	// Servicio de recomendaciones, con la caché compartida por los transportes
+	Recommendations *recommendation.Service
+	// Monitor de frescura de los datos
+	Freshness *freshness.Monitor
+	// Hub del stream de eventos
+	Stream *stream.Hub
+	// Intervalo entre heartbeats del stream de eventos
+	StreamHeartbeat time.Duration
+	// Servicio de consultas GraphQL
+	GraphQL *graphqlapi.Service
+	// Documento OpenAPI con el que se validan las solicitudes
+	OpenAPI *openapi.Spec
+	// Autenticador de solicitudes
+	Authenticator *auth.Authenticator
+	// Almacén de API keys
+	APIKeys *auth.KeyStore
+	// Limitador de solicitudes por cliente; nil lo desactiva
+	RateLimiter *ratelimit.Limiter
+	// Proxies de confianza (IP o CIDR) cuyo X-Forwarded-For se usa como IP del
+	// cliente; vacío usa siempre la IP de la conexión
+	TrustedProxies []string
+	// Política CORS, validada previamente
+	CORS middlewares.CORSConfig
+}
+
 // Router maneja la configuración de rutas de la API.
 type Router struct {
 	stockHandler          *handlers.StockHandler
 	recommendationHandler *handlers.RecommendationHandler
+	backtestHandler       *handlers.BacktestHandler
+	snapshotHandler       *handlers.SnapshotHandler
+	sectorHandler         *handlers.SectorHandler
+	watchlistHandler      *handlers.WatchlistHandler
+	alertHandler          *handlers.AlertHandler
+	apiKeyHandler         *handlers.APIKeyHandler
+	metaHandler           *handlers.MetaHandler
+	streamHandler         *handlers.StreamHandler
+	graphqlHandler        *handlers.GraphQLHandler
 	healthHandler         *health.HealthHandler
+	spec                  *openapi.Spec
+	freshness             *freshness.Monitor
+	authenticator         *auth.Authenticator
+	rateLimiter           *ratelimit.Limiter
+	trustedProxies        []string
+	cors                  middlewares.CORSConfig
 }
 
 // NewRouter crea una nueva instancia del router.
-func NewRouter(repo *repository.StockRepository) *Router {
+func NewRouter(deps Dependencies) *Router {
 	return &Router{
-		stockHandler:          handlers.NewStockHandler(repo),
-		recommendationHandler: handlers.NewRecommendationHandler(repo),
-		healthHandler:         health.NewHealthHandler(repo),
+		stockHandler:          handlers.NewStockHandler(deps.Stocks),
+		recommendationHandler: handlers.NewRecommendationHandler(deps.Recommendations, deps.Watchlists),
+		backtestHandler:       handlers.NewBacktestHandler(backtest.NewJobManager(deps.Stocks)),
+		snapshotHandler:       handlers.NewSnapshotHandler(deps.Snapshots, deps.SnapshotService),
+		sectorHandler:         handlers.NewSectorHandler(deps.Stocks),
+		watchlistHandler:      handlers.NewWatchlistHandler(deps.Watchlists, deps.Stocks),
+		alertHandler:          handlers.NewAlertHandler(deps.Alerts),
+		apiKeyHandler:         handlers.NewAPIKeyHandler(deps.APIKeys),
+		metaHandler:           handlers.NewMetaHandler(deps.Freshness),
+		streamHandler:         handlers.NewStreamHandler(deps.Stream, deps.StreamHeartbeat),
+		graphqlHandler:        handlers.NewGraphQLHandler(deps.GraphQL),
+		healthHandler:         health.NewHealthHandler(deps.Stocks, deps.Freshness),
+		spec:                  deps.OpenAPI,
+		freshness:             deps.Freshness,
+		authenticator:         deps.Authenticator,
+		rateLimiter:           deps.RateLimiter,
+		trustedProxies:        deps.TrustedProxies,
+		cors:                  deps.CORS,
 	}
 }
 
 // SetupRoutes configura las rutas del router.
 func (r *Router) SetupRoutes(router *gin.Engine) {
 	// Middleware para todas las rutas
+	router.Use(otelgin.Middleware(ServiceName, otelgin.WithFilter(traced)))
+	router.Use(middlewares.RequestID())
+	router.Use(metrics.Middleware())
 	router.Use(middlewares.Logger())
-	router.Use(middlewares.CORS())
+	router.Use(middlewares.Errors())
+	router.Use(middlewares.CORS(r.cors))
 
-	// Rutas para la API
+	// Rutas para la API, todas requieren autenticación. El límite por IP va antes
+	// para que los intentos con credenciales inválidas también se limiten. Cada
+	// grupo valida la solicitud contra el documento OpenAPI después de verificar
+	// el rol y el límite, para no revelar detalles del contrato sin credenciales
 	api := router.Group("/api/v1")
+	api.Use(r.rateLimiter.LimitIP(), r.authenticator.Authenticate())
+
+	// Rutas de lectura
+	reader := api.Group("")
+	reader.Use(auth.RequireRole(auth.RoleReader), r.rateLimiter.Limit(ratelimit.GroupRead), r.spec.Validate())
 	{
-		// Rutas para stocks
-		api.GET("/stocks", r.stockHandler.ListStocks)
-		api.GET("/stocks/:ticker", r.stockHandler.GetStockDetails)
+		// Rutas para stocks; indican hasta cuándo están actualizados los datos
+		reader.GET("/stocks", r.freshness.Header(), r.stockHandler.ListStocks)
+		reader.GET("/stocks/:ticker", r.freshness.Header(), r.stockHandler.GetStockDetails)
+		reader.GET("/stocks/:ticker/history", r.freshness.Header(), r.stockHandler.GetTickerHistory)
+
+		// Rutas para snapshots de recomendaciones
+		reader.GET("/recommendations/snapshots", r.snapshotHandler.ListSnapshots)
+		reader.GET("/recommendations/snapshots/diff", r.snapshotHandler.DiffSnapshots)
+		reader.GET("/recommendations/snapshots/:id", r.snapshotHandler.GetSnapshot)
+
+		// Ruta para el resumen por sector
+		reader.GET("/sectors", r.sectorHandler.ListSectors)
+
+		// Ruta para consultar backtests
+		reader.GET("/backtests/:id", r.backtestHandler.GetBacktest)
+
+		// Ruta para la frescura de los datos
+		reader.GET("/meta/freshness", r.metaHandler.GetFreshness)
+
+		// Stream de eventos nuevos por Server-Sent Events
+		reader.GET("/stream/events", r.streamHandler.StreamEvents)
+
+		// Listas de seguimiento del principal
+		reader.GET("/watchlists", r.watchlistHandler.ListWatchlists)
+		reader.GET("/watchlists/:id", r.watchlistHandler.GetWatchlist)
+		reader.GET("/watchlists/:id/feed", r.watchlistHandler.GetFeed)
 
-		// Ruta para recomendaciones
-		api.GET("/recommendations", r.recommendationHandler.GetRecommendations)
+		// Reglas de alerta y alertas disparadas del principal
+		reader.GET("/alerts", r.alertHandler.ListAlerts)
+		reader.GET("/alerts/rules", r.alertHandler.ListAlertRules)
+		reader.GET("/alerts/rules/:id", r.alertHandler.GetAlertRule)
+	}
+
+	// Modificación de las listas de seguimiento; cada principal administra las suyas
+	watchlists := api.Group("/watchlists")
+	watchlists.Use(auth.RequireRole(auth.RoleReader), r.rateLimiter.Limit(ratelimit.GroupWrite), r.spec.Validate())
+	{
+		watchlists.POST("", r.watchlistHandler.CreateWatchlist)
+		watchlists.PUT("/:id", r.watchlistHandler.UpdateWatchlist)
+		watchlists.DELETE("/:id", r.watchlistHandler.DeleteWatchlist)
+		watchlists.POST("/:id/tickers", r.watchlistHandler.AddTickers)
+		watchlists.DELETE("/:id/tickers/:ticker", r.watchlistHandler.RemoveTicker)
+	}
+
+	// Modificación de las reglas de alerta y reconocimiento de alertas
+	alerts := api.Group("/alerts")
+	alerts.Use(auth.RequireRole(auth.RoleReader), r.rateLimiter.Limit(ratelimit.GroupWrite), r.spec.Validate())
+	{
+		alerts.POST("/rules", r.alertHandler.CreateAlertRule)
+		alerts.PUT("/rules/:id", r.alertHandler.UpdateAlertRule)
+		alerts.DELETE("/rules/:id", r.alertHandler.DeleteAlertRule)
+		alerts.POST("/:id/ack", r.alertHandler.AcknowledgeAlert)
+	}
+
+	// Ruta para recomendaciones, con un límite más estricto por su costo
+	recommendations := api.Group("")
+	recommendations.Use(auth.RequireRole(auth.RoleReader), r.rateLimiter.Limit(ratelimit.GroupRecommendations), r.spec.Validate())
+	{
+		recommendations.GET("/recommendations", r.freshness.Header(), r.recommendationHandler.GetRecommendations)
 	}
 
-	// Rutas para health checks
-	router.GET("/health", r.healthHandler.BasicHealth)
-	router.GET("/health/detailed", r.healthHandler.DetailedHealth)
+	// Rutas que lanzan trabajos o escriben datos
+	operator := api.Group("")
+	operator.Use(auth.RequireRole(auth.RoleOperator), r.rateLimiter.Limit(ratelimit.GroupWrite), r.spec.Validate())
+	{
+		operator.POST("/recommendations/snapshots", r.snapshotHandler.CreateSnapshot)
+		operator.POST("/backtests", r.backtestHandler.CreateBacktest)
+	}
+
+	// Rutas de administración
+	admin := api.Group("/admin")
+	admin.Use(auth.RequireRole(auth.RoleAdmin), r.rateLimiter.Limit(ratelimit.GroupWrite), r.spec.Validate())
+	{
+		admin.POST("/api-keys", r.apiKeyHandler.CreateAPIKey)
+		admin.GET("/api-keys", r.apiKeyHandler.ListAPIKeys)
+		admin.DELETE("/api-keys/:id", r.apiKeyHandler.RevokeAPIKey)
+	}
+
+	// Consultas GraphQL, con la autenticación y el límite de lectura de la API
+	graphql := router.Group("/graphql")
+	graphql.Use(r.rateLimiter.LimitIP(), r.authenticator.Authenticate(), auth.RequireRole(auth.RoleReader), r.rateLimiter.Limit(ratelimit.GroupRead), r.spec.Validate())
+	{
+		graphql.POST("", r.graphqlHandler.Query)
+		graphql.GET("", r.graphqlHandler.Query)
+		graphql.GET("/schema", r.graphqlHandler.Schema)
+	}
+
+	// Sondas de vida y disponibilidad
+	router.GET("/livez", r.healthHandler.Livez)
+	router.GET("/readyz", r.healthHandler.Readyz)
+
+	// Rutas anteriores, se mantienen por compatibilidad
+	router.GET("/health", r.healthHandler.Livez)
+	router.GET("/health/detailed", r.healthHandler.Readyz)
+
+	// Ruta para métricas de Prometheus
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Documento OpenAPI de la API
+	router.GET(openapi.Path, r.spec.Handler())
+
+	// Las rutas desconocidas también responden application/problem+json
+	router.NoRoute(middlewares.NotFound)
 }
 
-// SetupServer configura y devuelve un servidor HTTP listo para usar.
-func (r *Router) SetupServer(port string) *http.Server {
+// SetupServer configura y devuelve un servidor HTTP listo para usar. Devuelve
+// un error si algún proxy de confianza no es una IP o un CIDR válido.
+func (r *Router) SetupServer(port string) (*http.Server, error) {
 	if port == "" {
 		port = "8080"
 	}
 
-	// Crear router Gin
-	router := gin.Default()
+	// Crear router Gin; los logs de solicitudes los escribe middlewares.Logger
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	// Sin proxies de confianza, X-Forwarded-For se ignora y cada cliente se
+	// identifica por la IP de la conexión
+	if err := router.SetTrustedProxies(r.trustedProxies); err != nil {
+		return nil, err
+	}
 
 	// Configurar rutas
 	r.SetupRoutes(router)
@@ -71,5 +265,16 @@ func (r *Router) SetupServer(port string) *http.Server {
 		IdleTimeout:  120 * time.Second,
 	}
 
-	return server
+	return server, nil
+}
+
+// traced indica si una solicitud debe generar un span. Las sondas de salud y las
+// métricas se excluyen porque se consultan continuamente, y el stream de eventos
+// porque una conexión puede durar horas.
+func traced(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/metrics", "/livez", "/readyz", "/api/v1/stream/events":
+		return false
+	}
+	return !strings.HasPrefix(r.URL.Path, "/health")
 }
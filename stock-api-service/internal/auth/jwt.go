@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTConfig contiene la configuración para validar tokens JWT.
+type JWTConfig struct {
+	// Secreto compartido para tokens HS256
+	HS256Secret string
+	// Ruta a un archivo JWKS local con las claves públicas RSA para tokens RS256
+	JWKSFile string
+	// Emisor esperado (iss); vacío para no validarlo
+	Issuer string
+	// Audiencia esperada (aud); vacía para no validarla
+	Audience string
+}
+
+// claims son los claims que el servicio interpreta de un JWT.
+type claims struct {
+	Name  string   `json:"name"`
+	Role  string   `json:"role"`
+	Roles []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// JWTVerifier valida tokens JWT firmados con HS256 o RS256.
+type JWTVerifier struct {
+	hsSecret []byte
+	rsaKeys  map[string]*rsa.PublicKey
+	parser   *jwt.Parser
+}
+
+// NewJWTVerifier crea un verificador de JWT. Devuelve nil si no hay ningún
+// secreto ni JWKS configurado.
+func NewJWTVerifier(cfg JWTConfig) (*JWTVerifier, error) {
+	if cfg.HS256Secret == "" && cfg.JWKSFile == "" {
+		return nil, nil
+	}
+
+	verifier := &JWTVerifier{
+		hsSecret: []byte(cfg.HS256Secret),
+	}
+
+	var methods []string
+	if cfg.HS256Secret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	verifier.parser = jwt.NewParser(options...)
+
+	return verifier, nil
+}
+
+// Verify valida el token y devuelve el principal que representa. El
+// identificador lleva el prefijo jwt: para que un sub no coincida con el de
+// una API key u otro principal.
+func (v *JWTVerifier) Verify(tokenString string) (Principal, error) {
+	var c claims
+	if _, err := v.parser.ParseWithClaims(tokenString, &c, v.key); err != nil {
+		return Principal{}, fmt.Errorf("token JWT inválido: %w", err)
+	}
+
+	if c.Subject == "" {
+		return Principal{}, fmt.Errorf("token JWT inválido: falta el claim sub")
+	}
+
+	role, ok := highestRole(append(c.Roles, c.Role))
+	if !ok {
+		return Principal{}, fmt.Errorf("token JWT inválido: no contiene un rol conocido")
+	}
+
+	name := c.Name
+	if name == "" {
+		name = c.Subject
+	}
+
+	return Principal{
+		ID:     "jwt:" + c.Subject,
+		Name:   name,
+		Role:   role,
+		Method: MethodJWT,
+	}, nil
+}
+
+// key selecciona la clave de verificación según el algoritmo y el kid del token.
+func (v *JWTVerifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.hsSecret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := v.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		// Sin kid solo se acepta si el JWKS contiene una única clave
+		if kid == "" && len(v.rsaKeys) == 1 {
+			for _, key := range v.rsaKeys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("clave desconocida: %q", kid)
+	default:
+		return nil, fmt.Errorf("algoritmo no soportado: %s", token.Method.Alg())
+	}
+}
+
+// jwk representa una clave pública RSA en formato JSON Web Key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// LoadJWKS lee un archivo JWKS local y devuelve sus claves RSA indexadas por kid.
+func LoadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el archivo JWKS: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("error al decodificar el archivo JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("módulo inválido en la clave %q: %w", key.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponente inválido en la clave %q: %w", key.Kid, err)
+		}
+
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("el archivo JWKS %s no contiene claves RSA de firma", path)
+	}
+
+	return keys, nil
+}
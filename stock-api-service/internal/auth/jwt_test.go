@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "secreto-de-prueba"
+
+// sign firma un token HS256 con el secreto de prueba y los claims indicados.
+func sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return token
+}
+
+// validClaims devuelve claims vigentes con el rol indicado, sobrescritos por extra.
+func validClaims(role string, extra jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"sub":  "servicio-interno",
+		"role": role,
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}
+	for key, value := range extra {
+		claims[key] = value
+	}
+	return claims
+}
+
+func TestNewJWTVerifierWithoutKeys(t *testing.T) {
+	verifier, err := NewJWTVerifier(JWTConfig{})
+	if verifier != nil || err != nil {
+		t.Errorf("NewJWTVerifier() = %v, %v; want nil, nil", verifier, err)
+	}
+}
+
+func TestVerifyHS256(t *testing.T) {
+	verifier, err := NewJWTVerifier(JWTConfig{HS256Secret: testSecret, Issuer: "emisor", Audience: "stocks"})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+	base := jwt.MapClaims{"iss": "emisor", "aud": "stocks"}
+	with := func(extra jwt.MapClaims) jwt.MapClaims {
+		claims := validClaims("reader", base)
+		for key, value := range extra {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantErr  bool
+		wantRole Role
+		wantName string
+	}{
+		{"válido", sign(t, with(nil)), false, RoleReader, "servicio-interno"},
+		{"con nombre", sign(t, with(jwt.MapClaims{"name": "Panel"})), false, RoleReader, "Panel"},
+		{"rol mayor de la lista", sign(t, with(jwt.MapClaims{"role": nil, "roles": []string{"reader", "desconocido", "admin"}})), false, RoleAdmin, "servicio-interno"},
+		{"vencido", sign(t, with(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})), true, "", ""},
+		{"sin exp", sign(t, with(jwt.MapClaims{"exp": nil})), true, "", ""},
+		{"sin sub", sign(t, with(jwt.MapClaims{"sub": nil})), true, "", ""},
+		{"sin rol conocido", sign(t, with(jwt.MapClaims{"role": "superusuario"})), true, "", ""},
+		{"otro emisor", sign(t, with(jwt.MapClaims{"iss": "otro"})), true, "", ""},
+		{"otra audiencia", sign(t, with(jwt.MapClaims{"aud": "otra"})), true, "", ""},
+		{"otro secreto", func() string {
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, with(nil)).SignedString([]byte("otro"))
+			return token
+		}(), true, "", ""},
+		{"algoritmo no permitido", func() string {
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodHS512, with(nil)).SignedString([]byte(testSecret))
+			return token
+		}(), true, "", ""},
+		{"sin firma", func() string {
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodNone, with(nil)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return token
+		}(), true, "", ""},
+		{"malformado", "no.es.un-jwt", true, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			want := Principal{ID: "jwt:servicio-interno", Name: tt.wantName, Role: tt.wantRole, Method: MethodJWT}
+			if principal != want {
+				t.Errorf("Verify() = %+v, want %+v", principal, want)
+			}
+		})
+	}
+}
+
+func TestVerifyNamespacesSubject(t *testing.T) {
+	verifier, err := NewJWTVerifier(JWTConfig{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+
+	// Un sub con la forma de una API key no se confunde con ella
+	principal, err := verifier.Verify(sign(t, validClaims("reader", jwt.MapClaims{"sub": "key:5"})))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if key := (APIKey{ID: 5}).Principal(); principal.ID == key.ID {
+		t.Errorf("Verify() ID = %q, coincide con la API key %q", principal.ID, key.ID)
+	}
+	if principal.ID != "jwt:key:5" {
+		t.Errorf("Verify() ID = %q, want %q", principal.ID, "jwt:key:5")
+	}
+}
+
+// writeJWKS guarda un JWKS con las claves públicas indicadas por kid.
+func writeJWKS(t *testing.T, keys map[string]*rsa.PublicKey) string {
+	t.Helper()
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+// signRS256 firma un token RS256 con la clave y el kid indicados.
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func TestVerifyRS256(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	single, err := NewJWTVerifier(JWTConfig{JWKSFile: writeJWKS(t, map[string]*rsa.PublicKey{"uno": &first.PublicKey})})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+	multiple, err := NewJWTVerifier(JWTConfig{JWKSFile: writeJWKS(t, map[string]*rsa.PublicKey{"uno": &first.PublicKey, "dos": &second.PublicKey})})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+	claims := validClaims("operator", nil)
+
+	tests := []struct {
+		name     string
+		verifier *JWTVerifier
+		token    string
+		wantErr  bool
+	}{
+		{"kid conocido", multiple, signRS256(t, second, "dos", claims), false},
+		{"sin kid con una clave", single, signRS256(t, first, "", claims), false},
+		{"sin kid con varias claves", multiple, signRS256(t, first, "", claims), true},
+		{"kid desconocido", multiple, signRS256(t, first, "tres", claims), true},
+		{"firmado con otra clave", multiple, signRS256(t, first, "dos", claims), true},
+		{"HS256 sin secreto configurado", single, sign(t, claims), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := tt.verifier.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && principal.Role != RoleOperator {
+				t.Errorf("Verify() rol = %q, want %q", principal.Role, RoleOperator)
+			}
+		})
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"archivo inexistente", filepath.Join(dir, "no-existe.json"), "error al leer"},
+		{"JSON inválido", write("invalido.json", "{"), "error al decodificar"},
+		{"sin claves RSA de firma", write("vacio.json", `{"keys":[{"kty":"EC","kid":"a"},{"kty":"RSA","kid":"b","use":"enc","n":"AQ","e":"AQAB"}]}`), "no contiene claves"},
+		{"módulo inválido", write("modulo.json", `{"keys":[{"kty":"RSA","kid":"a","n":"!","e":"AQAB"}]}`), "módulo inválido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadJWKS(tt.path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadJWKS() error = %v, want que contenga %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// errMissingCredentials indica que la solicitud no incluye credenciales.
+var errMissingCredentials = errors.New("se requiere una API key (X-API-Key) o un token Bearer")
+
+// errInvalidToken indica que el token Bearer no es válido.
+var errInvalidToken = errors.New("token inválido")
+
+// Authenticator identifica al cliente de cada solicitud mediante API key o JWT.
+type Authenticator struct {
+	enabled       bool
+	keys          *KeyStore
+	verifier      *JWTVerifier
+	bootstrapHash string
+}
+
+// NewAuthenticator crea un autenticador. Con enabled en false todas las solicitudes
+// se tratan como administrador, lo que solo es apropiado para desarrollo local.
+// bootstrapKey, si no está vacía, es una API key de administrador que no requiere
+// estar registrada en la base de datos y sirve para crear las primeras keys.
+func NewAuthenticator(enabled bool, keys *KeyStore, verifier *JWTVerifier, bootstrapKey string) *Authenticator {
+	authenticator := &Authenticator{
+		enabled:  enabled,
+		keys:     keys,
+		verifier: verifier,
+	}
+	if bootstrapKey != "" {
+		authenticator.bootstrapHash = HashKey(bootstrapKey)
+	}
+	return authenticator
+}
+
+// Authenticate es un middleware que exige credenciales válidas y guarda el
+// principal en el contexto de la solicitud.
+func (a *Authenticator) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := a.AuthenticateCredentials(c.Request.Context(), c.GetHeader("X-API-Key"), c.GetHeader("Authorization"))
+		if err != nil {
+			if IsCredentialError(err) {
+				c.Header("WWW-Authenticate", `Bearer realm="stock-microservices-api"`)
+				problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthenticated, "No autenticado: "+err.Error()))
+				return
+			}
+
+			problem.Abort(c, &problem.Error{
+				Status: http.StatusServiceUnavailable,
+				Code:   problem.CodeUnavailable,
+				Detail: "No se pudieron verificar las credenciales, intente más tarde",
+				Err:    err,
+			})
+			return
+		}
+
+		SetPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// RequireRole es un middleware que exige que el principal tenga al menos el rol indicado.
+func RequireRole(role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFromContext(c)
+		if !ok {
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthenticated, "No autenticado: "+errMissingCredentials.Error()))
+			return
+		}
+
+		if !principal.Role.Allows(role) {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeForbidden, "Acceso denegado: se requiere el rol "+string(role)))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AuthenticateCredentials obtiene el principal a partir de los valores de las
+// cabeceras X-API-Key y Authorization, de modo que otros transportes, como gRPC,
+// aceptan las mismas credenciales que la API REST. Con la autenticación
+// desactivada devuelve un administrador anónimo.
+func (a *Authenticator) AuthenticateCredentials(ctx context.Context, apiKey, authorization string) (Principal, error) {
+	if !a.enabled {
+		return Principal{ID: "anonymous", Name: "anonymous", Role: RoleAdmin, Method: MethodDisabled}, nil
+	}
+
+	if apiKey != "" {
+		return a.authenticateKey(ctx, apiKey)
+	}
+
+	if authorization == "" {
+		return Principal{}, errMissingCredentials
+	}
+
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return Principal{}, errMissingCredentials
+	}
+
+	// Las API keys también se aceptan como token Bearer
+	if strings.HasPrefix(token, keyPrefix) {
+		return a.authenticateKey(ctx, token)
+	}
+
+	if a.verifier == nil {
+		return Principal{}, fmt.Errorf("%w: la autenticación JWT no está configurada", errInvalidToken)
+	}
+
+	principal, err := a.verifier.Verify(token)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	return principal, nil
+}
+
+// authenticateKey valida una API key contra la key de arranque y la base de datos.
+func (a *Authenticator) authenticateKey(ctx context.Context, plaintext string) (Principal, error) {
+	if a.bootstrapHash != "" && subtle.ConstantTimeCompare([]byte(HashKey(plaintext)), []byte(a.bootstrapHash)) == 1 {
+		return Principal{ID: "bootstrap", Name: "bootstrap", Role: RoleAdmin, Method: MethodBootstrap}, nil
+	}
+
+	key, err := a.keys.Lookup(ctx, plaintext)
+	if err != nil {
+		return Principal{}, err
+	}
+	return key.Principal(), nil
+}
+
+// IsCredentialError indica si el error se debe a credenciales ausentes o
+// inválidas, y no a una falla al verificarlas.
+func IsCredentialError(err error) bool {
+	return errors.Is(err, errMissingCredentials) || errors.Is(err, ErrInvalidKey) || errors.Is(err, errInvalidToken)
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const testBootstrapKey = "sk_bootstrap_test"
+
+func TestAuthenticateCredentials(t *testing.T) {
+	verifier, err := NewJWTVerifier(JWTConfig{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+	authenticator := NewAuthenticator(true, nil, verifier, testBootstrapKey)
+	token := sign(t, validClaims("operator", nil))
+
+	tests := []struct {
+		name          string
+		apiKey        string
+		authorization string
+		wantID        string
+		wantCredError bool
+	}{
+		{"key de arranque", testBootstrapKey, "", "bootstrap", false},
+		{"key de arranque como Bearer", "", "Bearer " + testBootstrapKey, "bootstrap", false},
+		{"JWT", "", "Bearer " + token, "jwt:servicio-interno", false},
+		{"esquema en minúsculas", "", "bearer " + token, "jwt:servicio-interno", false},
+		{"sin credenciales", "", "", "", true},
+		{"esquema desconocido", "", "Basic dXNlcjpwYXNz", "", true},
+		{"Bearer vacío", "", "Bearer ", "", true},
+		{"JWT inválido", "", "Bearer no.es.valido", "", true},
+		{"key sin prefijo", "otra-key", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticator.AuthenticateCredentials(context.Background(), tt.apiKey, tt.authorization)
+			if tt.wantCredError {
+				if !IsCredentialError(err) {
+					t.Errorf("AuthenticateCredentials() error = %v, want error de credenciales", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateCredentials() error = %v", err)
+			}
+			if principal.ID != tt.wantID {
+				t.Errorf("AuthenticateCredentials() ID = %q, want %q", principal.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestAuthenticateCredentialsWithoutJWT(t *testing.T) {
+	authenticator := NewAuthenticator(true, nil, nil, "")
+	_, err := authenticator.AuthenticateCredentials(context.Background(), "", "Bearer "+sign(t, validClaims("reader", nil)))
+	if !errors.Is(err, errInvalidToken) {
+		t.Errorf("AuthenticateCredentials() error = %v, want %v", err, errInvalidToken)
+	}
+}
+
+func TestAuthenticateCredentialsDisabled(t *testing.T) {
+	principal, err := NewAuthenticator(false, nil, nil, "").AuthenticateCredentials(context.Background(), "", "")
+	if err != nil || principal.Role != RoleAdmin || principal.Method != MethodDisabled {
+		t.Errorf("AuthenticateCredentials() = %+v, %v; want administrador anónimo", principal, err)
+	}
+}
@@ -0,0 +1,423 @@
+// Paquete backtest permite evaluar el algoritmo de recomendación sobre datos históricos.
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/algorithm"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+)
+
+// EventSource proporciona el historial de eventos sobre el que se ejecuta el backtest.
+type EventSource interface {
+	GetStockEventsByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Stock, error)
+}
+
+// MaxRangeDays es la cantidad máxima de días entre el inicio y el fin de un
+// backtest lanzado desde la API.
+const MaxRangeDays = 730
+
+// Config contiene los parámetros de un backtest.
+type Config struct {
+	// Primera fecha de evaluación
+	Start time.Time `json:"start"`
+	// Última fecha de evaluación
+	End time.Time `json:"end"`
+	// Días entre fechas de evaluación consecutivas
+	StepDays int `json:"step_days"`
+	// Cantidad de recomendaciones generadas en cada fecha
+	TopN int `json:"top_n"`
+	// Días posteriores a cada fecha en los que se evalúa la recomendación
+	HorizonDays int `json:"horizon_days"`
+	// Días de historial que se entregan al recomendador en cada fecha
+	LookbackDays int `json:"lookback_days"`
+	// Estrategias a evaluar
+	Strategies []string `json:"strategies"`
+}
+
+// Normalize completa los valores predeterminados y valida la configuración.
+func (c *Config) Normalize() error {
+	if c.StepDays <= 0 {
+		c.StepDays = 7
+	}
+	if c.TopN <= 0 {
+		c.TopN = 10
+	}
+	if c.HorizonDays <= 0 {
+		c.HorizonDays = 30
+	}
+	if c.LookbackDays <= 0 {
+		c.LookbackDays = 30
+	}
+	if len(c.Strategies) == 0 {
+		for _, strategy := range algorithm.Strategies() {
+			c.Strategies = append(c.Strategies, strategy.Name)
+		}
+	}
+
+	if c.Start.IsZero() || c.End.IsZero() {
+		return fmt.Errorf("se requieren las fechas de inicio y fin")
+	}
+	if c.End.Before(c.Start) {
+		return fmt.Errorf("la fecha de fin debe ser posterior a la de inicio")
+	}
+	for _, name := range c.Strategies {
+		if _, ok := algorithm.StrategyByName(name); !ok {
+			return fmt.Errorf("estrategia desconocida: %s", name)
+		}
+	}
+
+	return nil
+}
+
+// StrategyReport resume el desempeño de una estrategia durante el backtest.
+type StrategyReport struct {
+	// Nombre de la estrategia
+	Strategy string `json:"strategy"`
+	// Fechas de evaluación en las que se generaron recomendaciones
+	AsOfDates int `json:"as_of_dates"`
+	// Total de recomendaciones generadas
+	Recommendations int `json:"recommendations"`
+	// Recomendaciones con movimientos posteriores para evaluar
+	Evaluated int `json:"evaluated"`
+	// Recomendaciones cuyo movimiento posterior fue positivo
+	Hits int `json:"hits"`
+	// Proporción de aciertos sobre las recomendaciones evaluadas
+	HitRate float64 `json:"hit_rate"`
+	// Cambio promedio del precio objetivo posterior a la recomendación (%)
+	AvgForwardTargetChange float64 `json:"avg_forward_target_change"`
+	// Retorno promedio según la serie de precios importada (%)
+	AvgForwardReturn *float64 `json:"avg_forward_return,omitempty"`
+	// Correlación de Spearman promedio entre el score y el resultado posterior
+	RankCorrelation float64 `json:"rank_correlation"`
+}
+
+// Report contiene el resultado de un backtest.
+type Report struct {
+	// Configuración utilizada
+	Config Config `json:"config"`
+	// Resultados por estrategia
+	Strategies []StrategyReport `json:"strategies"`
+	// Cantidad de eventos analizados
+	EventsAnalyzed int `json:"events_analyzed"`
+	// Indica si se evaluó contra una serie de precios importada
+	UsedPriceSeries bool `json:"used_price_series"`
+	// Fecha y hora de generación
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Run carga los eventos necesarios desde la fuente y ejecuta el backtest. Los
+// eventos se leen con consistencia fuerte, para que el resultado no dependa del
+// retraso de una réplica seguidora.
+func Run(ctx context.Context, source EventSource, cfg Config, prices PriceSeries) (Report, error) {
+	if err := cfg.Normalize(); err != nil {
+		return Report{}, err
+	}
+
+	startDate := cfg.Start.AddDate(0, 0, -cfg.LookbackDays)
+	endDate := cfg.End.AddDate(0, 0, cfg.HorizonDays)
+
+	events, err := source.GetStockEventsByDateRange(repository.WithStrongConsistency(ctx), startDate, endDate)
+	if err != nil {
+		return Report{}, fmt.Errorf("error al cargar eventos para el backtest: %w", err)
+	}
+
+	return NewEngine(events, prices).Run(ctx, cfg)
+}
+
+// Engine ejecuta backtests sobre un conjunto de eventos en memoria.
+type Engine struct {
+	events   []models.Stock
+	byTicker map[string][]models.Stock
+	prices   PriceSeries
+}
+
+// NewEngine crea un motor de backtest. La serie de precios es opcional.
+func NewEngine(events []models.Stock, prices PriceSeries) *Engine {
+	sorted := make([]models.Stock, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	byTicker := make(map[string][]models.Stock)
+	for _, event := range sorted {
+		byTicker[event.Ticker] = append(byTicker[event.Ticker], event)
+	}
+
+	return &Engine{
+		events:   sorted,
+		byTicker: byTicker,
+		prices:   prices,
+	}
+}
+
+// outcome representa el resultado posterior de una recomendación.
+type outcome struct {
+	score        float64
+	targetChange float64
+	hasTarget    bool
+	ratingDelta  float64
+	forwardRet   float64
+	hasReturn    bool
+}
+
+// Run ejecuta el backtest con la configuración indicada. Se detiene con el error
+// del contexto si este se cancela o vence antes de terminar.
+func (e *Engine) Run(ctx context.Context, cfg Config) (Report, error) {
+	if err := cfg.Normalize(); err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		Config:          cfg,
+		EventsAnalyzed:  len(e.events),
+		UsedPriceSeries: len(e.prices) > 0,
+		GeneratedAt:     time.Now(),
+	}
+
+	for _, name := range cfg.Strategies {
+		strategy, _ := algorithm.StrategyByName(name)
+		result, err := e.runStrategy(ctx, cfg, strategy)
+		if err != nil {
+			return Report{}, err
+		}
+		report.Strategies = append(report.Strategies, result)
+	}
+
+	return report, nil
+}
+
+// runStrategy evalúa una estrategia en cada fecha del backtest y comprueba el
+// contexto antes de cada una.
+func (e *Engine) runStrategy(ctx context.Context, cfg Config, strategy algorithm.Strategy) (StrategyReport, error) {
+	recommender := algorithm.NewStockRecommenderWithStrategy(strategy)
+	result := StrategyReport{Strategy: strategy.Name}
+
+	var targetChangeSum, returnSum, correlationSum float64
+	var targetChanges, returns, correlations int
+
+	for asOf := cfg.Start; !asOf.After(cfg.End); asOf = asOf.AddDate(0, 0, cfg.StepDays) {
+		if err := ctx.Err(); err != nil {
+			return StrategyReport{}, err
+		}
+
+		// Solo se entregan al recomendador los eventos conocidos en asOf
+		window := e.eventsBetween(asOf.AddDate(0, 0, -cfg.LookbackDays), asOf)
+		recommendations := recommender.GenerateRecommendationsAt(window, cfg.TopN, asOf)
+		if len(recommendations) == 0 {
+			continue
+		}
+
+		result.AsOfDates++
+		result.Recommendations += len(recommendations)
+
+		horizon := asOf.AddDate(0, 0, cfg.HorizonDays)
+		var outcomes []outcome
+
+		for _, rec := range recommendations {
+			out, ok := e.evaluate(recommender, rec, asOf, horizon)
+			if !ok {
+				continue
+			}
+
+			result.Evaluated++
+			if isHit(out) {
+				result.Hits++
+			}
+			if out.hasTarget {
+				targetChangeSum += out.targetChange
+				targetChanges++
+			}
+			if out.hasReturn {
+				returnSum += out.forwardRet
+				returns++
+			}
+			outcomes = append(outcomes, out)
+		}
+
+		if corr, ok := rankCorrelation(outcomes); ok {
+			correlationSum += corr
+			correlations++
+		}
+	}
+
+	if result.Evaluated > 0 {
+		result.HitRate = float64(result.Hits) / float64(result.Evaluated)
+	}
+	if targetChanges > 0 {
+		result.AvgForwardTargetChange = targetChangeSum / float64(targetChanges)
+	}
+	if returns > 0 {
+		avg := returnSum / float64(returns)
+		result.AvgForwardReturn = &avg
+	}
+	if correlations > 0 {
+		result.RankCorrelation = correlationSum / float64(correlations)
+	}
+
+	return result, nil
+}
+
+// eventsBetween devuelve los eventos con fecha en el intervalo [start, end].
+func (e *Engine) eventsBetween(start, end time.Time) []models.Stock {
+	from := sort.Search(len(e.events), func(i int) bool {
+		return !e.events[i].Time.Before(start)
+	})
+	to := sort.Search(len(e.events), func(i int) bool {
+		return e.events[i].Time.After(end)
+	})
+	if from >= to {
+		return nil
+	}
+	return e.events[from:to]
+}
+
+// evaluate compara una recomendación con los movimientos del ticker entre asOf y horizon.
+func (e *Engine) evaluate(recommender *algorithm.StockRecommender, rec models.RecommendationResult, asOf, horizon time.Time) (outcome, bool) {
+	out := outcome{score: rec.Score}
+	evaluated := false
+
+	// Último evento del ticker dentro del horizonte de evaluación
+	var last *models.Stock
+	for i, event := range e.byTicker[rec.Stock.Ticker] {
+		if event.Time.After(asOf) && !event.Time.After(horizon) {
+			last = &e.byTicker[rec.Stock.Ticker][i]
+		}
+	}
+
+	if last != nil {
+		basePrice := algorithm.ExtractPrice(rec.Stock.TargetTo)
+		newPrice := algorithm.ExtractPrice(last.TargetTo)
+		if basePrice > 0 && newPrice > 0 {
+			out.targetChange = ((newPrice - basePrice) / basePrice) * 100
+			out.hasTarget = true
+		}
+
+		baseRating, baseOK := recommender.RatingValue(rec.Stock.RatingTo)
+		newRating, newOK := recommender.RatingValue(last.RatingTo)
+		if baseOK && newOK {
+			out.ratingDelta = newRating - baseRating
+		}
+
+		evaluated = out.hasTarget || (baseOK && newOK)
+	}
+
+	if len(e.prices) > 0 {
+		if ret, ok := e.prices.Return(rec.Stock.Ticker, asOf, horizon); ok {
+			out.forwardRet = ret
+			out.hasReturn = true
+			evaluated = true
+		}
+	}
+
+	return out, evaluated
+}
+
+// isHit indica si el movimiento posterior a la recomendación fue favorable.
+// Con serie de precios se usa el retorno; sin ella, el balance entre el cambio
+// de precio objetivo y el cambio de calificación.
+func isHit(out outcome) bool {
+	if out.hasReturn {
+		return out.forwardRet > 0
+	}
+
+	signal := sign(out.ratingDelta)
+	if out.hasTarget {
+		signal += sign(out.targetChange)
+	}
+	return signal > 0
+}
+
+// rankCorrelation calcula la correlación de Spearman entre el score de las
+// recomendaciones y su resultado posterior.
+func rankCorrelation(outcomes []outcome) (float64, bool) {
+	var scores, results []float64
+	for _, out := range outcomes {
+		switch {
+		case out.hasReturn:
+			results = append(results, out.forwardRet)
+		case out.hasTarget:
+			results = append(results, out.targetChange)
+		default:
+			continue
+		}
+		scores = append(scores, out.score)
+	}
+
+	if len(scores) < 3 {
+		return 0, false
+	}
+
+	return pearson(ranks(scores), ranks(results))
+}
+
+// ranks asigna rangos a los valores, promediando los empates.
+func ranks(values []float64) []float64 {
+	indexes := make([]int, len(values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return values[indexes[i]] < values[indexes[j]]
+	})
+
+	result := make([]float64, len(values))
+	for i := 0; i < len(indexes); {
+		j := i
+		for j+1 < len(indexes) && values[indexes[j+1]] == values[indexes[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			result[indexes[k]] = rank
+		}
+		i = j + 1
+	}
+
+	return result
+}
+
+// pearson calcula el coeficiente de correlación de Pearson.
+func pearson(x, y []float64) (float64, bool) {
+	n := float64(len(x))
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx := x[i] - meanX
+		dy := y[i] - meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+
+	if varX == 0 || varY == 0 {
+		return 0, false
+	}
+
+	return cov / math.Sqrt(varX*varY), true
+}
+
+// sign devuelve el signo de un número.
+func sign(value float64) float64 {
+	switch {
+	case value > 0:
+		return 1
+	case value < 0:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,177 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+)
+
+func TestRanks(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   []float64
+	}{
+		{"sin empates", []float64{30, 10, 20}, []float64{3, 1, 2}},
+		{"empates promediados", []float64{5, 1, 5, 3}, []float64{3.5, 1, 3.5, 2}},
+		{"todos iguales", []float64{2, 2, 2}, []float64{2, 2, 2}},
+		{"vacío", nil, []float64{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ranks(tc.values); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ranks(%v) = %v, want %v", tc.values, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPearson(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want float64
+		ok   bool
+	}{
+		{"relación positiva perfecta", []float64{1, 2, 3}, []float64{10, 20, 30}, 1, true},
+		{"relación negativa perfecta", []float64{1, 2, 3}, []float64{3, 2, 1}, -1, true},
+		{"sin relación", []float64{1, 2, 3, 4}, []float64{1, -1, -1, 1}, 0, true},
+		{"correlación parcial", []float64{1, 2, 3, 4}, []float64{1, 3, 2, 4}, 0.8, true},
+		{"sin varianza en x", []float64{2, 2, 2}, []float64{1, 2, 3}, 0, false},
+		{"sin varianza en y", []float64{1, 2, 3}, []float64{5, 5, 5}, 0, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := pearson(tc.x, tc.y)
+			if ok != tc.ok || math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("pearson(%v, %v) = %v, %v; want %v, %v", tc.x, tc.y, got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestRankCorrelation(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []outcome
+		want     float64
+		ok       bool
+	}{
+		{
+			"orden igual al del retorno",
+			[]outcome{
+				{score: 3, forwardRet: 0.5, hasReturn: true},
+				{score: 1, forwardRet: -0.2, hasReturn: true},
+				{score: 2, forwardRet: 0.1, hasReturn: true},
+			},
+			1, true,
+		},
+		{
+			"sin retorno usa el cambio del precio objetivo",
+			[]outcome{
+				{score: 3, targetChange: -10, hasTarget: true},
+				{score: 2, targetChange: 0, hasTarget: true},
+				{score: 1, targetChange: 10, hasTarget: true},
+			},
+			-1, true,
+		},
+		{
+			"el retorno tiene prioridad sobre el precio objetivo",
+			[]outcome{
+				{score: 1, forwardRet: 1, hasReturn: true, targetChange: -50, hasTarget: true},
+				{score: 2, forwardRet: 2, hasReturn: true, targetChange: -60, hasTarget: true},
+				{score: 3, forwardRet: 3, hasReturn: true, targetChange: -70, hasTarget: true},
+			},
+			1, true,
+		},
+		{
+			"los resultados sin medida se descartan",
+			[]outcome{
+				{score: 1, forwardRet: 1, hasReturn: true},
+				{score: 2, forwardRet: 2, hasReturn: true},
+				{score: 3, ratingDelta: 1},
+			},
+			0, false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := rankCorrelation(tc.outcomes)
+			if ok != tc.ok || math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("rankCorrelation() = %v, %v; want %v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestIsHit(t *testing.T) {
+	tests := []struct {
+		name string
+		out  outcome
+		want bool
+	}{
+		{"retorno positivo", outcome{forwardRet: 0.01, hasReturn: true}, true},
+		{"retorno nulo", outcome{forwardRet: 0, hasReturn: true}, false},
+		{"el retorno tiene prioridad", outcome{forwardRet: -0.1, hasReturn: true, targetChange: 20, hasTarget: true, ratingDelta: 1}, false},
+		{"mejora de calificación", outcome{ratingDelta: 1}, true},
+		{"rebaja de calificación", outcome{ratingDelta: -1}, false},
+		{"alza del precio objetivo", outcome{targetChange: 5, hasTarget: true}, true},
+		{"señales opuestas se anulan", outcome{ratingDelta: -2, targetChange: 5, hasTarget: true}, false},
+		{"cambio de precio sin precio objetivo se ignora", outcome{targetChange: 5}, false},
+		{"sin cambios", outcome{}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isHit(tc.out); got != tc.want {
+				t.Errorf("isHit(%+v) = %v, want %v", tc.out, got, tc.want)
+			}
+		})
+	}
+}
+
+// strongSource registra si la lectura pidió consistencia fuerte.
+type strongSource struct {
+	strong bool
+}
+
+func (s *strongSource) GetStockEventsByDateRange(ctx context.Context, _, _ time.Time) ([]models.Stock, error) {
+	s.strong = repository.StrongConsistency(ctx)
+	return nil, nil
+}
+
+func TestRunReadsWithStrongConsistency(t *testing.T) {
+	source := &strongSource{}
+	cfg := Config{
+		Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if _, err := Run(context.Background(), source, cfg, nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !source.strong {
+		t.Error("Run() leyó los eventos sin consistencia fuerte")
+	}
+}
+
+func TestEngineRunStopsWhenContextIsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := Config{
+		Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if _, err := NewEngine(nil, nil).Run(ctx, cfg); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,214 @@
+package backtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobStatus representa el estado de un trabajo de backtest.
+type JobStatus string
+
+const (
+	// JobPending indica que el trabajo aún no ha comenzado
+	JobPending JobStatus = "pending"
+	// JobRunning indica que el trabajo está en ejecución
+	JobRunning JobStatus = "running"
+	// JobCompleted indica que el trabajo terminó correctamente
+	JobCompleted JobStatus = "completed"
+	// JobFailed indica que el trabajo terminó con error
+	JobFailed JobStatus = "failed"
+)
+
+// Job representa una ejecución asíncrona de un backtest.
+type Job struct {
+	ID         string     `json:"id"`
+	Status     JobStatus  `json:"status"`
+	Config     Config     `json:"config"`
+	Report     *Report    `json:"report,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Retención predeterminada de los trabajos terminados.
+const (
+	// DefaultJobRetention es el tiempo que se conserva un trabajo terminado
+	DefaultJobRetention = time.Hour
+	// DefaultMaxJobs es la cantidad máxima de trabajos en memoria
+	DefaultMaxJobs = 100
+	// DefaultMaxRunningJobs es la cantidad máxima de trabajos sin terminar
+	DefaultMaxRunningJobs = 4
+)
+
+// ErrTooManyJobs indica que se alcanzó el máximo de trabajos sin terminar.
+var ErrTooManyJobs = errors.New("demasiados backtests en ejecución")
+
+// JobManager ejecuta backtests en segundo plano y conserva sus resultados en
+// memoria. Los trabajos terminados se descartan al superar la retención o, si
+// hay demasiados, del más antiguo al más reciente. Los trabajos sin terminar
+// nunca se descartan, por lo que su cantidad se limita al enviarlos.
+type JobManager struct {
+	source     EventSource
+	timeout    time.Duration
+	retention  time.Duration
+	maxJobs    int
+	maxRunning int
+	now        func() time.Time
+
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+// NewJobManager crea un gestor de trabajos de backtest con la retención predeterminada.
+func NewJobManager(source EventSource) *JobManager {
+	return &JobManager{
+		source:     source,
+		timeout:    10 * time.Minute,
+		retention:  DefaultJobRetention,
+		maxJobs:    DefaultMaxJobs,
+		maxRunning: DefaultMaxRunningJobs,
+		now:        time.Now,
+		jobs:       make(map[string]*Job),
+	}
+}
+
+// Submit valida la configuración y lanza el backtest en una goroutine. El trabajo
+// conserva los valores del contexto (como el identificador de solicitud) pero no
+// su cancelación. Devuelve ErrTooManyJobs si ya hay demasiados trabajos sin terminar.
+func (m *JobManager) Submit(ctx context.Context, cfg Config, prices PriceSeries) (Job, error) {
+	if err := cfg.Normalize(); err != nil {
+		return Job{}, err
+	}
+
+	job := &Job{
+		ID:        newJobID(),
+		Status:    JobPending,
+		Config:    cfg,
+		CreatedAt: m.now(),
+	}
+
+	m.mu.Lock()
+	if m.running() >= m.maxRunning {
+		m.mu.Unlock()
+		return Job{}, ErrTooManyJobs
+	}
+	m.evict(1)
+	m.jobs[job.ID] = job
+	snapshot := *job
+	m.mu.Unlock()
+
+	go m.run(context.WithoutCancel(ctx), job.ID, cfg, prices)
+
+	return snapshot, nil
+}
+
+// Get devuelve una copia del trabajo con el identificador indicado.
+func (m *JobManager) Get(id string) (Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok || m.expired(job) {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// expired indica si un trabajo terminó hace más de la retención.
+func (m *JobManager) expired(job *Job) bool {
+	return job.FinishedAt != nil && m.now().Sub(*job.FinishedAt) > m.retention
+}
+
+// running cuenta los trabajos pendientes o en ejecución. Se llama con el lock tomado.
+func (m *JobManager) running() int {
+	count := 0
+	for _, job := range m.jobs {
+		if job.FinishedAt == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// evict descarta los trabajos vencidos y, si no queda lugar para reserve
+// trabajos nuevos, los terminados más antiguos. Se llama con el lock tomado.
+func (m *JobManager) evict(reserve int) {
+	var finished []*Job
+	for id, job := range m.jobs {
+		switch {
+		case m.expired(job):
+			delete(m.jobs, id)
+		case job.FinishedAt != nil:
+			finished = append(finished, job)
+		}
+	}
+
+	excess := len(m.jobs) + reserve - m.maxJobs
+	if excess <= 0 {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].FinishedAt.Before(*finished[j].FinishedAt)
+	})
+	for i := 0; i < excess && i < len(finished); i++ {
+		delete(m.jobs, finished[i].ID)
+	}
+}
+
+// run ejecuta el backtest y actualiza el estado del trabajo.
+func (m *JobManager) run(ctx context.Context, id string, cfg Config, prices PriceSeries) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	started := m.now()
+	m.update(id, func(job *Job) {
+		job.Status = JobRunning
+		job.StartedAt = &started
+	})
+
+	report, err := Run(ctx, m.source, cfg, prices)
+
+	finished := m.now()
+	m.update(id, func(job *Job) {
+		job.FinishedAt = &finished
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = JobCompleted
+		job.Report = &report
+	})
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Error en el backtest", "backtest_id", id, "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "Backtest completado", "backtest_id", id, "duration", finished.Sub(started))
+}
+
+// update aplica un cambio al trabajo bajo el lock del gestor.
+func (m *JobManager) update(id string, fn func(job *Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job, ok := m.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// newJobID genera un identificador aleatorio para un trabajo.
+func newJobID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,110 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// emptySource es una fuente sin eventos.
+type emptySource struct{}
+
+func (emptySource) GetStockEventsByDateRange(context.Context, time.Time, time.Time) ([]models.Stock, error) {
+	return nil, nil
+}
+
+// newTestJobManager crea un gestor con un reloj controlado por la prueba.
+func newTestJobManager(retention time.Duration, maxJobs int) (*JobManager, *time.Time) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := NewJobManager(emptySource{})
+	m.retention = retention
+	m.maxJobs = maxJobs
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+// addJob registra un trabajo con el estado indicado, terminado en finishedAt si no es nil.
+func addJob(m *JobManager, id string, status JobStatus, finishedAt *time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[id] = &Job{ID: id, Status: status, FinishedAt: finishedAt}
+}
+
+func TestJobManagerExpiresFinishedJobs(t *testing.T) {
+	m, now := newTestJobManager(time.Hour, 10)
+	finished := now.Add(-30 * time.Minute)
+	addJob(m, "reciente", JobCompleted, &finished)
+	addJob(m, "en-curso", JobRunning, nil)
+
+	if _, ok := m.Get("reciente"); !ok {
+		t.Fatal("Get() no encontró un trabajo dentro de la retención")
+	}
+
+	*now = now.Add(time.Hour)
+	if _, ok := m.Get("reciente"); ok {
+		t.Error("Get() devolvió un trabajo vencido")
+	}
+	if _, ok := m.Get("en-curso"); !ok {
+		t.Error("Get() no encontró un trabajo en ejecución")
+	}
+
+	m.mu.Lock()
+	m.evict(0)
+	_, kept := m.jobs["reciente"]
+	m.mu.Unlock()
+	if kept {
+		t.Error("evict() conservó un trabajo vencido")
+	}
+}
+
+func TestJobManagerEvictsOldestFinishedJobs(t *testing.T) {
+	m, now := newTestJobManager(time.Hour, 3)
+	older, newer := now.Add(-20*time.Minute), now.Add(-10*time.Minute)
+	addJob(m, "antiguo", JobFailed, &older)
+	addJob(m, "nuevo", JobCompleted, &newer)
+	addJob(m, "en-curso", JobRunning, nil)
+
+	cfg := Config{Start: now.AddDate(0, 0, -7), End: *now, Strategies: []string{"balanced"}}
+	job, err := m.Submit(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	for id, want := range map[string]bool{"antiguo": false, "nuevo": true, "en-curso": true, job.ID: true} {
+		if _, ok := m.Get(id); ok != want {
+			t.Errorf("Get(%q) encontrado = %v, want %v", id, ok, want)
+		}
+	}
+
+	// Los trabajos sin terminar se conservan aunque superen el máximo
+	addJob(m, "nuevo", JobRunning, nil)
+	if _, err := m.Submit(context.Background(), cfg, nil); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if _, ok := m.Get("en-curso"); !ok {
+		t.Error("evict() descartó un trabajo en ejecución")
+	}
+}
+
+func TestJobManagerLimitsRunningJobs(t *testing.T) {
+	m, now := newTestJobManager(time.Hour, 10)
+	m.maxRunning = 2
+	finished := now.Add(-time.Minute)
+	addJob(m, "terminado", JobCompleted, &finished)
+	addJob(m, "pendiente", JobPending, nil)
+	addJob(m, "en-curso", JobRunning, nil)
+
+	cfg := Config{Start: now.AddDate(0, 0, -7), End: *now, Strategies: []string{"balanced"}}
+	if _, err := m.Submit(context.Background(), cfg, nil); !errors.Is(err, ErrTooManyJobs) {
+		t.Fatalf("Submit() error = %v, want %v", err, ErrTooManyJobs)
+	}
+
+	// Al terminar un trabajo se libera su lugar
+	addJob(m, "en-curso", JobCompleted, &finished)
+	if _, err := m.Submit(context.Background(), cfg, nil); err != nil {
+		t.Errorf("Submit() error = %v", err)
+	}
+}
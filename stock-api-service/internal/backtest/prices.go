@@ -0,0 +1,121 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PricePoint representa el precio de cierre de un ticker en una fecha.
+type PricePoint struct {
+	// Símbolo o ticker de la acción
+	Ticker string `json:"ticker"`
+	// Fecha del precio
+	Date time.Time `json:"date"`
+	// Precio de cierre
+	Close float64 `json:"close"`
+}
+
+// PriceSeries agrupa los precios importados por ticker, ordenados por fecha.
+type PriceSeries map[string][]PricePoint
+
+// NewPriceSeries crea una serie de precios a partir de una lista de puntos.
+func NewPriceSeries(points []PricePoint) PriceSeries {
+	series := make(PriceSeries)
+	for _, point := range points {
+		series[point.Ticker] = append(series[point.Ticker], point)
+	}
+	for ticker := range series {
+		points := series[ticker]
+		sort.Slice(points, func(i, j int) bool {
+			return points[i].Date.Before(points[j].Date)
+		})
+	}
+	return series
+}
+
+// LoadPriceSeriesCSV lee una serie de precios en formato CSV con las columnas
+// ticker, date (YYYY-MM-DD o RFC3339) y close. La primera fila es la cabecera.
+func LoadPriceSeriesCSV(r io.Reader) (PriceSeries, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error al leer la serie de precios: %w", err)
+	}
+	if len(records) == 0 {
+		return PriceSeries{}, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"ticker", "date", "close"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("la serie de precios no contiene la columna %q", name)
+		}
+	}
+
+	var points []PricePoint
+	for line, record := range records[1:] {
+		date, err := ParseDate(record[columns["date"]])
+		if err != nil {
+			return nil, fmt.Errorf("fecha inválida en la línea %d: %w", line+2, err)
+		}
+
+		closePrice, err := strconv.ParseFloat(strings.TrimSpace(record[columns["close"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("precio inválido en la línea %d: %w", line+2, err)
+		}
+
+		points = append(points, PricePoint{
+			Ticker: strings.TrimSpace(record[columns["ticker"]]),
+			Date:   date,
+			Close:  closePrice,
+		})
+	}
+
+	return NewPriceSeries(points), nil
+}
+
+// PriceAt devuelve el último precio conocido del ticker en la fecha indicada.
+func (s PriceSeries) PriceAt(ticker string, date time.Time) (PricePoint, bool) {
+	points := s[ticker]
+	index := sort.Search(len(points), func(i int) bool {
+		return points[i].Date.After(date)
+	})
+	if index == 0 {
+		return PricePoint{}, false
+	}
+	return points[index-1], true
+}
+
+// Return calcula el retorno porcentual del ticker entre dos fechas.
+func (s PriceSeries) Return(ticker string, from, to time.Time) (float64, bool) {
+	start, ok := s.PriceAt(ticker, from)
+	if !ok || start.Close <= 0 {
+		return 0, false
+	}
+
+	end, ok := s.PriceAt(ticker, to)
+	if !ok || !end.Date.After(start.Date) {
+		return 0, false
+	}
+
+	return ((end.Close - start.Close) / start.Close) * 100, true
+}
+
+// ParseDate interpreta una fecha en formato YYYY-MM-DD o RFC3339.
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
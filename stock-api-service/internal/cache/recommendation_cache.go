@@ -0,0 +1,118 @@
+// Paquete cache proporciona cachés en memoria para respuestas costosas de calcular.
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// entry es un valor almacenado en la caché junto con su vencimiento.
+type entry struct {
+	response  models.RecommendationResponse
+	expiresAt time.Time
+}
+
+// RecommendationCache guarda respuestas de recomendaciones por clave de parámetros.
+// Las entradas vencen tras el TTL y se descartan todas al invalidar la caché.
+// Cada invalidación avanza la generación, de modo que una respuesta calculada
+// con datos anteriores a la invalidación no se guarda después de ella.
+type RecommendationCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu         sync.RWMutex
+	entries    map[string]entry
+	generation uint64
+}
+
+// NewRecommendationCache crea una caché con el TTL y la cantidad máxima de entradas indicados.
+func NewRecommendationCache(ttl time.Duration, maxEntries int) *RecommendationCache {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+
+	return &RecommendationCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]entry),
+	}
+}
+
+// Get devuelve la respuesta almacenada para la clave si existe y no ha vencido.
+func (c *RecommendationCache) Get(key string) (models.RecommendationResponse, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expiresAt) {
+		return models.RecommendationResponse{}, false
+	}
+	return e.response, true
+}
+
+// Generation devuelve la generación actual. Se obtiene antes de leer los datos
+// de una respuesta y se pasa a Set al guardarla.
+func (c *RecommendationCache) Generation() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.generation
+}
+
+// Set almacena la respuesta para la clave si la caché no se invalidó desde la
+// generación indicada; si se invalidó, la respuesta puede ser anterior a la
+// sincronización y se descarta.
+func (c *RecommendationCache) Set(key string, generation uint64, response models.RecommendationResponse) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := time.Now()
+	if len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+
+	c.entries[key] = entry{
+		response:  response,
+		expiresAt: now.Add(c.ttl),
+	}
+}
+
+// Invalidate descarta todas las entradas de la caché y avanza la generación.
+func (c *RecommendationCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]entry)
+	c.generation++
+}
+
+// evict elimina las entradas vencidas y, si no basta, la que vence antes.
+func (c *RecommendationCache) evict(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+
+	for key, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldestKey == "" || e.expiresAt.Before(oldest) {
+			oldestKey = key
+			oldest = e.expiresAt
+		}
+	}
+
+	if len(c.entries) >= c.maxEntries && oldestKey != "" {
+		delete(c.entries, oldestKey)
+	}
+}
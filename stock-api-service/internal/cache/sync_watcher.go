@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SyncSource indica cuándo terminó la última sincronización exitosa de stock-data-service.
+type SyncSource interface {
+	GetLastSuccessfulSync(ctx context.Context) (*time.Time, error)
+}
+
+// Invalidator es cualquier caché que pueda descartarse por completo.
+type Invalidator interface {
+	Invalidate()
+}
+
+// SyncWatcher consulta periódicamente la tabla de sincronizaciones e invalida
+// las cachés registradas cuando detecta una sincronización nueva.
+type SyncWatcher struct {
+	source   SyncSource
+	interval time.Duration
+	caches   []Invalidator
+	// Funciones llamadas después de invalidar las cachés
+	listeners []func()
+
+	// Última sincronización conocida; polled indica si ya hubo una consulta exitosa
+	last   *time.Time
+	polled bool
+}
+
+// NewSyncWatcher crea un observador de sincronizaciones.
+func NewSyncWatcher(source SyncSource, interval time.Duration, caches ...Invalidator) *SyncWatcher {
+	return &SyncWatcher{
+		source:   source,
+		interval: interval,
+		caches:   caches,
+	}
+}
+
+// OnSync registra una función que se llama cada vez que se detecta una
+// sincronización nueva, después de invalidar las cachés. Debe registrarse antes
+// de llamar a Run y no debe bloquear.
+func (w *SyncWatcher) OnSync(fn func()) {
+	w.listeners = append(w.listeners, fn)
+}
+
+// Run consulta las sincronizaciones hasta que el contexto se cancela.
+func (w *SyncWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll compara la última sincronización con la conocida e invalida si cambió.
+func (w *SyncWatcher) poll(ctx context.Context) {
+	last, err := w.source.GetLastSuccessfulSync(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error al consultar la última sincronización", "error", err)
+		return
+	}
+
+	changed := last != nil && (w.last == nil || last.After(*w.last))
+	if w.polled && changed {
+		slog.InfoContext(ctx, "Nueva sincronización detectada, invalidando cachés", "finished_at", last.Format(time.RFC3339))
+		for _, c := range w.caches {
+			c.Invalidate()
+		}
+		for _, fn := range w.listeners {
+			fn()
+		}
+	}
+	if changed {
+		w.last = last
+	}
+	w.polled = true
+}
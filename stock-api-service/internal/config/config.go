@@ -4,12 +4,29 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/database"
 )
 
 // Config contiene la configuración de la aplicación.
 type Config struct {
 	// Puerto del servidor
 	ServerPort string
+	// Puerto del servidor gRPC; vacío lo desactiva
+	GRPCPort string
+	// Nivel de log: debug, info, warn o error
+	LogLevel string
+	// Formato de log: json o text
+	LogFormat string
+	// Exportador de trazas: none, stdout u otlp
+	TracingExporter string
+	// URL del colector OTLP/HTTP
+	TracingOTLPEndpoint string
+	// Fracción de trazas muestreadas, entre 0 y 1
+	TracingSampleRatio float64
 	// Configuración de la base de datos
 	DBHost     string
 	DBPort     string
@@ -17,6 +34,82 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	DBSSLMode  string
+	// Pool de conexiones de la base de datos
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+	// Cadena de conexión de solo lectura para los listados y las recomendaciones; vacía usa la principal
+	DBReadDSN string
+	// Pool de conexiones de la base de datos de solo lectura
+	DBReadMaxOpenConns int
+	DBReadMaxIdleConns int
+	// Lee los listados y las recomendaciones con AS OF SYSTEM TIME follower_read_timestamp()
+	DBFollowerReads bool
+	// Hora UTC a partir de la cual se guarda el snapshot diario de recomendaciones
+	SnapshotDailyHour int
+	// Máximo de recomendaciones de un mismo sector (0 sin límite)
+	RecommendationMaxPerSector int
+	// Factor que multiplica la puntuación de los tickers de una lista de seguimiento (watchlist_mode=boost)
+	RecommendationWatchlistBoost float64
+	// Tiempo de vida de las recomendaciones en caché
+	RecommendationCacheTTL time.Duration
+	// Intervalo de consulta de sincronizaciones para invalidar la caché
+	SyncPollInterval time.Duration
+	// Tiempo máximo desde la última sincronización exitosa antes de marcar los datos como desactualizados (0 lo desactiva)
+	FreshnessMaxSyncAge time.Duration
+	// Antigüedad máxima del evento más reciente antes de marcar los datos como desactualizados (0 lo desactiva)
+	FreshnessMaxDataAge time.Duration
+	// Intervalo de consulta de la frescura de los datos
+	FreshnessCheckInterval time.Duration
+	// Intervalo de consulta de eventos nuevos para el stream
+	StreamPollInterval time.Duration
+	// Intervalo entre heartbeats del stream
+	StreamHeartbeatInterval time.Duration
+	// Eventos y snapshots recientes que el stream conserva en memoria
+	StreamBufferSize int
+	// Tiempo máximo entre la asignación del id de una fila y su confirmación
+	StreamLookback time.Duration
+	// Intervalo máximo entre evaluaciones de las reglas de alerta; además se evalúan tras cada sincronización
+	AlertEvaluationInterval time.Duration
+	// Ventana de eventos que el motor de alertas vuelve a evaluar para incluir los confirmados fuera de orden
+	AlertLookback time.Duration
+	// Profundidad máxima de las consultas GraphQL
+	GraphQLMaxDepth int
+	// Complejidad máxima estimada de las consultas GraphQL
+	GraphQLMaxComplexity int
+	// Configuración de autenticación
+	AuthEnabled      bool
+	AuthBootstrapKey string
+	JWTHS256Secret   string
+	JWTJWKSFile      string
+	JWTIssuer        string
+	JWTAudience      string
+	// Configuración de límites de solicitudes
+	RateLimitEnabled bool
+	// Almacén de contadores: "memory" (por réplica) o "cockroach" (compartido)
+	RateLimitStore                    string
+	RateLimitReadPerMinute            int
+	RateLimitReadBurst                int
+	RateLimitRecommendationsPerMinute int
+	RateLimitRecommendationsBurst     int
+	RateLimitWritePerMinute           int
+	RateLimitWriteBurst               int
+	// Solicitudes por día de cada cliente (0 sin cuota)
+	RateLimitDailyQuota int
+	// Límite por IP aplicado antes de la autenticación (0 lo desactiva)
+	RateLimitIPPerMinute int
+	RateLimitIPBurst     int
+	// Proxies de confianza cuyo X-Forwarded-For identifica la IP del cliente;
+	// vacío usa siempre la IP de la conexión
+	TrustedProxies []string
+	// Configuración de CORS
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
 }
 
 // NewConfig crea una nueva instancia de configuración con valores predeterminados
@@ -24,6 +117,14 @@ type Config struct {
 func NewConfig() *Config {
 	return &Config{
 		ServerPort: getEnv("SERVER_PORT", "8080"),
+		GRPCPort:   getEnv("GRPC_PORT", "9090"),
+		LogLevel:   getEnv("LOG_LEVEL", "info"),
+		LogFormat:  getEnv("LOG_FORMAT", "json"),
+
+		// Configuración de trazas
+		TracingExporter:     getEnv("TRACING_EXPORTER", "none"),
+		TracingOTLPEndpoint: getEnv("TRACING_OTLP_ENDPOINT", ""),
+		TracingSampleRatio:  getEnvFloat("TRACING_SAMPLE_RATIO", 1),
 
 		// Configuración de base de datos
 		DBHost:     getEnv("DB_HOST", "localhost"),
@@ -32,6 +133,76 @@ func NewConfig() *Config {
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBName:     getEnv("DB_NAME", "stockdb"),
 		DBSSLMode:  getEnv("DB_SSL_MODE", "disable"),
+
+		// Pools de conexiones y lecturas
+		DBMaxOpenConns:     getEnvInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:     getEnvInt("DB_MAX_IDLE_CONNS", 5),
+		DBConnMaxLifetime:  getEnvDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+		DBConnMaxIdleTime:  getEnvDuration("DB_CONN_MAX_IDLE_TIME", 0),
+		DBReadDSN:          getEnv("DB_READ_DSN", ""),
+		DBReadMaxOpenConns: getEnvInt("DB_READ_MAX_OPEN_CONNS", 25),
+		DBReadMaxIdleConns: getEnvInt("DB_READ_MAX_IDLE_CONNS", 5),
+		DBFollowerReads:    getEnvBool("DB_FOLLOWER_READS", false),
+
+		// Configuración de snapshots de recomendaciones
+		SnapshotDailyHour: getEnvInt("SNAPSHOT_DAILY_HOUR", 6),
+
+		// Configuración del algoritmo de recomendación
+		RecommendationMaxPerSector:   getEnvInt("RECOMMENDATION_MAX_PER_SECTOR", 0),
+		RecommendationWatchlistBoost: getEnvFloat("RECOMMENDATION_WATCHLIST_BOOST", 1.25),
+
+		// Configuración de la caché de recomendaciones
+		RecommendationCacheTTL: getEnvDuration("RECOMMENDATION_CACHE_TTL", 5*time.Minute),
+		SyncPollInterval:       getEnvDuration("SYNC_POLL_INTERVAL", 30*time.Second),
+
+		// Umbrales de frescura de los datos
+		FreshnessMaxSyncAge:    getEnvDuration("FRESHNESS_MAX_SYNC_AGE", 24*time.Hour),
+		FreshnessMaxDataAge:    getEnvDuration("FRESHNESS_MAX_DATA_AGE", 72*time.Hour),
+		FreshnessCheckInterval: getEnvDuration("FRESHNESS_CHECK_INTERVAL", time.Minute),
+
+		// Stream de eventos
+		StreamPollInterval:      getEnvDuration("STREAM_POLL_INTERVAL", 2*time.Second),
+		StreamHeartbeatInterval: getEnvDuration("STREAM_HEARTBEAT_INTERVAL", 15*time.Second),
+		StreamBufferSize:        getEnvInt("STREAM_BUFFER_SIZE", 1000),
+		StreamLookback:          getEnvDuration("STREAM_LOOKBACK", 30*time.Second),
+
+		// Reglas de alerta
+		AlertEvaluationInterval: getEnvDuration("ALERT_EVALUATION_INTERVAL", 5*time.Minute),
+		AlertLookback:           getEnvDuration("ALERT_LOOKBACK", 30*time.Second),
+
+		// Límites de las consultas GraphQL
+		GraphQLMaxDepth:      getEnvInt("GRAPHQL_MAX_DEPTH", 8),
+		GraphQLMaxComplexity: getEnvInt("GRAPHQL_MAX_COMPLEXITY", 1000),
+
+		// Configuración de autenticación
+		AuthEnabled:      getEnvBool("AUTH_ENABLED", true),
+		AuthBootstrapKey: getEnv("AUTH_BOOTSTRAP_ADMIN_KEY", ""),
+		JWTHS256Secret:   getEnv("AUTH_JWT_HS256_SECRET", ""),
+		JWTJWKSFile:      getEnv("AUTH_JWKS_FILE", ""),
+		JWTIssuer:        getEnv("AUTH_JWT_ISSUER", ""),
+		JWTAudience:      getEnv("AUTH_JWT_AUDIENCE", ""),
+
+		// Configuración de límites de solicitudes
+		RateLimitEnabled:                  getEnvBool("RATE_LIMIT_ENABLED", true),
+		RateLimitStore:                    getEnv("RATE_LIMIT_STORE", "memory"),
+		RateLimitReadPerMinute:            getEnvInt("RATE_LIMIT_READ_PER_MINUTE", 600),
+		RateLimitReadBurst:                getEnvInt("RATE_LIMIT_READ_BURST", 60),
+		RateLimitRecommendationsPerMinute: getEnvInt("RATE_LIMIT_RECOMMENDATIONS_PER_MINUTE", 30),
+		RateLimitRecommendationsBurst:     getEnvInt("RATE_LIMIT_RECOMMENDATIONS_BURST", 10),
+		RateLimitWritePerMinute:           getEnvInt("RATE_LIMIT_WRITE_PER_MINUTE", 10),
+		RateLimitWriteBurst:               getEnvInt("RATE_LIMIT_WRITE_BURST", 5),
+		RateLimitDailyQuota:               getEnvInt("RATE_LIMIT_DAILY_QUOTA", 50000),
+		RateLimitIPPerMinute:              getEnvInt("RATE_LIMIT_IP_PER_MINUTE", 1200),
+		RateLimitIPBurst:                  getEnvInt("RATE_LIMIT_IP_BURST", 120),
+		TrustedProxies:                    getEnvList("TRUSTED_PROXIES", nil),
+
+		// Configuración de CORS
+		CORSAllowedOrigins:   getEnvList("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		CORSAllowedMethods:   getEnvList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+		CORSAllowedHeaders:   getEnvList("CORS_ALLOWED_HEADERS", []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-CSRF-Token", "X-Request-ID"}),
+		CORSExposedHeaders:   getEnvList("CORS_EXPOSED_HEADERS", []string{"Link", "X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", "X-Cache", "X-Data-As-Of"}),
+		CORSAllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
+		CORSMaxAge:           getEnvDuration("CORS_MAX_AGE", 5*time.Minute),
 	}
 }
 
@@ -41,6 +212,25 @@ func (c *Config) GetDBConnectionString() string {
 		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName, c.DBSSLMode)
 }
 
+// DBPool devuelve la configuración del pool de conexiones principal.
+func (c *Config) DBPool() database.Pool {
+	return database.Pool{
+		MaxOpenConns:    c.DBMaxOpenConns,
+		MaxIdleConns:    c.DBMaxIdleConns,
+		ConnMaxLifetime: c.DBConnMaxLifetime,
+		ConnMaxIdleTime: c.DBConnMaxIdleTime,
+	}
+}
+
+// DBReadPool devuelve la configuración del pool de conexiones de solo lectura,
+// que comparte con el principal la duración de las conexiones.
+func (c *Config) DBReadPool() database.Pool {
+	pool := c.DBPool()
+	pool.MaxOpenConns = c.DBReadMaxOpenConns
+	pool.MaxIdleConns = c.DBReadMaxIdleConns
+	return pool
+}
+
 // getEnv obtiene el valor de una variable de entorno o devuelve un valor predeterminado.
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -48,3 +238,61 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt obtiene el valor entero de una variable de entorno o devuelve un valor predeterminado.
+func getEnvInt(key string, defaultValue int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDuration obtiene una duración (por ejemplo "30s" o "5m") de una variable de
+// entorno o devuelve un valor predeterminado.
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvBool obtiene el valor booleano de una variable de entorno o devuelve un valor predeterminado.
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList obtiene una lista separada por comas de una variable de entorno o
+// devuelve un valor predeterminado. Los elementos vacíos se descartan.
+func getEnvList(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvFloat obtiene el valor decimal de una variable de entorno o devuelve un valor predeterminado.
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
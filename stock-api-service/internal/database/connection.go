@@ -4,14 +4,26 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	_ "github.com/lib/pq"
 )
 
-// Connect establece una conexión con la base de datos CockroachDB.
-func Connect(connectionString string) (*sql.DB, error) {
+// Pool contiene la configuración del pool de conexiones de una base de datos.
+type Pool struct {
+	// Máximo de conexiones abiertas (0 sin límite)
+	MaxOpenConns int
+	// Máximo de conexiones inactivas que se conservan
+	MaxIdleConns int
+	// Tiempo máximo de vida de una conexión (0 sin límite)
+	ConnMaxLifetime time.Duration
+	// Tiempo máximo que una conexión permanece inactiva antes de cerrarse (0 sin límite)
+	ConnMaxIdleTime time.Duration
+}
+
+// Connect establece una conexión con la base de datos CockroachDB y configura su pool.
+func Connect(connectionString string, pool Pool) (*sql.DB, error) {
 	// Conectar a la base de datos
 	db, err := sql.Open("postgres", connectionString)
 	if err != nil {
@@ -19,15 +31,17 @@ func Connect(connectionString string) (*sql.DB, error) {
 	}
 
 	// Configurar la conexión
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
 	// Verificar la conexión
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("error al verificar la conexión a la base de datos: %w", err)
 	}
 
-	log.Println("Conexión exitosa a CockroachDB")
+	slog.Info("Conexión exitosa a CockroachDB", "max_open_conns", pool.MaxOpenConns, "max_idle_conns", pool.MaxIdleConns)
 	return db, nil
 }
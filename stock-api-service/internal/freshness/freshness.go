@@ -0,0 +1,223 @@
+// Paquete freshness vigila la frescura de los datos que ingiere stock-data-service
+// y marca el servicio como degradado cuando superan los umbrales configurados.
+package freshness
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// DataAsOfHeader es la cabecera que indica hasta cuándo están actualizados los datos.
+const DataAsOfHeader = "X-Data-As-Of"
+
+// Estados de frescura.
+const (
+	// Los datos están dentro de los umbrales
+	StatusOK = "ok"
+	// Algún umbral se superó: la ingesta se detuvo o no hay datos recientes
+	StatusDegraded = "degradado"
+	// Aún no se pudo consultar la base de datos
+	StatusUnknown = "desconocido"
+)
+
+// Source indica cuándo terminó la última sincronización exitosa y cuál es el
+// evento más reciente del historial.
+type Source interface {
+	GetLastSuccessfulSync(ctx context.Context) (*time.Time, error)
+	GetNewestEventTime(ctx context.Context) (*time.Time, error)
+}
+
+// Thresholds define la antigüedad máxima aceptable de los datos. Un valor 0
+// desactiva el umbral correspondiente.
+type Thresholds struct {
+	// Tiempo máximo desde la última sincronización exitosa
+	MaxSyncAge time.Duration
+	// Antigüedad máxima del evento más reciente
+	MaxDataAge time.Duration
+}
+
+// Report describe la frescura de los datos en un instante.
+type Report struct {
+	Status             string     `json:"status"`
+	LastSuccessfulSync *time.Time `json:"last_successful_sync"`
+	SyncAgeSeconds     *int64     `json:"sync_age_seconds"`
+	NewestEventTime    *time.Time `json:"newest_event_time"`
+	DataAgeSeconds     *int64     `json:"data_age_seconds"`
+	MaxSyncAgeSeconds  int64      `json:"max_sync_age_seconds"`
+	MaxDataAgeSeconds  int64      `json:"max_data_age_seconds"`
+	// Umbrales superados, vacío si el estado es ok
+	Violations []string `json:"violations"`
+	// Momento de la última consulta exitosa a la base de datos
+	CheckedAt *time.Time `json:"checked_at"`
+}
+
+// Monitor consulta periódicamente la frescura de los datos y guarda el último
+// resultado para que las respuestas no consulten la base de datos.
+type Monitor struct {
+	source     Source
+	thresholds Thresholds
+	interval   time.Duration
+
+	mu         sync.RWMutex
+	lastSync   *time.Time
+	newest     *time.Time
+	checkedAt  *time.Time
+	lastStatus string
+}
+
+// NewMonitor crea un monitor de frescura.
+func NewMonitor(source Source, thresholds Thresholds, interval time.Duration) *Monitor {
+	return &Monitor{
+		source:     source,
+		thresholds: thresholds,
+		interval:   interval,
+		lastStatus: StatusUnknown,
+	}
+}
+
+// Run consulta la frescura hasta que el contexto se cancela.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Refresh(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Error al consultar la frescura de los datos", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh consulta la base de datos, actualiza las métricas y registra en los
+// logs los cambios de estado.
+func (m *Monitor) Refresh(ctx context.Context) error {
+	lastSync, err := m.source.GetLastSuccessfulSync(ctx)
+	if err != nil {
+		return err
+	}
+	newest, err := m.source.GetNewestEventTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	m.lastSync = lastSync
+	m.newest = newest
+	m.checkedAt = &now
+	m.mu.Unlock()
+
+	report := m.Report()
+	metrics.SetFreshness(lastSync, newest, report.Status == StatusDegraded)
+
+	m.mu.Lock()
+	previous := m.lastStatus
+	m.lastStatus = report.Status
+	m.mu.Unlock()
+
+	if report.Status != previous {
+		switch report.Status {
+		case StatusDegraded:
+			slog.WarnContext(ctx, "Los datos están desactualizados", "violations", report.Violations)
+		case StatusOK:
+			if previous == StatusDegraded {
+				slog.InfoContext(ctx, "Los datos vuelven a estar actualizados")
+			}
+		}
+	}
+
+	return nil
+}
+
+// Report devuelve la frescura de los datos según la última consulta. Las
+// antigüedades se calculan al momento de la llamada, de modo que el estado se
+// degrada aunque la base de datos deje de responder.
+func (m *Monitor) Report() Report {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	report := Report{
+		Status:             StatusOK,
+		LastSuccessfulSync: m.lastSync,
+		NewestEventTime:    m.newest,
+		MaxSyncAgeSeconds:  int64(m.thresholds.MaxSyncAge.Seconds()),
+		MaxDataAgeSeconds:  int64(m.thresholds.MaxDataAge.Seconds()),
+		Violations:         []string{},
+		CheckedAt:          m.checkedAt,
+	}
+
+	if m.checkedAt == nil {
+		report.Status = StatusUnknown
+		return report
+	}
+
+	if m.lastSync != nil {
+		age := ageSeconds(now, *m.lastSync)
+		report.SyncAgeSeconds = &age
+	}
+	if m.newest != nil {
+		age := ageSeconds(now, *m.newest)
+		report.DataAgeSeconds = &age
+	}
+
+	if max := m.thresholds.MaxSyncAge; max > 0 {
+		if m.lastSync == nil {
+			report.Violations = append(report.Violations, "no hay sincronizaciones exitosas")
+		} else if now.Sub(*m.lastSync) > max {
+			report.Violations = append(report.Violations, "la última sincronización exitosa supera "+max.String())
+		}
+	}
+	if max := m.thresholds.MaxDataAge; max > 0 {
+		if m.newest == nil {
+			report.Violations = append(report.Violations, "el historial de eventos está vacío")
+		} else if now.Sub(*m.newest) > max {
+			report.Violations = append(report.Violations, "el evento más reciente supera "+max.String())
+		}
+	}
+
+	if len(report.Violations) > 0 {
+		report.Status = StatusDegraded
+	}
+	return report
+}
+
+// AsOf indica hasta cuándo están actualizados los datos: el final de la última
+// sincronización exitosa o, si no hay ninguna, el evento más reciente.
+// Devuelve nil si no se conoce.
+func (m *Monitor) AsOf() *time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.lastSync != nil {
+		return m.lastSync
+	}
+	return m.newest
+}
+
+// Header es un middleware que agrega la cabecera X-Data-As-Of a la respuesta.
+func (m *Monitor) Header() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m != nil {
+			if asOf := m.AsOf(); asOf != nil {
+				c.Header(DataAsOfHeader, asOf.UTC().Format(time.RFC3339))
+			}
+		}
+		c.Next()
+	}
+}
+
+// ageSeconds calcula la antigüedad en segundos enteros, sin valores negativos.
+func ageSeconds(now, t time.Time) int64 {
+	return max(int64(now.Sub(t).Seconds()), 0)
+}
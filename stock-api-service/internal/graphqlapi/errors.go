@@ -0,0 +1,75 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// argumentError indica que un argumento de la consulta no es válido. A
+// diferencia de los demás errores de los resolvers, su mensaje se muestra al cliente.
+type argumentError struct {
+	err error
+}
+
+// Error implementa la interfaz error.
+func (e argumentError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap devuelve el error original.
+func (e argumentError) Unwrap() error {
+	return e.err
+}
+
+// invalidArgument marca un error como argumento inválido.
+func invalidArgument(err error) error {
+	return argumentError{err: err}
+}
+
+// sanitizeErrors reemplaza los mensajes de los errores de los resolvers para no
+// exponer detalles de la base de datos y agrega en extensions.code el mismo
+// código estable que la API REST. Los errores de validación de la consulta no
+// tienen error original y se mantienen.
+func sanitizeErrors(ctx context.Context, errs []gqlerrors.FormattedError) {
+	for i, formatted := range errs {
+		located, ok := formatted.OriginalError().(*gqlerrors.Error)
+		if !ok || located.OriginalError == nil {
+			continue
+		}
+		err := located.OriginalError
+
+		code, message := problem.CodeInternal, "Error interno del servidor"
+		var argErr argumentError
+		var repoErr *repository.Error
+		switch {
+		case errors.As(err, &argErr):
+			code, message = problem.CodeInvalidRequest, argErr.Error()
+		case errors.As(err, &repoErr):
+			message = repoErr.Message
+			switch repoErr.Kind {
+			case repository.ErrNotFound:
+				code = problem.CodeNotFound
+			case repository.ErrInvalidFilter:
+				code = problem.CodeInvalidFilter
+			case repository.ErrConflict:
+				code = problem.CodeConflict
+			case repository.ErrUnavailable:
+				code = problem.CodeUnavailable
+			}
+		}
+		if code == problem.CodeInternal || code == problem.CodeUnavailable {
+			slog.ErrorContext(ctx, "Error al resolver la consulta GraphQL", "path", formatted.Path, "error", err)
+		}
+
+		errs[i].Message = message
+		if errs[i].Extensions == nil {
+			errs[i].Extensions = make(map[string]interface{})
+		}
+		errs[i].Extensions["code"] = code
+	}
+}
@@ -0,0 +1,146 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits acota el costo de una consulta antes de ejecutarla.
+type Limits struct {
+	// Profundidad máxima de la selección; los campos de primer nivel tienen profundidad 1
+	MaxDepth int
+	// Complejidad máxima estimada de la consulta
+	MaxComplexity int
+}
+
+// listFields son los campos que devuelven listas, con el argumento que fija su
+// tamaño y el valor que se usa si no se indica. La complejidad de sus subcampos
+// se multiplica por ese tamaño.
+var listFields = map[string]struct {
+	arg string
+	def int
+}{
+	"stocks":          {arg: "pageSize", def: defaultPageSize},
+	"brokerages":      {arg: "pageSize", def: defaultPageSize},
+	"ratingEvents":    {arg: "limit", def: defaultEventsLimit},
+	"history":         {arg: "limit", def: defaultEventsLimit},
+	"recentEvents":    {arg: "limit", def: defaultEventsLimit},
+	"recommendations": {arg: "limit", def: defaultRecommendations},
+}
+
+// queryCost recorre la operación y calcula su profundidad y su complejidad. Cada
+// campo cuesta 1 más el costo de sus subcampos, multiplicado por el tamaño de la
+// lista si el campo devuelve una. Los campos de introspección no cuentan. La
+// complejidad se satura en math.MaxInt en lugar de desbordarse. El documento
+// debe estar validado, lo que garantiza que no hay ciclos de fragmentos.
+func queryCost(doc *ast.Document, operationName string, variables map[string]interface{}) (depth, complexity int, err error) {
+	var operation *ast.OperationDefinition
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range doc.Definitions {
+		switch def := definition.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				operation = def
+			}
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		}
+	}
+	if operation == nil {
+		return 0, 0, fmt.Errorf("operación desconocida: %q", operationName)
+	}
+
+	c := costCounter{fragments: fragments, variables: variables}
+	depth, complexity = c.selectionSet(operation.SelectionSet, 1)
+	return depth, complexity, nil
+}
+
+// costCounter calcula el costo de las selecciones de una operación.
+type costCounter struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// selectionSet devuelve la profundidad máxima y la complejidad de una selección
+// cuyos campos están a la profundidad indicada.
+func (c costCounter) selectionSet(set *ast.SelectionSet, depth int) (int, int) {
+	if set == nil {
+		return depth - 1, 0
+	}
+
+	maxDepth, complexity := 0, 0
+	for _, selection := range set.Selections {
+		var d, cost int
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name.Value, "__") {
+				continue
+			}
+			d, cost = c.selectionSet(sel.SelectionSet, depth+1)
+			d = max(d, depth)
+			cost = saturatingAdd(1, saturatingMul(c.multiplier(sel), cost))
+		case *ast.InlineFragment:
+			d, cost = c.selectionSet(sel.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			if fragment, ok := c.fragments[sel.Name.Value]; ok {
+				d, cost = c.selectionSet(fragment.SelectionSet, depth)
+			}
+		}
+		maxDepth = max(maxDepth, d)
+		complexity = saturatingAdd(complexity, cost)
+	}
+	return maxDepth, complexity
+}
+
+// multiplier devuelve el tamaño de la lista que devuelve un campo, o 1.
+func (c costCounter) multiplier(field *ast.Field) int {
+	list, ok := listFields[field.Name.Value]
+	if !ok {
+		return 1
+	}
+
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != list.arg {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			// Las variables decodificadas de JSON llegan como float64
+			switch n := c.variables[value.Name.Value].(type) {
+			case float64:
+				if n > 0 {
+					return int(math.Min(n, math.MaxInt32))
+				}
+			case int:
+				if n > 0 {
+					return n
+				}
+			}
+		}
+	}
+	return list.def
+}
+
+// saturatingAdd suma dos costos no negativos sin pasar de math.MaxInt.
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+// saturatingMul multiplica dos costos no negativos sin pasar de math.MaxInt.
+func saturatingMul(a, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
+}
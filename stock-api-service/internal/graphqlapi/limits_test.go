@@ -0,0 +1,84 @@
+package graphqlapi
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+func TestQueryCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		variables  map[string]interface{}
+		depth      int
+		complexity int
+	}{
+		{"campo simple", `{ stock(ticker: "AAPL") { ticker } }`, nil, 2, 2},
+		{"lista con tamaño", `{ stocks(pageSize: 5) { items { ticker } } }`, nil, 3, 11},
+		{"lista con variable", `query($n: Int) { stocks(pageSize: $n) { items { ticker } } }`, map[string]interface{}{"n": float64(3)}, 3, 7},
+		{"lista sin tamaño", `{ stocks { items { ticker } } }`, nil, 3, 1 + 2*defaultPageSize},
+		{
+			"listas anidadas saturan la complejidad",
+			`query($n: Int) { stocks(pageSize: $n) { items { history(limit: $n) { recentEvents(limit: $n) { ratingEvents(limit: $n) { ticker } } } } } }`,
+			map[string]interface{}{"n": float64(1e300)},
+			6,
+			math.MaxInt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(tt.query)})})
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			depth, complexity, err := queryCost(doc, "", tt.variables)
+			if err != nil {
+				t.Fatalf("queryCost() error = %v", err)
+			}
+			if depth != tt.depth || complexity != tt.complexity {
+				t.Errorf("queryCost() = (%d, %d), want (%d, %d)", depth, complexity, tt.depth, tt.complexity)
+			}
+		})
+	}
+}
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		page    int
+		limit   int
+		wantErr bool
+	}{
+		{"predeterminados", map[string]interface{}{}, 1, defaultPageSize, false},
+		{"valores válidos", map[string]interface{}{"page": 3, "pageSize": 20}, 3, 20, false},
+		{"page cero", map[string]interface{}{"page": 0}, 0, 0, true},
+		{"pageSize cero", map[string]interface{}{"pageSize": 0}, 0, 0, true},
+		{"pageSize mayor al máximo", map[string]interface{}{"pageSize": maxPageSize + 1}, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pg, err := pagination(graphql.ResolveParams{Args: tt.args})
+			if tt.wantErr {
+				var argErr argumentError
+				if !errors.As(err, &argErr) {
+					t.Fatalf("pagination() error = %v, want argumento inválido", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pagination() error = %v", err)
+			}
+			if pg.Page != tt.page || pg.Limit != tt.limit || pg.Offset != (tt.page-1)*tt.limit {
+				t.Errorf("pagination() = %+v, want page %d y limit %d", pg, tt.page, tt.limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// batch agrupa las claves solicitadas por los resolvers de un mismo nivel de la
+// consulta y las resuelve con una sola llamada a fetch.
+//
+// Los resolvers llaman a load, que registra la clave y devuelve una función
+// diferida. graphql-go ejecuta las funciones diferidas después de resolver todos
+// los campos del nivel, de modo que la primera que se ejecuta encuentra todas las
+// claves pendientes y las consulta juntas.
+type batch[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	results map[K]V
+	errs    map[K]error
+}
+
+// newBatch crea un agrupador con la función de consulta indicada.
+func newBatch[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *batch[K, V] {
+	return &batch[K, V]{
+		fetch:   fetch,
+		queued:  map[K]bool{},
+		results: map[K]V{},
+		errs:    map[K]error{},
+	}
+}
+
+// load registra una clave y devuelve la función diferida que obtiene su valor.
+func (b *batch[K, V]) load(ctx context.Context, key K) func() (interface{}, error) {
+	b.mu.Lock()
+	if !b.queued[key] {
+		b.queued[key] = true
+		b.pending = append(b.pending, key)
+	}
+	b.mu.Unlock()
+
+	return func() (interface{}, error) {
+		return b.get(ctx, key)
+	}
+}
+
+// get devuelve el valor de una clave, consultando antes todas las pendientes.
+func (b *batch[K, V]) get(ctx context.Context, key K) (V, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) > 0 {
+		keys := b.pending
+		b.pending = nil
+		values, err := b.fetch(ctx, keys)
+		for _, k := range keys {
+			if err != nil {
+				b.errs[k] = err
+				continue
+			}
+			b.results[k] = values[k]
+		}
+	}
+
+	return b.results[key], b.errs[key]
+}
+
+// eventsKey identifica las últimas calificaciones de un ticker o de una casa de bolsa.
+type eventsKey struct {
+	value string
+	limit int
+}
+
+// EventsByKeySource consulta las últimas calificaciones de varios tickers o casas de bolsa a la vez.
+type EventsByKeySource func(ctx context.Context, values []string, perKey int) ([]models.StockEvent, error)
+
+// loaders son los agrupadores de una solicitud. No se comparten entre
+// solicitudes, de modo que no actúan como caché.
+type loaders struct {
+	tickerEvents    *batch[eventsKey, []models.StockEvent]
+	brokerageEvents *batch[eventsKey, []models.StockEvent]
+	brokerages      *batch[string, *models.Brokerage]
+}
+
+// newLoaders crea los agrupadores de una solicitud.
+func newLoaders(source Source) *loaders {
+	return &loaders{
+		tickerEvents: newBatch(eventsFetcher(source.GetRecentEventsByTicker, func(e models.StockEvent) string {
+			return e.Ticker
+		})),
+		brokerageEvents: newBatch(eventsFetcher(source.GetRecentEventsByBrokerage, func(e models.StockEvent) string {
+			return e.Brokerage
+		})),
+		brokerages: newBatch(func(ctx context.Context, names []string) (map[string]*models.Brokerage, error) {
+			found, err := source.GetBrokerages(ctx, names)
+			if err != nil {
+				return nil, err
+			}
+			values := make(map[string]*models.Brokerage, len(found))
+			for i := range found {
+				values[found[i].Name] = &found[i]
+			}
+			return values, nil
+		}),
+	}
+}
+
+// eventsFetcher agrupa las claves por límite y hace una consulta por cada límite distinto.
+func eventsFetcher(fetch EventsByKeySource, keyOf func(models.StockEvent) string) func(context.Context, []eventsKey) (map[eventsKey][]models.StockEvent, error) {
+	return func(ctx context.Context, keys []eventsKey) (map[eventsKey][]models.StockEvent, error) {
+		byLimit := map[int][]string{}
+		for _, key := range keys {
+			byLimit[key.limit] = append(byLimit[key.limit], key.value)
+		}
+
+		values := make(map[eventsKey][]models.StockEvent, len(keys))
+		for _, key := range keys {
+			values[key] = []models.StockEvent{}
+		}
+		for limit, group := range byLimit {
+			events, err := fetch(ctx, group, limit)
+			if err != nil {
+				return nil, err
+			}
+			for _, event := range events {
+				key := eventsKey{value: keyOf(event), limit: limit}
+				values[key] = append(values[key], event)
+			}
+		}
+		return values, nil
+	}
+}
+
+// loadersKey es la clave de los agrupadores en el contexto de la solicitud.
+type loadersKey struct{}
+
+// loadersFrom devuelve los agrupadores de la solicitud.
+func loadersFrom(ctx context.Context) *loaders {
+	l, _ := ctx.Value(loadersKey{}).(*loaders)
+	return l
+}
@@ -0,0 +1,577 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/algorithm"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/recommendation"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/graphql-go/graphql"
+)
+
+// Tamaños predeterminados y máximos de las listas.
+const (
+	// Elementos por página de stocks y brokerages, como en la API REST
+	defaultPageSize = 10
+	maxPageSize     = 100
+	// Calificaciones de ratingEvents, history y recentEvents
+	defaultEventsLimit = 10
+	maxEventsLimit     = 100
+	// Recomendaciones
+	defaultRecommendations = recommendation.DefaultLimit
+	maxRecommendations     = recommendation.MaxLimit
+)
+
+// stockOf devuelve el stock de un objeto Stock o RatingEvent.
+func stockOf(source interface{}) models.Stock {
+	switch value := source.(type) {
+	case models.Stock:
+		return value
+	case models.StockEvent:
+		return value.Stock
+	case *models.Stock:
+		return *value
+	}
+	return models.Stock{}
+}
+
+// text define un campo de texto no nulo a partir de una función sobre el stock.
+func text(description string, fn func(models.Stock) string) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.String),
+		Description: description,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return fn(stockOf(p.Source)), nil
+		},
+	}
+}
+
+// optionalText define un campo de texto que es nulo si está vacío.
+func optionalText(description string, fn func(models.Stock) string) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.String,
+		Description: description,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if value := fn(stockOf(p.Source)); value != "" {
+				return value, nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+// limitArg define un argumento de tamaño de lista.
+func limitArg(def, maximum int) *graphql.ArgumentConfig {
+	return &graphql.ArgumentConfig{
+		Type:         graphql.Int,
+		DefaultValue: def,
+		Description:  fmt.Sprintf("Cantidad máxima de elementos, de 1 a %d", maximum),
+	}
+}
+
+// intArg lee un argumento entero y verifica que esté entre 1 y maximum.
+func intArg(p graphql.ResolveParams, name string, def, maximum int) (int, error) {
+	value, ok := p.Args[name].(int)
+	if !ok {
+		return def, nil
+	}
+	if value < 1 || value > maximum {
+		return 0, invalidArgument(fmt.Errorf("%s debe ser un entero entre 1 y %d", name, maximum))
+	}
+	return value, nil
+}
+
+// pagination lee los argumentos page y pageSize con las reglas de la API REST:
+// los valores fuera de rango se rechazan en lugar de reemplazarse.
+func pagination(p graphql.ResolveParams) (models.Pagination, error) {
+	page := 1
+	if value, ok := p.Args["page"].(int); ok {
+		if value < 1 {
+			return models.Pagination{}, invalidArgument(fmt.Errorf("page debe ser un entero mayor o igual a 1: %d", value))
+		}
+		page = value
+	}
+	pageSize, err := intArg(p, "pageSize", defaultPageSize, maxPageSize)
+	if err != nil {
+		return models.Pagination{}, err
+	}
+	return models.Pagination{Page: page, Limit: pageSize, Offset: (page - 1) * pageSize}, nil
+}
+
+// pageArgs son los argumentos de paginación.
+func pageArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args["page"] = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1, Description: "Página, desde 1"}
+	args["pageSize"] = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize, Description: "Elementos por página, máximo 100"}
+	return args
+}
+
+// page es una página de resultados.
+type page struct {
+	items      interface{}
+	total      int
+	pagination models.Pagination
+}
+
+// pageType define el tipo de una página de elementos del tipo indicado.
+func pageType(name string, item graphql.Output) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        name,
+		Description: "Página de resultados",
+		Fields: graphql.Fields{
+			"items": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(item))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(page).items, nil
+				},
+			},
+			"totalCount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Total de elementos que cumplen los filtros",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(page).total, nil
+				},
+			},
+			"totalPages": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					pg := p.Source.(page)
+					return (pg.total + pg.pagination.Limit - 1) / pg.pagination.Limit, nil
+				},
+			},
+			"page": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(page).pagination.Page, nil
+				},
+			},
+			"pageSize": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(page).pagination.Limit, nil
+				},
+			},
+		},
+	})
+}
+
+// newSchema construye el esquema GraphQL.
+func (s *Service) newSchema() (graphql.Schema, error) {
+	orderField := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "StockOrderField",
+		Description: "Campo de ordenamiento de los stocks",
+		Values: graphql.EnumValueConfigMap{
+			"TICKER":      {Value: "ticker"},
+			"COMPANY":     {Value: "company"},
+			"BROKERAGE":   {Value: "brokerage"},
+			"RATING_FROM": {Value: "rating_from"},
+			"RATING_TO":   {Value: "rating_to"},
+			"TIME":        {Value: "time"},
+		},
+	})
+	sortOrder := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "SortOrder",
+		Description: "Dirección del ordenamiento",
+		Values: graphql.EnumValueConfigMap{
+			"ASC":  {Value: "ASC"},
+			"DESC": {Value: "DESC"},
+		},
+	})
+	consistency := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "Consistency",
+		Description: "Consistencia de la lectura, como el parámetro consistency de la API REST",
+		Values: graphql.EnumValueConfigMap{
+			"STRONG": {Value: consistencyStrong, Description: "Último estado confirmado, sin réplicas seguidoras ni caché"},
+		},
+	})
+
+	brokerageType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Brokerage",
+		Description: "Casa de bolsa con el resumen de su actividad en el historial",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.Brokerage).Name, nil
+				},
+			},
+			"ratingCount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Calificaciones emitidas",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.Brokerage).RatingCount, nil
+				},
+			},
+			"tickerCount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Tickers distintos calificados",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.Brokerage).TickerCount, nil
+				},
+			},
+			"lastRatingAt": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.DateTime),
+				Description: "Fecha de la calificación más reciente",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.Brokerage).LastRatingAt, nil
+				},
+			},
+		},
+	})
+
+	// Campos comunes de Stock y RatingEvent
+	stockFields := func() graphql.Fields {
+		return graphql.Fields{
+			"ticker":     text("Símbolo o ticker de la acción", func(s models.Stock) string { return s.Ticker }),
+			"company":    text("Nombre de la compañía", func(s models.Stock) string { return s.Company }),
+			"targetFrom": text("Precio objetivo anterior", func(s models.Stock) string { return s.TargetFrom }),
+			"targetTo":   text("Precio objetivo actual", func(s models.Stock) string { return s.TargetTo }),
+			"targetChangePct": &graphql.Field{
+				Type:        graphql.Float,
+				Description: "Variación porcentual del precio objetivo; nulo si falta algún precio",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					stock := stockOf(p.Source)
+					from, to := algorithm.ExtractPrice(stock.TargetFrom), algorithm.ExtractPrice(stock.TargetTo)
+					if from <= 0 || to <= 0 {
+						return nil, nil
+					}
+					return (to - from) / from * 100, nil
+				},
+			},
+			"action":     text("Acción realizada sobre la recomendación", func(s models.Stock) string { return s.Action }),
+			"ratingFrom": text("Calificación anterior", func(s models.Stock) string { return s.RatingFrom }),
+			"ratingTo":   text("Calificación actual", func(s models.Stock) string { return s.RatingTo }),
+			"time": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.DateTime),
+				Description: "Fecha y hora de la actualización",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return stockOf(p.Source).Time, nil
+				},
+			},
+			"sector":          optionalText("Sector económico de la compañía", func(s models.Stock) string { return s.Sector }),
+			"industry":        optionalText("Industria de la compañía", func(s models.Stock) string { return s.Industry }),
+			"exchange":        optionalText("Bolsa en la que cotiza", func(s models.Stock) string { return s.Exchange }),
+			"marketCapBucket": optionalText("Rango de capitalización de mercado", func(s models.Stock) string { return s.MarketCapBucket }),
+			"brokerage": &graphql.Field{
+				Type:        brokerageType,
+				Description: "Casa de bolsa que emitió la calificación",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).brokerages.load(p.Context, stockOf(p.Source).Brokerage), nil
+				},
+			},
+		}
+	}
+
+	ratingEventFields := stockFields()
+	ratingEventFields["id"] = &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.ID),
+		Description: "Identificador del evento en el historial",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return strconv.FormatInt(p.Source.(models.StockEvent).ID, 10), nil
+		},
+	}
+	ratingEventType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "RatingEvent",
+		Description: "Calificación guardada en el historial",
+		Fields:      ratingEventFields,
+	})
+
+	brokerageType.AddFieldConfig("recentEvents", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ratingEventType))),
+		Description: "Calificaciones más recientes de la casa de bolsa",
+		Args:        graphql.FieldConfigArgument{"limit": limitArg(defaultEventsLimit, maxEventsLimit)},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			limit, err := intArg(p, "limit", defaultEventsLimit, maxEventsLimit)
+			if err != nil {
+				return nil, err
+			}
+			key := eventsKey{value: p.Source.(*models.Brokerage).Name, limit: limit}
+			return loadersFrom(p.Context).brokerageEvents.load(p.Context, key), nil
+		},
+	})
+
+	stockTypeFields := stockFields()
+	stockTypeFields["history"] = &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ratingEventType))),
+		Description: "Calificaciones más recientes del ticker",
+		Args:        graphql.FieldConfigArgument{"limit": limitArg(defaultEventsLimit, maxEventsLimit)},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			limit, err := intArg(p, "limit", defaultEventsLimit, maxEventsLimit)
+			if err != nil {
+				return nil, err
+			}
+			key := eventsKey{value: stockOf(p.Source).Ticker, limit: limit}
+			return loadersFrom(p.Context).tickerEvents.load(p.Context, key), nil
+		},
+	}
+	stockType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Stock",
+		Description: "Última calificación conocida de una acción",
+		Fields:      stockTypeFields,
+	})
+
+	recommendationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Recommendation",
+		Description: "Recomendación de inversión",
+		Fields: graphql.Fields{
+			"rank": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Posición en el ranking (1 es la mejor)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.RankedRecommendation).Rank, nil
+				},
+			},
+			"score": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Puntuación de la recomendación",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.RankedRecommendation).Score, nil
+				},
+			},
+			"rationale": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Explicación de la recomendación",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.RankedRecommendation).Rationale, nil
+				},
+			},
+			"potentialReturn": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Retorno potencial estimado",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.RankedRecommendation).PotentialReturn, nil
+				},
+			},
+			"stock": &graphql.Field{
+				Type: graphql.NewNonNull(stockType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.RankedRecommendation).Stock, nil
+				},
+			},
+		},
+	})
+
+	asOfArg := &graphql.ArgumentConfig{
+		Type:        graphql.String,
+		Description: "Instante de referencia (RFC3339 o YYYY-MM-DD), como el parámetro as_of de la API REST",
+	}
+	consistencyArg := &graphql.ArgumentConfig{
+		Type:        consistency,
+		Description: "Consistencia de la lectura del campo; si no se indica, la del servicio",
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"stocks": &graphql.Field{
+				Type:        graphql.NewNonNull(pageType("StockPage", stockType)),
+				Description: "Stocks con los filtros, el ordenamiento y la paginación de GET /api/v1/stocks",
+				Args: pageArgs(graphql.FieldConfigArgument{
+					"ticker":      {Type: graphql.String, Description: "Patrón parcial del ticker"},
+					"brokerage":   {Type: graphql.String, Description: "Casa de bolsa exacta"},
+					"rating":      {Type: graphql.String, Description: "Calificación anterior o actual"},
+					"sector":      {Type: graphql.String},
+					"industry":    {Type: graphql.String},
+					"orderBy":     {Type: orderField, DefaultValue: "time"},
+					"sort":        {Type: sortOrder, DefaultValue: "DESC"},
+					"asOf":        asOfArg,
+					"consistency": consistencyArg,
+				}),
+				Resolve: s.resolveStocks,
+			},
+			"stock": &graphql.Field{
+				Type:        stockType,
+				Description: "Stock por ticker exacto",
+				Args: graphql.FieldConfigArgument{
+					"ticker":      {Type: graphql.NewNonNull(graphql.String)},
+					"asOf":        asOfArg,
+					"consistency": consistencyArg,
+				},
+				Resolve: s.resolveStock,
+			},
+			"ratingEvents": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ratingEventType))),
+				Description: "Calificaciones más recientes de un ticker",
+				Args: graphql.FieldConfigArgument{
+					"ticker": {Type: graphql.NewNonNull(graphql.String)},
+					"limit":  limitArg(defaultEventsLimit, maxEventsLimit),
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, err := intArg(p, "limit", defaultEventsLimit, maxEventsLimit)
+					if err != nil {
+						return nil, err
+					}
+					key := eventsKey{value: p.Args["ticker"].(string), limit: limit}
+					return loadersFrom(p.Context).tickerEvents.load(p.Context, key), nil
+				},
+			},
+			"brokerages": &graphql.Field{
+				Type:        graphql.NewNonNull(pageType("BrokeragePage", brokerageType)),
+				Description: "Casas de bolsa de la más activa a la menos activa",
+				Args: pageArgs(graphql.FieldConfigArgument{
+					"search": {Type: graphql.String, Description: "Parte del nombre"},
+				}),
+				Resolve: s.resolveBrokerages,
+			},
+			"brokerage": &graphql.Field{
+				Type:        brokerageType,
+				Description: "Casa de bolsa por nombre exacto",
+				Args: graphql.FieldConfigArgument{
+					"name": {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).brokerages.load(p.Context, p.Args["name"].(string)), nil
+				},
+			},
+			"recommendations": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(recommendationType))),
+				Description: "Recomendaciones de inversión, como GET /api/v1/recommendations",
+				Args: graphql.FieldConfigArgument{
+					"limit":        limitArg(defaultRecommendations, maxRecommendations),
+					"maxPerSector": {Type: graphql.Int, Description: "Máximo de recomendaciones de un mismo sector (0 sin límite)"},
+					"asOf":         asOfArg,
+					"consistency":  consistencyArg,
+				},
+				Resolve: s.resolveRecommendations,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// resolveStocks devuelve una página de stocks.
+func (s *Service) resolveStocks(p graphql.ResolveParams) (interface{}, error) {
+	asOf, err := models.ParseAsOf(stringArg(p, "asOf"))
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	filter := models.StockFilter{
+		Ticker:    stringArg(p, "ticker"),
+		Brokerage: stringArg(p, "brokerage"),
+		Rating:    stringArg(p, "rating"),
+		Sector:    stringArg(p, "sector"),
+		Industry:  stringArg(p, "industry"),
+		OrderBy:   stringArg(p, "orderBy"),
+		SortOrder: stringArg(p, "sort"),
+		AsOf:      asOf,
+	}
+	pg, err := pagination(p)
+	if err != nil {
+		return nil, err
+	}
+	ctx, _ := consistencyContext(p)
+
+	stocks, err := s.source.GetStocks(ctx, filter, pg.Offset, pg.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener stocks: %w", err)
+	}
+	total, err := s.source.CountStocks(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error al contar stocks: %w", err)
+	}
+	if stocks == nil {
+		stocks = []models.Stock{}
+	}
+	return page{items: stocks, total: total, pagination: pg}, nil
+}
+
+// resolveStock devuelve un stock por ticker.
+func (s *Service) resolveStock(p graphql.ResolveParams) (interface{}, error) {
+	asOf, err := models.ParseAsOf(stringArg(p, "asOf"))
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	ticker := p.Args["ticker"].(string)
+	ctx, _ := consistencyContext(p)
+	var stock models.Stock
+	if asOf != nil {
+		stock, err = s.source.GetStockByTickerAsOf(ctx, ticker, *asOf)
+	} else {
+		stock, err = s.source.GetStockByTicker(ctx, ticker)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stock, nil
+}
+
+// resolveBrokerages devuelve una página de casas de bolsa.
+func (s *Service) resolveBrokerages(p graphql.ResolveParams) (interface{}, error) {
+	search := stringArg(p, "search")
+	pg, err := pagination(p)
+	if err != nil {
+		return nil, err
+	}
+
+	brokerages, err := s.source.ListBrokerages(p.Context, search, pg.Offset, pg.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener casas de bolsa: %w", err)
+	}
+	total, err := s.source.CountBrokerages(p.Context, search)
+	if err != nil {
+		return nil, fmt.Errorf("error al contar casas de bolsa: %w", err)
+	}
+
+	items := make([]*models.Brokerage, len(brokerages))
+	for i := range brokerages {
+		items[i] = &brokerages[i]
+	}
+	return page{items: items, total: total, pagination: pg}, nil
+}
+
+// resolveRecommendations calcula las recomendaciones con el servicio de la API
+// REST y comparte su caché, que se invalida con cada sincronización.
+func (s *Service) resolveRecommendations(p graphql.ResolveParams) (interface{}, error) {
+	limit, err := intArg(p, "limit", defaultRecommendations, maxRecommendations)
+	if err != nil {
+		return nil, err
+	}
+	asOf, err := models.ParseAsOf(stringArg(p, "asOf"))
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	ctx, strong := consistencyContext(p)
+	req := recommendation.Request{Limit: limit, AsOf: asOf, Strong: strong}
+	if maxPerSector, ok := p.Args["maxPerSector"].(int); ok {
+		if maxPerSector < 0 {
+			return nil, invalidArgument(fmt.Errorf("maxPerSector debe ser un entero mayor o igual a cero"))
+		}
+		req.MaxPerSector = &maxPerSector
+	}
+
+	response, _, err := s.recommendations.Recommend(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]models.RankedRecommendation, len(response.Recommendations))
+	for i, result := range response.Recommendations {
+		ranked[i] = models.RankedRecommendation{Rank: i + 1, RecommendationResult: result}
+	}
+	return ranked, nil
+}
+
+// consistencyStrong es el valor del argumento consistency que pide leer el
+// último estado confirmado.
+const consistencyStrong = "strong"
+
+// consistencyContext devuelve el contexto del campo, marcado con
+// repository.WithStrongConsistency si el argumento consistency es STRONG.
+func consistencyContext(p graphql.ResolveParams) (context.Context, bool) {
+	if stringArg(p, "consistency") != consistencyStrong {
+		return p.Context, false
+	}
+	return repository.WithStrongConsistency(p.Context), true
+}
+
+// stringArg lee un argumento de texto opcional.
+func stringArg(p graphql.ResolveParams, name string) string {
+	value, _ := p.Args[name].(string)
+	return value
+}
@@ -0,0 +1,109 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// builtinScalars son los escalares que toda implementación de GraphQL define.
+var builtinScalars = map[string]bool{
+	"String": true, "Int": true, "Float": true, "Boolean": true, "ID": true,
+}
+
+// SDL devuelve el esquema en el lenguaje de definición de GraphQL, con los
+// tipos, campos, argumentos y valores en orden alfabético y Query al inicio.
+func (s *Service) SDL() string {
+	typeMap := s.schema.TypeMap()
+	names := make([]string, 0, len(typeMap))
+	for name := range typeMap {
+		if strings.HasPrefix(name, "__") || builtinScalars[name] || name == "Query" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	names = append([]string{"Query"}, names...)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		switch t := typeMap[name].(type) {
+		case *graphql.Object:
+			writeDescription(&b, "", t.Description())
+			fmt.Fprintf(&b, "type %s {\n", t.Name())
+			fields := t.Fields()
+			fieldNames := make([]string, 0, len(fields))
+			for fieldName := range fields {
+				fieldNames = append(fieldNames, fieldName)
+			}
+			sort.Strings(fieldNames)
+			for _, fieldName := range fieldNames {
+				writeField(&b, fields[fieldName])
+			}
+			b.WriteString("}\n")
+		case *graphql.Enum:
+			writeDescription(&b, "", t.Description())
+			fmt.Fprintf(&b, "enum %s {\n", t.Name())
+			values := make([]string, len(t.Values()))
+			for i, value := range t.Values() {
+				values[i] = value.Name
+			}
+			sort.Strings(values)
+			for _, value := range values {
+				fmt.Fprintf(&b, "  %s\n", value)
+			}
+			b.WriteString("}\n")
+		case *graphql.Scalar:
+			writeDescription(&b, "", t.Description())
+			fmt.Fprintf(&b, "scalar %s\n", t.Name())
+		}
+	}
+	return b.String()
+}
+
+// writeField escribe un campo con sus argumentos.
+func writeField(b *strings.Builder, field *graphql.FieldDefinition) {
+	writeDescription(b, "  ", field.Description)
+	b.WriteString("  " + field.Name)
+	if len(field.Args) > 0 {
+		args := make([]string, len(field.Args))
+		for i, arg := range field.Args {
+			args[i] = arg.Name() + ": " + arg.Type.String()
+			if arg.DefaultValue != nil {
+				args[i] += " = " + defaultLiteral(arg.Type, arg.DefaultValue)
+			}
+		}
+		sort.Strings(args)
+		b.WriteString("(" + strings.Join(args, ", ") + ")")
+	}
+	b.WriteString(": " + field.Type.String() + "\n")
+}
+
+// writeDescription escribe una descripción como cadena de bloque.
+func writeDescription(b *strings.Builder, indent, description string) {
+	if description != "" {
+		fmt.Fprintf(b, "%s\"\"\"%s\"\"\"\n", indent, description)
+	}
+}
+
+// defaultLiteral representa el valor predeterminado de un argumento. Los enums
+// guardan el valor interno, que se traduce al nombre del valor.
+func defaultLiteral(t graphql.Input, value interface{}) string {
+	if enum, ok := t.(*graphql.Enum); ok {
+		for _, v := range enum.Values() {
+			if v.Value == value {
+				return v.Name
+			}
+		}
+	}
+	if text, ok := value.(string); ok {
+		return strconv.Quote(text)
+	}
+	return fmt.Sprint(value)
+}
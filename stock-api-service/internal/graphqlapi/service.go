@@ -0,0 +1,101 @@
+// Paquete graphqlapi expone los stocks, las calificaciones, las casas de bolsa y
+// las recomendaciones mediante GraphQL.
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/recommendation"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Source es la fuente de datos de las consultas GraphQL.
+type Source interface {
+	GetStocks(ctx context.Context, filter models.StockFilter, offset, limit int) ([]models.Stock, error)
+	CountStocks(ctx context.Context, filter models.StockFilter) (int, error)
+	GetStockByTicker(ctx context.Context, ticker string) (models.Stock, error)
+	GetStockByTickerAsOf(ctx context.Context, ticker string, asOf time.Time) (models.Stock, error)
+	GetRecentEventsByTicker(ctx context.Context, tickers []string, perTicker int) ([]models.StockEvent, error)
+	GetRecentEventsByBrokerage(ctx context.Context, brokerages []string, perBrokerage int) ([]models.StockEvent, error)
+	GetBrokerages(ctx context.Context, names []string) ([]models.Brokerage, error)
+	ListBrokerages(ctx context.Context, search string, offset, limit int) ([]models.Brokerage, error)
+	CountBrokerages(ctx context.Context, search string) (int, error)
+}
+
+// Service ejecuta consultas GraphQL sobre una fuente de datos.
+type Service struct {
+	source          Source
+	recommendations *recommendation.Service
+	limits          Limits
+	schema          graphql.Schema
+}
+
+// NewService crea el servicio y construye el esquema. Las recomendaciones usan
+// el servicio y la caché de la API REST.
+func NewService(source Source, recommendations *recommendation.Service, limits Limits) (*Service, error) {
+	s := &Service{
+		source:          source,
+		recommendations: recommendations,
+		limits:          limits,
+	}
+
+	schema, err := s.newSchema()
+	if err != nil {
+		return nil, fmt.Errorf("error al construir el esquema GraphQL: %w", err)
+	}
+	s.schema = schema
+	return s, nil
+}
+
+// Execute ejecuta una consulta. Devuelve false si la consulta no se ejecutó por
+// ser inválida o exceder los límites; en ese caso el resultado solo contiene
+// los errores.
+func (s *Service) Execute(ctx context.Context, query, operationName string, variables map[string]interface{}) (*graphql.Result, bool) {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)}}, false
+	}
+
+	if validation := graphql.ValidateDocument(&s.schema, doc, nil); !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}, false
+	}
+
+	depth, complexity, err := queryCost(doc, operationName, variables)
+	if err != nil {
+		return requestError(err.Error()), false
+	}
+	if s.limits.MaxDepth > 0 && depth > s.limits.MaxDepth {
+		return requestError(fmt.Sprintf("la consulta tiene profundidad %d y el máximo es %d", depth, s.limits.MaxDepth)), false
+	}
+	if s.limits.MaxComplexity > 0 && complexity > s.limits.MaxComplexity {
+		return requestError(fmt.Sprintf("la consulta tiene complejidad %d y el máximo es %d", complexity, s.limits.MaxComplexity)), false
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: operationName,
+		Args:          variables,
+		Context:       context.WithValue(ctx, loadersKey{}, newLoaders(s.source)),
+	})
+	sanitizeErrors(ctx, result.Errors)
+	return result, true
+}
+
+// Schema devuelve el esquema, para la introspección.
+func (s *Service) Schema() graphql.Schema {
+	return s.schema
+}
+
+// requestError construye el resultado de una consulta rechazada.
+func requestError(message string) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(message)}}
+}
@@ -0,0 +1,246 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/auth"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/grpcapi/stockapiv1"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/ratelimit"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// authInterceptor exige credenciales con al menos el rol indicado. Acepta las
+// mismas que la API REST en los metadatos x-api-key y authorization.
+type authInterceptor struct {
+	authenticator *auth.Authenticator
+	role          auth.Role
+}
+
+// principalKey es la clave del principal autenticado en el contexto de la llamada.
+type principalKey struct{}
+
+// principalFromContext obtiene el principal que guardó authInterceptor.
+func principalFromContext(ctx context.Context) (auth.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(auth.Principal)
+	return principal, ok
+}
+
+// unary autentica las llamadas unarias.
+func (i *authInterceptor) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := i.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// stream autentica las llamadas con stream.
+func (i *authInterceptor) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := i.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorize verifica las credenciales de la llamada y devuelve el contexto con
+// el principal. El servicio de salud no las requiere, para que las sondas
+// puedan consultarlo.
+func (i *authInterceptor) authorize(ctx context.Context, method string) (context.Context, error) {
+	if isHealthMethod(method) {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	principal, err := i.authenticator.AuthenticateCredentials(ctx, first(md, "x-api-key"), first(md, "authorization"))
+	if err != nil {
+		if auth.IsCredentialError(err) {
+			return nil, status.Error(codes.Unauthenticated, "No autenticado: "+err.Error())
+		}
+		slog.ErrorContext(ctx, "Error al verificar credenciales", "error", err)
+		return nil, status.Error(codes.Unavailable, "No se pudieron verificar las credenciales, intente más tarde")
+	}
+
+	if !principal.Role.Allows(i.role) {
+		return nil, status.Error(codes.PermissionDenied, "Acceso denegado: se requiere el rol "+string(i.role))
+	}
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+// rateLimitInterceptor aplica los límites de la API REST: el bucket de la IP
+// antes de la autenticación y, después, el del grupo de la llamada y la cuota
+// diaria del principal. GetRecommendations usa el grupo de recomendaciones y
+// el resto de las llamadas el de lectura. Al rechazar responde
+// ResourceExhausted con el metadato retry-after en segundos.
+type rateLimitInterceptor struct {
+	limiter *ratelimit.Limiter
+}
+
+// recommendationsMethod es la llamada que usa el grupo de recomendaciones.
+var recommendationsMethod = "/" + stockapiv1.StockService_ServiceDesc.ServiceName + "/GetRecommendations"
+
+// ipUnary limita las llamadas unarias por IP, antes de la autenticación.
+func (i *rateLimitInterceptor) ipUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := i.checkIP(ctx, info.FullMethod, grpc.SetHeader); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// ipStream limita las llamadas con stream por IP, antes de la autenticación.
+func (i *rateLimitInterceptor) ipStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := i.checkIP(ss.Context(), info.FullMethod, setStreamHeader(ss)); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// unary limita las llamadas unarias por principal.
+func (i *rateLimitInterceptor) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := i.check(ctx, info.FullMethod, grpc.SetHeader); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// stream limita las llamadas con stream por principal al abrirlas.
+func (i *rateLimitInterceptor) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := i.check(ss.Context(), info.FullMethod, setStreamHeader(ss)); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// checkIP aplica el bucket de la IP de la conexión.
+func (i *rateLimitInterceptor) checkIP(ctx context.Context, method string, setHeader func(context.Context, metadata.MD) error) error {
+	if isHealthMethod(method) {
+		return nil
+	}
+	decision, retryAfter := i.limiter.CheckIP(ctx, peerIP(ctx))
+	return rejection(ctx, decision, retryAfter, setHeader)
+}
+
+// check aplica el bucket del grupo de la llamada y la cuota diaria del
+// principal, o de la IP si la autenticación está desactivada.
+func (i *rateLimitInterceptor) check(ctx context.Context, method string, setHeader func(context.Context, metadata.MD) error) error {
+	if isHealthMethod(method) {
+		return nil
+	}
+
+	client := "ip:" + peerIP(ctx)
+	if principal, ok := principalFromContext(ctx); ok && principal.Method != auth.MethodDisabled {
+		client = principal.ID
+	}
+	group := ratelimit.GroupRead
+	if method == recommendationsMethod {
+		group = ratelimit.GroupRecommendations
+	}
+
+	decision, retryAfter := i.limiter.Check(ctx, group, client)
+	return rejection(ctx, decision, retryAfter, setHeader)
+}
+
+// rejection devuelve ResourceExhausted con retry-after si la llamada no está permitida.
+func rejection(ctx context.Context, decision ratelimit.Decision, retryAfter time.Duration, setHeader func(context.Context, metadata.MD) error) error {
+	if decision.Allowed {
+		return nil
+	}
+	// Sin transporte, como en las pruebas, no hay cabeceras que escribir
+	_ = setHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(ratelimit.Seconds(retryAfter))))
+	return status.Error(codes.ResourceExhausted, ratelimit.RejectionMessage(decision))
+}
+
+// setStreamHeader adapta ServerStream.SetHeader a la firma de grpc.SetHeader.
+func setStreamHeader(ss grpc.ServerStream) func(context.Context, metadata.MD) error {
+	return func(_ context.Context, md metadata.MD) error {
+		return ss.SetHeader(md)
+	}
+}
+
+// peerIP devuelve la IP de la conexión de la llamada.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+// isHealthMethod indica si la llamada es del servicio de salud.
+func isHealthMethod(method string) bool {
+	return strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+// contextStream es un stream de servidor con otro contexto.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context devuelve el contexto del stream.
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// consistencyMetadata es el metadato que pide leer el último estado confirmado
+// en lugar de una réplica seguidora, con el valor strong.
+const consistencyMetadata = "x-consistency"
+
+// consistencyUnary marca el contexto de la llamada con
+// repository.WithStrongConsistency cuando el metadato x-consistency es strong,
+// como el parámetro consistency de la API REST.
+func consistencyUnary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	switch value := first(md, consistencyMetadata); value {
+	case "":
+	case "strong":
+		ctx = repository.WithStrongConsistency(ctx)
+	default:
+		return nil, status.Error(codes.InvalidArgument, consistencyMetadata+" debe ser strong: "+value)
+	}
+	return handler(ctx, req)
+}
+
+// first devuelve el primer valor de un metadato.
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// recoverUnary convierte un pánico de una llamada unaria en un error Internal,
+// como gin.Recovery en la API REST.
+func recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ any, err error) {
+	defer recoverPanic(ctx, info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+// recoverStream convierte un pánico de una llamada con stream en un error Internal.
+func recoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverPanic(ss.Context(), info.FullMethod, &err)
+	return handler(srv, ss)
+}
+
+// recoverPanic registra el pánico en curso, si lo hay, y reemplaza el error.
+func recoverPanic(ctx context.Context, method string, err *error) {
+	if r := recover(); r != nil {
+		slog.ErrorContext(ctx, "Pánico en una llamada gRPC", "method", method, "panic", r, "stack", string(debug.Stack()))
+		*err = status.Error(codes.Internal, "Error interno del servidor")
+	}
+}
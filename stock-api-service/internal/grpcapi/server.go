@@ -0,0 +1,101 @@
+// Paquete grpcapi expone los stocks, las recomendaciones y el stream de eventos
+// por gRPC para los servicios internos, con los mismos repositorios, el mismo
+// recomendador y las mismas credenciales que la API REST.
+package grpcapi
+
+import (
+	"context"
+	"net"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/auth"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/grpcapi/stockapiv1"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/ratelimit"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/recommendation"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/stream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Dependencies agrupa los componentes compartidos con la API REST.
+type Dependencies struct {
+	// Repositorio de stocks
+	Stocks repository.StockRepository
+	// Servicio de recomendaciones, con la caché compartida por los transportes
+	Recommendations *recommendation.Service
+	// Hub del stream de eventos
+	Stream *stream.Hub
+	// Autenticador de solicitudes
+	Authenticator *auth.Authenticator
+	// Límite de solicitudes compartido con la API REST; nil para no limitar
+	RateLimiter *ratelimit.Limiter
+}
+
+// Server es el servidor gRPC con StockService, el servicio de salud y la reflexión.
+type Server struct {
+	grpc   *grpc.Server
+	health *health.Server
+}
+
+// NewServer crea el servidor gRPC. Todas las llamadas, salvo las del servicio de
+// salud, requieren credenciales con el rol reader y cuentan para los mismos
+// límites de solicitudes que la API REST. Con el metadato x-consistency: strong
+// las lecturas usan el último estado confirmado, como consistency=strong en la
+// API REST.
+func NewServer(deps Dependencies) *Server {
+	interceptor := &authInterceptor{authenticator: deps.Authenticator, role: auth.RoleReader}
+	limiter := &rateLimitInterceptor{limiter: deps.RateLimiter}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoverUnary, limiter.ipUnary, interceptor.unary, limiter.unary, consistencyUnary),
+		grpc.ChainStreamInterceptor(recoverStream, limiter.ipStream, interceptor.stream, limiter.stream),
+	)
+
+	stockapiv1.RegisterStockServiceServer(server, &stockService{
+		stocks:          deps.Stocks,
+		recommendations: deps.Recommendations,
+		hub:             deps.Stream,
+	})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(stockapiv1.StockService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return &Server{
+		grpc:   server,
+		health: healthServer,
+	}
+}
+
+// Serve atiende conexiones en el puerto indicado hasta que se llama a Shutdown.
+func (s *Server) Serve(port string) error {
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+	return s.grpc.Serve(listener)
+}
+
+// Shutdown marca el servicio como no disponible en el servicio de salud, deja de
+// aceptar llamadas y espera a que terminen las que están en curso. Si el
+// contexto vence antes, cierra las conexiones restantes.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		return ctx.Err()
+	}
+}
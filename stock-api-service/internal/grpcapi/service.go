@@ -0,0 +1,316 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/grpcapi/stockapiv1"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/metrics"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/recommendation"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/stream"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Tamaños de página predeterminado y máximo, los mismos de la API REST y GraphQL.
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// stockService implementa StockService.
+type stockService struct {
+	stockapiv1.UnimplementedStockServiceServer
+
+	stocks          repository.StockRepository
+	recommendations *recommendation.Service
+	hub             *stream.Hub
+}
+
+// ListStocks lista stocks con los filtros de GET /api/v1/stocks. Un campo de
+// ordenamiento o una dirección desconocidos, un as_of futuro y una paginación
+// fuera de rango devuelven InvalidArgument, como el 400 de la API REST;
+// order_by y sort vacíos, y page y page_size en 0, usan los valores
+// predeterminados.
+func (s *stockService) ListStocks(ctx context.Context, req *stockapiv1.ListStocksRequest) (*stockapiv1.ListStocksResponse, error) {
+	asOf, err := asOfArg(req.GetAsOf())
+	if err != nil {
+		return nil, err
+	}
+
+	filter := stockFilter(req.GetFilter())
+	filter.OrderBy = req.GetOrderBy()
+	filter.SortOrder = req.GetSort()
+	filter.AsOf = asOf
+	if err := repository.ValidateStockFilter(filter); err != nil {
+		return nil, invalidFilter(err)
+	}
+
+	page, pageSize := int(req.GetPage()), int(req.GetPageSize())
+	if page < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "page debe ser un entero mayor o igual a 1: %d", page)
+	}
+	if page == 0 {
+		page = 1
+	}
+	if pageSize < 0 || pageSize > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page_size debe ser un entero entre 1 y %d: %d", maxPageSize, pageSize)
+	}
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+
+	stocks, err := s.stocks.GetStocks(ctx, filter, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, internalError(ctx, "Error al obtener stocks", err)
+	}
+	total, err := s.stocks.CountStocks(ctx, filter)
+	if err != nil {
+		return nil, internalError(ctx, "Error al contar stocks", err)
+	}
+
+	response := &stockapiv1.ListStocksResponse{
+		Stocks:      make([]*stockapiv1.Stock, len(stocks)),
+		TotalStocks: int32(total),
+		TotalPages:  int32((total + pageSize - 1) / pageSize),
+		Page:        int32(page),
+		PageSize:    int32(pageSize),
+	}
+	for i, stock := range stocks {
+		response.Stocks[i] = stockProto(stock)
+	}
+	return response, nil
+}
+
+// GetStock devuelve un stock por ticker, o su última calificación conocida en as_of.
+func (s *stockService) GetStock(ctx context.Context, req *stockapiv1.GetStockRequest) (*stockapiv1.GetStockResponse, error) {
+	if req.GetTicker() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Se requiere especificar un ticker")
+	}
+	asOf, err := asOfArg(req.GetAsOf())
+	if err != nil {
+		return nil, err
+	}
+
+	var stock models.Stock
+	if asOf != nil {
+		stock, err = s.stocks.GetStockByTickerAsOf(ctx, req.GetTicker(), *asOf)
+	} else {
+		stock, err = s.stocks.GetStockByTicker(ctx, req.GetTicker())
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrStockNotFound) {
+			return nil, status.Error(codes.NotFound, "Stock no encontrado: "+req.GetTicker())
+		}
+		return nil, internalError(ctx, "Error al obtener stock", err)
+	}
+
+	return &stockapiv1.GetStockResponse{Stock: stockProto(stock)}, nil
+}
+
+// GetRecommendations genera recomendaciones con el servicio de GET
+// /api/v1/recommendations y comparte su caché.
+func (s *stockService) GetRecommendations(ctx context.Context, req *stockapiv1.GetRecommendationsRequest) (*stockapiv1.GetRecommendationsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit < 0 || limit > recommendation.MaxLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit debe ser un entero entre 1 y %d", recommendation.MaxLimit)
+	}
+	asOf, err := asOfArg(req.GetAsOf())
+	if err != nil {
+		return nil, err
+	}
+
+	params := recommendation.Request{
+		Limit:  limit,
+		AsOf:   asOf,
+		Strong: repository.StrongConsistency(ctx),
+	}
+	if req.MaxPerSector != nil {
+		if req.GetMaxPerSector() < 0 {
+			return nil, status.Error(codes.InvalidArgument, "max_per_sector debe ser un entero mayor o igual a cero")
+		}
+		maxPerSector := int(req.GetMaxPerSector())
+		params.MaxPerSector = &maxPerSector
+	}
+
+	response, _, err := s.recommendations.Recommend(ctx, params)
+	if err != nil {
+		return nil, internalError(ctx, "Error al obtener stocks para recomendaciones", err)
+	}
+
+	result := &stockapiv1.GetRecommendationsResponse{
+		Recommendations: make([]*stockapiv1.Recommendation, len(response.Recommendations)),
+		GeneratedAt:     timestamppb.New(response.GeneratedAt),
+		AsOf:            timestamppb.New(response.AsOf),
+	}
+	for i, item := range response.Recommendations {
+		result.Recommendations[i] = &stockapiv1.Recommendation{
+			Rank:            int32(i + 1),
+			Score:           item.Score,
+			Rationale:       item.Rationale,
+			PotentialReturn: item.PotentialReturn,
+			Stock:           stockProto(item.Stock),
+		}
+	}
+	return result, nil
+}
+
+// WatchEvents envía las calificaciones que cumplen el filtro y los snapshots
+// nuevos, leídos del mismo hub que el stream de Server-Sent Events. Termina con
+// Unavailable cuando el servidor se apaga, para que el cliente reanude con el
+// cursor del último mensaje en otra réplica.
+func (s *stockService) WatchEvents(req *stockapiv1.WatchEventsRequest, ws stockapiv1.StockService_WatchEventsServer) error {
+	ctx := ws.Context()
+	filter := stockFilter(req.GetFilter())
+
+	var cursor stream.Cursor
+	var err error
+	if req.GetCursor() != "" {
+		cursor, err = stream.ParseCursor(req.GetCursor())
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	} else {
+		cursor, err = s.hub.Head(ctx)
+		if err != nil {
+			return internalError(ctx, "Error al iniciar el stream", err)
+		}
+	}
+
+	metrics.AddStreamClients(1)
+	defer metrics.AddStreamClients(-1)
+
+	for {
+		messages, next, wait, err := s.hub.Next(ctx, cursor, filter)
+		if err != nil {
+			if ctx.Err() != nil {
+				return status.FromContextError(ctx.Err()).Err()
+			}
+			return internalError(ctx, "Error al leer eventos del stream", err)
+		}
+		cursor = next
+
+		for _, message := range messages {
+			if err := ws.Send(eventProto(message)); err != nil {
+				return err
+			}
+		}
+		if len(messages) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-s.hub.Done():
+			return status.Error(codes.Unavailable, "El servidor se está apagando")
+		case <-wait:
+		}
+	}
+}
+
+// timestampArg convierte un instante opcional de la solicitud.
+func timestampArg(name string, ts *timestamppb.Timestamp) (*time.Time, error) {
+	if ts == nil {
+		return nil, nil
+	}
+	if err := ts.CheckValid(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s inválido: %v", name, err)
+	}
+	value := ts.AsTime()
+	return &value, nil
+}
+
+// asOfArg convierte el instante de referencia opcional de la solicitud y lo
+// rechaza si es futuro, como el parámetro as_of de la API REST.
+func asOfArg(ts *timestamppb.Timestamp) (*time.Time, error) {
+	asOf, err := timestampArg("as_of", ts)
+	if err != nil || asOf == nil {
+		return asOf, err
+	}
+	if err := models.ValidateAsOf(*asOf); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return asOf, nil
+}
+
+// invalidFilter convierte un error de filtro del repositorio en InvalidArgument,
+// como el 400 invalid_filter de la API REST.
+func invalidFilter(err error) error {
+	var repoErr *repository.Error
+	if errors.As(err, &repoErr) {
+		return status.Error(codes.InvalidArgument, repoErr.Message)
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// internalError registra un error inesperado y devuelve un Internal sin detalles
+// de la base de datos, Unavailable si la base de datos no está disponible, o
+// InvalidArgument si el repositorio rechazó el filtro.
+func internalError(ctx context.Context, message string, err error) error {
+	if errors.Is(err, repository.ErrInvalidFilter) {
+		return invalidFilter(err)
+	}
+	slog.ErrorContext(ctx, message, "error", err)
+	if errors.Is(err, repository.ErrUnavailable) {
+		return status.Error(codes.Unavailable, message+": la base de datos no está disponible, intente más tarde")
+	}
+	return status.Error(codes.Internal, message)
+}
+
+// stockFilter convierte el filtro de la solicitud.
+func stockFilter(filter *stockapiv1.StockFilter) models.StockFilter {
+	return models.StockFilter{
+		Ticker:    filter.GetTicker(),
+		Brokerage: filter.GetBrokerage(),
+		Rating:    filter.GetRating(),
+		Sector:    filter.GetSector(),
+		Industry:  filter.GetIndustry(),
+	}
+}
+
+// stockProto convierte un stock al mensaje de la API.
+func stockProto(stock models.Stock) *stockapiv1.Stock {
+	return &stockapiv1.Stock{
+		Ticker:          stock.Ticker,
+		Company:         stock.Company,
+		TargetFrom:      stock.TargetFrom,
+		TargetTo:        stock.TargetTo,
+		Action:          stock.Action,
+		Brokerage:       stock.Brokerage,
+		RatingFrom:      stock.RatingFrom,
+		RatingTo:        stock.RatingTo,
+		Time:            timestamppb.New(stock.Time),
+		Sector:          stock.Sector,
+		Industry:        stock.Industry,
+		Exchange:        stock.Exchange,
+		MarketCapBucket: stock.MarketCapBucket,
+	}
+}
+
+// eventProto convierte un mensaje del hub al mensaje de la API.
+func eventProto(message stream.Message) *stockapiv1.WatchEventsResponse {
+	response := &stockapiv1.WatchEventsResponse{Cursor: message.Cursor.String()}
+	switch data := message.Data.(type) {
+	case models.StockEvent:
+		response.Event = &stockapiv1.WatchEventsResponse_Rating{Rating: &stockapiv1.RatingEvent{
+			Id:    data.ID,
+			Stock: stockProto(data.Stock),
+		}}
+	case models.RecommendationSnapshot:
+		response.Event = &stockapiv1.WatchEventsResponse_Snapshot{Snapshot: &stockapiv1.SnapshotCreated{
+			Id:           data.ID,
+			Kind:         data.Kind,
+			SnapshotDate: timestamppb.New(data.SnapshotDate),
+			AsOf:         timestamppb.New(data.AsOf),
+			Strategy:     data.Strategy,
+			CreatedAt:    timestamppb.New(data.CreatedAt),
+		}}
+	}
+	return response
+}
@@ -0,0 +1,107 @@
+package grpcapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/grpcapi/stockapiv1"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newTestService crea el servicio con 25 stocks en memoria.
+func newTestService() *stockService {
+	repo := repository.NewMemoryStockRepository()
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 25; i++ {
+		repo.SaveStocks([]models.Stock{{
+			Ticker:    string(rune('A'+i)) + "X",
+			Action:    "upgraded by",
+			Brokerage: "Barclays",
+			Time:      start.Add(time.Duration(i) * time.Hour),
+		}})
+	}
+	return &stockService{stocks: repo}
+}
+
+func TestListStocksPaging(t *testing.T) {
+	service := newTestService()
+
+	tests := []struct {
+		name         string
+		page         int32
+		pageSize     int32
+		want         codes.Code
+		wantPage     int32
+		wantPageSize int32
+		wantStocks   int
+	}{
+		{"valores predeterminados", 0, 0, codes.OK, 1, defaultPageSize, 10},
+		{"última página", 3, 10, codes.OK, 3, 10, 5},
+		{"página fuera del total", 4, 10, codes.OK, 4, 10, 0},
+		{"tamaño máximo", 1, maxPageSize, codes.OK, 1, maxPageSize, 25},
+		{"página negativa", -1, 10, codes.InvalidArgument, 0, 0, 0},
+		{"tamaño negativo", 1, -5, codes.InvalidArgument, 0, 0, 0},
+		{"tamaño excesivo", 1, maxPageSize + 1, codes.InvalidArgument, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.ListStocks(context.Background(), &stockapiv1.ListStocksRequest{
+				Page:     tt.page,
+				PageSize: tt.pageSize,
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("ListStocks() = %v (%v), want %v", got, err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			if response.GetPage() != tt.wantPage || response.GetPageSize() != tt.wantPageSize || len(response.GetStocks()) != tt.wantStocks {
+				t.Errorf("página %d de %d con %d stocks, want %d de %d con %d",
+					response.GetPage(), response.GetPageSize(), len(response.GetStocks()),
+					tt.wantPage, tt.wantPageSize, tt.wantStocks)
+			}
+			if response.GetTotalStocks() != 25 {
+				t.Errorf("TotalStocks = %d, want 25", response.GetTotalStocks())
+			}
+		})
+	}
+}
+
+func TestListStocksValidation(t *testing.T) {
+	service := newTestService()
+
+	tests := []struct {
+		name string
+		req  *stockapiv1.ListStocksRequest
+		want codes.Code
+	}{
+		{"ordenamiento válido", &stockapiv1.ListStocksRequest{OrderBy: "ticker", Sort: "asc"}, codes.OK},
+		{"campo de ordenamiento desconocido", &stockapiv1.ListStocksRequest{OrderBy: "precio"}, codes.InvalidArgument},
+		{"dirección desconocida", &stockapiv1.ListStocksRequest{Sort: "arriba"}, codes.InvalidArgument},
+		{"as_of pasado", &stockapiv1.ListStocksRequest{AsOf: timestamppb.New(time.Now().Add(-time.Hour))}, codes.OK},
+		{"as_of futuro", &stockapiv1.ListStocksRequest{AsOf: timestamppb.New(time.Now().Add(time.Hour))}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ListStocks(context.Background(), tt.req)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("ListStocks() = %v (%v), want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	response, err := service.ListStocks(context.Background(), &stockapiv1.ListStocksRequest{OrderBy: "ticker", Sort: "asc"})
+	if err != nil {
+		t.Fatalf("ListStocks() error = %v", err)
+	}
+	if first := response.GetStocks()[0].GetTicker(); first != "AX" {
+		t.Errorf("primer ticker = %q, want AX", first)
+	}
+}
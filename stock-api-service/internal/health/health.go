@@ -3,57 +3,145 @@ package health
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/buildinfo"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/freshness"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
-// HealthStatus representa el estado de salud del servicio.
-type HealthStatus struct {
-	Status     string            `json:"status"`
-	Components map[string]string `json:"components,omitempty"`
-	Timestamp  time.Time         `json:"timestamp"`
-	Version    string            `json:"version"`
+// DataSchemaComponent es el servicio dueño de las tablas de stocks, cuya versión
+// de esquema se verifica antes de aceptar tráfico.
+const DataSchemaComponent = "stock-data-service"
+
+// RequiredDataSchemaVersion es la versión mínima del esquema de stock-data-service
+// con la que funciona este servicio.
+const RequiredDataSchemaVersion = 1
+
+// Estados de las verificaciones.
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degradado"
+	StatusUnavailable = "no_disponible"
+)
+
+// Mensajes de las verificaciones fallidas. /readyz no requiere autenticación,
+// así que el error original solo se registra en los logs.
+const (
+	databaseUnavailable = "la base de datos no responde"
+	schemaUnavailable   = "el esquema de stock-data-service no está disponible o es anterior al requerido"
+)
+
+// Check es el resultado de la verificación de una dependencia.
+type Check struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LivenessStatus representa el estado del proceso.
+type LivenessStatus struct {
+	Status        string         `json:"status"`
+	Build         buildinfo.Info `json:"build"`
+	StartedAt     time.Time      `json:"started_at"`
+	UptimeSeconds int64          `json:"uptime_seconds"`
+	Timestamp     time.Time      `json:"timestamp"`
+}
+
+// ReadinessStatus representa el estado de las dependencias y la frescura de los datos.
+type ReadinessStatus struct {
+	LivenessStatus
+	Checks             map[string]Check  `json:"checks"`
+	LastSuccessfulSync *time.Time        `json:"last_successful_sync"`
+	NewestEventTime    *time.Time        `json:"newest_event_time"`
+	DataAgeSeconds     *int64            `json:"data_age_seconds"`
+	Freshness          *freshness.Report `json:"freshness"`
 }
 
 // HealthHandler maneja las verificaciones de salud del servicio.
 type HealthHandler struct {
-	repo *repository.StockRepository
+	repo      repository.StockRepository
+	freshness *freshness.Monitor
 }
 
 // NewHealthHandler crea una nueva instancia de HealthHandler.
-func NewHealthHandler(repo *repository.StockRepository) *HealthHandler {
+func NewHealthHandler(repo repository.StockRepository, monitor *freshness.Monitor) *HealthHandler {
 	return &HealthHandler{
-		repo: repo,
+		repo:      repo,
+		freshness: monitor,
 	}
 }
 
-// BasicHealth verifica el estado básico del servicio.
-func (h *HealthHandler) BasicHealth(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+// Livez indica si el proceso está vivo. No consulta dependencias para que un
+// fallo de la base de datos no provoque reinicios.
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, liveness())
 }
 
-// DetailedHealth verifica el estado detallado del servicio.
-func (h *HealthHandler) DetailedHealth(c *gin.Context) {
+// Readyz indica si el servicio puede atender tráfico. Devuelve 503 si la base de
+// datos no responde o su esquema es anterior al requerido. Si los datos superan
+// los umbrales de frescura el estado es degradado, pero el servicio sigue
+// disponible porque los datos antiguos son preferibles a no responder.
+func (h *HealthHandler) Readyz(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	status := HealthStatus{
-		Status:     "ok",
-		Components: make(map[string]string),
-		Timestamp:  time.Now(),
-		Version:    "1.0.0",
+	status := ReadinessStatus{
+		LivenessStatus: liveness(),
+		Checks:         make(map[string]Check),
 	}
 
 	// Verificar conexión a la base de datos
-	if err := h.repo.Ping(ctx); err != nil {
-		status.Status = "degradado"
-		status.Components["database"] = "error: " + err.Error()
-	} else {
-		status.Components["database"] = "ok"
+	status.Checks["database"] = check(ctx, "database", h.repo.Ping(ctx), databaseUnavailable)
+
+	// Verificar la versión del esquema de stock-data-service
+	version, err := h.repo.GetSchemaVersion(ctx, DataSchemaComponent)
+	if err == nil && version < RequiredDataSchemaVersion {
+		err = fmt.Errorf("versión de esquema %d, se requiere al menos %d", version, RequiredDataSchemaVersion)
 	}
+	status.Checks["schema"] = check(ctx, "schema", err, schemaUnavailable)
 
-	c.JSON(http.StatusOK, status)
+	// Frescura de los datos según la última consulta del monitor
+	report := h.freshness.Report()
+	status.Freshness = &report
+	status.LastSuccessfulSync = report.LastSuccessfulSync
+	status.NewestEventTime = report.NewestEventTime
+	status.DataAgeSeconds = report.DataAgeSeconds
+	if report.Status == freshness.StatusDegraded {
+		status.Status = StatusDegraded
+	}
+
+	code := http.StatusOK
+	for _, result := range status.Checks {
+		if result.Status != StatusOK {
+			status.Status = StatusUnavailable
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	c.JSON(code, status)
+}
+
+// liveness construye el estado básico del proceso.
+func liveness() LivenessStatus {
+	return LivenessStatus{
+		Status:        StatusOK,
+		Build:         buildinfo.Get(),
+		StartedAt:     buildinfo.StartedAt(),
+		UptimeSeconds: int64(buildinfo.Uptime().Seconds()),
+		Timestamp:     time.Now(),
+	}
+}
+
+// check convierte el error de una verificación en su resultado. El error se
+// registra en los logs y la respuesta solo lleva el mensaje fijo.
+func check(ctx context.Context, name string, err error, message string) Check {
+	if err != nil {
+		slog.WarnContext(ctx, "Verificación de disponibilidad fallida", "check", name, "error", err)
+		return Check{Status: StatusUnavailable, Error: message}
+	}
+	return Check{Status: StatusOK}
 }
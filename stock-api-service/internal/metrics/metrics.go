@@ -0,0 +1,150 @@
+// Paquete metrics expone métricas del servicio en formato Prometheus.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute agrupa las solicitudes que no coinciden con ninguna ruta, para
+// que las URLs arbitrarias no generen series nuevas.
+const unmatchedRoute = "unmatched"
+
+// otherMethod agrupa los métodos HTTP no estándar, para que un cliente no pueda
+// generar series nuevas enviando métodos arbitrarios.
+const otherMethod = "other"
+
+// standardMethods son los métodos HTTP que se publican con su nombre.
+var standardMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+var (
+	// Solicitudes HTTP por método, ruta registrada y código de estado
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Solicitudes HTTP atendidas.",
+	}, []string{"method", "route", "status"})
+
+	// Latencia de las solicitudes HTTP por método, ruta registrada y código de estado
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duración de las solicitudes HTTP.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Duración del cálculo de recomendaciones por estrategia
+	recommendationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "stock_recommendation_duration_seconds",
+		Help:    "Tiempo de cálculo de las recomendaciones, sin incluir la consulta a la base de datos.",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"strategy"})
+
+	// Fin de la última sincronización exitosa observada en la base de datos
+	dataLastSync = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "stock_data_last_sync_timestamp_seconds",
+		Help: "Momento (Unix) en que terminó la última sincronización exitosa de stock-data-service.",
+	})
+
+	// Fecha del evento más reciente del historial
+	dataNewestEvent = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "stock_data_newest_event_timestamp_seconds",
+		Help: "Fecha (Unix) del evento de calificación más reciente.",
+	})
+
+	// 1 si los datos superan los umbrales de frescura
+	dataStale = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "stock_data_stale",
+		Help: "1 si los datos superan los umbrales de frescura configurados, 0 en otro caso.",
+	})
+
+	// Clientes conectados al stream de eventos
+	streamClients = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "stock_stream_clients",
+		Help: "Clientes conectados a /api/v1/stream/events.",
+	})
+
+	// Alertas disparadas por el motor de reglas
+	alertsTriggered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "stock_alerts_triggered_total",
+		Help: "Alertas disparadas por las reglas de alerta.",
+	})
+)
+
+// Middleware registra la cantidad y la duración de las solicitudes HTTP. Usa la
+// plantilla de la ruta (por ejemplo /api/v1/stocks/:ticker) y no la URL, y
+// "other" como método si no es uno estándar.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+		if !standardMethods[method] {
+			method = otherMethod
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		httpRequests.WithLabelValues(method, route, status).Inc()
+		httpDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDB publica las estadísticas del pool de conexiones de la base de datos.
+func RegisterDB(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler devuelve el handler HTTP que sirve las métricas.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveRecommendation registra el tiempo de cálculo de recomendaciones con una estrategia.
+func ObserveRecommendation(strategy string, duration time.Duration) {
+	recommendationDuration.WithLabelValues(strategy).Observe(duration.Seconds())
+}
+
+// SetFreshness publica la frescura de los datos. Los instantes desconocidos se
+// publican como 0.
+func SetFreshness(lastSync, newestEvent *time.Time, stale bool) {
+	dataLastSync.Set(unixSeconds(lastSync))
+	dataNewestEvent.Set(unixSeconds(newestEvent))
+	if stale {
+		dataStale.Set(1)
+	} else {
+		dataStale.Set(0)
+	}
+}
+
+// AddStreamClients suma delta a la cantidad de clientes conectados al stream de eventos.
+func AddStreamClients(delta int) {
+	streamClients.Add(float64(delta))
+}
+
+// AddAlertsTriggered suma n a la cantidad de alertas disparadas.
+func AddAlertsTriggered(n int) {
+	alertsTriggered.Add(float64(n))
+}
+
+// unixSeconds convierte un instante opcional en segundos Unix.
+func unixSeconds(t *time.Time) float64 {
+	if t == nil {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
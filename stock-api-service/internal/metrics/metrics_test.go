@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareGroupsNonStandardMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(Middleware())
+	engine.Handle("PURGE", "/cache", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	engine.GET("/cache", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, method := range []string{"PURGE", "BREW", http.MethodGet} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/cache", nil))
+	}
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues(otherMethod, "/cache", "204")); got != 1 {
+		t.Errorf("solicitudes con método other = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/cache", "204")); got != 1 {
+		t.Errorf("solicitudes GET = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(httpRequests); got != 3 {
+		t.Errorf("series = %d, want 3 (GET, other y BREW sin ruta)", got)
+	}
+}
@@ -2,6 +2,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -25,6 +27,38 @@ type Stock struct {
 	RatingTo string `json:"rating_to"`
 	// Fecha y hora de la actualización
 	Time time.Time `json:"time"`
+	// Sector económico de la compañía
+	Sector string `json:"sector,omitempty"`
+	// Industria de la compañía
+	Industry string `json:"industry,omitempty"`
+	// Bolsa en la que cotiza
+	Exchange string `json:"exchange,omitempty"`
+	// Rango de capitalización de mercado (mega, large, mid, small, micro)
+	MarketCapBucket string `json:"market_cap_bucket,omitempty"`
+}
+
+// StockEvent es una actualización de calificación guardada en el historial.
+type StockEvent struct {
+	// Identificador del evento en la tabla stock_events
+	ID int64 `json:"id"`
+	Stock
+}
+
+// Company representa los datos de referencia de una compañía que
+// stock-data-service guarda en la tabla companies.
+type Company struct {
+	// Símbolo o ticker de la acción
+	Ticker string `json:"ticker"`
+	// Nombre de la compañía
+	Company string `json:"company"`
+	// Sector económico
+	Sector string `json:"sector"`
+	// Industria dentro del sector
+	Industry string `json:"industry"`
+	// Bolsa en la que cotiza
+	Exchange string `json:"exchange"`
+	// Rango de capitalización de mercado (mega, large, mid, small, micro)
+	MarketCapBucket string `json:"market_cap_bucket"`
 }
 
 // Pagination contiene la información de paginación para las consultas.
@@ -37,6 +71,94 @@ type Pagination struct {
 	Offset int
 }
 
+// StockFilter contiene los criterios de búsqueda para el listado de stocks.
+type StockFilter struct {
+	// Casa de bolsa exacta
+	Brokerage string
+	// Patrón parcial del ticker
+	Ticker string
+	// Calificación anterior o actual
+	Rating string
+	// Sector de la compañía
+	Sector string
+	// Industria de la compañía
+	Industry string
+	// Campo de ordenamiento
+	OrderBy string
+	// Dirección del ordenamiento (ASC o DESC)
+	SortOrder string
+	// Instante de referencia; si se indica, solo se considera lo conocido hasta ese momento
+	AsOf *time.Time
+}
+
+// Matches indica si un stock cumple los criterios de búsqueda del filtro, con
+// las mismas reglas que la consulta a la base de datos. No considera AsOf.
+func (f StockFilter) Matches(stock Stock) bool {
+	if f.Ticker != "" && !strings.Contains(strings.ToLower(stock.Ticker), strings.ToLower(f.Ticker)) {
+		return false
+	}
+	if f.Brokerage != "" && stock.Brokerage != f.Brokerage {
+		return false
+	}
+	if f.Rating != "" && stock.RatingFrom != f.Rating && stock.RatingTo != f.Rating {
+		return false
+	}
+	if f.Sector != "" && !strings.EqualFold(stock.Sector, f.Sector) {
+		return false
+	}
+	if f.Industry != "" && !strings.EqualFold(stock.Industry, f.Industry) {
+		return false
+	}
+	return true
+}
+
+// ParseAsOf interpreta un instante de referencia. Acepta RFC3339 o una fecha
+// YYYY-MM-DD, que se interpreta como el final de ese día en UTC, y rechaza los
+// instantes futuros. La fecha de hoy se interpreta como el instante actual.
+// Devuelve nil si el valor está vacío.
+func ParseAsOf(value string) (*time.Time, error) {
+	return parseAsOf(value, time.Now())
+}
+
+// parseAsOf interpreta un instante de referencia respecto de now.
+func parseAsOf(value string, now time.Time) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		day, dayErr := time.Parse("2006-01-02", value)
+		if dayErr != nil {
+			return nil, fmt.Errorf("as_of debe tener formato RFC3339 o YYYY-MM-DD: %s", value)
+		}
+		asOf = day.Add(24*time.Hour - time.Nanosecond)
+		if day.Equal(now.UTC().Truncate(24 * time.Hour)) {
+			asOf = now
+		}
+	}
+
+	if err := validateAsOf(asOf, now); err != nil {
+		return nil, err
+	}
+
+	return &asOf, nil
+}
+
+// ValidateAsOf rechaza un instante de referencia futuro, como ParseAsOf, para
+// los transportes que reciben el instante ya interpretado.
+func ValidateAsOf(asOf time.Time) error {
+	return validateAsOf(asOf, time.Now())
+}
+
+// validateAsOf rechaza un instante posterior a now.
+func validateAsOf(asOf, now time.Time) error {
+	if asOf.After(now) {
+		return fmt.Errorf("as_of no puede estar en el futuro: %s", asOf.Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
 // StockListResponse representa la respuesta para el listado de stocks.
 type StockListResponse struct {
 	// Lista de stocks
@@ -51,6 +173,34 @@ type StockListResponse struct {
 	ItemsPerPage int `json:"items_per_page"`
 }
 
+// Brokerage resume la actividad de una casa de bolsa en el historial.
+type Brokerage struct {
+	// Nombre de la casa de bolsa
+	Name string `json:"name"`
+	// Calificaciones emitidas
+	RatingCount int `json:"rating_count"`
+	// Tickers distintos calificados
+	TickerCount int `json:"ticker_count"`
+	// Fecha de la calificación más reciente
+	LastRatingAt time.Time `json:"last_rating_at"`
+}
+
+// TickerHistoryResponse representa la respuesta del historial de un ticker.
+type TickerHistoryResponse struct {
+	// Símbolo o ticker de la acción
+	Ticker string `json:"ticker"`
+	// Calificaciones, de la más reciente a la más antigua
+	Events []StockEvent `json:"events"`
+	// Total de calificaciones del ticker
+	TotalEvents int `json:"total_events"`
+	// Total de páginas disponibles
+	TotalPages int `json:"total_pages"`
+	// Página actual
+	CurrentPage int `json:"current_page"`
+	// Cantidad de elementos por página
+	ItemsPerPage int `json:"items_per_page"`
+}
+
 // RecommendationResult representa el resultado de una recomendación.
 type RecommendationResult struct {
 	// Stock recomendado
@@ -69,8 +219,212 @@ type RecommendationResponse struct {
 	Recommendations []RecommendationResult `json:"recommendations"`
 	// Fecha y hora de generación
 	GeneratedAt time.Time `json:"generated_at"`
+	// Instante de referencia usado para seleccionar los datos y calcular su antigüedad
+	AsOf time.Time `json:"as_of"`
 	// Cantidad de recomendaciones
 	Count int `json:"count"`
 	// Mensaje informativo
 	Message string `json:"message"`
+	// Lista de seguimiento aplicada, si se indicó
+	WatchlistID int64 `json:"watchlist_id,omitempty"`
+	// Uso de la lista de seguimiento (restrict o boost)
+	WatchlistMode string `json:"watchlist_mode,omitempty"`
+}
+
+// RankedRecommendation es una recomendación con su posición dentro de un snapshot.
+type RankedRecommendation struct {
+	// Posición en el ranking (1 es la mejor)
+	Rank int `json:"rank"`
+	RecommendationResult
+}
+
+// RecommendationSnapshot representa una lista de recomendaciones persistida.
+type RecommendationSnapshot struct {
+	// Identificador del snapshot
+	ID int64 `json:"id"`
+	// Tipo de snapshot (daily u on_demand)
+	Kind string `json:"kind"`
+	// Día al que corresponde el snapshot
+	SnapshotDate time.Time `json:"snapshot_date"`
+	// Instante de referencia con el que se calcularon las recomendaciones
+	AsOf time.Time `json:"as_of"`
+	// Estrategia utilizada por el recomendador
+	Strategy string `json:"strategy"`
+	// Fecha y hora de creación
+	CreatedAt time.Time `json:"created_at"`
+	// Recomendaciones ordenadas por posición
+	Recommendations []RankedRecommendation `json:"recommendations,omitempty"`
+}
+
+// SnapshotDiffEntry describe el cambio de un ticker entre dos snapshots.
+type SnapshotDiffEntry struct {
+	// Símbolo o ticker de la acción
+	Ticker string `json:"ticker"`
+	// Nombre de la compañía
+	Company string `json:"company"`
+	// Posición en el snapshot de origen
+	FromRank *int `json:"from_rank,omitempty"`
+	// Posición en el snapshot de destino
+	ToRank *int `json:"to_rank,omitempty"`
+	// Posiciones ganadas (positivo) o perdidas (negativo)
+	RankChange int `json:"rank_change"`
+	// Puntuación en el snapshot de origen
+	FromScore *float64 `json:"from_score,omitempty"`
+	// Puntuación en el snapshot de destino
+	ToScore *float64 `json:"to_score,omitempty"`
+	// Diferencia de puntuación entre ambos snapshots
+	ScoreDelta *float64 `json:"score_delta,omitempty"`
+}
+
+// SnapshotDiff representa las diferencias entre dos snapshots de recomendaciones.
+type SnapshotDiff struct {
+	// Snapshot de origen (sin recomendaciones)
+	From RecommendationSnapshot `json:"from"`
+	// Snapshot de destino (sin recomendaciones)
+	To RecommendationSnapshot `json:"to"`
+	// Tickers que entraron en el ranking
+	Entered []SnapshotDiffEntry `json:"entered"`
+	// Tickers que salieron del ranking
+	Left []SnapshotDiffEntry `json:"left"`
+	// Tickers que cambiaron de posición
+	Moved []SnapshotDiffEntry `json:"moved"`
+	// Tickers que conservaron su posición
+	Unchanged []SnapshotDiffEntry `json:"unchanged"`
+}
+
+// UnclassifiedSector agrupa los tickers sin datos de referencia de compañía.
+const UnclassifiedSector = "Sin clasificar"
+
+// SectorSummary resume la actividad de los analistas en un sector.
+type SectorSummary struct {
+	// Sector económico
+	Sector string `json:"sector"`
+	// Cantidad de tickers con actividad
+	Tickers int `json:"tickers"`
+	// Total de eventos registrados
+	Events int `json:"events"`
+	// Mejoras de calificación
+	Upgrades int `json:"upgrades"`
+	// Rebajas de calificación
+	Downgrades int `json:"downgrades"`
+	// Aumentos de precio objetivo
+	TargetsRaised int `json:"targets_raised"`
+	// Reducciones de precio objetivo
+	TargetsLowered int `json:"targets_lowered"`
+	// Mejoras menos rebajas
+	NetUpgrades int `json:"net_upgrades"`
+}
+
+// SectorSummaryResponse representa la respuesta del resumen por sector.
+type SectorSummaryResponse struct {
+	// Resumen de cada sector
+	Sectors []SectorSummary `json:"sectors"`
+	// Inicio del periodo analizado
+	From time.Time `json:"from"`
+	// Fin del periodo analizado
+	To time.Time `json:"to"`
+	// Cantidad de sectores
+	Count int `json:"count"`
+}
+
+// Watchlist es una lista de tickers de un principal.
+type Watchlist struct {
+	// Identificador de la lista
+	ID int64 `json:"id"`
+	// Principal propietario
+	Owner string `json:"owner"`
+	// Nombre de la lista, único por propietario
+	Name string `json:"name"`
+	// Tickers en orden alfabético
+	Tickers []string `json:"tickers"`
+	// Fecha y hora de creación
+	CreatedAt time.Time `json:"created_at"`
+	// Fecha y hora de la última modificación
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WatchlistFeed son las actualizaciones recientes de los tickers de una lista.
+type WatchlistFeed struct {
+	// Lista consultada
+	Watchlist Watchlist `json:"watchlist"`
+	// Actualizaciones, de la más reciente a la más antigua
+	Events []StockEvent `json:"events"`
+	// Inicio del periodo consultado
+	Since time.Time `json:"since"`
+	// Cantidad de actualizaciones
+	Count int `json:"count"`
+}
+
+// AlertCondition es una comparación entre un campo de la calificación y un valor.
+type AlertCondition struct {
+	// Campo del stock (ticker, brokerage, action, ...) o derivado
+	// (target_change_pct, rating_delta)
+	Field string `json:"field"`
+	// Operador: eq, ne, gt, gte, lt, lte, in o contains
+	Op string `json:"op"`
+	// Valor de comparación: texto, número o lista de textos para in
+	Value any `json:"value"`
+}
+
+// AlertWindow exige que varias calificaciones del mismo ticker cumplan la regla
+// dentro de un periodo.
+type AlertWindow struct {
+	// Duración del periodo, por ejemplo 7d o 36h
+	Duration string `json:"duration"`
+	// Cantidad mínima de calificaciones que cumplen las condiciones, incluida la nueva
+	MinCount int `json:"min_count"`
+}
+
+// AlertRule es una regla de alerta definida por un principal.
+type AlertRule struct {
+	// Identificador de la regla
+	ID int64 `json:"id"`
+	// Principal propietario
+	Owner string `json:"owner"`
+	// Nombre de la regla, único por propietario
+	Name string `json:"name"`
+	// Indica si la regla se evalúa
+	Enabled bool `json:"enabled"`
+	// Condiciones que deben cumplirse todas
+	Conditions []AlertCondition `json:"conditions"`
+	// Periodo y cantidad mínima de calificaciones, opcional
+	Window *AlertWindow `json:"window,omitempty"`
+	// Fecha y hora de creación
+	CreatedAt time.Time `json:"created_at"`
+	// Fecha y hora de la última modificación
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Alert es el disparo de una regla por una calificación nueva.
+type Alert struct {
+	// Identificador de la alerta
+	ID int64 `json:"id"`
+	// Regla que se cumplió
+	RuleID int64 `json:"rule_id"`
+	// Nombre de la regla
+	RuleName string `json:"rule_name"`
+	// Principal propietario de la regla
+	Owner string `json:"owner"`
+	// Evento de stock_events que disparó la regla
+	EventID int64 `json:"event_id"`
+	// Calificación que disparó la regla
+	Stock Stock `json:"stock"`
+	// Valores derivados calculados al evaluar la regla
+	Values map[string]float64 `json:"values"`
+	// Fecha y hora del disparo
+	TriggeredAt time.Time `json:"triggered_at"`
+	// Fecha y hora del reconocimiento, si ya se reconoció
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+}
+
+// AlertFilter contiene los criterios de búsqueda de alertas de un propietario.
+type AlertFilter struct {
+	// Regla exacta; 0 incluye todas
+	RuleID int64
+	// Estado de reconocimiento; nil incluye ambos
+	Acknowledged *bool
+	// Solo alertas con identificador menor, para paginar
+	BeforeID int64
+	// Cantidad máxima de alertas
+	Limit int
 }
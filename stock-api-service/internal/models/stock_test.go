@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAsOf(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"fecha pasada", "2024-03-14", time.Date(2024, 3, 14, 23, 59, 59, 999999999, time.UTC), false},
+		{"fecha de hoy", "2024-03-15", now, false},
+		{"fecha futura", "2024-03-16", time.Time{}, true},
+		{"instante pasado", "2024-03-15T10:00:00Z", time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC), false},
+		{"instante futuro", "2024-03-15T11:00:00Z", time.Time{}, true},
+		{"formato inválido", "15/03/2024", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAsOf(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAsOf(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if err == nil && !got.Equal(tt.want) {
+				t.Errorf("parseAsOf(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	if got, err := parseAsOf("", now); got != nil || err != nil {
+		t.Errorf("parseAsOf(\"\") = %v, %v; want nil", got, err)
+	}
+}
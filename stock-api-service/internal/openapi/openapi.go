@@ -0,0 +1,76 @@
+// Paquete openapi contiene el documento OpenAPI 3 del servicio, lo publica en
+// /openapi.json y valida las solicitudes entrantes contra él.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/gin-gonic/gin"
+)
+
+// Path es la ruta en la que se publica el documento.
+const Path = "/openapi.json"
+
+// document es el documento OpenAPI del servicio.
+//
+//go:embed openapi.yaml
+var document []byte
+
+// Spec es el documento OpenAPI cargado, con su enrutador para ubicar la
+// operación de cada solicitud.
+type Spec struct {
+	doc    *openapi3.T
+	router routers.Router
+	json   []byte
+}
+
+// Load carga y valida el documento incluido en el binario.
+func Load() (*Spec, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(document)
+	if err != nil {
+		return nil, fmt.Errorf("error al cargar el documento OpenAPI: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("documento OpenAPI inválido: %w", err)
+	}
+
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el enrutador OpenAPI: %w", err)
+	}
+
+	data, err := doc.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("error al serializar el documento OpenAPI: %w", err)
+	}
+
+	return &Spec{
+		doc:    doc,
+		router: router,
+		json:   data,
+	}, nil
+}
+
+// Document devuelve el documento cargado.
+func (s *Spec) Document() *openapi3.T {
+	return s.doc
+}
+
+// FindRoute devuelve la operación del documento que corresponde a la solicitud.
+func (s *Spec) FindRoute(req *http.Request) (*routers.Route, map[string]string, error) {
+	return s.router.FindRoute(req)
+}
+
+// Handler publica el documento en JSON.
+func (s *Spec) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", s.json)
+	}
+}
@@ -0,0 +1,196 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+)
+
+// maxBodyBytes es el tamaño máximo de un cuerpo que se valida. Los handlers
+// pueden aplicar después un límite menor.
+const maxBodyBytes = 8 << 20
+
+// Validate es un middleware que valida los parámetros y el cuerpo de cada
+// solicitud contra la operación del documento y responde 400 con los errores de
+// cada campo en formato application/problem+json. Las rutas que no están en el
+// documento siguen sin validar. Debe ir después de la autenticación y del rol,
+// para que una solicitud sin credenciales reciba 401 o 403 y no los detalles
+// del contrato.
+func (s *Spec) Validate() gin.HandlerFunc {
+	options := &openapi3filter.Options{
+		MultiError:          true,
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults: true,
+	}
+
+	return func(c *gin.Context) {
+		route, pathParams, err := s.router.FindRoute(c.Request)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes)
+		}
+
+		err = openapi3filter.ValidateRequest(c.Request.Context(), &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		})
+		if err == nil {
+			c.Next()
+			return
+		}
+
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			problem.Abort(c, &problem.Error{
+				Status: http.StatusRequestEntityTooLarge,
+				Code:   problem.CodePayloadTooLarge,
+				Detail: fmt.Sprintf("El cuerpo de la solicitud supera el máximo de %d bytes", tooLarge.Limit),
+				Err:    err,
+			})
+			return
+		}
+
+		problem.Abort(c, &problem.Error{
+			Status: http.StatusBadRequest,
+			Code:   problem.CodeInvalidRequest,
+			Detail: "La solicitud no cumple el documento OpenAPI",
+			Errors: fieldErrors(err),
+			Err:    err,
+		})
+	}
+}
+
+// fieldErrors convierte los errores de validación en errores por campo.
+func fieldErrors(err error) []problem.FieldError {
+	if multi, ok := err.(openapi3.MultiError); ok {
+		var details []problem.FieldError
+		for _, e := range multi {
+			details = append(details, fieldErrors(e)...)
+		}
+		return details
+	}
+
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return []problem.FieldError{{Message: err.Error()}}
+	}
+
+	location, field := "body", ""
+	if requestErr.Parameter != nil {
+		location, field = requestErr.Parameter.In, requestErr.Parameter.Name
+	}
+
+	causes := []error{requestErr.Err}
+	if multi, ok := requestErr.Err.(openapi3.MultiError); ok {
+		causes = multi
+	}
+
+	details := make([]problem.FieldError, 0, len(causes))
+	for _, cause := range causes {
+		detail := problem.FieldError{Field: field, Location: location}
+
+		var schemaErr *openapi3.SchemaError
+		var parseErr *openapi3filter.ParseError
+		switch {
+		case cause == nil:
+			detail.Message = requestErr.Reason
+		case errors.As(cause, &schemaErr):
+			if location == "body" {
+				detail.Field = fieldPath(schemaErr.JSONPointer())
+			}
+			detail.Message = schemaMessage(schemaErr)
+		case errors.Is(cause, openapi3filter.ErrInvalidRequired) && location == "body":
+			detail.Message = "se requiere un cuerpo JSON"
+		case errors.Is(cause, openapi3filter.ErrInvalidRequired):
+			detail.Message = "es requerido"
+		case errors.Is(cause, openapi3filter.ErrInvalidEmptyValue):
+			detail.Message = "no puede estar vacío"
+		case errors.As(cause, &parseErr) && location == "body":
+			detail.Message = "el cuerpo no es JSON válido"
+		case errors.As(cause, &parseErr):
+			detail.Message = "valor inválido: " + fmt.Sprint(parseErr.Value)
+			if requestErr.Parameter.Schema != nil && requestErr.Parameter.Schema.Value != nil {
+				detail.Message += " (se espera " + typeName(requestErr.Parameter.Schema.Value) + ")"
+			}
+		default:
+			detail.Message = cause.Error()
+		}
+		details = append(details, detail)
+	}
+	return details
+}
+
+// schemaMessage describe en español la restricción del esquema que no se cumple.
+func schemaMessage(err *openapi3.SchemaError) string {
+	schema := err.Schema
+	if schema == nil {
+		return err.Reason
+	}
+
+	switch err.SchemaField {
+	case "type":
+		return "debe ser de tipo " + typeName(schema)
+	case "enum":
+		values := make([]string, len(schema.Enum))
+		for i, value := range schema.Enum {
+			values[i] = fmt.Sprint(value)
+		}
+		return "debe ser uno de: " + strings.Join(values, ", ")
+	case "minimum":
+		return fmt.Sprintf("debe ser mayor o igual a %v", *schema.Min)
+	case "maximum":
+		return fmt.Sprintf("debe ser menor o igual a %v", *schema.Max)
+	case "minLength":
+		return fmt.Sprintf("debe tener al menos %d caracteres", schema.MinLength)
+	case "maxLength":
+		return fmt.Sprintf("debe tener como máximo %d caracteres", *schema.MaxLength)
+	case "minItems":
+		return fmt.Sprintf("debe tener al menos %d elementos", schema.MinItems)
+	case "pattern":
+		return "no tiene el formato esperado"
+	case "format":
+		return "debe tener formato " + schema.Format
+	case "required":
+		return "es requerido"
+	case "nullable":
+		return "no puede ser null"
+	}
+	return err.Reason
+}
+
+// typeName devuelve el tipo esperado por un esquema.
+func typeName(schema *openapi3.Schema) string {
+	if schema.Type == nil || len(*schema.Type) == 0 {
+		return "un valor"
+	}
+	return strings.Join(*schema.Type, " o ")
+}
+
+// fieldPath convierte un JSON pointer en una ruta legible como conditions[0].op.
+func fieldPath(pointer []string) string {
+	var path strings.Builder
+	for _, part := range pointer {
+		if _, err := strconv.Atoi(part); err == nil {
+			path.WriteString("[" + part + "]")
+			continue
+		}
+		if path.Len() > 0 {
+			path.WriteString(".")
+		}
+		path.WriteString(part)
+	}
+	return path.String()
+}
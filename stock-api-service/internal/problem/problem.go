@@ -0,0 +1,151 @@
+// Paquete problem define las respuestas de error de la API en el formato
+// application/problem+json (RFC 9457), con un código estable que los clientes
+// pueden usar en lugar del mensaje.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType es el tipo de contenido de las respuestas de error.
+const ContentType = "application/problem+json"
+
+// Códigos estables de los errores. Los mensajes pueden cambiar; los códigos no.
+const (
+	// La solicitud no cumple el contrato de la API
+	CodeInvalidRequest = "invalid_request"
+	// Un filtro u ordenamiento no es válido
+	CodeInvalidFilter = "invalid_filter"
+	// Faltan las credenciales o no son válidas
+	CodeUnauthenticated = "unauthenticated"
+	// El principal no tiene el rol requerido
+	CodeForbidden = "forbidden"
+	// El recurso no existe
+	CodeNotFound = "not_found"
+	// La operación entra en conflicto con el estado actual del recurso
+	CodeConflict = "conflict"
+	// El cuerpo de la solicitud supera el tamaño máximo
+	CodePayloadTooLarge = "payload_too_large"
+	// Se superó el límite de solicitudes o la cuota diaria
+	CodeRateLimited = "rate_limited"
+	// Error inesperado del servidor
+	CodeInternal = "internal"
+	// Una dependencia, como la base de datos, no está disponible
+	CodeUnavailable = "unavailable"
+)
+
+// Problem es el cuerpo de una respuesta de error.
+type Problem struct {
+	// Tipo del error; about:blank indica que lo describen el estado y el código
+	Type string `json:"type"`
+	// Resumen del estado HTTP
+	Title string `json:"title"`
+	// Estado HTTP
+	Status int `json:"status"`
+	// Descripción para el cliente, sin detalles internos
+	Detail string `json:"detail,omitempty"`
+	// Ruta de la solicitud
+	Instance string `json:"instance,omitempty"`
+	// Código estable del error
+	Code string `json:"code"`
+	// Identificador de la solicitud, el mismo de los logs y de X-Request-ID
+	RequestID string `json:"request_id,omitempty"`
+	// Errores por campo, presentes cuando la solicitud no cumple el contrato
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describe un campo de la solicitud que no es válido.
+type FieldError struct {
+	// Nombre del parámetro, o ruta del campo en el cuerpo (por ejemplo conditions[0].op)
+	Field string `json:"field"`
+	// Ubicación del campo: query, path, header o body
+	Location string `json:"location"`
+	// Motivo del rechazo
+	Message string `json:"message"`
+}
+
+// Error es un error con la respuesta que recibe el cliente. Detail se muestra
+// tal cual, por lo que no debe incluir detalles internos; Err, si existe, solo
+// se registra en los logs.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Errors []FieldError
+	Err    error
+}
+
+// Error implementa la interfaz error.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+// Unwrap devuelve la causa del error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New crea un error con el estado, el código y la descripción indicados.
+func New(status int, code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// BadRequest indica que la solicitud no es válida.
+func BadRequest(detail string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, detail)
+}
+
+// NotFound indica que el recurso no existe.
+func NotFound(detail string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, detail)
+}
+
+// Conflict indica que la operación entra en conflicto con el estado del recurso.
+func Conflict(detail string) *Error {
+	return New(http.StatusConflict, CodeConflict, detail)
+}
+
+// Internal indica un error inesperado. La causa solo se registra.
+func Internal(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Detail: "Error interno del servidor", Err: err}
+}
+
+// Abort registra el error en la solicitud y detiene la cadena de handlers. El
+// middleware de errores escribe la respuesta.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// Write escribe la respuesta de error.
+func Write(c *gin.Context, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	c.Render(p.Status, render{p})
+}
+
+// render serializa un Problem con el tipo de contenido application/problem+json.
+type render struct {
+	problem Problem
+}
+
+// Render implementa la interfaz render.Render de gin.
+func (r render) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.problem)
+}
+
+// WriteContentType implementa la interfaz render.Render de gin.
+func (r render) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ContentType)
+}
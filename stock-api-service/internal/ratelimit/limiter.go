@@ -0,0 +1,235 @@
+// Paquete ratelimit limita la cantidad de solicitudes por cliente con token buckets
+// y cuotas diarias.
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// Grupos de rutas con límites propios.
+const (
+	// Consultas de stocks, sectores, snapshots y backtests
+	GroupRead = "read"
+	// Cálculo de recomendaciones, la consulta más costosa
+	GroupRecommendations = "recommendations"
+	// Operaciones que lanzan trabajos o escriben datos
+	GroupWrite = "write"
+	// Todas las solicitudes de una IP, antes de la autenticación
+	GroupIP = "ip"
+)
+
+// Policy define el token bucket de un grupo de rutas.
+type Policy struct {
+	// Solicitudes por minuto que se reponen de forma continua
+	PerMinute int
+	// Solicitudes que se pueden hacer de golpe
+	Burst int
+}
+
+// rate devuelve la cantidad de tokens que se reponen por segundo.
+func (p Policy) rate() float64 {
+	return float64(p.PerMinute) / 60
+}
+
+// window devuelve el tiempo que tarda el bucket en llenarse desde vacío.
+func (p Policy) window() time.Duration {
+	if p.PerMinute <= 0 {
+		return 0
+	}
+	return time.Duration(float64(p.Burst) / p.rate() * float64(time.Second))
+}
+
+// Request describe una solicitud que consume un token y una unidad de cuota.
+type Request struct {
+	// Clave del token bucket (grupo y cliente)
+	BucketKey string
+	// Política del bucket
+	Policy Policy
+	// Clave de la cuota diaria (cliente)
+	QuotaKey string
+	// Solicitudes permitidas por día; 0 sin cuota
+	QuotaLimit int
+	// Instante de la solicitud
+	Now time.Time
+}
+
+// Decision es el resultado de evaluar una solicitud.
+type Decision struct {
+	// Indica si la solicitud está permitida
+	Allowed bool
+	// Tokens que quedan en el bucket después de la solicitud
+	Tokens float64
+	// Solicitudes de la cuota diaria usadas, incluida esta si se permitió
+	QuotaUsed int
+	// Indica si la solicitud se rechazó por la cuota diaria
+	QuotaExceeded bool
+}
+
+// Store guarda el estado de los buckets y las cuotas. Las implementaciones
+// deben evaluar cada solicitud de forma atómica.
+type Store interface {
+	Allow(ctx context.Context, req Request) (Decision, error)
+}
+
+// Limiter aplica las políticas de cada grupo y la cuota diaria por cliente.
+type Limiter struct {
+	store      Store
+	policies   map[string]Policy
+	dailyQuota int
+	now        func() time.Time
+}
+
+// NewLimiter crea un limitador. dailyQuota es la cantidad de solicitudes por día
+// de cada cliente en todos los grupos; 0 la desactiva.
+func NewLimiter(store Store, policies map[string]Policy, dailyQuota int) *Limiter {
+	return &Limiter{
+		store:      store,
+		policies:   policies,
+		dailyQuota: dailyQuota,
+		now:        time.Now,
+	}
+}
+
+// Check aplica la política del grupo y la cuota diaria al cliente indicado, como
+// Limit, para los transportes que no usan Gin. Devuelve la decisión y, si la
+// solicitud se rechazó, cuánto falta para poder repetirla. Si el limitador es
+// nil o el almacén falla, la solicitud se permite.
+func (l *Limiter) Check(ctx context.Context, group, client string) (Decision, time.Duration) {
+	if l == nil {
+		return Decision{Allowed: true}, 0
+	}
+	return l.check(ctx, l.groupRequest(group, client))
+}
+
+// CheckIP aplica la política de GroupIP a la IP indicada, como LimitIP. Sin
+// política para GroupIP la solicitud se permite.
+func (l *Limiter) CheckIP(ctx context.Context, ip string) (Decision, time.Duration) {
+	if l == nil {
+		return Decision{Allowed: true}, 0
+	}
+	req, ok := l.ipRequest(ip)
+	if !ok {
+		return Decision{Allowed: true}, 0
+	}
+	return l.check(ctx, req)
+}
+
+// check evalúa la solicitud en el almacén.
+func (l *Limiter) check(ctx context.Context, req Request) (Decision, time.Duration) {
+	decision, err := l.store.Allow(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error en el límite de solicitudes, se permite la solicitud", "error", err)
+		return Decision{Allowed: true}, 0
+	}
+	if decision.Allowed {
+		return decision, 0
+	}
+	return decision, retryAfter(req, decision)
+}
+
+// groupRequest crea la solicitud de un cliente en un grupo. Un grupo sin límite
+// propio solo aplica la cuota diaria.
+func (l *Limiter) groupRequest(group, client string) Request {
+	policy, ok := l.policies[group]
+	if !ok || policy.PerMinute <= 0 || policy.Burst <= 0 {
+		policy = Policy{PerMinute: math.MaxInt32, Burst: math.MaxInt32}
+	}
+	return Request{
+		BucketKey:  group + ":" + client,
+		Policy:     policy,
+		QuotaKey:   client,
+		QuotaLimit: l.dailyQuota,
+		Now:        l.now(),
+	}
+}
+
+// ipPolicy devuelve la política de GroupIP, si está configurada.
+func (l *Limiter) ipPolicy() (Policy, bool) {
+	policy, ok := l.policies[GroupIP]
+	return policy, ok && policy.PerMinute > 0 && policy.Burst > 0
+}
+
+// ipRequest crea la solicitud de una IP en GroupIP, sin cuota diaria.
+func (l *Limiter) ipRequest(ip string) (Request, bool) {
+	policy, ok := l.ipPolicy()
+	if !ok {
+		return Request{}, false
+	}
+	return Request{
+		BucketKey: GroupIP + ":" + ip,
+		Policy:    policy,
+		Now:       l.now(),
+	}, true
+}
+
+// RejectionMessage describe el motivo por el que se rechazó una solicitud.
+func RejectionMessage(decision Decision) string {
+	if decision.QuotaExceeded {
+		return "Cuota diaria de solicitudes agotada"
+	}
+	return "Demasiadas solicitudes, intente de nuevo más tarde"
+}
+
+// retryAfter devuelve cuánto falta para que una solicitud rechazada pueda
+// repetirse: el siguiente token o, si se agotó la cuota, el día siguiente.
+func retryAfter(req Request, decision Decision) time.Duration {
+	if decision.QuotaExceeded {
+		return untilTomorrow(req.Now)
+	}
+	return untilNextToken(req.Policy, decision.Tokens)
+}
+
+// untilNextToken devuelve cuánto falta para que el bucket tenga un token.
+func untilNextToken(policy Policy, tokens float64) time.Duration {
+	return time.Duration((1 - tokens) / policy.rate() * float64(time.Second))
+}
+
+// untilTomorrow devuelve cuánto falta para que se reinicie la cuota diaria.
+func untilTomorrow(now time.Time) time.Duration {
+	return quotaDay(now).Add(24 * time.Hour).Sub(now)
+}
+
+// Seconds redondea una duración hacia arriba a segundos enteros no negativos,
+// como se informa en Retry-After.
+func Seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// refill calcula los tokens del bucket en now a partir de su último estado.
+func refill(tokens float64, updatedAt, now time.Time, policy Policy) float64 {
+	elapsed := now.Sub(updatedAt).Seconds()
+	if elapsed > 0 {
+		tokens += elapsed * policy.rate()
+	}
+	return math.Min(tokens, float64(policy.Burst))
+}
+
+// decide evalúa una solicitud sobre el estado actual del bucket y la cuota, y
+// devuelve la decisión junto con los tokens que deben guardarse.
+func decide(req Request, tokens float64, quotaUsed int) Decision {
+	decision := Decision{Tokens: tokens, QuotaUsed: quotaUsed}
+
+	if tokens < 1 {
+		return decision
+	}
+	if req.QuotaLimit > 0 && quotaUsed >= req.QuotaLimit {
+		decision.QuotaExceeded = true
+		return decision
+	}
+
+	decision.Allowed = true
+	decision.Tokens = tokens - 1
+	decision.QuotaUsed = quotaUsed + 1
+	return decision
+}
+
+// quotaDay devuelve el día UTC al que pertenece una solicitud.
+func quotaDay(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour)
+}
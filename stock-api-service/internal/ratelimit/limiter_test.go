@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRefill(t *testing.T) {
+	policy := Policy{PerMinute: 60, Burst: 10}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{"sin tiempo transcurrido", 2, 0, 2},
+		{"un token por segundo", 2, 3 * time.Second, 5},
+		{"fracción de token", 0, 500 * time.Millisecond, 0.5},
+		{"no supera la ráfaga", 8, time.Minute, 10},
+		{"reloj hacia atrás", 4, -time.Minute, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := refill(tt.tokens, start, start.Add(tt.elapsed), policy)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("refill() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokens    float64
+		quotaUsed int
+		quota     int
+		want      Decision
+	}{
+		{"permitida", 3, 5, 10, Decision{Allowed: true, Tokens: 2, QuotaUsed: 6}},
+		{"sin cuota", 1, 0, 0, Decision{Allowed: true, Tokens: 0, QuotaUsed: 1}},
+		{"bucket vacío", 0.5, 5, 10, Decision{Tokens: 0.5, QuotaUsed: 5}},
+		{"cuota agotada", 3, 10, 10, Decision{Tokens: 3, QuotaUsed: 10, QuotaExceeded: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decide(Request{QuotaLimit: tt.quota}, tt.tokens, tt.quotaUsed)
+			if got != tt.want {
+				t.Errorf("decide() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyWindow(t *testing.T) {
+	if got := (Policy{PerMinute: 30, Burst: 10}).window(); got != 20*time.Second {
+		t.Errorf("window() = %v, want 20s", got)
+	}
+	if got := (Policy{Burst: 10}).window(); got != 0 {
+		t.Errorf("window() sin reposición = %v, want 0", got)
+	}
+}
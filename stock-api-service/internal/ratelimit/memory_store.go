@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval es cada cuánto se eliminan los buckets llenos y las cuotas de días anteriores.
+const pruneInterval = 10 * time.Minute
+
+// bucketState es el estado guardado de un token bucket.
+type bucketState struct {
+	tokens    float64
+	updatedAt time.Time
+	policy    Policy
+}
+
+// quotaState es el uso de la cuota de un cliente en un día.
+type quotaState struct {
+	day  time.Time
+	used int
+}
+
+// MemoryStore guarda los buckets y las cuotas en memoria. Cada réplica lleva sus
+// propios contadores.
+type MemoryStore struct {
+	mu         sync.Mutex
+	buckets    map[string]*bucketState
+	quotas     map[string]*quotaState
+	lastPruned time.Time
+}
+
+// NewMemoryStore crea un almacén en memoria.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucketState),
+		quotas:  make(map[string]*quotaState),
+	}
+}
+
+// Allow evalúa la solicitud y actualiza el bucket y la cuota si se permite.
+func (s *MemoryStore) Allow(ctx context.Context, req Request) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(req.Now)
+
+	tokens := float64(req.Policy.Burst)
+	if bucket, ok := s.buckets[req.BucketKey]; ok {
+		tokens = refill(bucket.tokens, bucket.updatedAt, req.Now, req.Policy)
+	}
+
+	day := quotaDay(req.Now)
+	quotaUsed := 0
+	if quota, ok := s.quotas[req.QuotaKey]; ok && quota.day.Equal(day) {
+		quotaUsed = quota.used
+	}
+
+	decision := decide(req, tokens, quotaUsed)
+
+	s.buckets[req.BucketKey] = &bucketState{tokens: decision.Tokens, updatedAt: req.Now, policy: req.Policy}
+	if decision.Allowed && req.QuotaLimit > 0 {
+		s.quotas[req.QuotaKey] = &quotaState{day: day, used: decision.QuotaUsed}
+	}
+
+	return decision, nil
+}
+
+// prune elimina los buckets que ya se habrían llenado y las cuotas de días
+// anteriores, para que la memoria no crezca con cada cliente nuevo.
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.lastPruned) < pruneInterval {
+		return
+	}
+	s.lastPruned = now
+
+	for key, bucket := range s.buckets {
+		if refill(bucket.tokens, bucket.updatedAt, now, bucket.policy) >= float64(bucket.policy.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+
+	day := quotaDay(now)
+	for key, quota := range s.quotas {
+		if quota.day.Before(day) {
+			delete(s.quotas, key)
+		}
+	}
+}
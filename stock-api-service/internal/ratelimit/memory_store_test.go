@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testStore verifica el comportamiento común de los almacenes: la ráfaga, la
+// reposición, la cuota diaria y su reinicio a medianoche UTC.
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 23, 59, 50, 0, time.UTC)
+	policy := Policy{PerMinute: 60, Burst: 2}
+
+	allow := func(bucket string, at time.Time) Decision {
+		t.Helper()
+		decision, err := store.Allow(ctx, Request{
+			BucketKey:  bucket,
+			Policy:     policy,
+			QuotaKey:   "cliente",
+			QuotaLimit: 4,
+			Now:        at,
+		})
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		return decision
+	}
+
+	// La ráfaga permite dos solicitudes seguidas y rechaza la tercera
+	for i := 1; i <= 2; i++ {
+		if d := allow("read:cliente", now); !d.Allowed || d.QuotaUsed != i {
+			t.Fatalf("solicitud %d = %+v, want permitida con cuota %d", i, d, i)
+		}
+	}
+	if d := allow("read:cliente", now); d.Allowed || d.QuotaExceeded || d.QuotaUsed != 2 {
+		t.Fatalf("tercera solicitud = %+v, want rechazada por el bucket", d)
+	}
+
+	// Un segundo después se repone un token
+	if d := allow("read:cliente", now.Add(time.Second)); !d.Allowed || d.Tokens > 1e-9 {
+		t.Errorf("tras un segundo = %+v, want permitida sin tokens restantes", d)
+	}
+
+	// Otro bucket del mismo cliente comparte la cuota diaria
+	if d := allow("write:cliente", now.Add(time.Second)); !d.Allowed || d.QuotaUsed != 4 {
+		t.Errorf("otro grupo = %+v, want permitida con cuota 4", d)
+	}
+	if d := allow("write:cliente", now.Add(time.Second)); d.Allowed || !d.QuotaExceeded {
+		t.Errorf("cuota agotada = %+v, want rechazada por la cuota", d)
+	}
+
+	// La cuota se reinicia al día siguiente
+	if d := allow("write:cliente", now.Add(time.Minute)); !d.Allowed || d.QuotaUsed != 1 {
+		t.Errorf("día siguiente = %+v, want permitida con cuota 1", d)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestMemoryStorePrunesIdleState(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	req := Request{BucketKey: "read:cliente", Policy: Policy{PerMinute: 60, Burst: 5}, QuotaKey: "cliente", QuotaLimit: 10, Now: now}
+
+	if _, err := store.Allow(ctx, req); err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+
+	// Otra solicitud un día después elimina el bucket lleno y la cuota anterior
+	req.BucketKey, req.QuotaKey, req.Now = "read:otro", "otro", now.Add(24*time.Hour)
+	if _, err := store.Allow(ctx, req); err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if _, ok := store.buckets["read:cliente"]; ok {
+		t.Error("prune() conservó un bucket lleno")
+	}
+	if _, ok := store.quotas["cliente"]; ok {
+		t.Error("prune() conservó una cuota de un día anterior")
+	}
+}
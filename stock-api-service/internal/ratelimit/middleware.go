@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/auth"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// Limit es un middleware que aplica la política del grupo indicado y la cuota
+// diaria al cliente de la solicitud. Debe ir después de la autenticación para
+// identificar al cliente por su principal; sin principal se usa la IP.
+// Si el almacén falla, la solicitud se deja pasar.
+func (l *Limiter) Limit(group string) gin.HandlerFunc {
+	if l == nil {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		l.limit(c, l.groupRequest(group, clientKey(c)), true)
+	}
+}
+
+// LimitIP es un middleware que aplica la política de GroupIP a la IP de la
+// solicitud, sin cuota diaria. Debe ir antes de la autenticación, para que las
+// solicitudes sin credenciales o con credenciales inválidas también consuman
+// tokens y no puedan probar API keys sin límite. Sin política para GroupIP no
+// limita. Solo escribe Retry-After al rechazar, para no pisar las cabeceras
+// RateLimit-* del límite del principal.
+func (l *Limiter) LimitIP() gin.HandlerFunc {
+	if l == nil {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	if _, ok := l.ipPolicy(); !ok {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		req, _ := l.ipRequest(c.ClientIP())
+		l.limit(c, req, false)
+	}
+}
+
+// limit evalúa la solicitud y la rechaza con 429 si no está permitida. Con
+// headers escribe además las cabeceras RateLimit-*.
+func (l *Limiter) limit(c *gin.Context, req Request, headers bool) {
+	decision, err := l.store.Allow(c.Request.Context(), req)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error en el límite de solicitudes, se permite la solicitud", "error", err)
+		c.Next()
+		return
+	}
+
+	if headers {
+		setHeaders(c, req, decision)
+	}
+
+	if !decision.Allowed {
+		c.Header("Retry-After", strconv.Itoa(Seconds(retryAfter(req, decision))))
+		problem.Abort(c, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited, RejectionMessage(decision)))
+		return
+	}
+
+	c.Next()
+}
+
+// setHeaders escribe las cabeceras RateLimit-* con la restricción más cercana a
+// agotarse (el bucket o la cuota diaria).
+func setHeaders(c *gin.Context, req Request, decision Decision) {
+	limit := req.Policy.Burst
+	remaining := int(math.Floor(decision.Tokens))
+	// Tiempo hasta que el bucket vuelve a estar lleno
+	reset := time.Duration((float64(limit) - decision.Tokens) / req.Policy.rate() * float64(time.Second))
+
+	policies := []string{}
+	if req.Policy.Burst < math.MaxInt32 {
+		policies = append(policies, fmt.Sprintf("%d;w=%d", req.Policy.Burst, Seconds(req.Policy.window())))
+	} else {
+		limit = 0
+	}
+
+	if req.QuotaLimit > 0 {
+		policies = append(policies, fmt.Sprintf("%d;w=86400", req.QuotaLimit))
+
+		quotaRemaining := req.QuotaLimit - decision.QuotaUsed
+		if limit == 0 || quotaRemaining < remaining {
+			limit = req.QuotaLimit
+			remaining = quotaRemaining
+			reset = untilTomorrow(req.Now)
+		}
+	}
+
+	if limit == 0 {
+		return
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(max(remaining, 0)))
+	c.Header("RateLimit-Reset", strconv.Itoa(Seconds(reset)))
+	for _, policy := range policies {
+		c.Writer.Header().Add("RateLimit-Policy", policy)
+	}
+}
+
+// clientKey identifica al cliente por su principal o, si no está autenticado,
+// por su IP. La IP solo considera X-Forwarded-For de los proxies de confianza
+// configurados en el router.
+func clientKey(c *gin.Context) string {
+	if principal, ok := auth.PrincipalFromContext(c); ok && principal.Method != auth.MethodDisabled {
+		return principal.ID
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/api/middlewares"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// newTestEngine crea un router con el limitador y un reloj fijo. Si principal
+// no está vacío, las solicitudes se autentican con ese ID antes del límite.
+func newTestEngine(t *testing.T, limiter *Limiter, principal string, now *time.Time) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	limiter.now = func() time.Time { return *now }
+
+	engine := gin.New()
+	if err := engine.SetTrustedProxies(nil); err != nil {
+		t.Fatalf("SetTrustedProxies() error = %v", err)
+	}
+	engine.Use(middlewares.Errors(), limiter.LimitIP())
+	if principal != "" {
+		engine.Use(func(c *gin.Context) {
+			auth.SetPrincipal(c, auth.Principal{ID: principal, Role: auth.RoleReader, Method: auth.MethodAPIKey})
+		})
+	}
+	engine.GET("/", limiter.Limit(GroupRead), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return engine
+}
+
+// get hace una solicitud desde la IP indicada.
+func get(engine *gin.Engine, ip string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = ip + ":1234"
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLimitHeaders(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(NewMemoryStore(), map[string]Policy{
+		GroupRead: {PerMinute: 30, Burst: 2},
+	}, 100)
+	engine := newTestEngine(t, limiter, "key:1", &now)
+
+	rec := get(engine, "192.0.2.1", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("primera solicitud = %d, want 204", rec.Code)
+	}
+	want := map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		// Un token cada 2 s
+		"RateLimit-Reset": "2",
+	}
+	for header, value := range want {
+		if got := rec.Header().Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+	if got := rec.Header().Values("RateLimit-Policy"); len(got) != 2 || got[0] != "2;w=4" || got[1] != "100;w=86400" {
+		t.Errorf("RateLimit-Policy = %v, want [2;w=4 100;w=86400]", got)
+	}
+
+	get(engine, "192.0.2.1", nil)
+	rec = get(engine, "192.0.2.1", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("tercera solicitud = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want 0", got)
+	}
+
+	// El principal conserva su bucket aunque cambie de IP
+	if rec := get(engine, "192.0.2.2", nil); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("otra IP del mismo principal = %d, want 429", rec.Code)
+	}
+
+	now = now.Add(2 * time.Second)
+	if rec := get(engine, "192.0.2.1", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("tras la reposición = %d, want 204", rec.Code)
+	}
+}
+
+func TestLimitDailyQuota(t *testing.T) {
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(NewMemoryStore(), map[string]Policy{
+		GroupRead: {PerMinute: 600, Burst: 10},
+	}, 1)
+	engine := newTestEngine(t, limiter, "key:1", &now)
+
+	get(engine, "192.0.2.1", nil)
+	rec := get(engine, "192.0.2.1", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("cuota agotada = %d, want 429", rec.Code)
+	}
+	// Hasta la medianoche UTC
+	if got := rec.Header().Get("Retry-After"); got != "3600" {
+		t.Errorf("Retry-After = %q, want 3600", got)
+	}
+}
+
+func TestLimitIP(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(NewMemoryStore(), map[string]Policy{
+		GroupIP: {PerMinute: 60, Burst: 2},
+	}, 0)
+	// Sin principal, como una solicitud que la autenticación rechazaría
+	engine := newTestEngine(t, limiter, "", &now)
+
+	for i := 1; i <= 2; i++ {
+		if rec := get(engine, "192.0.2.1", http.Header{"X-Forwarded-For": {"198.51.100." + strconv.Itoa(i)}}); rec.Code != http.StatusNoContent {
+			t.Fatalf("solicitud %d = %d, want 204", i, rec.Code)
+		}
+	}
+
+	// X-Forwarded-For de un proxy que no es de confianza no cambia el bucket
+	rec := get(engine, "192.0.2.1", http.Header{"X-Forwarded-For": {"198.51.100.9"}})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("tercera solicitud = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+
+	if rec := get(engine, "192.0.2.2", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("otra IP = %d, want 204", rec.Code)
+	}
+}
+
+func TestLimitIPWithoutPolicy(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(NewMemoryStore(), map[string]Policy{}, 0)
+	engine := newTestEngine(t, limiter, "", &now)
+
+	for i := 0; i < 5; i++ {
+		if rec := get(engine, "192.0.2.1", nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("solicitud %d = %d, want 204", i, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// maxRetries es la cantidad de intentos ante conflictos de transacción.
+const maxRetries = 3
+
+// SQLStore guarda los buckets y las cuotas en CockroachDB, de modo que todas las
+// réplicas comparten los mismos contadores.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore crea un almacén respaldado por la base de datos.
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{
+		db: db,
+	}
+}
+
+// InitDB crea las tablas de buckets y cuotas si no existen.
+func (s *SQLStore) InitDB(ctx context.Context) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+            key STRING PRIMARY KEY,
+            tokens FLOAT NOT NULL,
+            updated_at TIMESTAMPTZ NOT NULL
+        )`,
+		`CREATE TABLE IF NOT EXISTS rate_limit_quotas (
+            key STRING NOT NULL,
+            day DATE NOT NULL,
+            used INT NOT NULL,
+            PRIMARY KEY (key, day)
+        )`,
+	}
+
+	for _, query := range queries {
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("error al crear las tablas de límites de solicitudes: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Allow evalúa la solicitud en una transacción, reintentando si CockroachDB
+// la aborta por un conflicto con otra réplica.
+func (s *SQLStore) Allow(ctx context.Context, req Request) (Decision, error) {
+	var err error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		var decision Decision
+		decision, err = s.allow(ctx, req)
+		if err == nil {
+			return decision, nil
+		}
+
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) || pqErr.Code != "40001" {
+			break
+		}
+	}
+	return Decision{}, fmt.Errorf("error al evaluar el límite de solicitudes: %w", err)
+}
+
+// allow ejecuta un intento de evaluación de la solicitud.
+func (s *SQLStore) allow(ctx context.Context, req Request) (Decision, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Decision{}, err
+	}
+	defer tx.Rollback()
+
+	tokens := float64(req.Policy.Burst)
+	var stored float64
+	var updatedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+        SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE
+    `, req.BucketKey).Scan(&stored, &updatedAt)
+	switch {
+	case err == nil:
+		tokens = refill(stored, updatedAt, req.Now, req.Policy)
+	case err != sql.ErrNoRows:
+		return Decision{}, err
+	}
+
+	day := quotaDay(req.Now)
+	quotaUsed := 0
+	if req.QuotaLimit > 0 {
+		err = tx.QueryRowContext(ctx, `
+            SELECT used FROM rate_limit_quotas WHERE key = $1 AND day = $2 FOR UPDATE
+        `, req.QuotaKey, day).Scan(&quotaUsed)
+		if err != nil && err != sql.ErrNoRows {
+			return Decision{}, err
+		}
+	}
+
+	decision := decide(req, tokens, quotaUsed)
+
+	if _, err := tx.ExecContext(ctx, `
+        UPSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES ($1, $2, $3)
+    `, req.BucketKey, decision.Tokens, req.Now); err != nil {
+		return Decision{}, err
+	}
+
+	if decision.Allowed && req.QuotaLimit > 0 {
+		if _, err := tx.ExecContext(ctx, `
+            UPSERT INTO rate_limit_quotas (key, day, used) VALUES ($1, $2, $3)
+        `, req.QuotaKey, day, decision.QuotaUsed); err != nil {
+			return Decision{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Decision{}, err
+	}
+
+	return decision, nil
+}
+
+// Prune elimina las cuotas de días anteriores y los buckets sin uso durante el
+// tiempo indicado.
+func (s *SQLStore) Prune(ctx context.Context, idle time.Duration) error {
+	now := time.Now()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_quotas WHERE day < $1`, quotaDay(now)); err != nil {
+		return fmt.Errorf("error al eliminar cuotas antiguas: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1`, now.Add(-idle)); err != nil {
+		return fmt.Errorf("error al eliminar buckets sin uso: %w", err)
+	}
+	return nil
+}
+
+// RunPruner ejecuta Prune periódicamente hasta que el contexto se cancela.
+func (s *SQLStore) RunPruner(ctx context.Context, interval, idle time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Prune(ctx, idle); err != nil {
+				slog.ErrorContext(ctx, "Error al limpiar los límites de solicitudes", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// openCockroach crea una base de datos vacía en el clúster de TEST_DATABASE_URL
+// y la elimina al terminar la prueba. Sin TEST_DATABASE_URL la prueba se omite.
+func openCockroach(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL no está definida")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	name := fmt.Sprintf("ratelimit_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("CREATE DATABASE error = %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP DATABASE " + name + " CASCADE") })
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL inválida: %v", err)
+	}
+	u.Path = "/" + name
+
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLStore(t *testing.T) {
+	store := NewSQLStore(openCockroach(t))
+	if err := store.InitDB(context.Background()); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	testStore(t, store)
+}
+
+func TestSQLStorePrune(t *testing.T) {
+	db := openCockroach(t)
+	store := NewSQLStore(db)
+	ctx := context.Background()
+	if err := store.InitDB(ctx); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+
+	now := time.Now()
+	for _, req := range []Request{
+		{BucketKey: "read:antiguo", QuotaKey: "antiguo", Now: now.Add(-48 * time.Hour)},
+		{BucketKey: "read:reciente", QuotaKey: "reciente", Now: now},
+	} {
+		req.Policy, req.QuotaLimit = Policy{PerMinute: 60, Burst: 5}, 10
+		if _, err := store.Allow(ctx, req); err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+	}
+
+	if err := store.Prune(ctx, 24*time.Hour); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+
+	var buckets, quotas int
+	if err := db.QueryRow(`SELECT count(*) FROM rate_limit_buckets`).Scan(&buckets); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`SELECT count(*) FROM rate_limit_quotas`).Scan(&quotas); err != nil {
+		t.Fatal(err)
+	}
+	if buckets != 1 || quotas != 1 {
+		t.Errorf("tras Prune() quedan %d buckets y %d cuotas, want 1 y 1", buckets, quotas)
+	}
+}
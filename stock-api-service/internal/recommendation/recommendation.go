@@ -0,0 +1,201 @@
+// Paquete recommendation calcula las recomendaciones de inversión con la caché
+// que comparten la API REST, GraphQL y gRPC.
+package recommendation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/algorithm"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/cache"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/metrics"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+)
+
+// Cantidad de recomendaciones.
+const (
+	DefaultLimit = 10
+	MaxLimit     = 50
+)
+
+// Usos de una lista de seguimiento en las recomendaciones.
+const (
+	// Solo se recomiendan los tickers de la lista
+	WatchlistModeRestrict = "restrict"
+	// Se multiplica la puntuación de los tickers de la lista
+	WatchlistModeBoost = "boost"
+)
+
+// Source lee los stocks con los que se calculan las recomendaciones.
+type Source interface {
+	GetStocksByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Stock, error)
+	GetStockEventsByDateRangeAsOf(ctx context.Context, startDate, endDate, asOf time.Time) ([]models.Stock, error)
+}
+
+// Request son los parámetros de una solicitud de recomendaciones, ya validados
+// por cada transporte.
+type Request struct {
+	// Cantidad de recomendaciones; 0 usa DefaultLimit
+	Limit int
+	// Máximo de recomendaciones de un mismo sector; nil usa el del recomendador
+	MaxPerSector *int
+	// Instante de referencia; solo se considera lo conocido hasta ese momento
+	AsOf *time.Time
+	// Lee el último estado confirmado sin réplicas seguidoras, omite la caché
+	// y la actualiza con el resultado
+	Strong bool
+	// Lista de seguimiento del principal, opcional
+	Watchlist *models.Watchlist
+	// Uso de la lista: WatchlistModeRestrict (predeterminado) o WatchlistModeBoost
+	WatchlistMode string
+}
+
+// Service calcula las recomendaciones de los últimos 30 días y las guarda en
+// la caché por combinación de parámetros efectivos, de modo que una solicitud
+// REST, GraphQL o gRPC con los mismos parámetros usa la misma entrada.
+type Service struct {
+	source         Source
+	recommender    *algorithm.StockRecommender
+	cache          *cache.RecommendationCache
+	watchlistBoost float64
+}
+
+// NewService crea el servicio. watchlistBoost es el factor que multiplica la
+// puntuación de los tickers de una lista de seguimiento con WatchlistModeBoost.
+func NewService(source Source, recommender *algorithm.StockRecommender, cache *cache.RecommendationCache, watchlistBoost float64) *Service {
+	return &Service{
+		source:         source,
+		recommender:    recommender,
+		cache:          cache,
+		watchlistBoost: watchlistBoost,
+	}
+}
+
+// Recommend devuelve las recomendaciones de la solicitud e indica si se
+// obtuvieron de la caché.
+func (s *Service) Recommend(ctx context.Context, req Request) (models.RecommendationResponse, bool, error) {
+	if req.Limit == 0 {
+		req.Limit = DefaultLimit
+	}
+	if req.Watchlist != nil && req.WatchlistMode == "" {
+		req.WatchlistMode = WatchlistModeRestrict
+	}
+	if req.Strong {
+		ctx = repository.WithStrongConsistency(ctx)
+	}
+
+	recommender := s.recommender
+	if req.MaxPerSector != nil {
+		recommender = recommender.WithMaxPerSector(*req.MaxPerSector)
+	}
+	if req.WatchlistMode == WatchlistModeBoost {
+		recommender = recommender.WithBoost(req.Watchlist.Tickers, s.watchlistBoost)
+	}
+
+	key := s.cacheKey(recommender, req)
+	// Se obtiene antes de leer los datos, para no guardar una respuesta
+	// calculada antes de una sincronización que invalidó la caché
+	generation := s.cache.Generation()
+	if !req.Strong {
+		if cached, ok := s.cache.Get(key); ok {
+			return withWatchlist(cached, req.Watchlist), true, nil
+		}
+	}
+
+	// Con as_of se reconstruye lo conocido en ese instante a partir del historial
+	if req.AsOf != nil {
+		recommender = recommender.WithClock(algorithm.FixedClock(*req.AsOf))
+	}
+	endDate := recommender.Now()
+	startDate := endDate.AddDate(0, -1, 0)
+
+	var stocks []models.Stock
+	var err error
+	if req.AsOf != nil {
+		stocks, err = s.source.GetStockEventsByDateRangeAsOf(ctx, startDate, endDate, *req.AsOf)
+	} else {
+		stocks, err = s.source.GetStocksByDateRange(ctx, startDate, endDate)
+	}
+	if err != nil {
+		return models.RecommendationResponse{}, false, fmt.Errorf("error al obtener stocks para recomendaciones: %w", err)
+	}
+
+	if req.WatchlistMode == WatchlistModeRestrict {
+		stocks = restrictToTickers(stocks, req.Watchlist.Tickers)
+	}
+
+	computeStart := time.Now()
+	results := recommender.GenerateRecommendations(stocks, req.Limit)
+	metrics.ObserveRecommendation(recommender.Strategy().Name, time.Since(computeStart))
+
+	response := models.RecommendationResponse{
+		Recommendations: results,
+		GeneratedAt:     time.Now(),
+		AsOf:            endDate,
+		Count:           len(results),
+		Message:         responseMessage(len(results)),
+		WatchlistMode:   req.WatchlistMode,
+	}
+	s.cache.Set(key, generation, response)
+
+	return withWatchlist(response, req.Watchlist), false, nil
+}
+
+// cacheKey construye la clave de caché a partir de los parámetros efectivos. La
+// lista de seguimiento se identifica por sus tickers, de modo que las listas con
+// los mismos tickers comparten la entrada y un cambio en la lista no reutiliza
+// la anterior.
+func (s *Service) cacheKey(recommender *algorithm.StockRecommender, req Request) string {
+	key := fmt.Sprintf("strategy=%s&limit=%d&max_per_sector=%d",
+		recommender.Strategy().Name, req.Limit, recommender.MaxPerSector())
+	if req.AsOf != nil {
+		key += "&as_of=" + req.AsOf.UTC().Format(time.RFC3339Nano)
+	}
+	if req.Watchlist != nil {
+		sum := sha256.Sum256([]byte(strings.Join(req.Watchlist.Tickers, ",")))
+		key += fmt.Sprintf("&watchlist=%s&watchlist_mode=%s&boost=%g",
+			hex.EncodeToString(sum[:8]), req.WatchlistMode, s.watchlistBoost)
+	}
+	return key
+}
+
+// withWatchlist identifica la lista de la solicitud en la respuesta, que no
+// forma parte de la entrada de la caché.
+func withWatchlist(response models.RecommendationResponse, watchlist *models.Watchlist) models.RecommendationResponse {
+	if watchlist != nil {
+		response.WatchlistID = watchlist.ID
+	}
+	return response
+}
+
+// restrictToTickers conserva solo los stocks de los tickers indicados.
+func restrictToTickers(stocks []models.Stock, tickers []string) []models.Stock {
+	allowed := make(map[string]bool, len(tickers))
+	for _, ticker := range tickers {
+		allowed[ticker] = true
+	}
+
+	var restricted []models.Stock
+	for _, stock := range stocks {
+		if allowed[stock.Ticker] {
+			restricted = append(restricted, stock)
+		}
+	}
+	return restricted
+}
+
+// responseMessage genera un mensaje para la respuesta.
+func responseMessage(count int) string {
+	if count == 0 {
+		return "No se encontraron recomendaciones para hoy. Intente más tarde cuando haya nuevas actualizaciones."
+	} else if count == 1 {
+		return "Se encontró 1 recomendación de inversión para hoy."
+	} else {
+		return fmt.Sprintf("Se encontraron %d recomendaciones de inversión para hoy.", count)
+	}
+}
@@ -0,0 +1,166 @@
+package recommendation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/algorithm"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/cache"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+)
+
+// countingSource cuenta las lecturas y cuántas pidieron consistencia fuerte.
+// onRead, si no es nil, se ejecuta después de cada lectura.
+type countingSource struct {
+	*repository.MemoryStockRepository
+	reads       int
+	strongReads int
+	onRead      func()
+}
+
+func (s *countingSource) GetStocksByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Stock, error) {
+	s.reads++
+	if repository.StrongConsistency(ctx) {
+		s.strongReads++
+	}
+	stocks, err := s.MemoryStockRepository.GetStocksByDateRange(ctx, startDate, endDate)
+	if s.onRead != nil {
+		s.onRead()
+	}
+	return stocks, err
+}
+
+// newTestService crea un servicio con mejoras recientes de NVDA, AAPL y MSFT.
+func newTestService(t *testing.T) (*Service, *countingSource) {
+	t.Helper()
+	repo := repository.NewMemoryStockRepository()
+	yesterday := time.Now().Add(-24 * time.Hour)
+	for i, ticker := range []string{"NVDA", "AAPL", "MSFT"} {
+		repo.SaveStocks([]models.Stock{{
+			Ticker:     ticker,
+			Company:    ticker + " Inc.",
+			Action:     "upgraded by",
+			Brokerage:  "Barclays",
+			RatingFrom: "Hold",
+			RatingTo:   "Buy",
+			TargetFrom: "$100.00",
+			TargetTo:   "$130.00",
+			Time:       yesterday.Add(time.Duration(i) * time.Minute),
+		}})
+	}
+
+	source := &countingSource{MemoryStockRepository: repo}
+	service := NewService(source, algorithm.NewStockRecommender(), cache.NewRecommendationCache(time.Hour, 100), 1.5)
+	return service, source
+}
+
+func TestRecommendDropsResultsComputedBeforeInvalidation(t *testing.T) {
+	service, source := newTestService(t)
+	ctx := context.Background()
+
+	// Una sincronización invalida la caché mientras se calcula la respuesta
+	source.onRead = service.cache.Invalidate
+	if _, _, err := service.Recommend(ctx, Request{}); err != nil {
+		t.Fatalf("Recommend() error = %v", err)
+	}
+	source.onRead = nil
+
+	if _, hit, err := service.Recommend(ctx, Request{}); err != nil || hit {
+		t.Errorf("Recommend() tras invalidar = hit %v, %v; want miss", hit, err)
+	}
+	if _, hit, err := service.Recommend(ctx, Request{}); err != nil || !hit {
+		t.Errorf("Recommend() repetido = hit %v, %v; want hit", hit, err)
+	}
+}
+
+// tickers devuelve los tickers recomendados.
+func tickers(response models.RecommendationResponse) []string {
+	var out []string
+	for _, result := range response.Recommendations {
+		out = append(out, result.Stock.Ticker)
+	}
+	return out
+}
+
+func TestRecommendSharesCacheAcrossEquivalentRequests(t *testing.T) {
+	service, source := newTestService(t)
+	ctx := context.Background()
+
+	first, hit, err := service.Recommend(ctx, Request{})
+	if err != nil || hit {
+		t.Fatalf("Recommend() = hit %v, %v; want miss", hit, err)
+	}
+	if first.Count == 0 {
+		t.Fatal("Recommend() sin recomendaciones")
+	}
+
+	// El límite predeterminado explícito usa la misma entrada
+	if _, hit, err := service.Recommend(ctx, Request{Limit: DefaultLimit}); err != nil || !hit {
+		t.Errorf("Recommend(limit=%d) = hit %v, %v; want hit", DefaultLimit, hit, err)
+	}
+	if source.reads != 1 {
+		t.Errorf("lecturas = %d, want 1", source.reads)
+	}
+
+	// Otros parámetros efectivos usan otra entrada
+	one := 1
+	if _, hit, err := service.Recommend(ctx, Request{MaxPerSector: &one}); err != nil || hit {
+		t.Errorf("Recommend(max_per_sector=1) = hit %v, %v; want miss", hit, err)
+	}
+	if _, hit, err := service.Recommend(ctx, Request{Limit: 2}); err != nil || hit {
+		t.Errorf("Recommend(limit=2) = hit %v, %v; want miss", hit, err)
+	}
+}
+
+func TestRecommendStrongBypassesCache(t *testing.T) {
+	service, source := newTestService(t)
+	ctx := context.Background()
+
+	if _, _, err := service.Recommend(ctx, Request{}); err != nil {
+		t.Fatalf("Recommend() error = %v", err)
+	}
+	if _, hit, err := service.Recommend(ctx, Request{Strong: true}); err != nil || hit {
+		t.Fatalf("Recommend(strong) = hit %v, %v; want miss", hit, err)
+	}
+	if source.reads != 2 || source.strongReads != 1 {
+		t.Errorf("lecturas = %d (%d fuertes), want 2 (1 fuerte)", source.reads, source.strongReads)
+	}
+
+	// La lectura fuerte actualiza la entrada que usan las demás solicitudes
+	if _, hit, err := service.Recommend(ctx, Request{}); err != nil || !hit {
+		t.Errorf("Recommend() tras strong = hit %v, %v; want hit", hit, err)
+	}
+}
+
+func TestRecommendWatchlist(t *testing.T) {
+	service, _ := newTestService(t)
+	ctx := context.Background()
+	watchlist := &models.Watchlist{ID: 7, Tickers: []string{"AAPL"}}
+
+	restricted, _, err := service.Recommend(ctx, Request{Watchlist: watchlist})
+	if err != nil {
+		t.Fatalf("Recommend(watchlist) error = %v", err)
+	}
+	if got := tickers(restricted); len(got) != 1 || got[0] != "AAPL" {
+		t.Errorf("tickers = %v, want [AAPL]", got)
+	}
+	if restricted.WatchlistID != 7 || restricted.WatchlistMode != WatchlistModeRestrict {
+		t.Errorf("lista = %d %q, want 7 %q", restricted.WatchlistID, restricted.WatchlistMode, WatchlistModeRestrict)
+	}
+
+	// Otra lista con los mismos tickers comparte la entrada con su propio identificador
+	cached, hit, err := service.Recommend(ctx, Request{Watchlist: &models.Watchlist{ID: 8, Tickers: []string{"AAPL"}}})
+	if err != nil || !hit || cached.WatchlistID != 8 {
+		t.Errorf("Recommend(otra lista) = hit %v, lista %d, %v; want hit y lista 8", hit, cached.WatchlistID, err)
+	}
+
+	boosted, hit, err := service.Recommend(ctx, Request{Watchlist: watchlist, WatchlistMode: WatchlistModeBoost})
+	if err != nil || hit {
+		t.Fatalf("Recommend(boost) = hit %v, %v; want miss", hit, err)
+	}
+	if got := tickers(boosted); len(got) != 3 || got[0] != "AAPL" {
+		t.Errorf("tickers con boost = %v, want AAPL primero de 3", got)
+	}
+}
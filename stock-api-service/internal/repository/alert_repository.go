@@ -0,0 +1,480 @@
+// Paquete repository proporciona acceso a la capa de persistencia de datos.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// MaxAlertRules es la cantidad máxima de reglas de alerta de un propietario.
+const MaxAlertRules = 50
+
+// alertCursorName identifica la posición del motor de alertas en alert_cursors.
+const alertCursorName = "rules"
+
+var (
+	// ErrAlertRuleNotFound indica que la regla no existe o pertenece a otro principal.
+	ErrAlertRuleNotFound error = notFound("regla de alerta no encontrada")
+	// ErrAlertRuleExists indica que el principal ya tiene una regla con ese nombre.
+	ErrAlertRuleExists error = conflict("ya existe una regla de alerta con ese nombre")
+	// ErrAlertRuleLimit indica que el principal alcanzó MaxAlertRules.
+	ErrAlertRuleLimit error = conflict("se alcanzó la cantidad máxima de reglas de alerta")
+	// ErrAlertNotFound indica que la alerta no existe o pertenece a otro principal.
+	ErrAlertNotFound error = notFound("alerta no encontrada")
+)
+
+// AlertRepository maneja la persistencia de las reglas de alerta, de las alertas
+// disparadas y de la posición del motor que las evalúa. Las operaciones de
+// reglas y alertas se limitan al propietario indicado.
+type AlertRepository struct {
+	db *sql.DB
+}
+
+// NewAlertRepository crea una nueva instancia del repositorio de alertas.
+func NewAlertRepository(db *sql.DB) *AlertRepository {
+	return &AlertRepository{
+		db: db,
+	}
+}
+
+// InitDB crea las tablas de alertas si no existen.
+func (r *AlertRepository) InitDB(ctx context.Context) error {
+	queries := []string{
+		`
+    CREATE TABLE IF NOT EXISTS alert_rules (
+        id INT PRIMARY KEY DEFAULT unique_rowid(),
+        owner STRING NOT NULL,
+        name STRING NOT NULL,
+        enabled BOOL NOT NULL DEFAULT true,
+        definition JSONB NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT current_timestamp(),
+        updated_at TIMESTAMP NOT NULL DEFAULT current_timestamp(),
+        UNIQUE INDEX alert_rules_owner_name_key (owner, name)
+    )
+    `,
+		`
+    CREATE TABLE IF NOT EXISTS alerts (
+        id INT PRIMARY KEY DEFAULT unique_rowid(),
+        rule_id INT NOT NULL REFERENCES alert_rules (id) ON DELETE CASCADE,
+        owner STRING NOT NULL,
+        event_id INT NOT NULL,
+        stock JSONB NOT NULL,
+        "values" JSONB NOT NULL,
+        triggered_at TIMESTAMP NOT NULL DEFAULT current_timestamp(),
+        acknowledged_at TIMESTAMP,
+        UNIQUE INDEX alerts_rule_event_key (rule_id, event_id),
+        INDEX alerts_owner_id_idx (owner, id DESC)
+    )
+    `,
+		`
+    CREATE TABLE IF NOT EXISTS alert_cursors (
+        name STRING PRIMARY KEY,
+        last_event_id INT NOT NULL,
+        updated_at TIMESTAMP NOT NULL DEFAULT current_timestamp()
+    )
+    `,
+	}
+
+	for _, query := range queries {
+		if _, err := r.db.ExecContext(ctx, query); err != nil {
+			return logging.Errorf(ctx, "error al crear las tablas de alertas: %w", classify(err))
+		}
+	}
+
+	return nil
+}
+
+// alertDefinition es la parte de la regla que se guarda como JSON.
+type alertDefinition struct {
+	Conditions []models.AlertCondition `json:"conditions"`
+	Window     *models.AlertWindow     `json:"window,omitempty"`
+}
+
+// alertRuleColumns son las columnas necesarias para construir un models.AlertRule.
+const alertRuleColumns = `id, owner, name, enabled, definition, created_at, updated_at`
+
+// CreateAlertRule guarda una regla y completa su identificador y fechas.
+func (r *AlertRepository) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	definition, err := json.Marshal(alertDefinition{Conditions: rule.Conditions, Window: rule.Window})
+	if err != nil {
+		return logging.Errorf(ctx, "error al serializar la regla de alerta: %w", classify(err))
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return logging.Errorf(ctx, "error al iniciar la transacción: %w", classify(err))
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM alert_rules WHERE owner = $1
+	`, rule.Owner).Scan(&count); err != nil {
+		return logging.Errorf(ctx, "error al contar las reglas de alerta: %w", classify(err))
+	}
+	if count >= MaxAlertRules {
+		return ErrAlertRuleLimit
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO alert_rules (owner, name, enabled, definition)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, rule.Owner, rule.Name, rule.Enabled, definition).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrAlertRuleExists
+	}
+	if err != nil {
+		return logging.Errorf(ctx, "error al guardar la regla de alerta: %w", classify(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return logging.Errorf(ctx, "error al confirmar la transacción: %w", classify(err))
+	}
+	return nil
+}
+
+// ListAlertRules devuelve las reglas de un propietario ordenadas por nombre.
+func (r *AlertRepository) ListAlertRules(ctx context.Context, owner string) ([]models.AlertRule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+alertRuleColumns+`
+		FROM alert_rules
+		WHERE owner = $1
+		ORDER BY name ASC
+	`, owner)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar reglas de alerta: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanAlertRules(ctx, rows)
+}
+
+// ListEnabledAlertRules devuelve las reglas activas de todos los propietarios.
+func (r *AlertRepository) ListEnabledAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+alertRuleColumns+`
+		FROM alert_rules
+		WHERE enabled
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar reglas de alerta activas: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanAlertRules(ctx, rows)
+}
+
+// GetAlertRule obtiene una regla de un propietario.
+func (r *AlertRepository) GetAlertRule(ctx context.Context, owner string, id int64) (models.AlertRule, error) {
+	rule, err := scanAlertRule(r.db.QueryRowContext(ctx, `
+		SELECT `+alertRuleColumns+`
+		FROM alert_rules
+		WHERE owner = $1 AND id = $2
+	`, owner, id))
+	if err == sql.ErrNoRows {
+		return rule, ErrAlertRuleNotFound
+	}
+	if err != nil {
+		return rule, logging.Errorf(ctx, "error al obtener la regla de alerta: %w", classify(err))
+	}
+	return rule, nil
+}
+
+// UpdateAlertRule reemplaza el nombre, el estado y la definición de una regla.
+func (r *AlertRepository) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	definition, err := json.Marshal(alertDefinition{Conditions: rule.Conditions, Window: rule.Window})
+	if err != nil {
+		return logging.Errorf(ctx, "error al serializar la regla de alerta: %w", classify(err))
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		UPDATE alert_rules
+		SET name = $3, enabled = $4, definition = $5, updated_at = current_timestamp()
+		WHERE owner = $1 AND id = $2
+		RETURNING created_at, updated_at
+	`, rule.Owner, rule.ID, rule.Name, rule.Enabled, definition).Scan(&rule.CreatedAt, &rule.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrAlertRuleNotFound
+	}
+	if isUniqueViolation(err) {
+		return ErrAlertRuleExists
+	}
+	if err != nil {
+		return logging.Errorf(ctx, "error al actualizar la regla de alerta: %w", classify(err))
+	}
+	return nil
+}
+
+// DeleteAlertRule elimina una regla junto con sus alertas.
+func (r *AlertRepository) DeleteAlertRule(ctx context.Context, owner string, id int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM alert_rules WHERE owner = $1 AND id = $2
+	`, owner, id)
+	if err != nil {
+		return logging.Errorf(ctx, "error al eliminar la regla de alerta: %w", classify(err))
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return logging.Errorf(ctx, "error al eliminar la regla de alerta: %w", classify(err))
+	}
+	if affected == 0 {
+		return ErrAlertRuleNotFound
+	}
+	return nil
+}
+
+// SaveAlerts guarda alertas disparadas y devuelve cuántas eran nuevas. Una
+// alerta de la misma regla y el mismo evento se guarda una sola vez, de modo que
+// volver a evaluar un evento no la duplica.
+func (r *AlertRepository) SaveAlerts(ctx context.Context, alerts []models.Alert) (int, error) {
+	if len(alerts) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al iniciar la transacción: %w", classify(err))
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO alerts (rule_id, owner, event_id, stock, "values")
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (rule_id, event_id) DO NOTHING
+	`)
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al preparar la consulta de alertas: %w", classify(err))
+	}
+	defer stmt.Close()
+
+	saved := 0
+	for _, alert := range alerts {
+		stock, err := json.Marshal(alert.Stock)
+		if err != nil {
+			return 0, logging.Errorf(ctx, "error al serializar la alerta: %w", classify(err))
+		}
+		values, err := json.Marshal(alert.Values)
+		if err != nil {
+			return 0, logging.Errorf(ctx, "error al serializar la alerta: %w", classify(err))
+		}
+
+		result, err := stmt.ExecContext(ctx, alert.RuleID, alert.Owner, alert.EventID, stock, values)
+		if err != nil {
+			return 0, logging.Errorf(ctx, "error al guardar la alerta: %w", classify(err))
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, logging.Errorf(ctx, "error al guardar la alerta: %w", classify(err))
+		}
+		saved += int(affected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, logging.Errorf(ctx, "error al confirmar la transacción: %w", classify(err))
+	}
+	return saved, nil
+}
+
+// alertQuery selecciona las alertas con el nombre de su regla; se completa con la condición WHERE.
+const alertQuery = `
+		SELECT a.id, a.rule_id, r.name, a.owner, a.event_id, a.stock, a."values",
+			a.triggered_at, a.acknowledged_at
+		FROM alerts a
+		JOIN alert_rules r ON r.id = a.rule_id
+	`
+
+// ListAlerts devuelve las alertas de un propietario que cumplen el filtro, de la
+// más reciente a la más antigua.
+func (r *AlertRepository) ListAlerts(ctx context.Context, owner string, filter models.AlertFilter) ([]models.Alert, error) {
+	conditions := []string{"a.owner = $1"}
+	args := []interface{}{owner}
+
+	if filter.RuleID != 0 {
+		args = append(args, filter.RuleID)
+		conditions = append(conditions, fmt.Sprintf("a.rule_id = $%d", len(args)))
+	}
+	if filter.Acknowledged != nil {
+		if *filter.Acknowledged {
+			conditions = append(conditions, "a.acknowledged_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "a.acknowledged_at IS NULL")
+		}
+	}
+	if filter.BeforeID != 0 {
+		args = append(args, filter.BeforeID)
+		conditions = append(conditions, fmt.Sprintf("a.id < $%d", len(args)))
+	}
+	args = append(args, filter.Limit)
+
+	query := alertQuery + `
+		WHERE ` + strings.Join(conditions, " AND ") + fmt.Sprintf(`
+		ORDER BY a.id DESC
+		LIMIT $%d
+	`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar alertas: %w", classify(err))
+	}
+	defer rows.Close()
+
+	alerts := []models.Alert{}
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear alerta: %w", classify(err))
+		}
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logging.Errorf(ctx, "error al iterar alertas: %w", classify(err))
+	}
+
+	return alerts, nil
+}
+
+// AcknowledgeAlert marca una alerta como reconocida y la devuelve. Reconocer
+// una alerta ya reconocida conserva la fecha original.
+func (r *AlertRepository) AcknowledgeAlert(ctx context.Context, owner string, id int64) (models.Alert, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE alerts SET acknowledged_at = COALESCE(acknowledged_at, current_timestamp())
+		WHERE owner = $1 AND id = $2
+	`, owner, id)
+	if err != nil {
+		return models.Alert{}, logging.Errorf(ctx, "error al reconocer la alerta: %w", classify(err))
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return models.Alert{}, logging.Errorf(ctx, "error al reconocer la alerta: %w", classify(err))
+	}
+	if affected == 0 {
+		return models.Alert{}, ErrAlertNotFound
+	}
+
+	alert, err := scanAlert(r.db.QueryRowContext(ctx, alertQuery+`
+		WHERE a.owner = $1 AND a.id = $2
+	`, owner, id))
+	if err == sql.ErrNoRows {
+		return alert, ErrAlertNotFound
+	}
+	if err != nil {
+		return alert, logging.Errorf(ctx, "error al obtener la alerta: %w", classify(err))
+	}
+	return alert, nil
+}
+
+// GetAlertCursor obtiene el último evento evaluado por el motor de alertas. El
+// segundo valor es false si el motor todavía no guardó ninguna posición.
+func (r *AlertRepository) GetAlertCursor(ctx context.Context) (int64, bool, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT last_event_id FROM alert_cursors WHERE name = $1
+	`, alertCursorName).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, logging.Errorf(ctx, "error al consultar la posición del motor de alertas: %w", classify(err))
+	}
+	return id, true, nil
+}
+
+// SaveAlertCursor guarda el último evento evaluado por el motor de alertas.
+func (r *AlertRepository) SaveAlertCursor(ctx context.Context, eventID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPSERT INTO alert_cursors (name, last_event_id, updated_at)
+		VALUES ($1, $2, current_timestamp())
+	`, alertCursorName, eventID)
+	if err != nil {
+		return logging.Errorf(ctx, "error al guardar la posición del motor de alertas: %w", classify(err))
+	}
+	return nil
+}
+
+// scanAlertRule convierte una fila con alertRuleColumns en una regla.
+func scanAlertRule(row rowScanner) (models.AlertRule, error) {
+	var rule models.AlertRule
+	var definition []byte
+	err := row.Scan(
+		&rule.ID,
+		&rule.Owner,
+		&rule.Name,
+		&rule.Enabled,
+		&definition,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return rule, err
+	}
+
+	var def alertDefinition
+	if err := json.Unmarshal(definition, &def); err != nil {
+		return rule, fmt.Errorf("definición de la regla %d inválida: %w", rule.ID, err)
+	}
+	rule.Conditions = def.Conditions
+	rule.Window = def.Window
+	if rule.Conditions == nil {
+		rule.Conditions = []models.AlertCondition{}
+	}
+	return rule, nil
+}
+
+// scanAlertRules recorre el resultado de una consulta de reglas.
+func scanAlertRules(ctx context.Context, rows *sql.Rows) ([]models.AlertRule, error) {
+	rules := []models.AlertRule{}
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear regla de alerta: %w", classify(err))
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logging.Errorf(ctx, "error al iterar reglas de alerta: %w", classify(err))
+	}
+
+	return rules, nil
+}
+
+// scanAlert convierte una fila de alertQuery en una alerta.
+func scanAlert(row rowScanner) (models.Alert, error) {
+	var alert models.Alert
+	var stock, values []byte
+	var acknowledgedAt sql.NullTime
+	err := row.Scan(
+		&alert.ID,
+		&alert.RuleID,
+		&alert.RuleName,
+		&alert.Owner,
+		&alert.EventID,
+		&stock,
+		&values,
+		&alert.TriggeredAt,
+		&acknowledgedAt,
+	)
+	if err != nil {
+		return alert, err
+	}
+
+	if err := json.Unmarshal(stock, &alert.Stock); err != nil {
+		return alert, fmt.Errorf("stock de la alerta %d inválido: %w", alert.ID, err)
+	}
+	if err := json.Unmarshal(values, &alert.Values); err != nil {
+		return alert, fmt.Errorf("valores de la alerta %d inválidos: %w", alert.ID, err)
+	}
+	if acknowledgedAt.Valid {
+		alert.AcknowledgedAt = &acknowledgedAt.Time
+	}
+	return alert, nil
+}
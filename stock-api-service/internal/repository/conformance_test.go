@@ -0,0 +1,842 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Estas pruebas verifican que todas las implementaciones de StockRepository se
+// comporten igual. Cada prueba carga los mismos datos en un repositorio vacío;
+// en las bases de datos se escriben con SQL, como lo haría stock-data-service.
+// CockroachDB solo se prueba si TEST_DATABASE_URL apunta a un clúster; cada
+// prueba crea y elimina su propia base de datos.
+
+// loader carga datos de prueba en un repositorio. MemoryStockRepository lo
+// implementa directamente; sqlLoader lo implementa sobre una base de datos.
+type loader interface {
+	SaveStocksAt(stocks []models.Stock, ingestedAt time.Time) []models.StockEvent
+	SaveCompanies(companies []models.Company)
+	RecordSyncRun(status string, finishedAt time.Time)
+	SetSchemaVersion(component string, version int)
+}
+
+// backends crea un repositorio vacío por cada implementación, junto con el
+// loader que escribe en él.
+var backends = []struct {
+	name string
+	open func(t *testing.T) (StockRepository, loader)
+}{
+	{"memory", func(t *testing.T) (StockRepository, loader) {
+		repo := NewMemoryStockRepository()
+		return repo, repo
+	}},
+	{"sqlite", func(t *testing.T) (StockRepository, loader) {
+		db := openSQLite(t)
+		return NewSQLiteStockRepository(db), sqlLoader{t: t, db: db}
+	}},
+	{"cockroach", func(t *testing.T) (StockRepository, loader) {
+		db := openCockroach(t)
+		return NewCockroachStockRepository(db), sqlLoader{t: t, db: db}
+	}},
+}
+
+// sqliteSchema es el esquema que crea stock-data-service en SQLite, reducido a
+// las columnas que lee este servicio.
+var sqliteSchema = []string{
+	`CREATE TABLE stocks (
+        ticker TEXT PRIMARY KEY,
+        company TEXT NOT NULL,
+        target_from TEXT NOT NULL,
+        target_to TEXT NOT NULL,
+        action TEXT NOT NULL,
+        brokerage TEXT NOT NULL,
+        rating_from TEXT NOT NULL,
+        rating_to TEXT NOT NULL,
+        time TIMESTAMP NOT NULL
+    )`,
+	`CREATE TABLE stock_events (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        ticker TEXT NOT NULL,
+        company TEXT NOT NULL,
+        target_from TEXT NOT NULL,
+        target_to TEXT NOT NULL,
+        action TEXT NOT NULL,
+        brokerage TEXT NOT NULL,
+        rating_from TEXT NOT NULL,
+        rating_to TEXT NOT NULL,
+        time TIMESTAMP NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (ticker, brokerage, action, time)
+    )`,
+	`CREATE TABLE sync_runs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        status TEXT NOT NULL,
+        started_at TIMESTAMP NOT NULL,
+        finished_at TIMESTAMP NOT NULL
+    )`,
+	`CREATE TABLE companies (
+        ticker TEXT PRIMARY KEY,
+        company TEXT NOT NULL,
+        sector TEXT NOT NULL DEFAULT '',
+        industry TEXT NOT NULL DEFAULT '',
+        exchange TEXT NOT NULL DEFAULT '',
+        market_cap_bucket TEXT NOT NULL DEFAULT ''
+    )`,
+	`CREATE TABLE schema_versions (
+        component TEXT PRIMARY KEY,
+        version INTEGER NOT NULL
+    )`,
+}
+
+// cockroachSchema es el esquema que crea stock-data-service en CockroachDB,
+// reducido a las columnas que lee este servicio.
+var cockroachSchema = []string{
+	`CREATE TABLE stocks (
+        ticker STRING PRIMARY KEY,
+        company STRING NOT NULL,
+        target_from STRING NOT NULL,
+        target_to STRING NOT NULL,
+        action STRING NOT NULL,
+        brokerage STRING NOT NULL,
+        rating_from STRING NOT NULL,
+        rating_to STRING NOT NULL,
+        time TIMESTAMP NOT NULL
+    )`,
+	`CREATE TABLE stock_events (
+        id INT PRIMARY KEY DEFAULT unique_rowid(),
+        ticker STRING NOT NULL,
+        company STRING NOT NULL,
+        target_from STRING NOT NULL,
+        target_to STRING NOT NULL,
+        action STRING NOT NULL,
+        brokerage STRING NOT NULL,
+        rating_from STRING NOT NULL,
+        rating_to STRING NOT NULL,
+        time TIMESTAMP NOT NULL,
+        created_at TIMESTAMP DEFAULT current_timestamp(),
+        UNIQUE INDEX stock_events_natural_key (ticker, brokerage, action, time)
+    )`,
+	`CREATE TABLE sync_runs (
+        id INT PRIMARY KEY DEFAULT unique_rowid(),
+        status STRING NOT NULL,
+        started_at TIMESTAMP NOT NULL,
+        finished_at TIMESTAMP NOT NULL
+    )`,
+	`CREATE TABLE companies (
+        ticker STRING PRIMARY KEY,
+        company STRING NOT NULL,
+        sector STRING NOT NULL DEFAULT '',
+        industry STRING NOT NULL DEFAULT '',
+        exchange STRING NOT NULL DEFAULT '',
+        market_cap_bucket STRING NOT NULL DEFAULT ''
+    )`,
+	`CREATE TABLE schema_versions (
+        component STRING PRIMARY KEY,
+        version INT NOT NULL
+    )`,
+}
+
+// createSchema ejecuta las sentencias de un esquema.
+func createSchema(t *testing.T, db *sql.DB, schema []string) {
+	t.Helper()
+
+	for _, query := range schema {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("error al crear el esquema: %v", err)
+		}
+	}
+}
+
+// openSQLite abre una base de datos SQLite con el esquema en un directorio temporal.
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "stocks.db"))
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	createSchema(t, db, sqliteSchema)
+	return db
+}
+
+// openCockroach crea una base de datos con el esquema en el clúster de
+// TEST_DATABASE_URL y la elimina al terminar la prueba.
+func openCockroach(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL no está definida")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	name := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("CREATE DATABASE error = %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP DATABASE " + name + " CASCADE") })
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL inválida: %v", err)
+	}
+	u.Path = "/" + name
+
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	createSchema(t, db, cockroachSchema)
+	return db
+}
+
+// sqlLoader escribe los datos de prueba con SQL válido en SQLite y en
+// CockroachDB. Las fechas se guardan en UTC, como en stock-data-service.
+type sqlLoader struct {
+	t  *testing.T
+	db *sql.DB
+}
+
+// SaveStocksAt actualiza la tabla stocks y agrega al historial los eventos
+// nuevos con ingestedAt como created_at.
+func (l sqlLoader) SaveStocksAt(stocks []models.Stock, ingestedAt time.Time) []models.StockEvent {
+	l.t.Helper()
+
+	events := []models.StockEvent{}
+	for _, stock := range stocks {
+		args := []interface{}{
+			stock.Ticker, stock.Company, stock.TargetFrom, stock.TargetTo,
+			stock.Action, stock.Brokerage, stock.RatingFrom, stock.RatingTo, stock.Time.UTC(),
+		}
+		_, err := l.db.Exec(`
+            INSERT INTO stocks (`+eventColumns+`)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+            ON CONFLICT (ticker) DO UPDATE SET
+                company = excluded.company, target_from = excluded.target_from,
+                target_to = excluded.target_to, action = excluded.action,
+                brokerage = excluded.brokerage, rating_from = excluded.rating_from,
+                rating_to = excluded.rating_to, time = excluded.time
+        `, args...)
+		if err != nil {
+			l.t.Fatalf("error al guardar el stock: %v", err)
+		}
+
+		var id int64
+		err = l.db.QueryRow(`
+            INSERT INTO stock_events (`+eventColumns+`, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+            ON CONFLICT DO NOTHING
+            RETURNING id
+        `, append(args, ingestedAt.UTC())...).Scan(&id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			l.t.Fatalf("error al guardar el evento: %v", err)
+		}
+		events = append(events, models.StockEvent{ID: id, Stock: stock})
+	}
+	return events
+}
+
+// SaveCompanies guarda o reemplaza los datos de referencia de las compañías.
+func (l sqlLoader) SaveCompanies(companies []models.Company) {
+	l.t.Helper()
+
+	for _, company := range companies {
+		_, err := l.db.Exec(`
+            INSERT INTO companies (ticker, company, sector, industry, exchange, market_cap_bucket)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            ON CONFLICT (ticker) DO UPDATE SET
+                company = excluded.company, sector = excluded.sector,
+                industry = excluded.industry, exchange = excluded.exchange,
+                market_cap_bucket = excluded.market_cap_bucket
+        `, company.Ticker, company.Company, company.Sector, company.Industry, company.Exchange, company.MarketCapBucket)
+		if err != nil {
+			l.t.Fatalf("error al guardar la compañía: %v", err)
+		}
+	}
+}
+
+// RecordSyncRun registra una sincronización.
+func (l sqlLoader) RecordSyncRun(status string, finishedAt time.Time) {
+	l.t.Helper()
+
+	_, err := l.db.Exec(`
+        INSERT INTO sync_runs (status, started_at, finished_at) VALUES ($1, $2, $3)
+    `, status, finishedAt.Add(-time.Minute).UTC(), finishedAt.UTC())
+	if err != nil {
+		l.t.Fatalf("error al registrar la sincronización: %v", err)
+	}
+}
+
+// SetSchemaVersion registra la versión del esquema de un servicio.
+func (l sqlLoader) SetSchemaVersion(component string, version int) {
+	l.t.Helper()
+
+	_, err := l.db.Exec(`
+        INSERT INTO schema_versions (component, version) VALUES ($1, $2)
+        ON CONFLICT (component) DO UPDATE SET version = excluded.version
+    `, component, version)
+	if err != nil {
+		l.t.Fatalf("error al registrar la versión del esquema: %v", err)
+	}
+}
+
+// at devuelve una fecha fija del 1 de marzo de 2024 en UTC. Las fechas no usan
+// fracciones de segundo porque CockroachDB guarda microsegundos.
+func at(hour, minute int) time.Time {
+	return time.Date(2024, 3, 1, hour, minute, 0, 0, time.UTC)
+}
+
+// rating crea una calificación de prueba.
+func rating(ticker, brokerage, action, ratingTo string, when time.Time) models.Stock {
+	return models.Stock{
+		Ticker:     ticker,
+		Company:    ticker + " Inc.",
+		TargetFrom: "$10.00",
+		TargetTo:   "$12.00",
+		Action:     action,
+		Brokerage:  brokerage,
+		RatingFrom: "Hold",
+		RatingTo:   ratingTo,
+		Time:       when,
+	}
+}
+
+// fixtureRatings son las calificaciones de prueba en el orden en que se
+// sincronizan. La tabla stocks queda con AAPL a las 11:00, MSFT a las 12:00,
+// JPM a las 10:00 y TSLA a las 10:30; TSLA no tiene datos de compañía.
+var fixtureRatings = []models.Stock{
+	rating("AAPL", "Goldman Sachs", "upgraded by", "Buy", at(9, 0)),
+	rating("MSFT", "Morgan Stanley", "target raised by", "Overweight", at(9, 30)),
+	rating("JPM", "Barclays", "downgraded by", "Sell", at(10, 0)),
+	rating("TSLA", "Goldman Sachs", "target lowered by", "Sell", at(10, 30)),
+	rating("AAPL", "Barclays", "reiterated by", "Buy", at(11, 0)),
+	rating("MSFT", "Goldman Sachs", "upgraded by", "Buy", at(12, 0)),
+}
+
+var fixtureCompanies = []models.Company{
+	{Ticker: "AAPL", Company: "Apple Inc.", Sector: "Technology", Industry: "Consumer Electronics", Exchange: "NASDAQ", MarketCapBucket: "mega"},
+	{Ticker: "MSFT", Company: "Microsoft Corporation", Sector: "Technology", Industry: "Software", Exchange: "NASDAQ", MarketCapBucket: "mega"},
+	{Ticker: "JPM", Company: "JPMorgan Chase & Co.", Sector: "Financials", Industry: "Banks", Exchange: "NYSE", MarketCapBucket: "mega"},
+}
+
+// ingestLag es lo que tarda cada calificación de prueba en sincronizarse
+// después de su fecha.
+const ingestLag = 5 * time.Minute
+
+// loadRatings carga las calificaciones de una en una, cada una registrada
+// ingestLag después de su fecha, y devuelve los eventos creados.
+func loadRatings(load loader, ratings []models.Stock) []models.StockEvent {
+	events := []models.StockEvent{}
+	for _, stock := range ratings {
+		events = append(events, load.SaveStocksAt([]models.Stock{stock}, stock.Time.Add(ingestLag))...)
+	}
+	return events
+}
+
+// runBackends ejecuta una prueba contra cada implementación con los datos de
+// prueba cargados. ids son los identificadores de fixtureRatings, en orden.
+func runBackends(t *testing.T, test func(t *testing.T, ctx context.Context, repo StockRepository, ids []int64)) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repo, load := backend.open(t)
+			load.SaveCompanies(fixtureCompanies)
+			events := loadRatings(load, fixtureRatings)
+			if len(events) != len(fixtureRatings) {
+				t.Fatalf("se cargaron %d eventos, want %d", len(events), len(fixtureRatings))
+			}
+			ids := make([]int64, len(events))
+			for i, event := range events {
+				ids[i] = event.ID
+			}
+			load.RecordSyncRun("completed", at(8, 0))
+			load.RecordSyncRun("completed", at(12, 5))
+			load.RecordSyncRun("failed", at(13, 0))
+			load.SetSchemaVersion("stock-data-service", 1)
+
+			test(t, context.Background(), repo, ids)
+		})
+	}
+}
+
+// labels resume stocks como "TICKER@hh:mm" para comparar listados.
+func labels(stocks []models.Stock) []string {
+	result := []string{}
+	for _, stock := range stocks {
+		result = append(result, stock.Ticker+"@"+stock.Time.UTC().Format("15:04"))
+	}
+	return result
+}
+
+// eventLabels resume eventos como "TICKER@hh:mm".
+func eventLabels(events []models.StockEvent) []string {
+	stocks := make([]models.Stock, len(events))
+	for i, event := range events {
+		stocks[i] = event.Stock
+	}
+	return labels(stocks)
+}
+
+// assertLabels compara un listado resumido con el esperado.
+func assertLabels(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+
+	if want == nil {
+		want = []string{}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func TestConformanceGetStocks(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository, ids []int64) {
+		stocks, err := repo.GetStocks(ctx, models.StockFilter{}, 0, 10)
+		if err != nil {
+			t.Fatalf("GetStocks() error = %v", err)
+		}
+		assertLabels(t, "GetStocks()", labels(stocks), "MSFT@12:00", "AAPL@11:00", "TSLA@10:30", "JPM@10:00")
+
+		// Los datos de la compañía se completan si existen
+		want := fixtureRatings[4]
+		want.Sector, want.Industry, want.Exchange, want.MarketCapBucket = "Technology", "Consumer Electronics", "NASDAQ", "mega"
+		got := stocks[1]
+		got.Time = got.Time.UTC()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetStocks()[1] = %+v, want %+v", got, want)
+		}
+		if stocks[2].Sector != "" {
+			t.Errorf("TSLA sin compañía tiene sector %q", stocks[2].Sector)
+		}
+
+		stocks, err = repo.GetStocks(ctx, models.StockFilter{}, 1, 2)
+		if err != nil {
+			t.Fatalf("GetStocks() paginado error = %v", err)
+		}
+		assertLabels(t, "GetStocks(offset 1, limit 2)", labels(stocks), "AAPL@11:00", "TSLA@10:30")
+
+		stocks, err = repo.GetStocks(ctx, models.StockFilter{OrderBy: "ticker", SortOrder: "asc"}, 0, 10)
+		if err != nil {
+			t.Fatalf("GetStocks() por ticker error = %v", err)
+		}
+		assertLabels(t, "GetStocks(ticker asc)", labels(stocks), "AAPL@11:00", "JPM@10:00", "MSFT@12:00", "TSLA@10:30")
+
+		_, err = repo.GetStocks(ctx, models.StockFilter{OrderBy: "sector"}, 0, 10)
+		if !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("GetStocks(order_by=sector) error = %v, want ErrInvalidFilter", err)
+		}
+	})
+}
+
+func TestConformanceStockFilters(t *testing.T) {
+	asOf := at(10, 15)
+	tests := []struct {
+		name   string
+		filter models.StockFilter
+		want   []string
+	}{
+		{"ticker parcial sin mayúsculas", models.StockFilter{Ticker: "a"}, []string{"AAPL@11:00", "TSLA@10:30"}},
+		{"casa de bolsa", models.StockFilter{Brokerage: "Goldman Sachs"}, []string{"MSFT@12:00", "TSLA@10:30"}},
+		{"calificación", models.StockFilter{Rating: "Sell"}, []string{"TSLA@10:30", "JPM@10:00"}},
+		{"sector sin mayúsculas", models.StockFilter{Sector: "technology"}, []string{"MSFT@12:00", "AAPL@11:00"}},
+		{"industria", models.StockFilter{Industry: "Banks"}, []string{"JPM@10:00"}},
+		{"sin resultados", models.StockFilter{Ticker: "ZZZ"}, nil},
+		{"as_of", models.StockFilter{AsOf: &asOf}, []string{"JPM@10:00", "MSFT@09:30", "AAPL@09:00"}},
+		{"as_of con filtro", models.StockFilter{AsOf: &asOf, Brokerage: "Goldman Sachs"}, []string{"AAPL@09:00"}},
+	}
+
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository, ids []int64) {
+		for _, tt := range tests {
+			stocks, err := repo.GetStocks(ctx, tt.filter, 0, 10)
+			if err != nil {
+				t.Fatalf("GetStocks(%s) error = %v", tt.name, err)
+			}
+			assertLabels(t, "GetStocks("+tt.name+")", labels(stocks), tt.want...)
+
+			count, err := repo.CountStocks(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("CountStocks(%s) error = %v", tt.name, err)
+			}
+			if count != len(tt.want) {
+				t.Errorf("CountStocks(%s) = %d, want %d", tt.name, count, len(tt.want))
+			}
+
+			var streamed []models.Stock
+			err = repo.StreamStocks(ctx, tt.filter, func(stock models.Stock) error {
+				streamed = append(streamed, stock)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("StreamStocks(%s) error = %v", tt.name, err)
+			}
+			assertLabels(t, "StreamStocks("+tt.name+")", labels(streamed), tt.want...)
+		}
+	})
+}
+
+func TestConformanceStreamStocksStops(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository, ids []int64) {
+		stop := errors.New("stop")
+		calls := 0
+		err := repo.StreamStocks(ctx, models.StockFilter{}, func(models.Stock) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("StreamStocks() = %v tras %d llamadas, want el error de fn tras 1", err, calls)
+		}
+	})
+}
+
+func TestConformanceGetStockByTicker(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository, ids []int64) {
+		stock, err := repo.GetStockByTicker(ctx, "AAPL")
+		if err != nil {
+			t.Fatalf("GetStockByTicker() error = %v", err)
+		}
+		if stock.Brokerage != "Barclays" || stock.Sector != "Technology" {
+			t.Errorf("GetStockByTicker() = %+v, want la calificación de Barclays con su sector", stock)
+		}
+
+		_, err = repo.GetStockByTicker(ctx, "ZZZ")
+		if !errors.Is(err, ErrStockNotFound) || !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetStockByTicker(ZZZ) error = %v, want ErrStockNotFound", err)
+		}
+
+		stock, err = repo.GetStockByTickerAsOf(ctx, "AAPL", at(10, 0))
+		if err != nil {
+			t.Fatalf("GetStockByTickerAsOf() error = %v", err)
+		}
+		assertLabels(t, "GetStockByTickerAsOf()", labels([]models.Stock{stock}), "AAPL@09:00")
+
+		_, err = repo.GetStockByTickerAsOf(ctx, "AAPL", at(8, 0))
+		if !errors.Is(err, ErrStockNotFound) {
+			t.Errorf("GetStockByTickerAsOf(antes del primer evento) error = %v, want ErrStockNotFound", err)
+		}
+	})
+}
+
+func TestConformanceDateRanges(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository, ids []int64) {
+		stocks, err := repo.GetStocksByDateRange(ctx, at(10, 0), at(11, 0))
+		if err != nil {
+			t.Fatalf("GetStocksByDateRange() error = %v", err)
+		}
+		assertLabels(t, "GetStocksByDateRange()", labels(stocks), "AAPL@11:00", "TSLA@10:30", "JPM@10:00")
+
+		stocks, err = repo.GetStockEventsByDateRange(ctx, at(9, 30), at(11, 0))
+		if err != nil {
+			t.Fatalf("GetStockEventsByDateRange() error = %v", err)
+		}
+		assertLabels(t, "GetStockEventsByDateRange()", labels(stocks), "MSFT@09:30", "JPM@10:00", "TSLA@10:30", "AAPL@11:00")
+	})
+}
+
+func TestConformanceAsOfUsesIngestionTime(t *testing.T) {
+	// Una calificación de las 09:45 que llega en la sincronización de las 12:30
+	// no existía para las vistas anteriores a esa sincronización
+	backfill := rating("JPM", "Morgan Stanley", "upgraded by", "Buy", at(9, 45))
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, load := backend.open(t)
+			loadRatings(load, fixtureRatings)
+			load.SaveStocksAt([]models.Stock{backfill}, at(12, 30))
+
+			tests := []struct {
+				asOf   time.Time
+				stocks []string
+				jpm    string
+				events []string
+			}{
+				{
+					at(9, 2), nil, "",
+					[]string{},
+				},
+				{
+					at(12, 0), []string{"AAPL@11:00", "TSLA@10:30", "JPM@10:00", "MSFT@09:30"}, "JPM@10:00",
+					[]string{"MSFT@09:30", "JPM@10:00", "TSLA@10:30", "AAPL@11:00"},
+				},
+				{
+					at(13, 0), []string{"MSFT@12:00", "AAPL@11:00", "TSLA@10:30", "JPM@09:45"}, "JPM@09:45",
+					[]string{"MSFT@09:30", "JPM@09:45", "JPM@10:00", "TSLA@10:30", "AAPL@11:00"},
+				},
+			}
+			for _, tt := range tests {
+				name := tt.asOf.Format("15:04")
+
+				stocks, err := repo.GetStocks(ctx, models.StockFilter{AsOf: &tt.asOf}, 0, 10)
+				if err != nil {
+					t.Fatalf("GetStocks(as_of=%s) error = %v", name, err)
+				}
+				assertLabels(t, "GetStocks(as_of="+name+")", labels(stocks), tt.stocks...)
+
+				stock, err := repo.GetStockByTickerAsOf(ctx, "JPM", tt.asOf)
+				switch {
+				case tt.jpm == "" && !errors.Is(err, ErrStockNotFound):
+					t.Errorf("GetStockByTickerAsOf(as_of=%s) error = %v, want ErrStockNotFound", name, err)
+				case tt.jpm != "" && err != nil:
+					t.Fatalf("GetStockByTickerAsOf(as_of=%s) error = %v", name, err)
+				case tt.jpm != "":
+					assertLabels(t, "GetStockByTickerAsOf(as_of="+name+")", labels([]models.Stock{stock}), tt.jpm)
+				}
+
+				stocks, err = repo.GetStockEventsByDateRangeAsOf(ctx, at(9, 30), at(11, 0), tt.asOf)
+				if err != nil {
+					t.Fatalf("GetStockEventsByDateRangeAsOf(as_of=%s) error = %v", name, err)
+				}
+				assertLabels(t, "GetStockEventsByDateRangeAsOf(as_of="+name+")", labels(stocks), tt.events...)
+			}
+		})
+	}
+}
+
+func TestConformanceTickerHistory(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository, ids []int64) {
+		events, err := repo.GetTickerHistory(ctx, "AAPL", 0, 10)
+		if err != nil {
+			t.Fatalf("GetTickerHistory() error = %v", err)
+		}
+		assertLabels(t, "GetTickerHistory()", eventLabels(events), "AAPL@11:00", "AAPL@09:00")
+		if len(events) == 2 && (events[0].ID != ids[4] || events[1].ID != ids[0]) {
+			t.Errorf("GetTickerHistory() identificadores = %d, %d; want %d, %d", events[0].ID, events[1].ID, ids[4], ids[0])
+		}
+
+		events, err = repo.GetTickerHistory(ctx, "AAPL", 1, 1)
+		if err != nil {
+			t.Fatalf("GetTickerHistory() paginado error = %v", err)
+		}
+		assertLabels(t, "GetTickerHistory(offset 1, limit 1)", eventLabels(events), "AAPL@09:00")
+
+		events, err = repo.GetTickerHistory(ctx, "ZZZ", 0, 10)
+		if err != nil || events == nil || len(events) != 0 {
+			t.Errorf("GetTickerHistory(ZZZ) = %v, %v; want un listado vacío", events, err)
+		}
+
+		count, err := repo.CountTickerHistory(ctx, "AAPL")
+		if err != nil || count != 2 {
+			t.Errorf("CountTickerHistory() = %d, %v; want 2", count, err)
+		}
+
+		var streamed []models.StockEvent
+		err = repo.StreamTickerHistory(ctx, "MSFT", func(event models.StockEvent) error {
+			streamed = append(streamed, event)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("StreamTickerHistory() error = %v", err)
+		}
+		assertLabels(t, "StreamTickerHistory()", eventLabels(streamed), "MSFT@12:00", "MSFT@09:30")
+	})
+}
+
+func TestConformanceEventQueries(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository, ids []int64) {
+		events, err := repo.GetStockEventsAfter(ctx, ids[1], 2)
+		if err != nil {
+			t.Fatalf("GetStockEventsAfter() error = %v", err)
+		}
+		assertLabels(t, "GetStockEventsAfter()", eventLabels(events), "JPM@10:00", "TSLA@10:30")
+
+		latest, err := repo.GetLatestStockEventID(ctx)
+		if err != nil || latest != ids[len(ids)-1] {
+			t.Errorf("GetLatestStockEventID() = %d, %v; want %d", latest, err, ids[len(ids)-1])
+		}
+
+		events, err = repo.GetStockEventsForTickers(ctx, []string{"AAPL", "MSFT"}, at(9, 30), 10)
+		if err != nil {
+			t.Fatalf("GetStockEventsForTickers() error = %v", err)
+		}
+		assertLabels(t, "GetStockEventsForTickers()", eventLabels(events), "MSFT@12:00", "AAPL@11:00", "MSFT@09:30")
+
+		events, err = repo.GetStockEventsForTickers(ctx, []string{"AAPL", "MSFT"}, at(9, 30), 1)
+		if err != nil {
+			t.Fatalf("GetStockEventsForTickers() con límite error = %v", err)
+		}
+		assertLabels(t, "GetStockEventsForTickers(limit 1)", eventLabels(events), "MSFT@12:00")
+
+		events, err = repo.GetTickersEventsBetween(ctx, []string{"MSFT", "AAPL", "ZZZ"}, at(9, 0), at(12, 0), 10)
+		if err != nil {
+			t.Fatalf("GetTickersEventsBetween() error = %v", err)
+		}
+		assertLabels(t, "GetTickersEventsBetween()", eventLabels(events), "AAPL@11:00", "AAPL@09:00", "MSFT@12:00", "MSFT@09:30")
+
+		events, err = repo.GetTickersEventsBetween(ctx, []string{"MSFT", "AAPL"}, at(9, 0), at(11, 59), 1)
+		if err != nil {
+			t.Fatalf("GetTickersEventsBetween() con límite error = %v", err)
+		}
+		assertLabels(t, "GetTickersEventsBetween(perTicker 1)", eventLabels(events), "AAPL@11:00", "MSFT@09:30")
+
+		events, err = repo.GetRecentEventsByTicker(ctx, []string{"MSFT", "AAPL", "ZZZ"}, 1)
+		if err != nil {
+			t.Fatalf("GetRecentEventsByTicker() error = %v", err)
+		}
+		assertLabels(t, "GetRecentEventsByTicker()", eventLabels(events), "AAPL@11:00", "MSFT@12:00")
+
+		events, err = repo.GetRecentEventsByBrokerage(ctx, []string{"Goldman Sachs", "Barclays"}, 2)
+		if err != nil {
+			t.Fatalf("GetRecentEventsByBrokerage() error = %v", err)
+		}
+		assertLabels(t, "GetRecentEventsByBrokerage()", eventLabels(events), "AAPL@11:00", "JPM@10:00", "MSFT@12:00", "TSLA@10:30")
+	})
+}
+
+func TestConformanceBrokerages(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository, ids []int64) {
+		brokerages, err := repo.ListBrokerages(ctx, "", 0, 10)
+		if err != nil {
+			t.Fatalf("ListBrokerages() error = %v", err)
+		}
+		want := []models.Brokerage{
+			{Name: "Goldman Sachs", RatingCount: 3, TickerCount: 3, LastRatingAt: at(12, 0)},
+			{Name: "Barclays", RatingCount: 2, TickerCount: 2, LastRatingAt: at(11, 0)},
+			{Name: "Morgan Stanley", RatingCount: 1, TickerCount: 1, LastRatingAt: at(9, 30)},
+		}
+		for i := range brokerages {
+			brokerages[i].LastRatingAt = brokerages[i].LastRatingAt.UTC()
+		}
+		if !reflect.DeepEqual(brokerages, want) {
+			t.Errorf("ListBrokerages() = %+v, want %+v", brokerages, want)
+		}
+
+		brokerages, err = repo.ListBrokerages(ctx, "AN", 1, 10)
+		if err != nil || len(brokerages) != 1 || brokerages[0].Name != "Morgan Stanley" {
+			t.Errorf("ListBrokerages(AN, offset 1) = %+v, %v; want Morgan Stanley", brokerages, err)
+		}
+
+		count, err := repo.CountBrokerages(ctx, "bar")
+		if err != nil || count != 1 {
+			t.Errorf("CountBrokerages(bar) = %d, %v; want 1", count, err)
+		}
+
+		brokerages, err = repo.GetBrokerages(ctx, []string{"Morgan Stanley", "Barclays", "Desconocida"})
+		if err != nil {
+			t.Fatalf("GetBrokerages() error = %v", err)
+		}
+		var names []string
+		for _, brokerage := range brokerages {
+			names = append(names, brokerage.Name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, []string{"Barclays", "Morgan Stanley"}) {
+			t.Errorf("GetBrokerages() = %v, want Barclays y Morgan Stanley", names)
+		}
+	})
+}
+
+func TestConformanceSectorSummaries(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository, ids []int64) {
+		summaries, err := repo.GetSectorSummaries(ctx, at(0, 0), at(23, 0))
+		if err != nil {
+			t.Fatalf("GetSectorSummaries() error = %v", err)
+		}
+		if len(summaries) == 0 || summaries[0].Sector != "Technology" {
+			t.Fatalf("GetSectorSummaries() = %+v, want Technology primero", summaries)
+		}
+
+		got := make(map[string]models.SectorSummary)
+		for _, summary := range summaries {
+			got[summary.Sector] = summary
+		}
+		want := map[string]models.SectorSummary{
+			"Technology":              {Sector: "Technology", Tickers: 2, Events: 4, Upgrades: 2, TargetsRaised: 1, NetUpgrades: 2},
+			"Financials":              {Sector: "Financials", Tickers: 1, Events: 1, Downgrades: 1, NetUpgrades: -1},
+			models.UnclassifiedSector: {Sector: models.UnclassifiedSector, Tickers: 1, Events: 1, TargetsLowered: 1},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetSectorSummaries() = %+v, want %+v", got, want)
+		}
+
+		summaries, err = repo.GetSectorSummaries(ctx, at(13, 0), at(14, 0))
+		if err != nil || len(summaries) != 0 {
+			t.Errorf("GetSectorSummaries(sin eventos) = %+v, %v; want vacío", summaries, err)
+		}
+	})
+}
+
+func TestConformanceSyncStatus(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository, ids []int64) {
+		last, err := repo.GetLastSuccessfulSync(ctx)
+		if err != nil {
+			t.Fatalf("GetLastSuccessfulSync() error = %v", err)
+		}
+		// La sincronización fallida posterior no cuenta
+		if last == nil || !last.Equal(at(12, 5)) {
+			t.Errorf("GetLastSuccessfulSync() = %v, want %s", last, at(12, 5))
+		}
+
+		newest, err := repo.GetNewestEventTime(ctx)
+		if err != nil {
+			t.Fatalf("GetNewestEventTime() error = %v", err)
+		}
+		if newest == nil || !newest.Equal(at(12, 0)) {
+			t.Errorf("GetNewestEventTime() = %v, want %s", newest, at(12, 0))
+		}
+
+		version, err := repo.GetSchemaVersion(ctx, "stock-data-service")
+		if err != nil || version != 1 {
+			t.Errorf("GetSchemaVersion() = %d, %v; want 1", version, err)
+		}
+		version, err = repo.GetSchemaVersion(ctx, "desconocido")
+		if err != nil || version != 0 {
+			t.Errorf("GetSchemaVersion(desconocido) = %d, %v; want 0", version, err)
+		}
+
+		if err := repo.Ping(ctx); err != nil {
+			t.Errorf("Ping() error = %v", err)
+		}
+	})
+}
+
+func TestConformanceEmpty(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, _ := backend.open(t)
+
+			stocks, err := repo.GetStocks(ctx, models.StockFilter{}, 0, 10)
+			if err != nil || len(stocks) != 0 {
+				t.Errorf("GetStocks() = %v, %v; want vacío", stocks, err)
+			}
+			latest, err := repo.GetLatestStockEventID(ctx)
+			if err != nil || latest != 0 {
+				t.Errorf("GetLatestStockEventID() = %d, %v; want 0", latest, err)
+			}
+			newest, err := repo.GetNewestEventTime(ctx)
+			if err != nil || newest != nil {
+				t.Errorf("GetNewestEventTime() = %v, %v; want nil", newest, err)
+			}
+			last, err := repo.GetLastSuccessfulSync(ctx)
+			if err != nil || last != nil {
+				t.Errorf("GetLastSuccessfulSync() = %v, %v; want nil", last, err)
+			}
+			brokerages, err := repo.ListBrokerages(ctx, "", 0, 10)
+			if err != nil || brokerages == nil || len(brokerages) != 0 {
+				t.Errorf("ListBrokerages() = %v, %v; want un listado vacío", brokerages, err)
+			}
+		})
+	}
+}
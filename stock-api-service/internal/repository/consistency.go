@@ -0,0 +1,20 @@
+package repository
+
+import "context"
+
+// strongConsistencyKey es la clave de contexto de WithStrongConsistency.
+type strongConsistencyKey struct{}
+
+// WithStrongConsistency marca el contexto para que los listados y las consultas
+// de las recomendaciones lean el último estado confirmado desde el pool
+// principal, sin lecturas de réplicas seguidoras. Sirve a las solicitudes que
+// necesitan ver una sincronización recién terminada.
+func WithStrongConsistency(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strongConsistencyKey{}, true)
+}
+
+// StrongConsistency indica si el contexto pide consistencia fuerte.
+func StrongConsistency(ctx context.Context) bool {
+	strong, _ := ctx.Value(strongConsistencyKey{}).(bool)
+	return strong
+}
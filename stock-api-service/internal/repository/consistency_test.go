@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+func TestBuildStockQuerySystemTime(t *testing.T) {
+	const systemTime = "AS OF SYSTEM TIME follower_read_timestamp()"
+
+	from, _ := buildStockQuery(models.StockFilter{Ticker: "AA", Sector: "Technology"}, systemTime)
+	join := strings.Index(from, companyJoin)
+	clause := strings.Index(from, systemTime)
+	where := strings.Index(from, " WHERE ")
+	// CockroachDB solo acepta AS OF SYSTEM TIME al final de la cláusula FROM
+	if join < 0 || clause < join || where < clause {
+		t.Errorf("buildStockQuery() = %q, want AS OF SYSTEM TIME entre el JOIN y el WHERE", from)
+	}
+
+	if from, _ := buildStockQuery(models.StockFilter{}, ""); strings.Contains(from, "AS OF") {
+		t.Errorf("buildStockQuery() sin lecturas de réplicas = %q", from)
+	}
+}
+
+func TestCockroachReadRouting(t *testing.T) {
+	db := openCockroach(t)
+	loadRatings(sqlLoader{t: t, db: db}, fixtureRatings)
+	ctx := context.Background()
+	strong := WithStrongConsistency(ctx)
+
+	// Un pool principal cerrado muestra qué consultas lo usan
+	closed, err := sql.Open("postgres", "postgresql://localhost/closed")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	closed.Close()
+
+	repo := NewCockroachStockRepository(closed).WithReadPool(db)
+	if count, err := repo.CountStocks(ctx, models.StockFilter{}); err != nil || count != 4 {
+		t.Errorf("CountStocks() con pool de lectura = %d, %v; want 4", count, err)
+	}
+	if _, err := repo.GetStocksByDateRange(ctx, time.Time{}, time.Now()); err != nil {
+		t.Errorf("GetStocksByDateRange() con pool de lectura error = %v", err)
+	}
+	if _, err := repo.GetStocks(strong, models.StockFilter{}, 0, 10); err == nil {
+		t.Error("GetStocks() con consistencia fuerte usó el pool de lectura")
+	}
+
+	// Las lecturas de réplicas no ven datos de hace menos de unos segundos; con
+	// consistencia fuerte se ven de inmediato
+	repo = NewCockroachStockRepository(db).WithFollowerReads(true)
+	stocks, err := repo.GetStocks(strong, models.StockFilter{}, 0, 10)
+	if err != nil || len(stocks) != 4 {
+		t.Errorf("GetStocks() con consistencia fuerte = %d stocks, %v; want 4", len(stocks), err)
+	}
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/lib/pq"
+)
+
+// Categorías de los errores del repositorio. Los errores concretos, como
+// ErrStockNotFound, pertenecen a una de ellas y se comparan con errors.Is.
+var (
+	// ErrNotFound indica que el recurso solicitado no existe.
+	ErrNotFound = errors.New("recurso no encontrado")
+	// ErrInvalidFilter indica que un filtro u ordenamiento no es válido.
+	ErrInvalidFilter = errors.New("filtro inválido")
+	// ErrConflict indica que la operación entra en conflicto con los datos existentes.
+	ErrConflict = errors.New("conflicto con los datos existentes")
+	// ErrUnavailable indica que la base de datos no está disponible o no
+	// respondió a tiempo; la operación puede reintentarse.
+	ErrUnavailable = errors.New("base de datos no disponible")
+)
+
+// Error es un error del repositorio de una categoría conocida. Su mensaje se
+// puede mostrar al cliente; la causa, si existe, solo debe registrarse.
+type Error struct {
+	// Categoría: ErrNotFound, ErrInvalidFilter, ErrConflict o ErrUnavailable
+	Kind error
+	// Mensaje sin detalles internos
+	Message string
+	// Causa del error
+	Err error
+}
+
+// Error implementa la interfaz error.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Is permite comparar el error con su categoría mediante errors.Is.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap devuelve la causa del error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// notFound crea un error de la categoría ErrNotFound.
+func notFound(message string) *Error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+// conflict crea un error de la categoría ErrConflict.
+func conflict(message string) *Error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+// invalidFilter crea un error de la categoría ErrInvalidFilter.
+func invalidFilter(message string) *Error {
+	return &Error{Kind: ErrInvalidFilter, Message: message}
+}
+
+// classify marca como ErrUnavailable los errores de conexión, de tiempo de
+// espera y los reintentables de la base de datos, y devuelve los demás sin cambios.
+func classify(err error) error {
+	if err == nil || !unavailable(err) {
+		return err
+	}
+	return &Error{Kind: ErrUnavailable, Message: "La base de datos no está disponible, intente más tarde", Err: err}
+}
+
+// unavailable indica si el error se debe a que la base de datos no está
+// disponible y no a la consulta.
+func unavailable(err error) bool {
+	if errors.Is(err, ErrUnavailable) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		// Excepciones de conexión, recursos insuficientes e intervención del operador
+		case "08", "53", "57":
+			return true
+		}
+		// CockroachDB pide reintentar las transacciones con este código
+		return pqErr.Code == "40001"
+	}
+	return false
+}
@@ -0,0 +1,715 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// eventKey identifica un evento del historial, como el índice único
+// stock_events_natural_key de stock-data-service.
+type eventKey struct {
+	ticker    string
+	brokerage string
+	action    string
+	time      time.Time
+}
+
+// syncRun es una sincronización registrada en memoria.
+type syncRun struct {
+	status     string
+	finishedAt time.Time
+}
+
+// MemoryStockRepository implementa StockRepository en memoria. Sirve para
+// pruebas; como este servicio solo lee, los datos se cargan
+// con SaveStocks o SaveStocksAt, SaveCompanies, RecordSyncRun y SetSchemaVersion, que imitan lo
+// que stock-data-service escribe en la base de datos.
+type MemoryStockRepository struct {
+	mu             sync.RWMutex
+	stocks         map[string]models.Stock
+	events         []models.StockEvent
+	eventKeys      map[eventKey]bool
+	ingestedAt     map[int64]time.Time
+	companies      map[string]models.Company
+	syncRuns       []syncRun
+	schemaVersions map[string]int
+	nextID         int64
+}
+
+// NewMemoryStockRepository crea un repositorio de stocks en memoria vacío.
+func NewMemoryStockRepository() *MemoryStockRepository {
+	return &MemoryStockRepository{
+		stocks:         make(map[string]models.Stock),
+		eventKeys:      make(map[eventKey]bool),
+		ingestedAt:     make(map[int64]time.Time),
+		companies:      make(map[string]models.Company),
+		schemaVersions: make(map[string]int),
+	}
+}
+
+// SaveStocks guarda el último estado de cada stock y agrega al historial los
+// eventos nuevos, como la sincronización de stock-data-service. Devuelve solo
+// los eventos que no existían.
+func (r *MemoryStockRepository) SaveStocks(stocks []models.Stock) []models.StockEvent {
+	return r.SaveStocksAt(stocks, time.Now())
+}
+
+// SaveStocksAt es SaveStocks con el momento de la sincronización explícito,
+// que las vistas as_of comparan como stock_events.created_at.
+func (r *MemoryStockRepository) SaveStocksAt(stocks []models.Stock, ingestedAt time.Time) []models.StockEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	newEvents := []models.StockEvent{}
+	for _, stock := range stocks {
+		// Los datos de la compañía se leen de companies, no del stock
+		stock.Sector, stock.Industry, stock.Exchange, stock.MarketCapBucket = "", "", "", ""
+		r.stocks[stock.Ticker] = stock
+
+		key := eventKey{ticker: stock.Ticker, brokerage: stock.Brokerage, action: stock.Action, time: stock.Time.UTC()}
+		if r.eventKeys[key] {
+			continue
+		}
+		r.eventKeys[key] = true
+		r.nextID++
+		event := models.StockEvent{ID: r.nextID, Stock: stock}
+		r.events = append(r.events, event)
+		r.ingestedAt[event.ID] = ingestedAt
+		newEvents = append(newEvents, event)
+	}
+	return newEvents
+}
+
+// SaveCompanies guarda o reemplaza los datos de referencia de las compañías.
+func (r *MemoryStockRepository) SaveCompanies(companies []models.Company) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, company := range companies {
+		r.companies[company.Ticker] = company
+	}
+}
+
+// RecordSyncRun registra una sincronización con su estado (completed, failed)
+// y el momento en que terminó.
+func (r *MemoryStockRepository) RecordSyncRun(status string, finishedAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.syncRuns = append(r.syncRuns, syncRun{status: status, finishedAt: finishedAt})
+}
+
+// SetSchemaVersion registra la versión del esquema de un servicio.
+func (r *MemoryStockRepository) SetSchemaVersion(component string, version int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.schemaVersions[component] = version
+}
+
+// withCompany completa un stock con los datos de referencia de su compañía,
+// como companyJoin. Debe llamarse con el mutex tomado.
+func (r *MemoryStockRepository) withCompany(stock models.Stock) models.Stock {
+	company := r.companies[stock.Ticker]
+	stock.Sector = company.Sector
+	stock.Industry = company.Industry
+	stock.Exchange = company.Exchange
+	stock.MarketCapBucket = company.MarketCapBucket
+	return stock
+}
+
+// withCompanyEvent completa un evento con los datos de referencia de su compañía.
+func (r *MemoryStockRepository) withCompanyEvent(event models.StockEvent) models.StockEvent {
+	event.Stock = r.withCompany(event.Stock)
+	return event
+}
+
+// registeredBy indica si el evento ya estaba registrado en asOf. Debe llamarse
+// con el mutex tomado.
+func (r *MemoryStockRepository) registeredBy(event models.StockEvent, asOf time.Time) bool {
+	return !r.ingestedAt[event.ID].After(asOf)
+}
+
+// newerEvent indica si a se registró después que b, desempatando por fecha e
+// identificador como el ORDER BY de las vistas as_of. Debe llamarse con el
+// mutex tomado.
+func (r *MemoryStockRepository) newerEvent(a, b models.StockEvent) bool {
+	if ia, ib := r.ingestedAt[a.ID], r.ingestedAt[b.ID]; !ia.Equal(ib) {
+		return ia.After(ib)
+	}
+	if !a.Time.Equal(b.Time) {
+		return a.Time.After(b.Time)
+	}
+	return a.ID > b.ID
+}
+
+// filterStocks devuelve los stocks que cumplen el filtro, ordenados según él y
+// desempatados por ticker. Debe llamarse con el mutex tomado.
+func (r *MemoryStockRepository) filterStocks(filter models.StockFilter, orderBy, sortOrder string) []models.Stock {
+	var source []models.Stock
+	if filter.AsOf != nil {
+		// La última actualización de cada ticker registrada hasta AsOf
+		latest := make(map[string]models.StockEvent)
+		for _, event := range r.events {
+			if !r.registeredBy(event, *filter.AsOf) {
+				continue
+			}
+			current, ok := latest[event.Ticker]
+			if !ok || r.newerEvent(event, current) {
+				latest[event.Ticker] = event
+			}
+		}
+		for _, event := range latest {
+			source = append(source, event.Stock)
+		}
+	} else {
+		for _, stock := range r.stocks {
+			source = append(source, stock)
+		}
+	}
+
+	var stocks []models.Stock
+	for _, stock := range source {
+		stock = r.withCompany(stock)
+		if filter.Matches(stock) {
+			stocks = append(stocks, stock)
+		}
+	}
+
+	sort.SliceStable(stocks, func(i, j int) bool {
+		c := compareStockColumn(stocks[i], stocks[j], orderBy)
+		if c == 0 {
+			return stocks[i].Ticker < stocks[j].Ticker
+		}
+		if sortOrder == "DESC" {
+			return c > 0
+		}
+		return c < 0
+	})
+	return stocks
+}
+
+// compareStockColumn compara dos stocks por una columna de stockOrderColumns.
+func compareStockColumn(a, b models.Stock, column string) int {
+	switch column {
+	case "ticker":
+		return strings.Compare(a.Ticker, b.Ticker)
+	case "company":
+		return strings.Compare(a.Company, b.Company)
+	case "brokerage":
+		return strings.Compare(a.Brokerage, b.Brokerage)
+	case "rating_from":
+		return strings.Compare(a.RatingFrom, b.RatingFrom)
+	case "rating_to":
+		return strings.Compare(a.RatingTo, b.RatingTo)
+	default:
+		return a.Time.Compare(b.Time)
+	}
+}
+
+// page aplica offset y limit a un listado.
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// GetStocks recupera stocks que cumplen el filtro, con paginación y ordenamiento.
+func (r *MemoryStockRepository) GetStocks(ctx context.Context, filter models.StockFilter, offset, limit int) ([]models.Stock, error) {
+	orderBy, sortOrder, err := stockOrder(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return page(r.filterStocks(filter, orderBy, sortOrder), offset, limit), nil
+}
+
+// StreamStocks recorre todos los stocks que cumplen el filtro, en el orden
+// indicado y sin paginación, y llama a fn con cada uno.
+func (r *MemoryStockRepository) StreamStocks(ctx context.Context, filter models.StockFilter, fn func(models.Stock) error) error {
+	orderBy, sortOrder, err := stockOrder(filter)
+	if err != nil {
+		return err
+	}
+
+	// Se copia el listado para no llamar a fn con el mutex tomado
+	r.mu.RLock()
+	stocks := r.filterStocks(filter, orderBy, sortOrder)
+	r.mu.RUnlock()
+
+	for _, stock := range stocks {
+		if err := fn(stock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountStocks cuenta el total de stocks que cumplen el filtro.
+func (r *MemoryStockRepository) CountStocks(ctx context.Context, filter models.StockFilter) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.filterStocks(filter, "ticker", "ASC")), nil
+}
+
+// GetStockByTicker obtiene un stock por su ticker.
+func (r *MemoryStockRepository) GetStockByTicker(ctx context.Context, ticker string) (models.Stock, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stock, ok := r.stocks[ticker]
+	if !ok {
+		return models.Stock{}, logging.Errorf(ctx, "%w: %s", ErrStockNotFound, ticker)
+	}
+	return r.withCompany(stock), nil
+}
+
+// GetStockByTickerAsOf obtiene la última actualización de un stock registrada
+// hasta el instante indicado.
+func (r *MemoryStockRepository) GetStockByTickerAsOf(ctx context.Context, ticker string, asOf time.Time) (models.Stock, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *models.StockEvent
+	for i, event := range r.events {
+		if event.Ticker != ticker || !r.registeredBy(event, asOf) {
+			continue
+		}
+		if latest == nil || r.newerEvent(event, *latest) {
+			latest = &r.events[i]
+		}
+	}
+	if latest == nil {
+		return models.Stock{}, logging.Errorf(ctx, "%w: %s", ErrStockNotFound, ticker)
+	}
+	return r.withCompany(latest.Stock), nil
+}
+
+// GetStocksByDateRange recupera stocks en un rango de fechas específico.
+func (r *MemoryStockRepository) GetStocksByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Stock, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stocks []models.Stock
+	for _, stock := range r.stocks {
+		if !stock.Time.Before(startDate) && !stock.Time.After(endDate) {
+			stocks = append(stocks, r.withCompany(stock))
+		}
+	}
+	sort.SliceStable(stocks, func(i, j int) bool {
+		return stocks[i].Time.After(stocks[j].Time)
+	})
+	return stocks, nil
+}
+
+// GetStockEventsByDateRange recupera el historial de eventos de stocks en un
+// rango de fechas, ordenado cronológicamente.
+func (r *MemoryStockRepository) GetStockEventsByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Stock, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stocks []models.Stock
+	for _, event := range r.sortedEvents(false) {
+		if !event.Time.Before(startDate) && !event.Time.After(endDate) {
+			stocks = append(stocks, r.withCompany(event.Stock))
+		}
+	}
+	return stocks, nil
+}
+
+// GetStockEventsByDateRangeAsOf recupera los eventos entre dos fechas que ya
+// estaban registrados en asOf, en orden cronológico.
+func (r *MemoryStockRepository) GetStockEventsByDateRangeAsOf(ctx context.Context, startDate, endDate, asOf time.Time) ([]models.Stock, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stocks []models.Stock
+	for _, event := range r.sortedEvents(false) {
+		if !event.Time.Before(startDate) && !event.Time.After(endDate) && r.registeredBy(event, asOf) {
+			stocks = append(stocks, r.withCompany(event.Stock))
+		}
+	}
+	return stocks, nil
+}
+
+// sortedEvents devuelve una copia del historial ordenada por fecha e
+// identificador, del más reciente al más antiguo si newestFirst.
+func (r *MemoryStockRepository) sortedEvents(newestFirst bool) []models.StockEvent {
+	events := make([]models.StockEvent, len(r.events))
+	copy(events, r.events)
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if newestFirst {
+			a, b = b, a
+		}
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		return a.ID < b.ID
+	})
+	return events
+}
+
+// selectEvents devuelve los eventos que cumplen match, del más reciente al más
+// antiguo y con los datos de su compañía. Debe llamarse con el mutex tomado.
+func (r *MemoryStockRepository) selectEvents(match func(models.StockEvent) bool) []models.StockEvent {
+	events := []models.StockEvent{}
+	for _, event := range r.sortedEvents(true) {
+		if match(event) {
+			events = append(events, r.withCompanyEvent(event))
+		}
+	}
+	return events
+}
+
+// GetTickerHistory recupera el historial de calificaciones de un ticker, de la
+// más reciente a la más antigua, con paginación.
+func (r *MemoryStockRepository) GetTickerHistory(ctx context.Context, ticker string, offset, limit int) ([]models.StockEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := page(r.selectEvents(func(event models.StockEvent) bool {
+		return event.Ticker == ticker
+	}), offset, limit)
+	if events == nil {
+		events = []models.StockEvent{}
+	}
+	return events, nil
+}
+
+// CountTickerHistory cuenta las calificaciones del historial de un ticker.
+func (r *MemoryStockRepository) CountTickerHistory(ctx context.Context, ticker string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, event := range r.events {
+		if event.Ticker == ticker {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// StreamTickerHistory recorre todo el historial de calificaciones de un ticker,
+// de la más reciente a la más antigua, y llama a fn con cada una.
+func (r *MemoryStockRepository) StreamTickerHistory(ctx context.Context, ticker string, fn func(models.StockEvent) error) error {
+	r.mu.RLock()
+	events := r.selectEvents(func(event models.StockEvent) bool {
+		return event.Ticker == ticker
+	})
+	r.mu.RUnlock()
+
+	for _, event := range events {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStockEventsAfter recupera los eventos del historial con identificador mayor
+// que afterID, en orden de identificador.
+func (r *MemoryStockRepository) GetStockEventsAfter(ctx context.Context, afterID int64, limit int) ([]models.StockEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Los identificadores se asignan en orden, así que el historial ya está ordenado
+	events := []models.StockEvent{}
+	for _, event := range r.events {
+		if len(events) == limit {
+			break
+		}
+		if event.ID > afterID {
+			events = append(events, r.withCompanyEvent(event))
+		}
+	}
+	return events, nil
+}
+
+// GetStockEventsForTickers recupera los eventos más recientes de un conjunto de
+// tickers desde una fecha, del más reciente al más antiguo.
+func (r *MemoryStockRepository) GetStockEventsForTickers(ctx context.Context, tickers []string, since time.Time, limit int) ([]models.StockEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := make(map[string]bool, len(tickers))
+	for _, ticker := range tickers {
+		set[ticker] = true
+	}
+	events := r.selectEvents(func(event models.StockEvent) bool {
+		return set[event.Ticker] && !event.Time.Before(since)
+	})
+	return events[:min(limit, len(events))], nil
+}
+
+// GetTickersEventsBetween recupera las perTicker calificaciones más recientes
+// de cada ticker indicado entre dos fechas, ambas incluidas, ordenadas por
+// ticker y de la más reciente a la más antigua.
+func (r *MemoryStockRepository) GetTickersEventsBetween(ctx context.Context, tickers []string, startDate, endDate time.Time, perTicker int) ([]models.StockEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sorted := append([]string(nil), tickers...)
+	sort.Strings(sorted)
+	events := []models.StockEvent{}
+	for i, ticker := range sorted {
+		if i > 0 && ticker == sorted[i-1] {
+			continue
+		}
+		matched := r.selectEvents(func(event models.StockEvent) bool {
+			return event.Ticker == ticker && !event.Time.Before(startDate) && !event.Time.After(endDate)
+		})
+		events = append(events, matched[:min(perTicker, len(matched))]...)
+	}
+	return events, nil
+}
+
+// GetRecentEventsByTicker recupera las perTicker calificaciones más recientes de
+// cada ticker indicado.
+func (r *MemoryStockRepository) GetRecentEventsByTicker(ctx context.Context, tickers []string, perTicker int) ([]models.StockEvent, error) {
+	return r.getRecentEventsBy(tickers, perTicker, func(event models.StockEvent) string {
+		return event.Ticker
+	}), nil
+}
+
+// GetRecentEventsByBrokerage recupera las perBrokerage calificaciones más
+// recientes de cada casa de bolsa indicada.
+func (r *MemoryStockRepository) GetRecentEventsByBrokerage(ctx context.Context, brokerages []string, perBrokerage int) ([]models.StockEvent, error) {
+	return r.getRecentEventsBy(brokerages, perBrokerage, func(event models.StockEvent) string {
+		return event.Brokerage
+	}), nil
+}
+
+// getRecentEventsBy recupera las últimas calificaciones de cada valor de la
+// columna que devuelve key, ordenadas por ese valor y luego de la más reciente
+// a la más antigua.
+func (r *MemoryStockRepository) getRecentEventsBy(values []string, perKey int, key func(models.StockEvent) string) []models.StockEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sorted := r.sortedEvents(true)
+	events := []models.StockEvent{}
+	for _, value := range values {
+		count := 0
+		for _, event := range sorted {
+			if count == perKey {
+				break
+			}
+			if key(event) == value {
+				events = append(events, r.withCompanyEvent(event))
+				count++
+			}
+		}
+	}
+	// El orden dentro de cada valor ya es el del historial
+	sort.SliceStable(events, func(i, j int) bool {
+		return key(events[i]) < key(events[j])
+	})
+	return events
+}
+
+// brokerages resume la actividad de las casas de bolsa que cumplen match, de la
+// más activa a la menos activa. Debe llamarse con el mutex tomado.
+func (r *MemoryStockRepository) brokerages(match func(name string) bool) []models.Brokerage {
+	summaries := make(map[string]*models.Brokerage)
+	tickers := make(map[string]map[string]bool)
+	for _, event := range r.events {
+		if !match(event.Brokerage) {
+			continue
+		}
+		summary, ok := summaries[event.Brokerage]
+		if !ok {
+			summary = &models.Brokerage{Name: event.Brokerage}
+			summaries[event.Brokerage] = summary
+			tickers[event.Brokerage] = make(map[string]bool)
+		}
+		summary.RatingCount++
+		tickers[event.Brokerage][event.Ticker] = true
+		if event.Time.After(summary.LastRatingAt) {
+			summary.LastRatingAt = event.Time
+		}
+	}
+
+	brokerages := []models.Brokerage{}
+	for name, summary := range summaries {
+		summary.TickerCount = len(tickers[name])
+		brokerages = append(brokerages, *summary)
+	}
+	sort.Slice(brokerages, func(i, j int) bool {
+		if brokerages[i].RatingCount != brokerages[j].RatingCount {
+			return brokerages[i].RatingCount > brokerages[j].RatingCount
+		}
+		return brokerages[i].Name < brokerages[j].Name
+	})
+	return brokerages
+}
+
+// containsFold indica si name contiene search sin distinguir mayúsculas, como ILIKE.
+func containsFold(name, search string) bool {
+	return strings.Contains(strings.ToLower(name), strings.ToLower(search))
+}
+
+// ListBrokerages devuelve las casas de bolsa cuyo nombre contiene search, de la
+// más activa a la menos activa, con paginación.
+func (r *MemoryStockRepository) ListBrokerages(ctx context.Context, search string, offset, limit int) ([]models.Brokerage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	brokerages := page(r.brokerages(func(name string) bool {
+		return containsFold(name, search)
+	}), offset, limit)
+	if brokerages == nil {
+		brokerages = []models.Brokerage{}
+	}
+	return brokerages, nil
+}
+
+// CountBrokerages cuenta las casas de bolsa cuyo nombre contiene search.
+func (r *MemoryStockRepository) CountBrokerages(ctx context.Context, search string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.brokerages(func(name string) bool {
+		return containsFold(name, search)
+	})), nil
+}
+
+// GetBrokerages devuelve el resumen de las casas de bolsa indicadas. Las que no
+// tienen calificaciones se omiten.
+func (r *MemoryStockRepository) GetBrokerages(ctx context.Context, names []string) ([]models.Brokerage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return r.brokerages(func(name string) bool {
+		return set[name]
+	}), nil
+}
+
+// GetLatestStockEventID obtiene el identificador más alto del historial, o 0 si está vacío.
+func (r *MemoryStockRepository) GetLatestStockEventID(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.nextID, nil
+}
+
+// GetSectorSummaries agrupa por sector la actividad de analistas registrada entre dos fechas.
+func (r *MemoryStockRepository) GetSectorSummaries(ctx context.Context, startDate, endDate time.Time) ([]models.SectorSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	summaries := make(map[string]*models.SectorSummary)
+	tickers := make(map[string]map[string]bool)
+	for _, event := range r.events {
+		if event.Time.Before(startDate) || event.Time.After(endDate) {
+			continue
+		}
+		sector := r.companies[event.Ticker].Sector
+		if sector == "" {
+			sector = models.UnclassifiedSector
+		}
+		summary, ok := summaries[sector]
+		if !ok {
+			summary = &models.SectorSummary{Sector: sector}
+			summaries[sector] = summary
+			tickers[sector] = make(map[string]bool)
+		}
+
+		tickers[sector][event.Ticker] = true
+		summary.Events++
+		action := strings.ToLower(event.Action)
+		switch {
+		case strings.HasPrefix(action, "upgrade"):
+			summary.Upgrades++
+		case strings.HasPrefix(action, "downgrade"):
+			summary.Downgrades++
+		case strings.HasPrefix(action, "target raised"):
+			summary.TargetsRaised++
+		case strings.HasPrefix(action, "target lowered"):
+			summary.TargetsLowered++
+		}
+	}
+
+	var result []models.SectorSummary
+	for sector, summary := range summaries {
+		summary.Tickers = len(tickers[sector])
+		summary.NetUpgrades = summary.Upgrades - summary.Downgrades
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Events != result[j].Events {
+			return result[i].Events > result[j].Events
+		}
+		return result[i].Sector < result[j].Sector
+	})
+	return result, nil
+}
+
+// GetLastSuccessfulSync obtiene el momento en que terminó la última
+// sincronización exitosa. Devuelve nil si no hay ninguna.
+func (r *MemoryStockRepository) GetLastSuccessfulSync(ctx context.Context) (*time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var last *time.Time
+	for _, run := range r.syncRuns {
+		if run.status != "completed" {
+			continue
+		}
+		if last == nil || run.finishedAt.After(*last) {
+			finishedAt := run.finishedAt
+			last = &finishedAt
+		}
+	}
+	return last, nil
+}
+
+// GetNewestEventTime obtiene la fecha del evento más reciente del historial.
+// Devuelve nil si el historial está vacío.
+func (r *MemoryStockRepository) GetNewestEventTime(ctx context.Context) (*time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var newest *time.Time
+	for _, event := range r.events {
+		if newest == nil || event.Time.After(*newest) {
+			eventTime := event.Time
+			newest = &eventTime
+		}
+	}
+	return newest, nil
+}
+
+// GetSchemaVersion obtiene la versión del esquema que registró un servicio.
+// Devuelve 0 si el servicio no ha registrado ninguna.
+func (r *MemoryStockRepository) GetSchemaVersion(ctx context.Context, component string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.schemaVersions[component], nil
+}
+
+// Ping siempre responde; el almacenamiento en memoria no puede desconectarse.
+func (r *MemoryStockRepository) Ping(ctx context.Context) error {
+	return nil
+}
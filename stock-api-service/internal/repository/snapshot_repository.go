@@ -0,0 +1,320 @@
+// Paquete repository proporciona acceso a la capa de persistencia de datos.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// Tipos de snapshot de recomendaciones.
+const (
+	SnapshotKindDaily    = "daily"
+	SnapshotKindOnDemand = "on_demand"
+)
+
+// ErrSnapshotNotFound indica que el snapshot no existe.
+var ErrSnapshotNotFound error = notFound("snapshot no encontrado")
+
+// SnapshotRepository maneja la persistencia de los snapshots de recomendaciones.
+type SnapshotRepository struct {
+	db *sql.DB
+}
+
+// NewSnapshotRepository crea una nueva instancia del repositorio de snapshots.
+func NewSnapshotRepository(db *sql.DB) *SnapshotRepository {
+	return &SnapshotRepository{
+		db: db,
+	}
+}
+
+// InitDB crea las tablas de snapshots si no existen.
+func (r *SnapshotRepository) InitDB(ctx context.Context) error {
+	queries := []string{
+		`
+    CREATE TABLE IF NOT EXISTS recommendation_snapshots (
+        id INT PRIMARY KEY DEFAULT unique_rowid(),
+        kind STRING NOT NULL,
+        snapshot_date DATE NOT NULL,
+        as_of TIMESTAMP NOT NULL,
+        strategy STRING NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT current_timestamp(),
+        INDEX recommendation_snapshots_date_idx (snapshot_date),
+        UNIQUE INDEX recommendation_snapshots_daily_key (snapshot_date) WHERE kind = 'daily'
+    )
+    `,
+		`
+    CREATE TABLE IF NOT EXISTS recommendation_snapshot_items (
+        snapshot_id INT NOT NULL REFERENCES recommendation_snapshots (id) ON DELETE CASCADE,
+        rank INT NOT NULL,
+        ticker STRING NOT NULL,
+        score FLOAT NOT NULL,
+        rationale STRING NOT NULL,
+        potential_return STRING NOT NULL,
+        stock JSONB NOT NULL,
+        PRIMARY KEY (snapshot_id, rank)
+    )
+    `,
+	}
+
+	for _, query := range queries {
+		if _, err := r.db.ExecContext(ctx, query); err != nil {
+			return logging.Errorf(ctx, "error al crear las tablas de snapshots: %w", classify(err))
+		}
+	}
+
+	return nil
+}
+
+// SaveSnapshot guarda un snapshot con sus recomendaciones en una transacción.
+// Para snapshots diarios devuelve false, sin error, si ya existía uno para ese día.
+func (r *SnapshotRepository) SaveSnapshot(ctx context.Context, snapshot *models.RecommendationSnapshot) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, logging.Errorf(ctx, "error al iniciar la transacción: %w", classify(err))
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO recommendation_snapshots (kind, snapshot_date, as_of, strategy)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (snapshot_date) WHERE kind = 'daily' DO NOTHING
+		RETURNING id, created_at
+	`, snapshot.Kind, snapshot.SnapshotDate, snapshot.AsOf, snapshot.Strategy).Scan(&snapshot.ID, &snapshot.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, logging.Errorf(ctx, "error al guardar el snapshot: %w", classify(err))
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO recommendation_snapshot_items (
+			snapshot_id, rank, ticker, score, rationale, potential_return, stock
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`)
+	if err != nil {
+		return false, logging.Errorf(ctx, "error al preparar el statement: %w", classify(err))
+	}
+	defer stmt.Close()
+
+	for _, item := range snapshot.Recommendations {
+		stock, err := json.Marshal(item.Stock)
+		if err != nil {
+			return false, logging.Errorf(ctx, "error al serializar el stock %s: %w", item.Stock.Ticker, classify(err))
+		}
+
+		if _, err := stmt.ExecContext(ctx,
+			snapshot.ID,
+			item.Rank,
+			item.Stock.Ticker,
+			item.Score,
+			item.Rationale,
+			item.PotentialReturn,
+			stock,
+		); err != nil {
+			return false, logging.Errorf(ctx, "error al guardar la recomendación %s: %w", item.Stock.Ticker, classify(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, logging.Errorf(ctx, "error al confirmar la transacción: %w", classify(err))
+	}
+
+	return true, nil
+}
+
+// ListSnapshots recupera los snapshots más recientes, sin sus recomendaciones.
+// Si se indica una fecha, solo devuelve los snapshots de ese día.
+func (r *SnapshotRepository) ListSnapshots(ctx context.Context, date *time.Time, limit int) ([]models.RecommendationSnapshot, error) {
+	query := `
+		SELECT id, kind, snapshot_date, as_of, strategy, created_at
+		FROM recommendation_snapshots
+		WHERE ($1::DATE IS NULL OR snapshot_date = $1::DATE)
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	var dateArg interface{}
+	if date != nil {
+		dateArg = date.Format("2006-01-02")
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, dateArg, limit)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar snapshots: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var snapshots []models.RecommendationSnapshot
+	for rows.Next() {
+		var snapshot models.RecommendationSnapshot
+		if err := rows.Scan(
+			&snapshot.ID,
+			&snapshot.Kind,
+			&snapshot.SnapshotDate,
+			&snapshot.AsOf,
+			&snapshot.Strategy,
+			&snapshot.CreatedAt,
+		); err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear snapshot: %w", classify(err))
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logging.Errorf(ctx, "error al iterar snapshots: %w", classify(err))
+	}
+
+	return snapshots, nil
+}
+
+// ListSnapshotsAfter recupera los snapshots con identificador mayor que afterID,
+// sin sus recomendaciones y en orden de identificador.
+func (r *SnapshotRepository) ListSnapshotsAfter(ctx context.Context, afterID int64, limit int) ([]models.RecommendationSnapshot, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, kind, snapshot_date, as_of, strategy, created_at
+		FROM recommendation_snapshots
+		WHERE id > $1
+		ORDER BY id ASC
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar snapshots nuevos: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var snapshots []models.RecommendationSnapshot
+	for rows.Next() {
+		var snapshot models.RecommendationSnapshot
+		if err := rows.Scan(
+			&snapshot.ID,
+			&snapshot.Kind,
+			&snapshot.SnapshotDate,
+			&snapshot.AsOf,
+			&snapshot.Strategy,
+			&snapshot.CreatedAt,
+		); err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear snapshot: %w", classify(err))
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logging.Errorf(ctx, "error al iterar snapshots: %w", classify(err))
+	}
+
+	return snapshots, nil
+}
+
+// GetLatestSnapshotID obtiene el identificador más alto de los snapshots, o 0 si no hay ninguno.
+func (r *SnapshotRepository) GetLatestSnapshotID(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(id), 0) FROM recommendation_snapshots
+	`).Scan(&id)
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al consultar el último snapshot: %w", classify(err))
+	}
+	return id, nil
+}
+
+// GetSnapshot obtiene un snapshot con sus recomendaciones.
+func (r *SnapshotRepository) GetSnapshot(ctx context.Context, id int64) (models.RecommendationSnapshot, error) {
+	return r.getSnapshot(ctx, `
+		SELECT id, kind, snapshot_date, as_of, strategy, created_at
+		FROM recommendation_snapshots
+		WHERE id = $1
+	`, id)
+}
+
+// GetLatestSnapshot obtiene el snapshot más reciente del tipo indicado.
+// Si kind está vacío se considera cualquier tipo.
+func (r *SnapshotRepository) GetLatestSnapshot(ctx context.Context, kind string) (models.RecommendationSnapshot, error) {
+	return r.getSnapshot(ctx, `
+		SELECT id, kind, snapshot_date, as_of, strategy, created_at
+		FROM recommendation_snapshots
+		WHERE $1 = '' OR kind = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, kind)
+}
+
+// GetPreviousSnapshot obtiene el snapshot del tipo indicado inmediatamente anterior a otro.
+func (r *SnapshotRepository) GetPreviousSnapshot(ctx context.Context, snapshot models.RecommendationSnapshot, kind string) (models.RecommendationSnapshot, error) {
+	return r.getSnapshot(ctx, `
+		SELECT id, kind, snapshot_date, as_of, strategy, created_at
+		FROM recommendation_snapshots
+		WHERE created_at < $1 AND ($2 = '' OR kind = $2)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, snapshot.CreatedAt, kind)
+}
+
+// HasDailySnapshot indica si ya existe el snapshot diario de una fecha.
+func (r *SnapshotRepository) HasDailySnapshot(ctx context.Context, date time.Time) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM recommendation_snapshots
+			WHERE kind = 'daily' AND snapshot_date = $1::DATE
+		)
+	`, date.Format("2006-01-02")).Scan(&exists)
+	if err != nil {
+		return false, logging.Errorf(ctx, "error al verificar el snapshot diario: %w", classify(err))
+	}
+	return exists, nil
+}
+
+// getSnapshot ejecuta una consulta que devuelve la cabecera de un snapshot y carga sus recomendaciones.
+func (r *SnapshotRepository) getSnapshot(ctx context.Context, query string, args ...interface{}) (models.RecommendationSnapshot, error) {
+	var snapshot models.RecommendationSnapshot
+
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&snapshot.ID,
+		&snapshot.Kind,
+		&snapshot.SnapshotDate,
+		&snapshot.AsOf,
+		&snapshot.Strategy,
+		&snapshot.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return snapshot, logging.Errorf(ctx, "%w", ErrSnapshotNotFound)
+		}
+		return snapshot, logging.Errorf(ctx, "error al obtener snapshot: %w", classify(err))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rank, score, rationale, potential_return, stock
+		FROM recommendation_snapshot_items
+		WHERE snapshot_id = $1
+		ORDER BY rank ASC
+	`, snapshot.ID)
+	if err != nil {
+		return snapshot, logging.Errorf(ctx, "error al consultar recomendaciones del snapshot: %w", classify(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.RankedRecommendation
+		var stock []byte
+		if err := rows.Scan(&item.Rank, &item.Score, &item.Rationale, &item.PotentialReturn, &stock); err != nil {
+			return snapshot, logging.Errorf(ctx, "error al escanear recomendación: %w", classify(err))
+		}
+		if err := json.Unmarshal(stock, &item.Stock); err != nil {
+			return snapshot, logging.Errorf(ctx, "error al decodificar el stock de la recomendación: %w", classify(err))
+		}
+		snapshot.Recommendations = append(snapshot.Recommendations, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return snapshot, logging.Errorf(ctx, "error al iterar recomendaciones del snapshot: %w", classify(err))
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,652 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// SQLiteStockRepository implementa StockRepository sobre SQLite, para probar
+// las consultas sin un clúster de CockroachDB. Lee el esquema que crea
+// SQLiteStockRepository.InitDB en stock-data-service, con las fechas guardadas
+// como texto en UTC. No registra ningún driver: quien abre la base de datos debe
+// importar uno que se registre como "sqlite3", por ejemplo
+// github.com/mattn/go-sqlite3.
+type SQLiteStockRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteStockRepository crea una nueva instancia del repositorio de stocks sobre SQLite.
+func NewSQLiteStockRepository(db *sql.DB) *SQLiteStockRepository {
+	return &SQLiteStockRepository{
+		db: db,
+	}
+}
+
+// sqliteLatestEvents es la última actualización de cada ticker registrada hasta
+// un instante, equivalente al DISTINCT ON de buildStockQuery. julianday compara
+// created_at aunque CURRENT_TIMESTAMP y el driver lo guarden con formatos
+// distintos.
+const sqliteLatestEvents = `(
+			SELECT` + eventColumns + `
+			FROM (
+				SELECT *, ROW_NUMBER() OVER (
+					PARTITION BY ticker ORDER BY julianday(created_at) DESC, time DESC, id DESC
+				) AS rn
+				FROM stock_events
+				WHERE julianday(created_at) <= julianday(?)
+			)
+			WHERE rn = 1
+		) AS s`
+
+// buildSQLiteStockQuery construye las cláusulas FROM y WHERE de un filtro de
+// stocks con la sintaxis de SQLite. LIKE no distingue mayúsculas en texto ASCII,
+// como ILIKE.
+func buildSQLiteStockQuery(filter models.StockFilter) (string, []interface{}) {
+	var args []interface{}
+	var conditions []string
+
+	source := "stocks s"
+	if filter.AsOf != nil {
+		args = append(args, filter.AsOf.UTC())
+		source = sqliteLatestEvents
+	}
+
+	if filter.Ticker != "" {
+		args = append(args, "%"+filter.Ticker+"%")
+		conditions = append(conditions, "s.ticker LIKE ?")
+	}
+	if filter.Brokerage != "" {
+		args = append(args, filter.Brokerage)
+		conditions = append(conditions, "s.brokerage = ?")
+	}
+	if filter.Rating != "" {
+		args = append(args, filter.Rating, filter.Rating)
+		conditions = append(conditions, "(s.rating_from = ? OR s.rating_to = ?)")
+	}
+	if filter.Sector != "" {
+		args = append(args, filter.Sector)
+		conditions = append(conditions, "lower(c.sector) = lower(?)")
+	}
+	if filter.Industry != "" {
+		args = append(args, filter.Industry)
+		conditions = append(conditions, "lower(c.industry) = lower(?)")
+	}
+
+	query := "FROM " + source + " " + companyJoin
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return query, args
+}
+
+// sqliteIn devuelve los marcadores de una lista IN y sus argumentos.
+func sqliteIn(values []string) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")", args
+}
+
+// GetStocks recupera stocks que cumplen el filtro, con paginación y ordenamiento.
+func (r *SQLiteStockRepository) GetStocks(ctx context.Context, filter models.StockFilter, offset, limit int) ([]models.Stock, error) {
+	orderBy, sortOrder, err := stockOrder(filter)
+	if err != nil {
+		return nil, err
+	}
+	from, args := buildSQLiteStockQuery(filter)
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		%s
+		ORDER BY s.%s %s, s.ticker ASC
+		LIMIT ? OFFSET ?
+	`, stockColumns, from, orderBy, sortOrder)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar stocks: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanStocks(ctx, sqliteRows{rows})
+}
+
+// StreamStocks recorre todos los stocks que cumplen el filtro, en el orden
+// indicado y sin paginación, y llama a fn con cada uno.
+func (r *SQLiteStockRepository) StreamStocks(ctx context.Context, filter models.StockFilter, fn func(models.Stock) error) error {
+	orderBy, sortOrder, err := stockOrder(filter)
+	if err != nil {
+		return err
+	}
+	from, args := buildSQLiteStockQuery(filter)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		%s
+		ORDER BY s.%s %s, s.ticker ASC
+	`, stockColumns, from, orderBy, sortOrder)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return logging.Errorf(ctx, "error al consultar stocks: %w", classify(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		stock, err := scanStock(sqliteRows{rows})
+		if err != nil {
+			return logging.Errorf(ctx, "error al escanear stock: %w", classify(err))
+		}
+		if err := fn(stock); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return logging.Errorf(ctx, "error al iterar stocks: %w", classify(err))
+	}
+	return nil
+}
+
+// CountStocks cuenta el total de stocks que cumplen el filtro.
+func (r *SQLiteStockRepository) CountStocks(ctx context.Context, filter models.StockFilter) (int, error) {
+	from, args := buildSQLiteStockQuery(filter)
+
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) "+from, args...).Scan(&count)
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al contar stocks: %w", classify(err))
+	}
+	return count, nil
+}
+
+// GetStockByTicker obtiene un stock por su ticker.
+func (r *SQLiteStockRepository) GetStockByTicker(ctx context.Context, ticker string) (models.Stock, error) {
+	query := `
+	SELECT ` + stockColumns + `
+	FROM stocks s ` + companyJoin + `
+	WHERE s.ticker = ?
+	`
+
+	return r.getStock(ctx, ticker, query, ticker)
+}
+
+// GetStockByTickerAsOf obtiene la última actualización de un stock registrada
+// hasta el instante indicado.
+func (r *SQLiteStockRepository) GetStockByTickerAsOf(ctx context.Context, ticker string, asOf time.Time) (models.Stock, error) {
+	query := `
+	SELECT ` + stockColumns + `
+	FROM stock_events s ` + companyJoin + `
+	WHERE s.ticker = ? AND julianday(s.created_at) <= julianday(?)
+	ORDER BY julianday(s.created_at) DESC, s.time DESC, s.id DESC
+	LIMIT 1
+	`
+
+	return r.getStock(ctx, ticker, query, ticker, asOf.UTC())
+}
+
+// getStock obtiene un solo stock o devuelve ErrStockNotFound.
+func (r *SQLiteStockRepository) getStock(ctx context.Context, ticker, query string, args ...interface{}) (models.Stock, error) {
+	stock, err := scanStock(sqliteRow{r.db.QueryRowContext(ctx, query, args...)})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return stock, logging.Errorf(ctx, "%w: %s", ErrStockNotFound, ticker)
+		}
+		return stock, logging.Errorf(ctx, "error al obtener stock: %w", classify(err))
+	}
+	return stock, nil
+}
+
+// GetStocksByDateRange recupera stocks en un rango de fechas específico.
+func (r *SQLiteStockRepository) GetStocksByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Stock, error) {
+	query := `
+		SELECT ` + stockColumns + `
+		FROM stocks s ` + companyJoin + `
+		WHERE s.time BETWEEN ? AND ?
+		ORDER BY s.time DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, startDate.UTC(), endDate.UTC())
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar stocks por rango de fechas: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanStocks(ctx, sqliteRows{rows})
+}
+
+// GetStockEventsByDateRange recupera el historial de eventos de stocks en un
+// rango de fechas, ordenado cronológicamente.
+func (r *SQLiteStockRepository) GetStockEventsByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Stock, error) {
+	query := `
+		SELECT ` + stockColumns + `
+		FROM stock_events s ` + companyJoin + `
+		WHERE s.time BETWEEN ? AND ?
+		ORDER BY s.time ASC, s.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, startDate.UTC(), endDate.UTC())
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar eventos por rango de fechas: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanStocks(ctx, sqliteRows{rows})
+}
+
+// GetStockEventsByDateRangeAsOf recupera los eventos entre dos fechas que ya
+// estaban registrados en asOf, en orden cronológico.
+func (r *SQLiteStockRepository) GetStockEventsByDateRangeAsOf(ctx context.Context, startDate, endDate, asOf time.Time) ([]models.Stock, error) {
+	query := `
+		SELECT ` + stockColumns + `
+		FROM stock_events s ` + companyJoin + `
+		WHERE s.time BETWEEN ? AND ? AND julianday(s.created_at) <= julianday(?)
+		ORDER BY s.time ASC, s.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, startDate.UTC(), endDate.UTC(), asOf.UTC())
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar eventos por rango de fechas: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanStocks(ctx, sqliteRows{rows})
+}
+
+// queryEvents ejecuta una consulta con el identificador del evento seguido de stockColumns.
+func (r *SQLiteStockRepository) queryEvents(ctx context.Context, description, query string, args ...interface{}) ([]models.StockEvent, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar %s: %w", description, classify(err))
+	}
+	defer rows.Close()
+
+	return scanStockEvents(ctx, sqliteRows{rows})
+}
+
+// GetTickerHistory recupera el historial de calificaciones de un ticker, de la
+// más reciente a la más antigua, con paginación.
+func (r *SQLiteStockRepository) GetTickerHistory(ctx context.Context, ticker string, offset, limit int) ([]models.StockEvent, error) {
+	return r.queryEvents(ctx, "el historial del ticker", `
+		SELECT s.id, `+stockColumns+`
+		FROM stock_events s `+companyJoin+`
+		WHERE s.ticker = ?
+		ORDER BY s.time DESC, s.id DESC
+		LIMIT ? OFFSET ?
+	`, ticker, limit, offset)
+}
+
+// CountTickerHistory cuenta las calificaciones del historial de un ticker.
+func (r *SQLiteStockRepository) CountTickerHistory(ctx context.Context, ticker string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM stock_events WHERE ticker = ?
+	`, ticker).Scan(&count)
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al contar el historial del ticker: %w", classify(err))
+	}
+	return count, nil
+}
+
+// StreamTickerHistory recorre todo el historial de calificaciones de un ticker,
+// de la más reciente a la más antigua, y llama a fn con cada una.
+func (r *SQLiteStockRepository) StreamTickerHistory(ctx context.Context, ticker string, fn func(models.StockEvent) error) error {
+	query := `
+		SELECT s.id, ` + stockColumns + `
+		FROM stock_events s ` + companyJoin + `
+		WHERE s.ticker = ?
+		ORDER BY s.time DESC, s.id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ticker)
+	if err != nil {
+		return logging.Errorf(ctx, "error al consultar el historial del ticker: %w", classify(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.StockEvent
+		stock, err := scanStock(prefixScanner{row: sqliteRows{rows}, prefix: []interface{}{&event.ID}})
+		if err != nil {
+			return logging.Errorf(ctx, "error al escanear evento: %w", classify(err))
+		}
+		event.Stock = stock
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return logging.Errorf(ctx, "error al iterar eventos: %w", classify(err))
+	}
+	return nil
+}
+
+// GetStockEventsAfter recupera los eventos del historial con identificador mayor
+// que afterID, en orden de identificador.
+func (r *SQLiteStockRepository) GetStockEventsAfter(ctx context.Context, afterID int64, limit int) ([]models.StockEvent, error) {
+	return r.queryEvents(ctx, "eventos nuevos", `
+		SELECT s.id, `+stockColumns+`
+		FROM stock_events s `+companyJoin+`
+		WHERE s.id > ?
+		ORDER BY s.id ASC
+		LIMIT ?
+	`, afterID, limit)
+}
+
+// GetStockEventsForTickers recupera los eventos más recientes de un conjunto de
+// tickers desde una fecha, del más reciente al más antiguo.
+func (r *SQLiteStockRepository) GetStockEventsForTickers(ctx context.Context, tickers []string, since time.Time, limit int) ([]models.StockEvent, error) {
+	in, args := sqliteIn(tickers)
+	return r.queryEvents(ctx, "eventos de los tickers", `
+		SELECT s.id, `+stockColumns+`
+		FROM stock_events s `+companyJoin+`
+		WHERE s.ticker IN `+in+` AND s.time >= ?
+		ORDER BY s.time DESC, s.id DESC
+		LIMIT ?
+	`, append(args, since.UTC(), limit)...)
+}
+
+// GetTickersEventsBetween recupera con una sola consulta las perTicker
+// calificaciones más recientes de cada ticker indicado entre dos fechas, ambas
+// incluidas.
+func (r *SQLiteStockRepository) GetTickersEventsBetween(ctx context.Context, tickers []string, startDate, endDate time.Time, perTicker int) ([]models.StockEvent, error) {
+	in, args := sqliteIn(tickers)
+	return r.queryEvents(ctx, "eventos de los tickers", `
+		SELECT s.id, `+stockColumns+`
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY ticker ORDER BY time DESC, id DESC) AS rn
+			FROM stock_events
+			WHERE ticker IN `+in+` AND time >= ? AND time <= ?
+		) s `+companyJoin+`
+		WHERE s.rn <= ?
+		ORDER BY s.ticker, s.time DESC, s.id DESC
+	`, append(args, startDate.UTC(), endDate.UTC(), perTicker)...)
+}
+
+// GetRecentEventsByTicker recupera las perTicker calificaciones más recientes de
+// cada ticker indicado con una sola consulta.
+func (r *SQLiteStockRepository) GetRecentEventsByTicker(ctx context.Context, tickers []string, perTicker int) ([]models.StockEvent, error) {
+	return r.getRecentEventsBy(ctx, "ticker", tickers, perTicker)
+}
+
+// GetRecentEventsByBrokerage recupera las perBrokerage calificaciones más
+// recientes de cada casa de bolsa indicada con una sola consulta.
+func (r *SQLiteStockRepository) GetRecentEventsByBrokerage(ctx context.Context, brokerages []string, perBrokerage int) ([]models.StockEvent, error) {
+	return r.getRecentEventsBy(ctx, "brokerage", brokerages, perBrokerage)
+}
+
+// getRecentEventsBy recupera las últimas calificaciones de cada valor de una
+// columna de stock_events con una ventana en lugar de LATERAL. column debe ser
+// una constante, nunca un valor del usuario.
+func (r *SQLiteStockRepository) getRecentEventsBy(ctx context.Context, column string, values []string, perKey int) ([]models.StockEvent, error) {
+	in, args := sqliteIn(values)
+	return r.queryEvents(ctx, "eventos recientes por "+column, `
+		SELECT s.id, `+stockColumns+`
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY `+column+` ORDER BY time DESC, id DESC) AS rn
+			FROM stock_events
+			WHERE `+column+` IN `+in+`
+		) s `+companyJoin+`
+		WHERE s.rn <= ?
+		ORDER BY s.`+column+`, s.time DESC, s.id DESC
+	`, append(args, perKey)...)
+}
+
+// queryBrokerages ejecuta brokerageQuery con las condiciones indicadas.
+func (r *SQLiteStockRepository) queryBrokerages(ctx context.Context, conditions string, args ...interface{}) ([]models.Brokerage, error) {
+	rows, err := r.db.QueryContext(ctx, brokerageQuery+conditions, args...)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar casas de bolsa: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanBrokerages(ctx, sqliteRows{rows})
+}
+
+// ListBrokerages devuelve las casas de bolsa cuyo nombre contiene search, de la
+// más activa a la menos activa, con paginación.
+func (r *SQLiteStockRepository) ListBrokerages(ctx context.Context, search string, offset, limit int) ([]models.Brokerage, error) {
+	return r.queryBrokerages(ctx, `
+		WHERE brokerage LIKE ?
+		GROUP BY brokerage
+		ORDER BY COUNT(*) DESC, brokerage ASC
+		LIMIT ? OFFSET ?
+	`, "%"+search+"%", limit, offset)
+}
+
+// CountBrokerages cuenta las casas de bolsa cuyo nombre contiene search.
+func (r *SQLiteStockRepository) CountBrokerages(ctx context.Context, search string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT brokerage) FROM stock_events WHERE brokerage LIKE ?
+	`, "%"+search+"%").Scan(&count)
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al contar casas de bolsa: %w", classify(err))
+	}
+	return count, nil
+}
+
+// GetBrokerages devuelve el resumen de las casas de bolsa indicadas con una sola
+// consulta. Las que no tienen calificaciones se omiten.
+func (r *SQLiteStockRepository) GetBrokerages(ctx context.Context, names []string) ([]models.Brokerage, error) {
+	in, args := sqliteIn(names)
+	return r.queryBrokerages(ctx, `
+		WHERE brokerage IN `+in+`
+		GROUP BY brokerage
+	`, args...)
+}
+
+// GetLatestStockEventID obtiene el identificador más alto del historial, o 0 si está vacío.
+func (r *SQLiteStockRepository) GetLatestStockEventID(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(id), 0) FROM stock_events
+	`).Scan(&id)
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al consultar el último evento: %w", classify(err))
+	}
+	return id, nil
+}
+
+// GetSectorSummaries agrupa por sector la actividad de analistas registrada entre dos fechas.
+func (r *SQLiteStockRepository) GetSectorSummaries(ctx context.Context, startDate, endDate time.Time) ([]models.SectorSummary, error) {
+	query := `
+		SELECT
+			COALESCE(NULLIF(c.sector, ''), ?) AS sector,
+			COUNT(DISTINCT s.ticker),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE s.action LIKE 'upgrade%'),
+			COUNT(*) FILTER (WHERE s.action LIKE 'downgrade%'),
+			COUNT(*) FILTER (WHERE s.action LIKE 'target raised%'),
+			COUNT(*) FILTER (WHERE s.action LIKE 'target lowered%')
+		FROM stock_events s ` + companyJoin + `
+		WHERE s.time BETWEEN ? AND ?
+		GROUP BY 1
+		ORDER BY 3 DESC, 1 ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, models.UnclassifiedSector, startDate.UTC(), endDate.UTC())
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar el resumen por sector: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var summaries []models.SectorSummary
+	for rows.Next() {
+		var summary models.SectorSummary
+		if err := rows.Scan(
+			&summary.Sector,
+			&summary.Tickers,
+			&summary.Events,
+			&summary.Upgrades,
+			&summary.Downgrades,
+			&summary.TargetsRaised,
+			&summary.TargetsLowered,
+		); err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear el resumen por sector: %w", classify(err))
+		}
+		summary.NetUpgrades = summary.Upgrades - summary.Downgrades
+		summaries = append(summaries, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logging.Errorf(ctx, "error al iterar el resumen por sector: %w", classify(err))
+	}
+
+	return summaries, nil
+}
+
+// GetLastSuccessfulSync obtiene el momento en que terminó la última
+// sincronización exitosa. Devuelve nil si no hay ninguna.
+func (r *SQLiteStockRepository) GetLastSuccessfulSync(ctx context.Context) (*time.Time, error) {
+	var last sqliteTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT MAX(finished_at) FROM sync_runs WHERE status = 'completed'
+	`).Scan(&last)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar la última sincronización: %w", classify(err))
+	}
+	return last.ptr(), nil
+}
+
+// GetNewestEventTime obtiene la fecha del evento más reciente del historial.
+// Devuelve nil si el historial está vacío.
+func (r *SQLiteStockRepository) GetNewestEventTime(ctx context.Context) (*time.Time, error) {
+	var newest sqliteTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT MAX(time) FROM stock_events
+	`).Scan(&newest)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar el evento más reciente: %w", classify(err))
+	}
+	return newest.ptr(), nil
+}
+
+// GetSchemaVersion obtiene la versión del esquema que registró un servicio en la
+// tabla schema_versions. Devuelve 0 si el servicio no ha registrado ninguna.
+func (r *SQLiteStockRepository) GetSchemaVersion(ctx context.Context, component string) (int, error) {
+	var version int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT version FROM schema_versions WHERE component = ?
+	`, component).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al consultar la versión del esquema: %w", classify(err))
+	}
+	return version, nil
+}
+
+// Ping verifica la conexión a la base de datos.
+func (r *SQLiteStockRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// sqliteTimeFormats son los formatos en que los drivers de SQLite guardan las
+// fechas, más el de CURRENT_TIMESTAMP.
+var sqliteTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	time.RFC3339Nano,
+}
+
+// sqliteTime escanea una fecha de SQLite. El driver solo convierte a time.Time
+// las columnas declaradas como TIMESTAMP; el resultado de funciones como MAX o
+// de columnas de subconsultas puede llegar como texto.
+type sqliteTime struct {
+	time  time.Time
+	valid bool
+}
+
+// Scan implementa sql.Scanner.
+func (t *sqliteTime) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		t.valid = false
+		return nil
+	case time.Time:
+		t.time, t.valid = v.UTC(), true
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("no se puede convertir %T en una fecha", value)
+	}
+
+	for _, format := range sqliteTimeFormats {
+		if parsed, err := time.Parse(format, text); err == nil {
+			t.time, t.valid = parsed.UTC(), true
+			return nil
+		}
+	}
+	return fmt.Errorf("fecha inválida: %q", text)
+}
+
+// ptr devuelve la fecha, o nil si era NULL.
+func (t sqliteTime) ptr() *time.Time {
+	if !t.valid {
+		return nil
+	}
+	return &t.time
+}
+
+// scanSQLite escanea una fila reemplazando los destinos *time.Time por
+// sqliteTime, para que las fechas guardadas como texto se lean igual que en
+// CockroachDB.
+func scanSQLite(row rowScanner, dest []interface{}) error {
+	times := make(map[int]*sqliteTime)
+	args := make([]interface{}, len(dest))
+	for i, d := range dest {
+		if _, ok := d.(*time.Time); ok {
+			times[i] = &sqliteTime{}
+			args[i] = times[i]
+			continue
+		}
+		args[i] = d
+	}
+
+	if err := row.Scan(args...); err != nil {
+		return err
+	}
+	for i, t := range times {
+		*dest[i].(*time.Time) = t.time
+	}
+	return nil
+}
+
+// sqliteRows adapta *sql.Rows a las funciones de escaneo con scanSQLite.
+type sqliteRows struct {
+	*sql.Rows
+}
+
+// Scan implementa rowScanner.
+func (r sqliteRows) Scan(dest ...interface{}) error {
+	return scanSQLite(r.Rows, dest)
+}
+
+// sqliteRow adapta *sql.Row a las funciones de escaneo con scanSQLite.
+type sqliteRow struct {
+	row *sql.Row
+}
+
+// Scan implementa rowScanner.
+func (r sqliteRow) Scan(dest ...interface{}) error {
+	return scanSQLite(r.row, dest)
+}
@@ -5,266 +5,865 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/logging"
 	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/tracing"
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// StockRepository maneja las operaciones de base de datos para los stocks.
-type StockRepository struct {
+// ErrStockNotFound indica que el ticker no tiene stock, o no lo tenía en el instante indicado.
+var ErrStockNotFound error = notFound("stock no encontrado")
+
+// StockRepository consulta los stocks, su historial de calificaciones y los
+// datos que stock-data-service registra al sincronizar. La implementación de
+// producción, y la única con la que arranca el servicio, es
+// CockroachStockRepository; MemoryStockRepository y SQLiteStockRepository
+// sirven para pruebas, y todas cumplen las pruebas de conformance_test.go.
+type StockRepository interface {
+	// GetStocks recupera stocks que cumplen el filtro, con paginación y ordenamiento.
+	GetStocks(ctx context.Context, filter models.StockFilter, offset, limit int) ([]models.Stock, error)
+	// StreamStocks recorre los stocks que cumplen el filtro sin paginación y
+	// llama a fn con cada uno; se detiene con el primer error de fn.
+	StreamStocks(ctx context.Context, filter models.StockFilter, fn func(models.Stock) error) error
+	// CountStocks cuenta el total de stocks que cumplen el filtro.
+	CountStocks(ctx context.Context, filter models.StockFilter) (int, error)
+	// GetStockByTicker obtiene un stock por su ticker o devuelve ErrStockNotFound.
+	GetStockByTicker(ctx context.Context, ticker string) (models.Stock, error)
+	// GetStockByTickerAsOf obtiene la última actualización de un stock registrada
+	// hasta asOf o devuelve ErrStockNotFound.
+	GetStockByTickerAsOf(ctx context.Context, ticker string, asOf time.Time) (models.Stock, error)
+	// GetStocksByDateRange recupera los stocks actualizados entre dos fechas, del más reciente al más antiguo.
+	GetStocksByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Stock, error)
+	// GetStockEventsByDateRange recupera los eventos entre dos fechas en orden cronológico.
+	GetStockEventsByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Stock, error)
+	// GetStockEventsByDateRangeAsOf recupera los eventos entre dos fechas que ya
+	// estaban registrados en asOf, en orden cronológico.
+	GetStockEventsByDateRangeAsOf(ctx context.Context, startDate, endDate, asOf time.Time) ([]models.Stock, error)
+	// GetTickerHistory recupera el historial de un ticker, del más reciente al más antiguo.
+	GetTickerHistory(ctx context.Context, ticker string, offset, limit int) ([]models.StockEvent, error)
+	// CountTickerHistory cuenta las calificaciones del historial de un ticker.
+	CountTickerHistory(ctx context.Context, ticker string) (int, error)
+	// StreamTickerHistory recorre todo el historial de un ticker y llama a fn con cada evento.
+	StreamTickerHistory(ctx context.Context, ticker string, fn func(models.StockEvent) error) error
+	// GetStockEventsAfter recupera los eventos con identificador mayor que afterID, en orden de identificador.
+	GetStockEventsAfter(ctx context.Context, afterID int64, limit int) ([]models.StockEvent, error)
+	// GetStockEventsForTickers recupera los eventos recientes de varios tickers desde una fecha.
+	GetStockEventsForTickers(ctx context.Context, tickers []string, since time.Time, limit int) ([]models.StockEvent, error)
+	// GetTickersEventsBetween recupera los eventos de varios tickers entre dos fechas, ambas incluidas.
+	GetTickersEventsBetween(ctx context.Context, tickers []string, startDate, endDate time.Time, perTicker int) ([]models.StockEvent, error)
+	// GetRecentEventsByTicker recupera las perTicker calificaciones más recientes de cada ticker.
+	GetRecentEventsByTicker(ctx context.Context, tickers []string, perTicker int) ([]models.StockEvent, error)
+	// GetRecentEventsByBrokerage recupera las perBrokerage calificaciones más recientes de cada casa de bolsa.
+	GetRecentEventsByBrokerage(ctx context.Context, brokerages []string, perBrokerage int) ([]models.StockEvent, error)
+	// ListBrokerages devuelve las casas de bolsa cuyo nombre contiene search, de la más activa a la menos activa.
+	ListBrokerages(ctx context.Context, search string, offset, limit int) ([]models.Brokerage, error)
+	// CountBrokerages cuenta las casas de bolsa cuyo nombre contiene search.
+	CountBrokerages(ctx context.Context, search string) (int, error)
+	// GetBrokerages devuelve el resumen de las casas de bolsa indicadas que tienen calificaciones.
+	GetBrokerages(ctx context.Context, names []string) ([]models.Brokerage, error)
+	// GetLatestStockEventID obtiene el identificador más alto del historial, o 0.
+	GetLatestStockEventID(ctx context.Context) (int64, error)
+	// GetSectorSummaries agrupa por sector la actividad registrada entre dos fechas.
+	GetSectorSummaries(ctx context.Context, startDate, endDate time.Time) ([]models.SectorSummary, error)
+	// GetLastSuccessfulSync obtiene el fin de la última sincronización exitosa, o nil.
+	GetLastSuccessfulSync(ctx context.Context) (*time.Time, error)
+	// GetNewestEventTime obtiene la fecha del evento más reciente, o nil si no hay eventos.
+	GetNewestEventTime(ctx context.Context) (*time.Time, error)
+	// GetSchemaVersion obtiene la versión del esquema registrada por un servicio, o 0.
+	GetSchemaVersion(ctx context.Context, component string) (int, error)
+	// Ping verifica que el almacenamiento responda.
+	Ping(ctx context.Context) error
+}
+
+// CockroachStockRepository implementa StockRepository sobre CockroachDB.
+type CockroachStockRepository struct {
 	db *sql.DB
+	// read atiende los listados y las recomendaciones; es db si no hay pool de lectura
+	read *sql.DB
+	// followerReads lee los listados y las recomendaciones de la réplica más cercana
+	followerReads bool
 }
 
-// NewStockRepository crea una nueva instancia del repositorio de stocks.
-func NewStockRepository(db *sql.DB) *StockRepository {
-	return &StockRepository{
-		db: db,
+// NewCockroachStockRepository crea una nueva instancia del repositorio de stocks sobre CockroachDB.
+func NewCockroachStockRepository(db *sql.DB) *CockroachStockRepository {
+	return &CockroachStockRepository{
+		db:   db,
+		read: db,
 	}
 }
 
-// GetStocks recupera stocks con paginación y ordenamiento.
-func (r *StockRepository) GetStocks(ctx context.Context, orderBy string, sortOrder string, offset, limit int) ([]models.Stock, error) {
-	// Establecer valores predeterminados si no se proporcionan
-	if orderBy == "" {
-		orderBy = "time"
+// WithReadPool devuelve una copia del repositorio que atiende los listados y
+// las recomendaciones con un pool propio, por ejemplo el de un usuario de solo
+// lectura, para que no compitan por conexiones con el resto de las consultas.
+func (r *CockroachStockRepository) WithReadPool(read *sql.DB) *CockroachStockRepository {
+	clone := *r
+	clone.read = read
+	return &clone
+}
+
+// WithFollowerReads devuelve una copia del repositorio que, si enabled es true,
+// lee los listados y las recomendaciones con AS OF SYSTEM TIME
+// follower_read_timestamp(). CockroachDB los atiende desde la réplica más
+// cercana a cambio de unos segundos de retraso, salvo en las solicitudes
+// marcadas con WithStrongConsistency.
+func (r *CockroachStockRepository) WithFollowerReads(enabled bool) *CockroachStockRepository {
+	clone := *r
+	clone.followerReads = enabled
+	return &clone
+}
+
+// reader devuelve el pool y la cláusula AS OF SYSTEM TIME de un listado o de
+// las consultas de las recomendaciones. Con consistencia fuerte se usa el pool
+// principal sin lecturas históricas, por si el de lectura apunta a una réplica.
+func (r *CockroachStockRepository) reader(ctx context.Context) (*sql.DB, string) {
+	if StrongConsistency(ctx) {
+		return r.db, ""
 	}
-	if sortOrder == "" {
-		sortOrder = "DESC"
+	if r.followerReads {
+		return r.read, "AS OF SYSTEM TIME follower_read_timestamp()"
+	}
+	return r.read, ""
+}
+
+// stockColumns son las columnas necesarias para construir un models.Stock. Las
+// consultas usan el alias s para la fuente de stocks y c para la tabla companies.
+const stockColumns = `
+			s.ticker, s.company, s.target_from, s.target_to, 
+			s.action, s.brokerage, s.rating_from, s.rating_to, s.time,
+			COALESCE(c.sector, ''), COALESCE(c.industry, ''),
+			COALESCE(c.exchange, ''), COALESCE(c.market_cap_bucket, '')`
+
+// eventColumns son las columnas propias de las tablas stocks y stock_events.
+const eventColumns = `
+				ticker, company, target_from, target_to,
+				action, brokerage, rating_from, rating_to, time`
+
+// companyJoin une los datos de referencia de cada compañía, si existen.
+const companyJoin = `LEFT JOIN companies c ON c.ticker = s.ticker`
+
+// GetStocks recupera stocks que cumplen el filtro, con paginación y ordenamiento.
+func (r *CockroachStockRepository) GetStocks(ctx context.Context, filter models.StockFilter, offset, limit int) (_ []models.Stock, err error) {
+	ctx, span := startSpan(ctx, "GetStocks")
+	defer func() { tracing.End(span, err) }()
+
+	orderBy, sortOrder, err := stockOrder(filter)
+	if err != nil {
+		return nil, err
 	}
+	db, systemTime := r.reader(ctx)
+	from, args := buildStockQuery(filter, systemTime)
+	args = append(args, limit, offset)
 
 	// Consulta con ordenamiento y paginación
 	query := fmt.Sprintf(`
-		SELECT 
-			ticker, company, target_from, target_to, 
-			action, brokerage, rating_from, rating_to, time
-		FROM stocks
-		ORDER BY %s %s
-		LIMIT $1 OFFSET $2
-	`, orderBy, sortOrder)
-
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+		SELECT %s
+		%s
+		ORDER BY s.%s %s
+		LIMIT $%d OFFSET $%d
+	`, stockColumns, from, orderBy, sortOrder, len(args)-1, len(args))
+
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("error al consultar stocks: %w", err)
+		return nil, logging.Errorf(ctx, "error al consultar stocks: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanStocks(ctx, rows)
+}
+
+// StreamStocks recorre todos los stocks que cumplen el filtro, en el orden
+// indicado y sin paginación, y llama a fn con cada uno a medida que se leen, sin
+// cargarlos en memoria. Si fn devuelve un error, el recorrido se detiene y se
+// devuelve ese error.
+func (r *CockroachStockRepository) StreamStocks(ctx context.Context, filter models.StockFilter, fn func(models.Stock) error) (err error) {
+	ctx, span := startSpan(ctx, "StreamStocks")
+	defer func() { tracing.End(span, err) }()
+
+	orderBy, sortOrder, err := stockOrder(filter)
+	if err != nil {
+		return err
+	}
+	db, systemTime := r.reader(ctx)
+	from, args := buildStockQuery(filter, systemTime)
+
+	// El ticker desempata el orden para que sea estable
+	query := fmt.Sprintf(`
+		SELECT %s
+		%s
+		ORDER BY s.%s %s, s.ticker ASC
+	`, stockColumns, from, orderBy, sortOrder)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return logging.Errorf(ctx, "error al consultar stocks: %w", classify(err))
 	}
 	defer rows.Close()
 
-	var stocks []models.Stock
 	for rows.Next() {
-		var stock models.Stock
-		if err := rows.Scan(
-			&stock.Ticker,
-			&stock.Company,
-			&stock.TargetFrom,
-			&stock.TargetTo,
-			&stock.Action,
-			&stock.Brokerage,
-			&stock.RatingFrom,
-			&stock.RatingTo,
-			&stock.Time,
-		); err != nil {
-			return nil, fmt.Errorf("error al escanear stock: %w", err)
+		stock, err := scanStock(rows)
+		if err != nil {
+			return logging.Errorf(ctx, "error al escanear stock: %w", classify(err))
+		}
+		if err := fn(stock); err != nil {
+			return err
 		}
-		stocks = append(stocks, stock)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error al iterar stocks: %w", err)
+		return logging.Errorf(ctx, "error al iterar stocks: %w", classify(err))
 	}
-
-	return stocks, nil
+	return nil
 }
 
-// CountStocks cuenta el total de stocks en la base de datos.
-func (r *StockRepository) CountStocks(ctx context.Context) (int, error) {
+// CountStocks cuenta el total de stocks que cumplen el filtro.
+func (r *CockroachStockRepository) CountStocks(ctx context.Context, filter models.StockFilter) (_ int, err error) {
+	ctx, span := startSpan(ctx, "CountStocks")
+	defer func() { tracing.End(span, err) }()
+
+	db, systemTime := r.reader(ctx)
+	from, args := buildStockQuery(filter, systemTime)
+
 	var count int
-	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM stocks").Scan(&count)
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) "+from, args...).Scan(&count)
 	if err != nil {
-		return 0, fmt.Errorf("error al contar stocks: %w", err)
+		return 0, logging.Errorf(ctx, "error al contar stocks: %w", classify(err))
 	}
 	return count, nil
 }
 
-// GetStocksByBrokerage recupera stocks filtrados por casa de bolsa con paginación.
-func (r *StockRepository) GetStocksByBrokerage(ctx context.Context, brokerage string, offset, limit int) ([]models.Stock, error) {
+// stockOrderColumns son las columnas por las que se puede ordenar el listado de stocks.
+var stockOrderColumns = map[string]bool{
+	"ticker": true, "company": true, "brokerage": true,
+	"rating_from": true, "rating_to": true, "time": true,
+}
+
+// ValidateStockFilter verifica el ordenamiento de un filtro de stocks y devuelve
+// un error de la categoría ErrInvalidFilter si no es válido. GetStocks y
+// StreamStocks la aplican; los handlers la llaman antes de empezar una
+// exportación, que ya no puede responder con un error.
+func ValidateStockFilter(filter models.StockFilter) error {
+	_, _, err := stockOrder(filter)
+	return err
+}
+
+// stockOrder devuelve la columna y la dirección de ordenamiento del filtro, con
+// valores predeterminados si no se proporcionan. La columna se interpola en la
+// consulta, por lo que solo se aceptan las de stockOrderColumns.
+func stockOrder(filter models.StockFilter) (string, string, error) {
+	orderBy := filter.OrderBy
+	if orderBy == "" {
+		orderBy = "time"
+	}
+	if !stockOrderColumns[orderBy] {
+		return "", "", invalidFilter("order_by debe ser ticker, company, brokerage, rating_from, rating_to o time: " + orderBy)
+	}
+
+	sortOrder := strings.ToUpper(filter.SortOrder)
+	if sortOrder == "" {
+		sortOrder = "DESC"
+	}
+	if sortOrder != "ASC" && sortOrder != "DESC" {
+		return "", "", invalidFilter("sort debe ser asc o desc: " + filter.SortOrder)
+	}
+	return orderBy, sortOrder, nil
+}
+
+// buildStockQuery construye las cláusulas FROM y WHERE de un filtro de stocks.
+// Con AsOf, la fuente deja de ser la tabla stocks y pasa a ser la última
+// actualización de cada ticker registrada en stock_events hasta ese instante.
+// Se usa created_at, el momento de la sincronización, y no la fecha de la
+// calificación, para que un evento cargado después con una fecha anterior no
+// cambie lo que se conocía en AsOf.
+// systemTime, si no está vacía, es la cláusula AS OF SYSTEM TIME de la consulta.
+func buildStockQuery(filter models.StockFilter, systemTime string) (string, []interface{}) {
+	var args []interface{}
+	var conditions []string
+
+	source := "stocks s"
+	if filter.AsOf != nil {
+		args = append(args, filter.AsOf.UTC())
+		source = `(
+			SELECT DISTINCT ON (ticker)` + eventColumns + `
+			FROM stock_events
+			WHERE created_at <= $1
+			ORDER BY ticker, created_at DESC, time DESC, id DESC
+		) AS s`
+	}
+
+	if filter.Ticker != "" {
+		// Realizamos búsqueda parcial
+		args = append(args, "%"+filter.Ticker+"%")
+		conditions = append(conditions, fmt.Sprintf("s.ticker ILIKE $%d", len(args)))
+	}
+	if filter.Brokerage != "" {
+		args = append(args, filter.Brokerage)
+		conditions = append(conditions, fmt.Sprintf("s.brokerage = $%d", len(args)))
+	}
+	if filter.Rating != "" {
+		args = append(args, filter.Rating)
+		conditions = append(conditions, fmt.Sprintf("(s.rating_from = $%d OR s.rating_to = $%d)", len(args), len(args)))
+	}
+	if filter.Sector != "" {
+		args = append(args, filter.Sector)
+		conditions = append(conditions, fmt.Sprintf("lower(c.sector) = lower($%d)", len(args)))
+	}
+	if filter.Industry != "" {
+		args = append(args, filter.Industry)
+		conditions = append(conditions, fmt.Sprintf("lower(c.industry) = lower($%d)", len(args)))
+	}
+
+	query := "FROM " + source + " " + companyJoin
+	if systemTime != "" {
+		query += " " + systemTime
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return query, args
+}
+
+// GetStockByTicker obtiene un stock por su ticker.
+func (r *CockroachStockRepository) GetStockByTicker(ctx context.Context, ticker string) (_ models.Stock, err error) {
+	ctx, span := startSpan(ctx, "GetStockByTicker")
+	defer func() { tracing.End(span, err) }()
+
 	query := `
-		SELECT 
-			ticker, company, target_from, target_to, 
-			action, brokerage, rating_from, rating_to, time
-		FROM stocks
-		WHERE brokerage = $1
-		ORDER BY time DESC
-		LIMIT $2 OFFSET $3
+	SELECT ` + stockColumns + `
+	FROM stocks s ` + companyJoin + `
+	WHERE s.ticker = $1
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, brokerage, limit, offset)
+	stock, err := scanStock(r.db.QueryRowContext(ctx, query, ticker))
 	if err != nil {
-		return nil, fmt.Errorf("error al consultar stocks por casa de bolsa: %w", err)
+		if err == sql.ErrNoRows {
+			return stock, logging.Errorf(ctx, "%w: %s", ErrStockNotFound, ticker)
+		}
+		return stock, logging.Errorf(ctx, "error al obtener stock: %w", classify(err))
 	}
-	defer rows.Close()
 
-	var stocks []models.Stock
-	for rows.Next() {
-		var stock models.Stock
-		if err := rows.Scan(
-			&stock.Ticker,
-			&stock.Company,
-			&stock.TargetFrom,
-			&stock.TargetTo,
-			&stock.Action,
-			&stock.Brokerage,
-			&stock.RatingFrom,
-			&stock.RatingTo,
-			&stock.Time,
-		); err != nil {
-			return nil, fmt.Errorf("error al escanear stock: %w", err)
+	return stock, nil
+}
+
+// GetStockByTickerAsOf obtiene la última actualización de un stock registrada
+// hasta el instante indicado.
+func (r *CockroachStockRepository) GetStockByTickerAsOf(ctx context.Context, ticker string, asOf time.Time) (_ models.Stock, err error) {
+	ctx, span := startSpan(ctx, "GetStockByTickerAsOf")
+	defer func() { tracing.End(span, err) }()
+
+	query := `
+	SELECT ` + stockColumns + `
+	FROM stock_events s ` + companyJoin + `
+	WHERE s.ticker = $1 AND s.created_at <= $2
+	ORDER BY s.created_at DESC, s.time DESC, s.id DESC
+	LIMIT 1
+	`
+
+	stock, err := scanStock(r.db.QueryRowContext(ctx, query, ticker, asOf.UTC()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return stock, logging.Errorf(ctx, "%w: %s", ErrStockNotFound, ticker)
 		}
-		stocks = append(stocks, stock)
+		return stock, logging.Errorf(ctx, "error al obtener stock: %w", classify(err))
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error al iterar stocks: %w", err)
+	return stock, nil
+}
+
+// GetStocksByDateRange recupera stocks en un rango de fechas específico.
+func (r *CockroachStockRepository) GetStocksByDateRange(ctx context.Context, startDate, endDate time.Time) (_ []models.Stock, err error) {
+	ctx, span := startSpan(ctx, "GetStocksByDateRange")
+	defer func() { tracing.End(span, err) }()
+
+	db, systemTime := r.reader(ctx)
+	query := `
+		SELECT ` + stockColumns + `
+		FROM stocks s ` + companyJoin + ` ` + systemTime + `
+		WHERE s.time BETWEEN $1 AND $2
+		ORDER BY s.time DESC
+	`
+
+	rows, err := db.QueryContext(ctx, query, startDate, endDate)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar stocks por rango de fechas: %w", classify(err))
 	}
+	defer rows.Close()
 
-	return stocks, nil
+	return scanStocks(ctx, rows)
 }
 
-// CountStocksByBrokerage cuenta el total de stocks para una casa de bolsa específica.
-func (r *StockRepository) CountStocksByBrokerage(ctx context.Context, brokerage string) (int, error) {
-	var count int
-	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM stocks WHERE brokerage = $1", brokerage).Scan(&count)
+// GetStockEventsByDateRange recupera el historial de eventos de stocks en un rango de
+// fechas, ordenado cronológicamente. A diferencia de la tabla stocks, que solo
+// conserva la última actualización por ticker, stock_events guarda todas.
+func (r *CockroachStockRepository) GetStockEventsByDateRange(ctx context.Context, startDate, endDate time.Time) (_ []models.Stock, err error) {
+	ctx, span := startSpan(ctx, "GetStockEventsByDateRange")
+	defer func() { tracing.End(span, err) }()
+
+	db, systemTime := r.reader(ctx)
+	query := `
+		SELECT ` + stockColumns + `
+		FROM stock_events s ` + companyJoin + ` ` + systemTime + `
+		WHERE s.time BETWEEN $1 AND $2
+		ORDER BY s.time ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, startDate, endDate)
 	if err != nil {
-		return 0, fmt.Errorf("error al contar stocks por casa de bolsa: %w", err)
+		return nil, logging.Errorf(ctx, "error al consultar eventos por rango de fechas: %w", classify(err))
 	}
-	return count, nil
+	defer rows.Close()
+
+	return scanStocks(ctx, rows)
 }
 
-// GetStocksByTickerPattern recupera stocks cuyo ticker coincida con un patrón.
-func (r *StockRepository) GetStocksByTickerPattern(ctx context.Context, tickerPattern string, offset, limit int) ([]models.Stock, error) {
+// GetStockEventsByDateRangeAsOf recupera los eventos entre dos fechas que ya
+// estaban registrados en asOf, en orden cronológico. Reconstruye el historial
+// que veían las recomendaciones en ese instante.
+func (r *CockroachStockRepository) GetStockEventsByDateRangeAsOf(ctx context.Context, startDate, endDate, asOf time.Time) (_ []models.Stock, err error) {
+	ctx, span := startSpan(ctx, "GetStockEventsByDateRangeAsOf")
+	defer func() { tracing.End(span, err) }()
+
+	db, systemTime := r.reader(ctx)
+	query := `
+		SELECT ` + stockColumns + `
+		FROM stock_events s ` + companyJoin + ` ` + systemTime + `
+		WHERE s.time BETWEEN $1 AND $2 AND s.created_at <= $3
+		ORDER BY s.time ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, startDate, endDate, asOf.UTC())
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar eventos por rango de fechas: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanStocks(ctx, rows)
+}
+
+// GetTickerHistory recupera el historial de calificaciones de un ticker, de la
+// más reciente a la más antigua, con paginación.
+func (r *CockroachStockRepository) GetTickerHistory(ctx context.Context, ticker string, offset, limit int) (_ []models.StockEvent, err error) {
+	ctx, span := startSpan(ctx, "GetTickerHistory")
+	defer func() { tracing.End(span, err) }()
+
 	query := `
-		SELECT 
-			ticker, company, target_from, target_to, 
-			action, brokerage, rating_from, rating_to, time
-		FROM stocks
-		WHERE ticker ILIKE $1
-		ORDER BY time DESC
+		SELECT s.id, ` + stockColumns + `
+		FROM stock_events s ` + companyJoin + `
+		WHERE s.ticker = $1
+		ORDER BY s.time DESC, s.id DESC
 		LIMIT $2 OFFSET $3
 	`
 
-	// Realizamos búsqueda parcial
-	pattern := "%" + tickerPattern + "%"
+	rows, err := r.db.QueryContext(ctx, query, ticker, limit, offset)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar el historial del ticker: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanStockEvents(ctx, rows)
+}
 
-	rows, err := r.db.QueryContext(ctx, query, pattern, limit, offset)
+// CountTickerHistory cuenta las calificaciones del historial de un ticker.
+func (r *CockroachStockRepository) CountTickerHistory(ctx context.Context, ticker string) (_ int, err error) {
+	ctx, span := startSpan(ctx, "CountTickerHistory")
+	defer func() { tracing.End(span, err) }()
+
+	var count int
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM stock_events WHERE ticker = $1
+	`, ticker).Scan(&count)
 	if err != nil {
-		return nil, fmt.Errorf("error al consultar stocks por patrón de ticker: %w", err)
+		return 0, logging.Errorf(ctx, "error al contar el historial del ticker: %w", classify(err))
+	}
+	return count, nil
+}
+
+// StreamTickerHistory recorre todo el historial de calificaciones de un ticker,
+// de la más reciente a la más antigua, y llama a fn con cada una a medida que se
+// leen, sin cargarlas en memoria. Si fn devuelve un error, el recorrido se
+// detiene y se devuelve ese error.
+func (r *CockroachStockRepository) StreamTickerHistory(ctx context.Context, ticker string, fn func(models.StockEvent) error) (err error) {
+	ctx, span := startSpan(ctx, "StreamTickerHistory")
+	defer func() { tracing.End(span, err) }()
+
+	query := `
+		SELECT s.id, ` + stockColumns + `
+		FROM stock_events s ` + companyJoin + `
+		WHERE s.ticker = $1
+		ORDER BY s.time DESC, s.id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ticker)
+	if err != nil {
+		return logging.Errorf(ctx, "error al consultar el historial del ticker: %w", classify(err))
 	}
 	defer rows.Close()
 
-	var stocks []models.Stock
 	for rows.Next() {
-		var stock models.Stock
-		if err := rows.Scan(
-			&stock.Ticker,
-			&stock.Company,
-			&stock.TargetFrom,
-			&stock.TargetTo,
-			&stock.Action,
-			&stock.Brokerage,
-			&stock.RatingFrom,
-			&stock.RatingTo,
-			&stock.Time,
-		); err != nil {
-			return nil, fmt.Errorf("error al escanear stock: %w", err)
+		var event models.StockEvent
+		stock, err := scanStock(prefixScanner{row: rows, prefix: []interface{}{&event.ID}})
+		if err != nil {
+			return logging.Errorf(ctx, "error al escanear evento: %w", classify(err))
+		}
+		event.Stock = stock
+		if err := fn(event); err != nil {
+			return err
 		}
-		stocks = append(stocks, stock)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error al iterar stocks: %w", err)
+		return logging.Errorf(ctx, "error al iterar eventos: %w", classify(err))
 	}
+	return nil
+}
 
-	return stocks, nil
+// GetStockEventsAfter recupera los eventos del historial con identificador mayor
+// que afterID, en orden de identificador. Permite seguir la tabla con un cursor
+// sin volver a leerla completa.
+func (r *CockroachStockRepository) GetStockEventsAfter(ctx context.Context, afterID int64, limit int) (_ []models.StockEvent, err error) {
+	ctx, span := startSpan(ctx, "GetStockEventsAfter")
+	defer func() { tracing.End(span, err) }()
+
+	query := `
+		SELECT s.id, ` + stockColumns + `
+		FROM stock_events s ` + companyJoin + `
+		WHERE s.id > $1
+		ORDER BY s.id ASC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar eventos nuevos: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanStockEvents(ctx, rows)
 }
 
-// CountStocksByTickerPattern cuenta el total de stocks que coinciden con un patrón de ticker.
-func (r *StockRepository) CountStocksByTickerPattern(ctx context.Context, tickerPattern string) (int, error) {
-	var count int
+// GetStockEventsForTickers recupera los eventos más recientes de un conjunto de
+// tickers desde una fecha, del más reciente al más antiguo.
+func (r *CockroachStockRepository) GetStockEventsForTickers(ctx context.Context, tickers []string, since time.Time, limit int) (_ []models.StockEvent, err error) {
+	ctx, span := startSpan(ctx, "GetStockEventsForTickers")
+	defer func() { tracing.End(span, err) }()
+
+	query := `
+		SELECT s.id, ` + stockColumns + `
+		FROM stock_events s ` + companyJoin + `
+		WHERE s.ticker = ANY($1) AND s.time >= $2
+		ORDER BY s.time DESC, s.id DESC
+		LIMIT $3
+	`
 
-	pattern := "%" + tickerPattern + "%"
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(tickers), since, limit)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar eventos de los tickers: %w", classify(err))
+	}
+	defer rows.Close()
 
-	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM stocks WHERE ticker ILIKE $1", pattern).Scan(&count)
+	return scanStockEvents(ctx, rows)
+}
+
+// GetTickersEventsBetween recupera con una sola consulta las perTicker
+// calificaciones más recientes de cada ticker indicado entre dos fechas, ambas
+// incluidas, ordenadas por ticker y de la más reciente a la más antigua.
+func (r *CockroachStockRepository) GetTickersEventsBetween(ctx context.Context, tickers []string, startDate, endDate time.Time, perTicker int) (_ []models.StockEvent, err error) {
+	ctx, span := startSpan(ctx, "GetTickersEventsBetween")
+	defer func() { tracing.End(span, err) }()
+
+	query := `
+		SELECT s.id, ` + stockColumns + `
+		FROM unnest($1::STRING[]) AS k (value)
+		JOIN LATERAL (
+			SELECT * FROM stock_events e
+			WHERE e.ticker = k.value AND e.time >= $2 AND e.time <= $3
+			ORDER BY e.time DESC, e.id DESC
+			LIMIT $4
+		) s ON true
+		` + companyJoin + `
+		ORDER BY s.ticker, s.time DESC, s.id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(tickers), startDate, endDate, perTicker)
 	if err != nil {
-		return 0, fmt.Errorf("error al contar stocks por patrón de ticker: %w", err)
+		return nil, logging.Errorf(ctx, "error al consultar eventos de los tickers: %w", classify(err))
 	}
-	return count, nil
+	defer rows.Close()
+
+	return scanStockEvents(ctx, rows)
+}
+
+// GetRecentEventsByTicker recupera las perTicker calificaciones más recientes de
+// cada ticker indicado con una sola consulta, de la más reciente a la más antigua
+// dentro de cada ticker.
+func (r *CockroachStockRepository) GetRecentEventsByTicker(ctx context.Context, tickers []string, perTicker int) (_ []models.StockEvent, err error) {
+	ctx, span := startSpan(ctx, "GetRecentEventsByTicker")
+	defer func() { tracing.End(span, err) }()
+
+	return r.getRecentEventsBy(ctx, "ticker", tickers, perTicker)
+}
+
+// GetRecentEventsByBrokerage recupera las perBrokerage calificaciones más
+// recientes de cada casa de bolsa indicada con una sola consulta, de la más
+// reciente a la más antigua dentro de cada casa de bolsa.
+func (r *CockroachStockRepository) GetRecentEventsByBrokerage(ctx context.Context, brokerages []string, perBrokerage int) (_ []models.StockEvent, err error) {
+	ctx, span := startSpan(ctx, "GetRecentEventsByBrokerage")
+	defer func() { tracing.End(span, err) }()
+
+	return r.getRecentEventsBy(ctx, "brokerage", brokerages, perBrokerage)
 }
 
-// GetStocksByRating recupera stocks por su rating (from o to).
-func (r *StockRepository) GetStocksByRating(ctx context.Context, rating string, offset, limit int) ([]models.Stock, error) {
+// getRecentEventsBy recupera las últimas calificaciones de cada valor de una
+// columna de stock_events. column debe ser una constante, nunca un valor del usuario.
+func (r *CockroachStockRepository) getRecentEventsBy(ctx context.Context, column string, values []string, perKey int) ([]models.StockEvent, error) {
 	query := `
-		SELECT 
-			ticker, company, target_from, target_to, 
-			action, brokerage, rating_from, rating_to, time
-		FROM stocks
-		WHERE rating_from = $1 OR rating_to = $1
-		ORDER BY time DESC
+		SELECT s.id, ` + stockColumns + `
+		FROM unnest($1::STRING[]) AS k (value)
+		JOIN LATERAL (
+			SELECT * FROM stock_events e
+			WHERE e.` + column + ` = k.value
+			ORDER BY e.time DESC, e.id DESC
+			LIMIT $2
+		) s ON true
+		` + companyJoin + `
+		ORDER BY s.` + column + `, s.time DESC, s.id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(values), perKey)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar eventos recientes por %s: %w", column, classify(err))
+	}
+	defer rows.Close()
+
+	return scanStockEvents(ctx, rows)
+}
+
+// brokerageQuery resume la actividad de cada casa de bolsa; se completa con las
+// condiciones WHERE y GROUP BY.
+const brokerageQuery = `
+		SELECT brokerage, COUNT(*), COUNT(DISTINCT ticker), MAX(time)
+		FROM stock_events
+	`
+
+// ListBrokerages devuelve las casas de bolsa cuyo nombre contiene search, de la
+// más activa a la menos activa, con paginación.
+func (r *CockroachStockRepository) ListBrokerages(ctx context.Context, search string, offset, limit int) (_ []models.Brokerage, err error) {
+	ctx, span := startSpan(ctx, "ListBrokerages")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, brokerageQuery+`
+		WHERE brokerage ILIKE $1
+		GROUP BY brokerage
+		ORDER BY COUNT(*) DESC, brokerage ASC
 		LIMIT $2 OFFSET $3
+	`, "%"+search+"%", limit, offset)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar casas de bolsa: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanBrokerages(ctx, rows)
+}
+
+// CountBrokerages cuenta las casas de bolsa cuyo nombre contiene search.
+func (r *CockroachStockRepository) CountBrokerages(ctx context.Context, search string) (_ int, err error) {
+	ctx, span := startSpan(ctx, "CountBrokerages")
+	defer func() { tracing.End(span, err) }()
+
+	var count int
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT brokerage) FROM stock_events WHERE brokerage ILIKE $1
+	`, "%"+search+"%").Scan(&count)
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al contar casas de bolsa: %w", classify(err))
+	}
+	return count, nil
+}
+
+// GetBrokerages devuelve el resumen de las casas de bolsa indicadas con una sola
+// consulta. Las que no tienen calificaciones se omiten.
+func (r *CockroachStockRepository) GetBrokerages(ctx context.Context, names []string) (_ []models.Brokerage, err error) {
+	ctx, span := startSpan(ctx, "GetBrokerages")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, brokerageQuery+`
+		WHERE brokerage = ANY($1)
+		GROUP BY brokerage
+	`, pq.Array(names))
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar casas de bolsa: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanBrokerages(ctx, rows)
+}
+
+// GetLatestStockEventID obtiene el identificador más alto del historial, o 0 si está vacío.
+func (r *CockroachStockRepository) GetLatestStockEventID(ctx context.Context) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "GetLatestStockEventID")
+	defer func() { tracing.End(span, err) }()
+
+	var id int64
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(id), 0) FROM stock_events
+	`).Scan(&id)
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al consultar el último evento: %w", classify(err))
+	}
+	return id, nil
+}
+
+// GetSectorSummaries agrupa por sector la actividad de analistas registrada entre dos fechas.
+func (r *CockroachStockRepository) GetSectorSummaries(ctx context.Context, startDate, endDate time.Time) (_ []models.SectorSummary, err error) {
+	ctx, span := startSpan(ctx, "GetSectorSummaries")
+	defer func() { tracing.End(span, err) }()
+
+	query := `
+		SELECT
+			COALESCE(NULLIF(c.sector, ''), $3) AS sector,
+			COUNT(DISTINCT s.ticker),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE s.action ILIKE 'upgrade%'),
+			COUNT(*) FILTER (WHERE s.action ILIKE 'downgrade%'),
+			COUNT(*) FILTER (WHERE s.action ILIKE 'target raised%'),
+			COUNT(*) FILTER (WHERE s.action ILIKE 'target lowered%')
+		FROM stock_events s ` + companyJoin + `
+		WHERE s.time BETWEEN $1 AND $2
+		GROUP BY 1
+		ORDER BY 3 DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, rating, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, startDate, endDate, models.UnclassifiedSector)
 	if err != nil {
-		return nil, fmt.Errorf("error al consultar stocks por rating: %w", err)
+		return nil, logging.Errorf(ctx, "error al consultar el resumen por sector: %w", classify(err))
 	}
 	defer rows.Close()
 
-	var stocks []models.Stock
+	var summaries []models.SectorSummary
 	for rows.Next() {
-		var stock models.Stock
+		var summary models.SectorSummary
 		if err := rows.Scan(
-			&stock.Ticker,
-			&stock.Company,
-			&stock.TargetFrom,
-			&stock.TargetTo,
-			&stock.Action,
-			&stock.Brokerage,
-			&stock.RatingFrom,
-			&stock.RatingTo,
-			&stock.Time,
+			&summary.Sector,
+			&summary.Tickers,
+			&summary.Events,
+			&summary.Upgrades,
+			&summary.Downgrades,
+			&summary.TargetsRaised,
+			&summary.TargetsLowered,
 		); err != nil {
-			return nil, fmt.Errorf("error al escanear stock: %w", err)
+			return nil, logging.Errorf(ctx, "error al escanear el resumen por sector: %w", classify(err))
 		}
-		stocks = append(stocks, stock)
+		summary.NetUpgrades = summary.Upgrades - summary.Downgrades
+		summaries = append(summaries, summary)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error al iterar stocks: %w", err)
+		return nil, logging.Errorf(ctx, "error al iterar el resumen por sector: %w", classify(err))
 	}
 
-	return stocks, nil
+	return summaries, nil
 }
 
-// CountStocksByRating cuenta el total de stocks con un rating específico.
-func (r *StockRepository) CountStocksByRating(ctx context.Context, rating string) (int, error) {
-	var count int
-	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM stocks WHERE rating_from = $1 OR rating_to = $1", rating).Scan(&count)
+// GetLastSuccessfulSync obtiene el momento en que terminó la última sincronización
+// exitosa registrada por stock-data-service. Devuelve nil si no hay ninguna.
+func (r *CockroachStockRepository) GetLastSuccessfulSync(ctx context.Context) (_ *time.Time, err error) {
+	ctx, span := startSpan(ctx, "GetLastSuccessfulSync")
+	defer func() { tracing.End(span, err) }()
+
+	var last sql.NullTime
+	err = r.db.QueryRowContext(ctx, `
+		SELECT MAX(finished_at) FROM sync_runs WHERE status = 'completed'
+	`).Scan(&last)
 	if err != nil {
-		return 0, fmt.Errorf("error al contar stocks por rating: %w", err)
+		return nil, logging.Errorf(ctx, "error al consultar la última sincronización: %w", classify(err))
 	}
-	return count, nil
+	if !last.Valid {
+		return nil, nil
+	}
+	return &last.Time, nil
 }
 
-// GetStockByTicker obtiene un stock por su ticker.
-func (r *StockRepository) GetStockByTicker(ctx context.Context, ticker string) (models.Stock, error) {
-	var stock models.Stock
+// GetNewestEventTime obtiene la fecha del evento más reciente del historial.
+// Devuelve nil si el historial está vacío.
+func (r *CockroachStockRepository) GetNewestEventTime(ctx context.Context) (_ *time.Time, err error) {
+	ctx, span := startSpan(ctx, "GetNewestEventTime")
+	defer func() { tracing.End(span, err) }()
 
-	query := `
-	SELECT 
-		ticker, company, target_from, target_to, 
-		action, brokerage, rating_from, rating_to, time
-	FROM stocks 
-	WHERE ticker = $1
-	`
+	var newest sql.NullTime
+	err = r.db.QueryRowContext(ctx, `
+		SELECT MAX(time) FROM stock_events
+	`).Scan(&newest)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar el evento más reciente: %w", classify(err))
+	}
+	if !newest.Valid {
+		return nil, nil
+	}
+	return &newest.Time, nil
+}
+
+// GetSchemaVersion obtiene la versión del esquema que registró un servicio en la
+// tabla schema_versions. Devuelve 0 si el servicio no ha registrado ninguna.
+func (r *CockroachStockRepository) GetSchemaVersion(ctx context.Context, component string) (_ int, err error) {
+	ctx, span := startSpan(ctx, "GetSchemaVersion")
+	defer func() { tracing.End(span, err) }()
+
+	var version int
+	err = r.db.QueryRowContext(ctx, `
+		SELECT version FROM schema_versions WHERE component = $1
+	`, component).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al consultar la versión del esquema: %w", classify(err))
+	}
+	return version, nil
+}
+
+// Ping verifica la conexión a la base de datos y, si es otro, al pool de lectura.
+func (r *CockroachStockRepository) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return err
+	}
+	if r.read != r.db {
+		return r.read.PingContext(ctx)
+	}
+	return nil
+}
+
+// rowScanner es la interfaz común de *sql.Row y *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// rowsScanner es la parte de *sql.Rows que usan las funciones de escaneo.
+type rowsScanner interface {
+	rowScanner
+	Next() bool
+	Err() error
+}
 
-	err := r.db.QueryRowContext(ctx, query, ticker).Scan(
+// prefixScanner antepone columnas propias de la consulta a las de stockColumns.
+type prefixScanner struct {
+	row    rowScanner
+	prefix []interface{}
+}
+
+// Scan escanea primero las columnas del prefijo y luego las indicadas.
+func (p prefixScanner) Scan(dest ...interface{}) error {
+	return p.row.Scan(append(p.prefix, dest...)...)
+}
+
+// scanStock convierte una fila con las columnas de stockColumns en un stock.
+func scanStock(row rowScanner) (models.Stock, error) {
+	var stock models.Stock
+	err := row.Scan(
 		&stock.Ticker,
 		&stock.Company,
 		&stock.TargetFrom,
@@ -274,62 +873,81 @@ func (r *StockRepository) GetStockByTicker(ctx context.Context, ticker string) (
 		&stock.RatingFrom,
 		&stock.RatingTo,
 		&stock.Time,
+		&stock.Sector,
+		&stock.Industry,
+		&stock.Exchange,
+		&stock.MarketCapBucket,
 	)
+	return stock, err
+}
 
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return stock, fmt.Errorf("stock no encontrado: %s", ticker)
+// scanStocks recorre las filas de una consulta y las convierte en stocks.
+func scanStocks(ctx context.Context, rows rowsScanner) ([]models.Stock, error) {
+	var stocks []models.Stock
+	for rows.Next() {
+		stock, err := scanStock(rows)
+		if err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear stock: %w", classify(err))
 		}
-		return stock, fmt.Errorf("error al obtener stock: %w", err)
+		stocks = append(stocks, stock)
 	}
 
-	return stock, nil
+	if err := rows.Err(); err != nil {
+		return nil, logging.Errorf(ctx, "error al iterar stocks: %w", classify(err))
+	}
+
+	return stocks, nil
 }
 
-// GetStocksByDateRange recupera stocks en un rango de fechas específico.
-func (r *StockRepository) GetStocksByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Stock, error) {
-	query := `
-		SELECT 
-			ticker, company, target_from, target_to, 
-			action, brokerage, rating_from, rating_to, time
-		FROM stocks
-		WHERE time BETWEEN $1 AND $2
-		ORDER BY time DESC
-	`
+// scanStockEvents recorre las filas de una consulta con el identificador del
+// evento seguido de stockColumns.
+func scanStockEvents(ctx context.Context, rows rowsScanner) ([]models.StockEvent, error) {
+	events := []models.StockEvent{}
+	for rows.Next() {
+		var event models.StockEvent
+		stock, err := scanStock(prefixScanner{row: rows, prefix: []interface{}{&event.ID}})
+		if err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear evento: %w", classify(err))
+		}
+		event.Stock = stock
+		events = append(events, event)
+	}
 
-	rows, err := r.db.QueryContext(ctx, query, startDate, endDate)
-	if err != nil {
-		return nil, fmt.Errorf("error al consultar stocks por rango de fechas: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, logging.Errorf(ctx, "error al iterar eventos: %w", classify(err))
 	}
-	defer rows.Close()
 
-	var stocks []models.Stock
+	return events, nil
+}
+
+// scanBrokerages recorre las filas de brokerageQuery.
+func scanBrokerages(ctx context.Context, rows rowsScanner) ([]models.Brokerage, error) {
+	brokerages := []models.Brokerage{}
 	for rows.Next() {
-		var stock models.Stock
-		if err := rows.Scan(
-			&stock.Ticker,
-			&stock.Company,
-			&stock.TargetFrom,
-			&stock.TargetTo,
-			&stock.Action,
-			&stock.Brokerage,
-			&stock.RatingFrom,
-			&stock.RatingTo,
-			&stock.Time,
-		); err != nil {
-			return nil, fmt.Errorf("error al escanear stock: %w", err)
+		var brokerage models.Brokerage
+		if err := rows.Scan(&brokerage.Name, &brokerage.RatingCount, &brokerage.TickerCount, &brokerage.LastRatingAt); err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear casa de bolsa: %w", classify(err))
 		}
-		stocks = append(stocks, stock)
+		brokerages = append(brokerages, brokerage)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error al iterar stocks: %w", err)
+		return nil, logging.Errorf(ctx, "error al iterar casas de bolsa: %w", classify(err))
 	}
 
-	return stocks, nil
+	return brokerages, nil
 }
 
-// Ping verifica la conexión a la base de datos.
-func (r *StockRepository) Ping(ctx context.Context) error {
-	return r.db.PingContext(ctx)
+// tracer crea los spans de las consultas del repositorio.
+var tracer = tracing.Tracer("github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository")
+
+// startSpan inicia un span hijo para una consulta del repositorio. Las consultas
+// sin una traza en curso, como los sondeos periódicos, no crean trazas nuevas.
+func startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tracer.Start(ctx, "StockRepository."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemCockroachdb, semconv.DBOperation(operation)))
 }
@@ -0,0 +1,318 @@
+// Paquete repository proporciona acceso a la capa de persistencia de datos.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/lib/pq"
+)
+
+// MaxWatchlistTickers es la cantidad máxima de tickers de una lista.
+const MaxWatchlistTickers = 200
+
+var (
+	// ErrWatchlistNotFound indica que la lista no existe o pertenece a otro principal.
+	ErrWatchlistNotFound error = notFound("lista de seguimiento no encontrada")
+	// ErrWatchlistExists indica que el principal ya tiene una lista con ese nombre.
+	ErrWatchlistExists error = conflict("ya existe una lista de seguimiento con ese nombre")
+	// ErrWatchlistFull indica que la lista superaría MaxWatchlistTickers.
+	ErrWatchlistFull error = conflict("la lista de seguimiento supera la cantidad máxima de tickers")
+)
+
+// WatchlistRepository maneja la persistencia de las listas de seguimiento. Todas
+// las operaciones se limitan a las listas del propietario indicado.
+type WatchlistRepository struct {
+	db *sql.DB
+}
+
+// NewWatchlistRepository crea una nueva instancia del repositorio de listas de seguimiento.
+func NewWatchlistRepository(db *sql.DB) *WatchlistRepository {
+	return &WatchlistRepository{
+		db: db,
+	}
+}
+
+// InitDB crea las tablas de listas de seguimiento si no existen.
+func (r *WatchlistRepository) InitDB(ctx context.Context) error {
+	queries := []string{
+		`
+    CREATE TABLE IF NOT EXISTS watchlists (
+        id INT PRIMARY KEY DEFAULT unique_rowid(),
+        owner STRING NOT NULL,
+        name STRING NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT current_timestamp(),
+        updated_at TIMESTAMP NOT NULL DEFAULT current_timestamp(),
+        UNIQUE INDEX watchlists_owner_name_key (owner, name)
+    )
+    `,
+		`
+    CREATE TABLE IF NOT EXISTS watchlist_items (
+        watchlist_id INT NOT NULL REFERENCES watchlists (id) ON DELETE CASCADE,
+        ticker STRING NOT NULL,
+        added_at TIMESTAMP NOT NULL DEFAULT current_timestamp(),
+        PRIMARY KEY (watchlist_id, ticker)
+    )
+    `,
+	}
+
+	for _, query := range queries {
+		if _, err := r.db.ExecContext(ctx, query); err != nil {
+			return logging.Errorf(ctx, "error al crear las tablas de listas de seguimiento: %w", classify(err))
+		}
+	}
+
+	return nil
+}
+
+// watchlistQuery selecciona las listas con sus tickers; se completa con la condición WHERE.
+const watchlistQuery = `
+		SELECT w.id, w.owner, w.name, w.created_at, w.updated_at,
+			COALESCE(array_agg(i.ticker ORDER BY i.ticker) FILTER (WHERE i.ticker IS NOT NULL), ARRAY[]::STRING[])
+		FROM watchlists w
+		LEFT JOIN watchlist_items i ON i.watchlist_id = w.id
+	`
+
+// CreateWatchlist guarda una lista con sus tickers y completa su identificador y fechas.
+func (r *WatchlistRepository) CreateWatchlist(ctx context.Context, watchlist *models.Watchlist) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return logging.Errorf(ctx, "error al iniciar la transacción: %w", classify(err))
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO watchlists (owner, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`, watchlist.Owner, watchlist.Name).Scan(&watchlist.ID, &watchlist.CreatedAt, &watchlist.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrWatchlistExists
+	}
+	if err != nil {
+		return logging.Errorf(ctx, "error al guardar la lista de seguimiento: %w", classify(err))
+	}
+
+	if err := insertWatchlistItems(ctx, tx, watchlist.ID, watchlist.Tickers); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return logging.Errorf(ctx, "error al confirmar la transacción: %w", classify(err))
+	}
+	return nil
+}
+
+// ListWatchlists devuelve las listas de un propietario ordenadas por nombre.
+func (r *WatchlistRepository) ListWatchlists(ctx context.Context, owner string) ([]models.Watchlist, error) {
+	rows, err := r.db.QueryContext(ctx, watchlistQuery+`
+		WHERE w.owner = $1
+		GROUP BY w.id, w.owner, w.name, w.created_at, w.updated_at
+		ORDER BY w.name ASC
+	`, owner)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar listas de seguimiento: %w", classify(err))
+	}
+	defer rows.Close()
+
+	watchlists := []models.Watchlist{}
+	for rows.Next() {
+		watchlist, err := scanWatchlist(rows)
+		if err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear lista de seguimiento: %w", classify(err))
+		}
+		watchlists = append(watchlists, watchlist)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logging.Errorf(ctx, "error al iterar listas de seguimiento: %w", classify(err))
+	}
+
+	return watchlists, nil
+}
+
+// GetWatchlist obtiene una lista de un propietario.
+func (r *WatchlistRepository) GetWatchlist(ctx context.Context, owner string, id int64) (models.Watchlist, error) {
+	watchlist, err := scanWatchlist(r.db.QueryRowContext(ctx, watchlistQuery+`
+		WHERE w.owner = $1 AND w.id = $2
+		GROUP BY w.id, w.owner, w.name, w.created_at, w.updated_at
+	`, owner, id))
+	if err == sql.ErrNoRows {
+		return watchlist, ErrWatchlistNotFound
+	}
+	if err != nil {
+		return watchlist, logging.Errorf(ctx, "error al obtener la lista de seguimiento: %w", classify(err))
+	}
+	return watchlist, nil
+}
+
+// UpdateWatchlist reemplaza el nombre y los tickers de una lista.
+func (r *WatchlistRepository) UpdateWatchlist(ctx context.Context, watchlist *models.Watchlist) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return logging.Errorf(ctx, "error al iniciar la transacción: %w", classify(err))
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE watchlists SET name = $3, updated_at = current_timestamp()
+		WHERE owner = $1 AND id = $2
+		RETURNING created_at, updated_at
+	`, watchlist.Owner, watchlist.ID, watchlist.Name).Scan(&watchlist.CreatedAt, &watchlist.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrWatchlistNotFound
+	}
+	if isUniqueViolation(err) {
+		return ErrWatchlistExists
+	}
+	if err != nil {
+		return logging.Errorf(ctx, "error al actualizar la lista de seguimiento: %w", classify(err))
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM watchlist_items WHERE watchlist_id = $1`, watchlist.ID); err != nil {
+		return logging.Errorf(ctx, "error al actualizar los tickers de la lista: %w", classify(err))
+	}
+	if err := insertWatchlistItems(ctx, tx, watchlist.ID, watchlist.Tickers); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return logging.Errorf(ctx, "error al confirmar la transacción: %w", classify(err))
+	}
+	return nil
+}
+
+// DeleteWatchlist elimina una lista con sus tickers.
+func (r *WatchlistRepository) DeleteWatchlist(ctx context.Context, owner string, id int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM watchlists WHERE owner = $1 AND id = $2
+	`, owner, id)
+	if err != nil {
+		return logging.Errorf(ctx, "error al eliminar la lista de seguimiento: %w", classify(err))
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return logging.Errorf(ctx, "error al eliminar la lista de seguimiento: %w", classify(err))
+	}
+	if affected == 0 {
+		return ErrWatchlistNotFound
+	}
+	return nil
+}
+
+// AddTickers agrega tickers a una lista; los que ya estaban se ignoran.
+func (r *WatchlistRepository) AddTickers(ctx context.Context, owner string, id int64, tickers []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return logging.Errorf(ctx, "error al iniciar la transacción: %w", classify(err))
+	}
+	defer tx.Rollback()
+
+	if err := touchWatchlist(ctx, tx, owner, id); err != nil {
+		return err
+	}
+	if err := insertWatchlistItems(ctx, tx, id, tickers); err != nil {
+		return err
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM watchlist_items WHERE watchlist_id = $1
+	`, id).Scan(&count); err != nil {
+		return logging.Errorf(ctx, "error al contar los tickers de la lista: %w", classify(err))
+	}
+	if count > MaxWatchlistTickers {
+		return ErrWatchlistFull
+	}
+
+	if err := tx.Commit(); err != nil {
+		return logging.Errorf(ctx, "error al confirmar la transacción: %w", classify(err))
+	}
+	return nil
+}
+
+// RemoveTicker quita un ticker de una lista. No es un error si no estaba.
+func (r *WatchlistRepository) RemoveTicker(ctx context.Context, owner string, id int64, ticker string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return logging.Errorf(ctx, "error al iniciar la transacción: %w", classify(err))
+	}
+	defer tx.Rollback()
+
+	if err := touchWatchlist(ctx, tx, owner, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM watchlist_items WHERE watchlist_id = $1 AND ticker = $2
+	`, id, ticker); err != nil {
+		return logging.Errorf(ctx, "error al quitar el ticker de la lista: %w", classify(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return logging.Errorf(ctx, "error al confirmar la transacción: %w", classify(err))
+	}
+	return nil
+}
+
+// touchWatchlist actualiza la fecha de modificación de una lista y verifica que
+// pertenezca al propietario.
+func touchWatchlist(ctx context.Context, tx *sql.Tx, owner string, id int64) error {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE watchlists SET updated_at = current_timestamp()
+		WHERE owner = $1 AND id = $2
+	`, owner, id)
+	if err != nil {
+		return logging.Errorf(ctx, "error al actualizar la lista de seguimiento: %w", classify(err))
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return logging.Errorf(ctx, "error al actualizar la lista de seguimiento: %w", classify(err))
+	}
+	if affected == 0 {
+		return ErrWatchlistNotFound
+	}
+	return nil
+}
+
+// insertWatchlistItems agrega tickers a una lista dentro de una transacción.
+func insertWatchlistItems(ctx context.Context, tx *sql.Tx, id int64, tickers []string) error {
+	if len(tickers) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO watchlist_items (watchlist_id, ticker)
+		SELECT $1, unnest($2::STRING[])
+		ON CONFLICT (watchlist_id, ticker) DO NOTHING
+	`, id, pq.Array(tickers))
+	if err != nil {
+		return logging.Errorf(ctx, "error al guardar los tickers de la lista: %w", classify(err))
+	}
+	return nil
+}
+
+// scanWatchlist convierte una fila de watchlistQuery en una lista.
+func scanWatchlist(row rowScanner) (models.Watchlist, error) {
+	var watchlist models.Watchlist
+	err := row.Scan(
+		&watchlist.ID,
+		&watchlist.Owner,
+		&watchlist.Name,
+		&watchlist.CreatedAt,
+		&watchlist.UpdatedAt,
+		pq.Array(&watchlist.Tickers),
+	)
+	if watchlist.Tickers == nil {
+		watchlist.Tickers = []string{}
+	}
+	return watchlist, err
+}
+
+// isUniqueViolation indica si el error es una violación de un índice único.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
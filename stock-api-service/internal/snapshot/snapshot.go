@@ -0,0 +1,205 @@
+// Paquete snapshot genera, persiste y compara snapshots de recomendaciones.
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/algorithm"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/metrics"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/repository"
+)
+
+// Size es la cantidad de recomendaciones que se guardan en cada snapshot.
+const Size = 10
+
+// Service genera snapshots de recomendaciones y los persiste.
+type Service struct {
+	stocks      repository.StockRepository
+	snapshots   *repository.SnapshotRepository
+	recommender *algorithm.StockRecommender
+}
+
+// NewService crea un nuevo servicio de snapshots.
+func NewService(stocks repository.StockRepository, snapshots *repository.SnapshotRepository, recommender *algorithm.StockRecommender) *Service {
+	return &Service{
+		stocks:      stocks,
+		snapshots:   snapshots,
+		recommender: recommender,
+	}
+}
+
+// Take calcula las recomendaciones actuales y las guarda como un snapshot del tipo indicado.
+// Para snapshots diarios devuelve false si otra réplica ya guardó el del día.
+func (s *Service) Take(ctx context.Context, kind string) (models.RecommendationSnapshot, bool, error) {
+	// Mismo criterio que el endpoint de recomendaciones: últimos 30 días
+	asOf := s.recommender.Now()
+	startDate := asOf.AddDate(0, -1, 0)
+
+	// El snapshot queda guardado con asOf, así que no se lee de una réplica
+	// seguidora que puede no tener aún la última sincronización
+	stocks, err := s.stocks.GetStocksByDateRange(repository.WithStrongConsistency(ctx), startDate, asOf)
+	if err != nil {
+		return models.RecommendationSnapshot{}, false, fmt.Errorf("error al obtener stocks para el snapshot: %w", err)
+	}
+
+	computeStart := time.Now()
+	results := s.recommender.GenerateRecommendationsAt(stocks, Size, asOf)
+	metrics.ObserveRecommendation(s.recommender.Strategy().Name, time.Since(computeStart))
+
+	snapshot := models.RecommendationSnapshot{
+		Kind:         kind,
+		SnapshotDate: asOf.UTC().Truncate(24 * time.Hour),
+		AsOf:         asOf,
+		Strategy:     s.recommender.Strategy().Name,
+	}
+	for i, result := range results {
+		snapshot.Recommendations = append(snapshot.Recommendations, models.RankedRecommendation{
+			Rank:                 i + 1,
+			RecommendationResult: result,
+		})
+	}
+
+	created, err := s.snapshots.SaveSnapshot(ctx, &snapshot)
+	if err != nil {
+		return snapshot, false, err
+	}
+
+	return snapshot, created, nil
+}
+
+// RunDaily guarda el snapshot diario una vez al día, a partir de la hora UTC indicada.
+// Se ejecuta hasta que el contexto se cancela.
+func (s *Service) RunDaily(ctx context.Context, hour int, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.takeDailyIfMissing(ctx, hour)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// takeDailyIfMissing guarda el snapshot diario si ya pasó la hora y aún no existe.
+func (s *Service) takeDailyIfMissing(ctx context.Context, hour int) {
+	now := time.Now().UTC()
+	if now.Hour() < hour {
+		return
+	}
+
+	exists, err := s.snapshots.HasDailySnapshot(ctx, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error al verificar el snapshot diario", "error", err)
+		return
+	}
+	if exists {
+		return
+	}
+
+	snapshot, created, err := s.Take(ctx, repository.SnapshotKindDaily)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error al guardar el snapshot diario", "error", err)
+		return
+	}
+	if created {
+		slog.InfoContext(ctx, "Snapshot diario guardado",
+			"snapshot_id", snapshot.ID, "recommendations", len(snapshot.Recommendations))
+	}
+}
+
+// Diff compara dos snapshots y clasifica cada ticker como nuevo, saliente,
+// desplazado o sin cambios de posición.
+func Diff(from, to models.RecommendationSnapshot) models.SnapshotDiff {
+	diff := models.SnapshotDiff{
+		From:      header(from),
+		To:        header(to),
+		Entered:   []models.SnapshotDiffEntry{},
+		Left:      []models.SnapshotDiffEntry{},
+		Moved:     []models.SnapshotDiffEntry{},
+		Unchanged: []models.SnapshotDiffEntry{},
+	}
+
+	previous := make(map[string]models.RankedRecommendation)
+	for _, item := range from.Recommendations {
+		previous[item.Stock.Ticker] = item
+	}
+
+	current := make(map[string]bool)
+	for _, item := range to.Recommendations {
+		current[item.Stock.Ticker] = true
+
+		toRank := item.Rank
+		toScore := item.Score
+		entry := models.SnapshotDiffEntry{
+			Ticker:  item.Stock.Ticker,
+			Company: item.Stock.Company,
+			ToRank:  &toRank,
+			ToScore: &toScore,
+		}
+
+		old, existed := previous[item.Stock.Ticker]
+		if !existed {
+			diff.Entered = append(diff.Entered, entry)
+			continue
+		}
+
+		fromRank := old.Rank
+		fromScore := old.Score
+		delta := item.Score - old.Score
+		entry.FromRank = &fromRank
+		entry.FromScore = &fromScore
+		entry.ScoreDelta = &delta
+		entry.RankChange = old.Rank - item.Rank
+
+		if entry.RankChange != 0 {
+			diff.Moved = append(diff.Moved, entry)
+		} else {
+			diff.Unchanged = append(diff.Unchanged, entry)
+		}
+	}
+
+	for _, item := range from.Recommendations {
+		if current[item.Stock.Ticker] {
+			continue
+		}
+
+		fromRank := item.Rank
+		fromScore := item.Score
+		diff.Left = append(diff.Left, models.SnapshotDiffEntry{
+			Ticker:    item.Stock.Ticker,
+			Company:   item.Stock.Company,
+			FromRank:  &fromRank,
+			FromScore: &fromScore,
+		})
+	}
+
+	// Los mayores desplazamientos primero
+	sort.SliceStable(diff.Moved, func(i, j int) bool {
+		return abs(diff.Moved[i].RankChange) > abs(diff.Moved[j].RankChange)
+	})
+
+	return diff
+}
+
+// header devuelve la cabecera de un snapshot sin sus recomendaciones.
+func header(snapshot models.RecommendationSnapshot) models.RecommendationSnapshot {
+	snapshot.Recommendations = nil
+	return snapshot
+}
+
+// abs devuelve el valor absoluto de un entero.
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,180 @@
+package stream
+
+import (
+	"context"
+	"time"
+)
+
+// feed sigue una de las fuentes y conserva sus filas recientes en el orden en
+// que aparecen. Next lee el búfer con h.mu; poll es el único que lo modifica.
+type feed[T any] struct {
+	read   func(ctx context.Context, afterID int64, limit int) ([]T, error)
+	latest func(ctx context.Context) (int64, error)
+	id     func(T) int64
+
+	// Filas recientes en orden de llegada
+	buf []item[T]
+	// Secuencia de la última fila recibida
+	seq int64
+	// Todas las filas con identificador menor o igual ya se leyeron
+	settled int64
+	// Mayor identificador que salió del búfer: los clientes con una posición
+	// anterior leen la fuente
+	floor int64
+
+	// Solo los usa poll
+	// Identificadores mayores que settled ya leídos
+	seen map[int64]struct{}
+	// Mayor identificador leído
+	head int64
+	// Consultas cuyas filas todavía pueden completarse
+	marks []mark
+}
+
+// item es una fila del búfer.
+type item[T any] struct {
+	value T
+	// Posición en el búfer, o 0 si se leyó de la fuente
+	seq int64
+	// Toda fila que llegue después tiene un identificador mayor
+	after int64
+}
+
+// mark registra el mayor identificador leído en una consulta. Todas las filas
+// con un identificador menor o igual se confirman antes de Lookback.
+type mark struct {
+	at time.Time
+	id int64
+}
+
+// batch son las filas que recibe un cliente y su posición después de ellas.
+type batch[T any] struct {
+	items []item[T]
+	// Posición tras las filas
+	after int64
+	seq   int64
+	// La posición es anterior al búfer: se lee la fuente hasta after
+	behind bool
+	// Quedan páginas de la fuente por leer
+	more bool
+}
+
+// start fija la posición inicial del búfer.
+func (f *feed[T]) start(latest int64) {
+	f.settled, f.floor, f.head = latest, latest, latest
+	f.seen = make(map[int64]struct{})
+}
+
+// next devuelve las filas del búfer posteriores a la posición del cliente, o
+// un lote con behind si la posición es anterior al búfer.
+func (f *feed[T]) next(after, seq int64) batch[T] {
+	first := f.seq - int64(len(f.buf))
+	var items []item[T]
+	switch {
+	case seq > 0 && seq >= first:
+		// Un cliente conectado continúa por su posición en el búfer
+		items = f.buf[seq-first:]
+	case after >= f.floor:
+		// Un cliente que reanuda recibe todas las filas con identificador
+		// mayor, incluso las que ya recibió antes de reconectarse
+		for _, item := range f.buf {
+			if f.id(item.value) > after {
+				items = append(items, item)
+			}
+		}
+	default:
+		return batch[T]{after: f.settled, behind: true}
+	}
+	return batch[T]{items: items, after: f.settled, seq: f.seq}
+}
+
+// page lee de la fuente las filas posteriores a after hasta settled, en orden
+// de identificador.
+func (f *feed[T]) page(ctx context.Context, after, settled int64) (batch[T], error) {
+	values, err := f.read(ctx, after, pageSize)
+	if err != nil {
+		return batch[T]{}, err
+	}
+
+	var b batch[T]
+	for _, value := range values {
+		id := f.id(value)
+		if id > settled {
+			// Las filas posteriores se leen del búfer
+			b.after = settled
+			return b, nil
+		}
+		b.items = append(b.items, item[T]{value: value, after: id})
+		after = id
+	}
+	if len(values) == pageSize {
+		b.after, b.more = after, true
+	} else {
+		b.after = settled
+	}
+	return b, nil
+}
+
+// poll lee las filas posteriores a settled que todavía no están en el búfer.
+func (f *feed[T]) poll(ctx context.Context) ([]T, error) {
+	var fresh []T
+	after := f.settled
+	for {
+		page, err := f.read(ctx, after, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range page {
+			if _, ok := f.seen[f.id(value)]; !ok {
+				fresh = append(fresh, value)
+			}
+		}
+		if len(page) < pageSize {
+			return fresh, nil
+		}
+		after = f.id(page[len(page)-1])
+	}
+}
+
+// add agrega al búfer las filas leídas en la consulta de now, asienta las
+// consultas anteriores a settleBefore y descarta las filas más antiguas que
+// excedan size. Requiere h.mu.
+func (f *feed[T]) add(values []T, now, settleBefore time.Time, size int) {
+	for _, value := range values {
+		id := f.id(value)
+		f.seq++
+		f.buf = append(f.buf, item[T]{value: value, seq: f.seq, after: f.settled})
+		f.seen[id] = struct{}{}
+		f.head = max(f.head, id)
+	}
+
+	if len(f.marks) == 0 || f.marks[len(f.marks)-1].id != f.head {
+		f.marks = append(f.marks, mark{at: now, id: f.head})
+	}
+	n := 0
+	for n < len(f.marks) && !f.marks[n].at.After(settleBefore) {
+		f.settled = max(f.settled, f.marks[n].id)
+		n++
+	}
+	f.marks = f.marks[n:]
+	for id := range f.seen {
+		if id <= f.settled {
+			delete(f.seen, id)
+		}
+	}
+
+	// Solo salen del búfer las filas asentadas, para que un cliente que lee la
+	// fuente hasta settled pueda continuar por el búfer
+	n = 0
+	for len(f.buf)-n > size {
+		id := f.id(f.buf[n].value)
+		if id > f.settled {
+			break
+		}
+		f.floor = max(f.floor, id)
+		n++
+	}
+	if n > 0 {
+		f.buf = append([]item[T](nil), f.buf[n:]...)
+	}
+}
@@ -0,0 +1,326 @@
+// Paquete stream sigue los eventos de calificación y los snapshots de
+// recomendaciones que se guardan en la base de datos y los reparte entre los
+// clientes conectados por Server-Sent Events.
+package stream
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// Tipos de mensaje.
+const (
+	// Una calificación nueva guardada por stock-data-service
+	TypeRating = "rating.changed"
+	// Un snapshot de recomendaciones nuevo
+	TypeSnapshot = "snapshot.created"
+)
+
+// pageSize es la cantidad máxima de filas leídas por consulta.
+const pageSize = 500
+
+// ErrInvalidCursor indica que el valor de Last-Event-ID no tiene el formato esperado.
+var ErrInvalidCursor = errors.New("Last-Event-ID inválido, se espera <evento>-<snapshot>")
+
+// EventSource lee el historial de eventos por identificador.
+type EventSource interface {
+	GetStockEventsAfter(ctx context.Context, afterID int64, limit int) ([]models.StockEvent, error)
+	GetLatestStockEventID(ctx context.Context) (int64, error)
+}
+
+// SnapshotSource lee los snapshots de recomendaciones por identificador.
+type SnapshotSource interface {
+	ListSnapshotsAfter(ctx context.Context, afterID int64, limit int) ([]models.RecommendationSnapshot, error)
+	GetLatestSnapshotID(ctx context.Context) (int64, error)
+}
+
+// Cursor es la posición de un cliente en ambas fuentes. Se envía como id de
+// cada mensaje para que el cliente pueda reanudar con Last-Event-ID.
+//
+// EventID y SnapshotID son identificadores por debajo de los cuales el cliente
+// ya recibió todas las filas; al reanudar se vuelven a enviar las posteriores
+// que el cliente pudo haber recibido, que se descartan por su id.
+type Cursor struct {
+	// Identificador por debajo del cual se recibieron todos los eventos
+	EventID int64
+	// Identificador por debajo del cual se recibieron todos los snapshots
+	SnapshotID int64
+
+	// Posición en el búfer del hub, solo válida en la conexión que la recibió
+	eventSeq    int64
+	snapshotSeq int64
+}
+
+// String devuelve el cursor con el formato <evento>-<snapshot>.
+func (c Cursor) String() string {
+	return strconv.FormatInt(c.EventID, 10) + "-" + strconv.FormatInt(c.SnapshotID, 10)
+}
+
+// ParseCursor interpreta un cursor con el formato <evento>-<snapshot>.
+func ParseCursor(value string) (Cursor, error) {
+	eventPart, snapshotPart, ok := strings.Cut(strings.TrimSpace(value), "-")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	eventID, err := strconv.ParseInt(eventPart, 10, 64)
+	if err != nil || eventID < 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	snapshotID, err := strconv.ParseInt(snapshotPart, 10, 64)
+	if err != nil || snapshotID < 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{EventID: eventID, SnapshotID: snapshotID}, nil
+}
+
+// Message es un mensaje para un cliente.
+type Message struct {
+	// Posición del cliente tras recibir el mensaje
+	Cursor Cursor
+	// Tipo de mensaje (rating.changed o snapshot.created)
+	Type string
+	// Un models.StockEvent o un models.RecommendationSnapshot sin recomendaciones
+	Data any
+}
+
+// Config define cómo se consultan las fuentes.
+type Config struct {
+	// Intervalo de consulta de filas nuevas
+	PollInterval time.Duration
+	// Cantidad de eventos y de snapshots recientes que se conservan en memoria
+	BufferSize int
+	// Tiempo máximo entre la asignación del identificador de una fila y su
+	// confirmación. Las filas de esa ventana se vuelven a leer en cada consulta.
+	Lookback time.Duration
+}
+
+// Hub consulta periódicamente las filas nuevas de ambas fuentes y conserva las
+// más recientes en memoria. Los clientes leen del búfer, de modo que la
+// cantidad de consultas no depende de la cantidad de conexiones; solo los
+// clientes que reanudan desde una posición anterior al búfer consultan la base
+// de datos.
+//
+// Los identificadores de stock_events y recommendation_snapshots los genera
+// unique_rowid() al insertar, no al confirmar, de modo que una fila puede
+// aparecer después de otra con un identificador mayor. Cada consulta vuelve a
+// leer las filas de los últimos Lookback, descarta por identificador las ya
+// vistas y agrega las demás al búfer en el orden en que aparecen.
+type Hub struct {
+	config Config
+	// Para reemplazar el reloj en las pruebas
+	now func() time.Time
+
+	mu        sync.RWMutex
+	events    *feed[models.StockEvent]
+	snapshots *feed[models.RecommendationSnapshot]
+	ready     bool
+	// Se cierra y se reemplaza cada vez que llegan filas nuevas
+	changed chan struct{}
+	// Se cierra cuando Run termina
+	done chan struct{}
+}
+
+// NewHub crea un hub de eventos.
+func NewHub(events EventSource, snapshots SnapshotSource, config Config) *Hub {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 2 * time.Second
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = 1000
+	}
+	if config.Lookback <= 0 {
+		config.Lookback = 30 * time.Second
+	}
+
+	return &Hub{
+		config: config,
+		now:    time.Now,
+		events: &feed[models.StockEvent]{
+			read:   events.GetStockEventsAfter,
+			latest: events.GetLatestStockEventID,
+			id:     func(event models.StockEvent) int64 { return event.ID },
+		},
+		snapshots: &feed[models.RecommendationSnapshot]{
+			read:   snapshots.ListSnapshotsAfter,
+			latest: snapshots.GetLatestSnapshotID,
+			id:     func(snapshot models.RecommendationSnapshot) int64 { return snapshot.ID },
+		},
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Run consulta las filas nuevas hasta que el contexto se cancela.
+func (h *Hub) Run(ctx context.Context) {
+	defer close(h.done)
+
+	ticker := time.NewTicker(h.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.poll(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Error al consultar eventos nuevos", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Done se cierra cuando el hub deja de consultar, para que las conexiones abiertas terminen.
+func (h *Hub) Done() <-chan struct{} {
+	return h.done
+}
+
+// Head devuelve la posición más reciente, desde la que empiezan los clientes
+// que no indican Last-Event-ID.
+func (h *Hub) Head(ctx context.Context) (Cursor, error) {
+	h.mu.RLock()
+	if h.ready {
+		defer h.mu.RUnlock()
+		return Cursor{
+			EventID:     h.events.settled,
+			SnapshotID:  h.snapshots.settled,
+			eventSeq:    h.events.seq,
+			snapshotSeq: h.snapshots.seq,
+		}, nil
+	}
+	h.mu.RUnlock()
+
+	eventID, err := h.events.latest(ctx)
+	if err != nil {
+		return Cursor{}, err
+	}
+	snapshotID, err := h.snapshots.latest(ctx)
+	if err != nil {
+		return Cursor{}, err
+	}
+	return Cursor{EventID: eventID, SnapshotID: snapshotID}, nil
+}
+
+// Next devuelve los mensajes posteriores al cursor que cumplen el filtro y la
+// nueva posición del cliente, que avanza también sobre las filas descartadas.
+// Si no hay mensajes, el canal devuelto se cierra cuando llegan filas nuevas.
+func (h *Hub) Next(ctx context.Context, cursor Cursor, filter models.StockFilter) ([]Message, Cursor, <-chan struct{}, error) {
+	h.mu.RLock()
+	ready := h.ready
+	var changed <-chan struct{} = h.changed
+	var events batch[models.StockEvent]
+	var snapshots batch[models.RecommendationSnapshot]
+	if ready {
+		events = h.events.next(cursor.EventID, cursor.eventSeq)
+		snapshots = h.snapshots.next(cursor.SnapshotID, cursor.snapshotSeq)
+	}
+	h.mu.RUnlock()
+
+	// Hasta la primera consulta del hub no se conoce el búfer
+	if !ready {
+		return nil, cursor, changed, nil
+	}
+
+	// Las posiciones anteriores al búfer se leen de la base de datos por páginas
+	var err error
+	if events.behind {
+		if events, err = h.events.page(ctx, cursor.EventID, events.after); err != nil {
+			return nil, cursor, nil, err
+		}
+	}
+	if snapshots.behind {
+		if snapshots, err = h.snapshots.page(ctx, cursor.SnapshotID, snapshots.after); err != nil {
+			return nil, cursor, nil, err
+		}
+	}
+
+	var messages []Message
+	for _, item := range events.items {
+		cursor.EventID, cursor.eventSeq = max(cursor.EventID, item.after), item.seq
+		if filter.Matches(item.value.Stock) {
+			messages = append(messages, Message{Cursor: cursor, Type: TypeRating, Data: item.value})
+		}
+	}
+	cursor.EventID, cursor.eventSeq = max(cursor.EventID, events.after), events.seq
+	for _, item := range snapshots.items {
+		cursor.SnapshotID, cursor.snapshotSeq = max(cursor.SnapshotID, item.after), item.seq
+		messages = append(messages, Message{Cursor: cursor, Type: TypeSnapshot, Data: item.value})
+	}
+	cursor.SnapshotID, cursor.snapshotSeq = max(cursor.SnapshotID, snapshots.after), snapshots.seq
+
+	if events.more || snapshots.more {
+		// Quedan páginas por leer: el cliente puede continuar de inmediato
+		changed = closedChan
+	}
+	return messages, cursor, changed, nil
+}
+
+// poll lee las filas nuevas de ambas fuentes, las agrega al búfer y avisa a los clientes.
+func (h *Hub) poll(ctx context.Context) error {
+	h.mu.RLock()
+	ready := h.ready
+	h.mu.RUnlock()
+
+	now := h.now()
+	if !ready {
+		eventID, err := h.events.latest(ctx)
+		if err != nil {
+			return err
+		}
+		snapshotID, err := h.snapshots.latest(ctx)
+		if err != nil {
+			return err
+		}
+		h.mu.Lock()
+		h.events.start(eventID)
+		h.snapshots.start(snapshotID)
+		h.ready = true
+		h.notify()
+		h.mu.Unlock()
+		return nil
+	}
+
+	// Solo poll modifica el búfer, de modo que puede leerlo sin bloqueo
+	events, err := h.events.poll(ctx)
+	if err != nil {
+		return err
+	}
+	snapshots, err := h.snapshots.poll(ctx)
+	if err != nil {
+		return err
+	}
+
+	settleBefore := now.Add(-h.config.Lookback)
+	h.mu.Lock()
+	h.events.add(events, now, settleBefore, h.config.BufferSize)
+	h.snapshots.add(snapshots, now, settleBefore, h.config.BufferSize)
+	if len(events) > 0 || len(snapshots) > 0 {
+		h.notify()
+	}
+	h.mu.Unlock()
+
+	if len(events) > 0 || len(snapshots) > 0 {
+		slog.DebugContext(ctx, "Eventos nuevos para el stream", "events", len(events), "snapshots", len(snapshots))
+	}
+	return nil
+}
+
+// notify avisa a los clientes que esperan filas nuevas. Requiere h.mu.
+func (h *Hub) notify() {
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+// closedChan es un canal ya cerrado.
+var closedChan = func() <-chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
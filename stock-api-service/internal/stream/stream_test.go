@@ -0,0 +1,360 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/models"
+)
+
+// fakeSource guarda las filas confirmadas, que pueden llegar en cualquier orden
+// de identificador.
+type fakeSource struct {
+	mu        sync.Mutex
+	events    []models.StockEvent
+	snapshots []models.RecommendationSnapshot
+	reads     int
+}
+
+// commit confirma eventos con los identificadores indicados.
+func (s *fakeSource) commit(ids ...int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.events = append(s.events, models.StockEvent{ID: id, Stock: models.Stock{Ticker: "NVDA"}})
+	}
+}
+
+// commitSnapshot confirma un snapshot.
+func (s *fakeSource) commitSnapshot(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots = append(s.snapshots, models.RecommendationSnapshot{ID: id})
+}
+
+func (s *fakeSource) GetStockEventsAfter(_ context.Context, afterID int64, limit int) ([]models.StockEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reads++
+	return after(s.events, afterID, limit, func(e models.StockEvent) int64 { return e.ID }), nil
+}
+
+func (s *fakeSource) GetLatestStockEventID(context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var latest int64
+	for _, event := range s.events {
+		latest = max(latest, event.ID)
+	}
+	return latest, nil
+}
+
+func (s *fakeSource) ListSnapshotsAfter(_ context.Context, afterID int64, limit int) ([]models.RecommendationSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return after(s.snapshots, afterID, limit, func(s models.RecommendationSnapshot) int64 { return s.ID }), nil
+}
+
+func (s *fakeSource) GetLatestSnapshotID(context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var latest int64
+	for _, snapshot := range s.snapshots {
+		latest = max(latest, snapshot.ID)
+	}
+	return latest, nil
+}
+
+// after devuelve las filas con identificador mayor que afterID en orden de identificador.
+func after[T any](rows []T, afterID int64, limit int, id func(T) int64) []T {
+	var out []T
+	for _, row := range rows {
+		if id(row) > afterID {
+			out = append(out, row)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return id(out[i]) < id(out[j]) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// newTestHub crea un hub con un reloj controlado por la prueba, ya iniciado.
+func newTestHub(t *testing.T, source *fakeSource, bufferSize int) (*Hub, *time.Time) {
+	t.Helper()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	h := NewHub(source, source, Config{BufferSize: bufferSize, Lookback: 30 * time.Second})
+	h.now = func() time.Time { return now }
+	poll(t, h)
+	return h, &now
+}
+
+// poll ejecuta una consulta del hub.
+func poll(t *testing.T, h *Hub) {
+	t.Helper()
+	if err := h.poll(context.Background()); err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+}
+
+// drain lee todos los mensajes disponibles desde el cursor y devuelve los
+// identificadores de los eventos recibidos y la posición final.
+func drain(t *testing.T, h *Hub, cursor Cursor) ([]int64, Cursor) {
+	t.Helper()
+	var ids []int64
+	for range 100 {
+		messages, next, wait, err := h.Next(context.Background(), cursor, models.StockFilter{})
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		for _, message := range messages {
+			if event, ok := message.Data.(models.StockEvent); ok {
+				ids = append(ids, event.ID)
+			}
+		}
+		cursor = next
+		if len(messages) == 0 && wait != closedChan {
+			return ids, cursor
+		}
+	}
+	t.Fatal("Next() no terminó de leer")
+	return nil, cursor
+}
+
+// assertIDs compara los identificadores recibidos.
+func assertIDs(t *testing.T, name string, got []int64, want ...int64) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Cursor
+		wantErr bool
+	}{
+		{"918273645-7261", Cursor{EventID: 918273645, SnapshotID: 7261}, false},
+		{" 0-0 ", Cursor{}, false},
+		{"918273645", Cursor{}, true},
+		{"-1-0", Cursor{}, true},
+		{"1-x", Cursor{}, true},
+		{"", Cursor{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCursor(tt.value)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("ParseCursor(%q) error = %v, want ErrInvalidCursor", tt.value, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseCursor(%q) = %+v, %v; want %+v", tt.value, got, err, tt.want)
+		}
+		if again, _ := ParseCursor(got.String()); again != got {
+			t.Errorf("ParseCursor(%q.String()) = %+v, want %+v", tt.value, again, got)
+		}
+	}
+}
+
+func TestHubDeliversOutOfOrderCommits(t *testing.T) {
+	source := &fakeSource{}
+	source.commit(1, 2, 3)
+	h, now := newTestHub(t, source, 100)
+
+	head, err := h.Head(context.Background())
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+
+	// El 5 se confirma antes que el 4, que recibió su identificador primero
+	source.commit(5)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+	got, cursor := drain(t, h, head)
+	assertIDs(t, "primera lectura", got, 5)
+
+	source.commit(4)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+	got, cursor = drain(t, h, cursor)
+	assertIDs(t, "confirmación tardía", got, 4)
+
+	// Las filas de la ventana se vuelven a leer pero no se repiten
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+	got, _ = drain(t, h, cursor)
+	assertIDs(t, "sin filas nuevas", got)
+}
+
+func TestHubSettlesAfterLookback(t *testing.T) {
+	source := &fakeSource{}
+	h, now := newTestHub(t, source, 100)
+
+	source.commit(10)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+	if h.events.settled != 0 {
+		t.Fatalf("settled = %d dentro de la ventana, want 0", h.events.settled)
+	}
+
+	*now = now.Add(30 * time.Second)
+	poll(t, h)
+	if h.events.settled != 10 || len(h.events.seen) != 0 {
+		t.Errorf("settled = %d, seen = %v tras la ventana; want 10 y vacío", h.events.settled, h.events.seen)
+	}
+
+	// Una fila confirmada dentro de la ventana se sigue recibiendo
+	head, _ := h.Head(context.Background())
+	source.commit(12)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+	source.commit(11)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+	got, _ := drain(t, h, head)
+	assertIDs(t, "eventos tras asentar", got, 12, 11)
+}
+
+func TestHubResumesFromLastEventID(t *testing.T) {
+	source := &fakeSource{}
+	source.commit(1)
+	h, now := newTestHub(t, source, 100)
+	head, _ := h.Head(context.Background())
+
+	source.commit(3)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+	messages, _, _, err := h.Next(context.Background(), head, models.StockFilter{})
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("Next() = %d mensajes, %v; want 1", len(messages), err)
+	}
+	lastEventID := messages[0].Cursor.String()
+
+	// El cliente se desconecta y el 2 se confirma tarde
+	source.commit(2, 4)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+
+	cursor, err := ParseCursor(lastEventID)
+	if err != nil {
+		t.Fatalf("ParseCursor(%q) error = %v", lastEventID, err)
+	}
+	got, cursor := drain(t, h, cursor)
+	// El 3 se repite: el cliente lo descarta por su id
+	assertIDs(t, "reanudación", got, 3, 2, 4)
+
+	source.commit(5)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+	got, _ = drain(t, h, cursor)
+	assertIDs(t, "tras reanudar", got, 5)
+}
+
+func TestHubReadsSourceBelowBufferFloor(t *testing.T) {
+	source := &fakeSource{}
+	h, now := newTestHub(t, source, 2)
+
+	for id := int64(1); id <= 5; id++ {
+		source.commit(id)
+		*now = now.Add(40 * time.Second)
+		poll(t, h)
+	}
+	if h.events.floor != 3 || len(h.events.buf) != 2 {
+		t.Fatalf("floor = %d, búfer = %d filas; want 3 y 2", h.events.floor, len(h.events.buf))
+	}
+
+	// Una fila sin asentar no sale del búfer aunque lo exceda
+	source.commit(7)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+	source.commit(6)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+	if h.events.settled != 4 || h.events.floor != 4 || len(h.events.buf) != 3 {
+		t.Errorf("settled = %d, floor = %d, búfer = %d filas; want 4, 4 y 3", h.events.settled, h.events.floor, len(h.events.buf))
+	}
+
+	// Un cliente anterior al búfer lee la fuente hasta settled y continúa por el búfer
+	reads := source.reads
+	got, cursor := drain(t, h, Cursor{EventID: 2})
+	assertIDs(t, "desde la fuente", got, 3, 4, 5, 7, 6)
+	if source.reads == reads {
+		t.Error("Next() no leyó la fuente para una posición anterior al búfer")
+	}
+
+	// Un cliente dentro del búfer no consulta la base de datos
+	reads = source.reads
+	got, _ = drain(t, h, Cursor{EventID: 4})
+	assertIDs(t, "desde el búfer", got, 5, 7, 6)
+	if source.reads != reads {
+		t.Error("Next() leyó la fuente para una posición dentro del búfer")
+	}
+
+	source.commit(8)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+	got, _ = drain(t, h, cursor)
+	assertIDs(t, "tras leer la fuente", got, 8)
+}
+
+func TestHubSnapshots(t *testing.T) {
+	source := &fakeSource{}
+	h, now := newTestHub(t, source, 100)
+	head, _ := h.Head(context.Background())
+
+	source.commitSnapshot(20)
+	source.commit(9)
+	*now = now.Add(2 * time.Second)
+	poll(t, h)
+
+	messages, cursor, _, err := h.Next(context.Background(), head, models.StockFilter{Ticker: "AAPL"})
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	// El evento no cumple el filtro pero el cursor avanza sobre él
+	if len(messages) != 1 || messages[0].Type != TypeSnapshot {
+		t.Fatalf("Next() = %+v, want solo el snapshot", messages)
+	}
+	if cursor.eventSeq != 1 || cursor.snapshotSeq != 1 {
+		t.Errorf("cursor = %+v, want la posición tras ambas filas", cursor)
+	}
+}
+
+func TestHubWaitsUntilReady(t *testing.T) {
+	source := &fakeSource{}
+	source.commit(1)
+	h := NewHub(source, source, Config{})
+
+	cursor := Cursor{EventID: 0}
+	messages, _, wait, err := h.Next(context.Background(), cursor, models.StockFilter{})
+	if err != nil || len(messages) != 0 {
+		t.Fatalf("Next() antes de iniciar = %d mensajes, %v", len(messages), err)
+	}
+	select {
+	case <-wait:
+		t.Fatal("el canal se cerró antes de la primera consulta")
+	default:
+	}
+
+	poll(t, h)
+	select {
+	case <-wait:
+	default:
+		t.Fatal("el canal no se cerró tras la primera consulta")
+	}
+	got, _ := drain(t, h, cursor)
+	assertIDs(t, "desde antes del inicio", got, 1)
+}
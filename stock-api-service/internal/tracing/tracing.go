@@ -0,0 +1,110 @@
+// Paquete tracing configura las trazas de OpenTelemetry del servicio.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-api-service/internal/buildinfo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exportadores de trazas admitidos.
+const (
+	// Sin exportador: las trazas se propagan pero no se envían
+	ExporterNone = "none"
+	// Imprime las trazas en la salida estándar, para desarrollo local
+	ExporterStdout = "stdout"
+	// Envía las trazas por OTLP/HTTP a un colector
+	ExporterOTLP = "otlp"
+)
+
+// Config contiene la configuración de trazas.
+type Config struct {
+	// Nombre del servicio en las trazas
+	ServiceName string
+	// Exportador: none, stdout u otlp
+	Exporter string
+	// URL del colector OTLP/HTTP (por ejemplo http://otel-collector:4318); vacía
+	// para usar OTEL_EXPORTER_OTLP_ENDPOINT o el valor predeterminado
+	OTLPEndpoint string
+	// Fracción de trazas nuevas que se muestrean, entre 0 y 1
+	SampleRatio float64
+}
+
+// Setup registra el proveedor de trazas y el propagador W3C globales. Devuelve
+// una función que envía las trazas pendientes y cierra el exportador.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	// El propagador se registra siempre para mantener el contexto de trazas
+	// recibido aunque este servicio no exporte
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if cfg.OTLPEndpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("exportador de trazas inválido %q: use none, stdout u otlp", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el exportador de trazas: %w", err)
+	}
+
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("fracción de muestreo inválida %v: debe estar entre 0 y 1", cfg.SampleRatio)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(buildinfo.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el recurso de trazas: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Se respeta la decisión de muestreo del servicio que originó la traza
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer devuelve un tracer del proveedor global con el nombre indicado.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// End registra el error, si lo hay, y cierra el span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
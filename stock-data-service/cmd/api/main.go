@@ -3,7 +3,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,56 +11,152 @@ import (
 	"time"
 
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/api"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/api/middlewares"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/auth"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/client"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/config"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/database"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/metrics"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/openapi"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/repository"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/tracing"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/webhooks"
 	"github.com/joho/godotenv"
 )
 
 func main() {
 	// Cargar variables de entorno
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Nota: No se pudo cargar el archivo .env: %v", err)
-	}
+	envErr := godotenv.Load()
 
 	// Inicializar configuración
 	cfg := config.NewConfig()
 
+	// Configurar logs estructurados
+	if _, err := logging.Setup(os.Stdout, cfg.LogLevel, cfg.LogFormat); err != nil {
+		slog.Error("Configuración de logs inválida", "error", err)
+		os.Exit(1)
+	}
+	if envErr != nil {
+		slog.Info("No se pudo cargar el archivo .env", "error", envErr)
+	}
+
+	// Configurar trazas de OpenTelemetry
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName:  api.ServiceName,
+		Exporter:     cfg.TracingExporter,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		SampleRatio:  cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		fatal("Error al configurar las trazas", err)
+	}
+
 	// Imprimir la configuración de la API (solo para debugging)
-	log.Printf("API Base URL configurada: %s", cfg.StockAPIBaseURL)
-	log.Printf("API Auth Token configurado: %s", maskToken(cfg.StockAPIToken))
+	slog.Debug("Configuración de la API externa",
+		"base_url", cfg.StockAPIBaseURL,
+		"auth_token", maskToken(cfg.StockAPIToken))
+
+	// Validar la política CORS antes de arrancar
+	corsConfig := middlewares.CORSConfig{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}
+	if err := corsConfig.Validate(); err != nil {
+		fatal("Configuración de CORS inválida", err)
+	}
 
 	// Conectar a la base de datos
 	db, err := database.Connect(cfg.GetDBConnectionString())
 	if err != nil {
-		log.Fatalf("Error al conectar a la base de datos: %v", err)
+		fatal("Error al conectar a la base de datos", err)
 	}
 	defer db.Close()
 
+	// Publicar las estadísticas del pool de conexiones
+	metrics.RegisterDB(db, "stockdb")
+
 	// Crear repositorio de stocks
-	repo := repository.NewStockRepository(db)
+	repo := repository.NewCockroachStockRepository(db)
 
 	// Inicializar la base de datos
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	if err := repo.InitDB(ctx); err != nil {
 		cancel()
-		log.Fatalf("Error al inicializar la base de datos: %v", err)
+		fatal("Error al inicializar la base de datos", err)
+	}
+
+	// Crear almacén de API keys e inicializar su tabla
+	apiKeys := auth.NewKeyStore(db)
+	if err := apiKeys.InitDB(ctx); err != nil {
+		cancel()
+		fatal("Error al inicializar la base de datos", err)
+	}
+
+	// Crear almacén de webhooks e inicializar sus tablas
+	hooks := webhooks.NewStore(db)
+	if err := hooks.InitDB(ctx); err != nil {
+		cancel()
+		fatal("Error al inicializar la base de datos", err)
 	}
 	cancel()
 
+	// Configurar autenticación
+	jwtVerifier, err := auth.NewJWTVerifier(auth.JWTConfig{
+		HS256Secret: cfg.JWTHS256Secret,
+		JWKSFile:    cfg.JWTJWKSFile,
+		Issuer:      cfg.JWTIssuer,
+		Audience:    cfg.JWTAudience,
+	})
+	if err != nil {
+		fatal("Error al configurar la autenticación JWT", err)
+	}
+	if !cfg.AuthEnabled {
+		slog.Warn("La autenticación está desactivada (AUTH_ENABLED=false)")
+	}
+	authenticator := auth.NewAuthenticator(cfg.AuthEnabled, apiKeys, jwtVerifier, cfg.AuthBootstrapKey)
+
 	// Crear cliente de API externa
-	externalClient := client.NewExternalAPIClient(cfg.StockAPIBaseURL, cfg.StockAPIToken)
+	externalClient := client.NewExternalAPIClient(cfg.StockAPIBaseURL, cfg.StockAPIToken, client.CircuitConfig{
+		Failures: cfg.UpstreamCircuitFailures,
+		Cooldown: cfg.UpstreamCircuitCooldown,
+	})
+
+	// Contexto de las tareas en segundo plano, cancelado al apagar el servidor
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Enviar los webhooks pendientes en segundo plano
+	dispatcher := webhooks.NewDispatcher(hooks, webhooks.Config{
+		MaxAttempts:          cfg.WebhookMaxAttempts,
+		BackoffBase:          cfg.WebhookBackoffBase,
+		BackoffMax:           cfg.WebhookBackoffMax,
+		Timeout:              cfg.WebhookTimeout,
+		PollInterval:         cfg.WebhookPollInterval,
+		Concurrency:          cfg.WebhookConcurrency,
+		AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks,
+	})
+	go dispatcher.Run(backgroundCtx)
+
+	// Cargar el documento OpenAPI con el que se validan las solicitudes
+	spec, err := openapi.Load()
+	if err != nil {
+		fatal("Error al cargar el documento OpenAPI", err)
+	}
 
 	// Configurar servidor HTTP con Gin
-	router := api.NewRouter(externalClient, repo)
+	router := api.NewRouter(externalClient, repo, authenticator, apiKeys, hooks, dispatcher, corsConfig, spec)
 	server := router.SetupServer(cfg.ServerPort)
 
 	// Arrancar servidor en una goroutine
 	go func() {
-		log.Printf("Servidor iniciado en el puerto %s", cfg.ServerPort)
+		slog.Info("Servidor iniciado", "port", cfg.ServerPort)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("Error al iniciar el servidor: %v", err)
+			slog.Error("Error al iniciar el servidor", "error", err)
 		}
 	}()
 
@@ -68,17 +164,23 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Apagando servidor...")
+	slog.Info("Apagando servidor...")
+	stopBackground()
 
 	// Cerrar con timeout
 	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Error al cerrar el servidor: %v", err)
+		fatal("Error al cerrar el servidor", err)
+	}
+
+	// Enviar las trazas pendientes
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Error al cerrar las trazas", "error", err)
 	}
 
-	log.Println("Servidor apagado correctamente")
+	slog.Info("Servidor apagado correctamente")
 }
 
 // Ocultar parte del token cuando se imprime en los logs
@@ -88,3 +190,9 @@ func maskToken(token string) string {
 	}
 	return token[:4] + "..." + token[len(token)-4:]
 }
+
+// fatal registra un error irrecuperable y termina el proceso.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
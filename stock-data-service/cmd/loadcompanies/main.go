@@ -0,0 +1,71 @@
+// Paquete main carga los datos de referencia de las compañías desde un CSV local.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/companies"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/config"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/database"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/repository"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	file := flag.String("file", "companies.csv", "Archivo CSV con las columnas ticker,company,sector,industry,exchange,market_cap")
+	batchSize := flag.Int("batch", 500, "Cantidad de compañías por transacción")
+	flag.Parse()
+
+	// Cargar variables de entorno
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Nota: No se pudo cargar el archivo .env: %v", err)
+	}
+
+	input, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Error al abrir el archivo de compañías: %v", err)
+	}
+	defer input.Close()
+
+	rows, err := companies.ParseCSV(input)
+	if err != nil {
+		log.Fatalf("Error al leer el archivo de compañías: %v", err)
+	}
+
+	// Conectar a la base de datos
+	db, err := database.Connect(config.NewConfig().GetDBConnectionString())
+	if err != nil {
+		log.Fatalf("Error al conectar a la base de datos: %v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewCockroachStockRepository(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if err := repo.InitDB(ctx); err != nil {
+		log.Fatalf("Error al inicializar la base de datos: %v", err)
+	}
+
+	if *batchSize <= 0 {
+		*batchSize = 500
+	}
+
+	for start := 0; start < len(rows); start += *batchSize {
+		end := start + *batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		if err := repo.SaveCompanies(ctx, rows[start:end]); err != nil {
+			log.Fatalf("Error al guardar compañías: %v", err)
+		}
+	}
+
+	log.Printf("Carga completada: %d compañías guardadas", len(rows))
+}
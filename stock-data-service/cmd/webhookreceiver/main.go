@@ -0,0 +1,73 @@
+// Paquete main es un receptor de webhooks para probar las entregas en local.
+// Verifica la firma de cada entrega e imprime el evento recibido.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/webhooks"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "Dirección en la que escucha el receptor")
+	secret := flag.String("secret", "", "Secreto de la suscripción (whsec_...); vacío omite la verificación de la firma")
+	status := flag.Int("status", http.StatusNoContent, "Código HTTP con el que se responde, por ejemplo 500 para probar los reintentos")
+	tolerance := flag.Duration("tolerance", 5*time.Minute, "Antigüedad máxima de la firma")
+	flag.Parse()
+
+	if *secret == "" {
+		log.Printf("Nota: sin -secret no se verifican las firmas")
+	}
+
+	http.Handle("/", newHandler(*secret, *status, *tolerance))
+
+	log.Printf("Receptor de webhooks escuchando en %s", *addr)
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		log.Fatalf("Error al iniciar el receptor: %v", err)
+	}
+}
+
+// newHandler crea el manejador que verifica la firma de cada entrega con secret,
+// si no está vacío, imprime el evento y responde con status.
+func newHandler(secret string, status int, tolerance time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			log.Printf("Error al leer el cuerpo: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if secret != "" {
+			if err := webhooks.Verify(secret, r.Header.Get(webhooks.SignatureHeader), body, tolerance); err != nil {
+				log.Printf("Entrega %s rechazada: %v", r.Header.Get(webhooks.DeliveryHeader), err)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, body, "", "  "); err != nil {
+			pretty.Reset()
+			pretty.Write(body)
+		}
+		log.Printf("Entrega %s, evento %s (%s):\n%s",
+			r.Header.Get(webhooks.DeliveryHeader),
+			r.Header.Get(webhooks.EventHeader),
+			r.Header.Get(webhooks.EventIDHeader),
+			pretty.String())
+
+		w.WriteHeader(status)
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/webhooks"
+)
+
+func TestHandler(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	const secret = "whsec_test"
+	body := `{"id":"evt_1","type":"ping","data":{}}`
+	signed := webhooks.Sign(secret, time.Now(), []byte(body))
+
+	tests := []struct {
+		name      string
+		secret    string
+		method    string
+		signature string
+		status    int
+		want      int
+	}{
+		{"firma válida", secret, http.MethodPost, signed, http.StatusNoContent, http.StatusNoContent},
+		{"código configurado", secret, http.MethodPost, signed, http.StatusInternalServerError, http.StatusInternalServerError},
+		{"firma de otro secreto", secret, http.MethodPost, webhooks.Sign("whsec_otro", time.Now(), []byte(body)), http.StatusNoContent, http.StatusUnauthorized},
+		{"firma antigua", secret, http.MethodPost, webhooks.Sign(secret, time.Now().Add(-time.Hour), []byte(body)), http.StatusNoContent, http.StatusUnauthorized},
+		{"sin firma", secret, http.MethodPost, "", http.StatusNoContent, http.StatusUnauthorized},
+		{"sin secreto no se verifica", "", http.MethodPost, "", http.StatusNoContent, http.StatusNoContent},
+		{"método no permitido", secret, http.MethodGet, signed, http.StatusNoContent, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(body))
+			if tt.signature != "" {
+				req.Header.Set(webhooks.SignatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			newHandler(tt.secret, tt.status, 5*time.Minute).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,497 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/api/handlers"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/api/middlewares"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/auth"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/buildinfo"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/client"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/health"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/openapi"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/problem"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/webhooks"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+)
+
+// Estas pruebas fallan si los handlers y el documento OpenAPI dejan de
+// coincidir: rutas sin documentar o documentadas sin handler, parámetros que un
+// handler lee y el documento no declara, modelos cuyos campos JSON difieren de
+// su esquema y respuestas que no cumplen el documento. No requieren base de datos.
+
+// schemaTypes asocia cada esquema del documento con el tipo que se serializa.
+// Los tipos de solicitud comparan los campos obligatorios con binding:"required".
+var schemaTypes = []struct {
+	schema  string
+	value   any
+	request bool
+}{
+	{"SyncResponse", handlers.SyncResponse{}, false},
+	{"Filters", webhooks.Filters{}, false},
+	{"Subscription", webhooks.Subscription{}, false},
+	{"CreateWebhookResponse", handlers.CreateWebhookResponse{}, false},
+	{"Delivery", webhooks.Delivery{}, false},
+	{"APIKey", auth.APIKey{}, false},
+	{"CreateAPIKeyResponse", handlers.CreateAPIKeyResponse{}, false},
+	{"BuildInfo", buildinfo.Info{}, false},
+	{"LivenessStatus", health.LivenessStatus{}, false},
+	{"CircuitStatus", client.CircuitStatus{}, false},
+	{"Check", health.Check{}, false},
+	{"ReadinessStatus", health.ReadinessStatus{}, false},
+	{"Problem", problem.Problem{}, false},
+	{"FieldError", problem.FieldError{}, false},
+	{"CreateWebhookRequest", handlers.CreateWebhookRequest{}, true},
+	{"CreateAPIKeyRequest", handlers.CreateAPIKeyRequest{}, true},
+}
+
+// newTestRouter crea el router con la autenticación desactivada y sin base de datos.
+func newTestRouter(t *testing.T) (*gin.Engine, *openapi.Spec) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	engine := gin.New()
+	authenticator := auth.NewAuthenticator(false, nil, nil, "")
+	NewRouter(nil, nil, authenticator, nil, nil, nil, middlewares.CORSConfig{}, spec).SetupRoutes(engine)
+	return engine, spec
+}
+
+// ginParam reconoce los parámetros de ruta de gin.
+var ginParam = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+func TestOpenAPIRoutesMatchRouter(t *testing.T) {
+	engine, spec := newTestRouter(t)
+
+	routes := make(map[string]bool)
+	for _, route := range engine.Routes() {
+		routes[route.Method+" "+ginParam.ReplaceAllString(route.Path, "{$1}")] = true
+	}
+
+	operations := make(map[string]bool)
+	for path, item := range spec.Document().Paths.Map() {
+		for method := range item.Operations() {
+			operations[method+" "+path] = true
+		}
+	}
+
+	for _, route := range sortedKeys(routes) {
+		if !operations[route] {
+			t.Errorf("la ruta %s no está en el documento OpenAPI", route)
+		}
+	}
+	for _, operation := range sortedKeys(operations) {
+		if !routes[operation] {
+			t.Errorf("la operación %s del documento OpenAPI no tiene handler", operation)
+		}
+	}
+}
+
+func TestOpenAPIDeclaresHandlerParameters(t *testing.T) {
+	engine, spec := newTestRouter(t)
+	reads := handlerQueryReads(t, filepath.Join("handlers"))
+
+	// Un handler que atiende varias rutas, como ListDeliveries, puede leer
+	// los parámetros declarados en cualquiera de ellas
+	declared := make(map[string]map[string]bool)
+	for _, route := range engine.Routes() {
+		name := handlerName(route.Handler)
+		if name == "" {
+			continue
+		}
+		if declared[name] == nil {
+			declared[name] = make(map[string]bool)
+		}
+
+		item := spec.Document().Paths.Find(ginParam.ReplaceAllString(route.Path, "{$1}"))
+		if item == nil {
+			continue
+		}
+		operation := item.GetOperation(route.Method)
+		if operation == nil {
+			continue
+		}
+		for _, params := range []openapi3.Parameters{item.Parameters, operation.Parameters} {
+			for _, param := range params {
+				if param.Value.In == openapi3.ParameterInQuery {
+					declared[name][param.Value.Name] = true
+				}
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(keySet(declared)) {
+		names, ok := reads[name]
+		if !ok {
+			t.Errorf("no se encontró el código del handler %s", name)
+			continue
+		}
+		for _, query := range sortedKeys(names) {
+			if !declared[name][query] {
+				t.Errorf("%s lee el parámetro %q, que el documento OpenAPI no declara en sus rutas", name, query)
+			}
+		}
+	}
+}
+
+func TestOpenAPISchemasMatchModels(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	schemas := spec.Document().Components.Schemas
+
+	for _, tc := range schemaTypes {
+		ref, ok := schemas[tc.schema]
+		if !ok {
+			t.Errorf("el esquema %s no existe en el documento OpenAPI", tc.schema)
+			continue
+		}
+		properties, required := schemaFields(ref.Value)
+		fields, mandatory := jsonFields(reflect.TypeOf(tc.value), tc.request)
+
+		if got, want := sortedKeys(properties), sortedKeys(fields); !reflect.DeepEqual(got, want) {
+			t.Errorf("propiedades de %s = %v, los campos JSON de %T son %v", tc.schema, got, tc.value, want)
+		}
+		if tc.request {
+			for _, field := range sortedKeys(mandatory) {
+				if !required[field] {
+					t.Errorf("%s.%s es obligatorio en %T pero no en el documento OpenAPI", tc.schema, field, tc.value)
+				}
+			}
+		} else if got, want := sortedKeys(required), sortedKeys(mandatory); !reflect.DeepEqual(got, want) {
+			t.Errorf("campos obligatorios de %s = %v, los campos sin omitempty de %T son %v", tc.schema, got, tc.value, want)
+		}
+	}
+}
+
+func TestOpenAPIRequestValidation(t *testing.T) {
+	engine, spec := newTestRouter(t)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		fields []string
+	}{
+		{"estado de entrega desconocido", "GET", "/api/v1/webhooks/deliveries?status=failed", "", 400, []string{"status"}},
+		{"límite fuera de rango", "GET", "/api/v1/webhooks/deliveries?limit=0&subscription_id=x", "", 400, []string{"limit", "subscription_id"}},
+		{"identificador no numérico", "GET", "/api/v1/webhooks/uno/deliveries?limit=1000", "", 400, []string{"id", "limit"}},
+		{"reintento con identificador inválido", "POST", "/api/v1/webhooks/deliveries/x/retry", "", 400, []string{"id"}},
+		{"webhook sin url", "POST", "/api/v1/webhooks", `{"filters":{"min_target_change_pct":-1}}`, 400, []string{"url", "filters.min_target_change_pct"}},
+		{"cuerpo inválido", "POST", "/api/v1/webhooks", `{"url":`, 400, []string{""}},
+		{"rol desconocido", "POST", "/api/v1/admin/api-keys", `{"name":"ci","role":"root"}`, 400, []string{"role"}},
+		{"sonda de vida", "GET", "/livez", "", 200, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tc.status, rec.Body.String())
+			}
+			validateResponse(t, spec, req, rec)
+
+			if rec.Code < http.StatusBadRequest {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != problem.ContentType {
+				t.Errorf("Content-Type = %q, want %q", got, problem.ContentType)
+			}
+			var body problem.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("respuesta inválida: %v", err)
+			}
+			if body.Code == "" || body.RequestID != rec.Header().Get("X-Request-ID") {
+				t.Errorf("code = %q, request_id = %q, X-Request-ID = %q", body.Code, body.RequestID, rec.Header().Get("X-Request-ID"))
+			}
+
+			got := make(map[string]bool)
+			for _, detail := range body.Errors {
+				if detail.Message == "" {
+					t.Errorf("el error de %q no tiene mensaje", detail.Field)
+				}
+				got[detail.Field] = true
+			}
+			for _, field := range tc.fields {
+				if !got[field] {
+					t.Errorf("falta el error del campo %q en %s", field, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAPIValidationRunsAfterAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	engine := gin.New()
+	authenticator := auth.NewAuthenticator(true, nil, nil, "")
+	NewRouter(nil, nil, authenticator, nil, nil, nil, middlewares.CORSConfig{}, spec).SetupRoutes(engine)
+
+	// Sin credenciales, una solicitud inválida responde 401 y no los errores del contrato
+	for _, target := range []string{"/api/v1/webhooks/deliveries?status=failed", "/api/v1/webhooks/uno/deliveries?limit=1000"} {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s: status = %d, want 401; body = %s", target, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestOpenAPIDocumentServed(t *testing.T) {
+	engine, _ := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, openapi.Path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(rec.Body.Bytes())
+	if err != nil {
+		t.Fatalf("el documento publicado no se puede cargar: %v", err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Paths.Find("/api/v1/sync") == nil {
+		t.Errorf("documento publicado incompleto: openapi = %q", doc.OpenAPI)
+	}
+}
+
+// validateResponse verifica la respuesta contra la operación del documento.
+func validateResponse(t *testing.T, spec *openapi.Spec, req *http.Request, rec *httptest.ResponseRecorder) {
+	t.Helper()
+
+	route, pathParams, err := spec.FindRoute(req)
+	if err != nil {
+		t.Fatalf("FindRoute(%s %s) error = %v", req.Method, req.URL, err)
+	}
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+		},
+		Status: rec.Code,
+		Header: rec.Header(),
+		Body:   io.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+			MultiError:            true,
+		},
+	}
+	if err := openapi3filter.ValidateResponse(req.Context(), input); err != nil {
+		t.Errorf("la respuesta no cumple el documento OpenAPI: %v", err)
+	}
+}
+
+// schemaFields devuelve las propiedades y los campos obligatorios de un esquema,
+// incluidos los de sus allOf.
+func schemaFields(schema *openapi3.Schema) (map[string]bool, map[string]bool) {
+	properties := make(map[string]bool)
+	required := make(map[string]bool)
+	for name := range schema.Properties {
+		properties[name] = true
+	}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	for _, part := range schema.AllOf {
+		p, r := schemaFields(part.Value)
+		for name := range p {
+			properties[name] = true
+		}
+		for name := range r {
+			required[name] = true
+		}
+	}
+	return properties, required
+}
+
+// jsonFields devuelve los campos JSON de un struct, incluidos los de los structs
+// embebidos, y los obligatorios: sin omitempty en las respuestas, con
+// binding:"required" en las solicitudes.
+func jsonFields(typ reflect.Type, request bool) (map[string]bool, map[string]bool) {
+	fields := make(map[string]bool)
+	mandatory := make(map[string]bool)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" {
+			f, m := jsonFields(field.Type, request)
+			for name := range f {
+				fields[name] = true
+			}
+			for name := range m {
+				mandatory[name] = true
+			}
+			continue
+		}
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = true
+		if request {
+			if strings.Contains(field.Tag.Get("binding"), "required") {
+				mandatory[name] = true
+			}
+		} else if !strings.Contains(options, "omitempty") {
+			mandatory[name] = true
+		}
+	}
+	return fields, mandatory
+}
+
+// handlerName devuelve "Tipo.Método" para los handlers del paquete handlers, o
+// una cadena vacía para los demás.
+func handlerName(name string) string {
+	const prefix = "/internal/api/handlers.(*"
+	i := strings.Index(name, prefix)
+	if i < 0 {
+		return ""
+	}
+	typ, method, _ := strings.Cut(strings.TrimSuffix(name[i+len(prefix):], "-fm"), ").")
+	return typ + "." + method
+}
+
+// handlerQueryReads analiza el código de los handlers y devuelve, para cada
+// método "Tipo.Método", los parámetros de consulta que lee con c.Query,
+// c.DefaultQuery o c.GetQuery, directamente o a través de las funciones y los
+// métodos del mismo paquete que llama.
+func handlerQueryReads(t *testing.T, dir string) map[string]map[string]bool {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, 0)
+	if err != nil {
+		t.Fatalf("ParseDir(%s) error = %v", dir, err)
+	}
+
+	direct := make(map[string]map[string]bool)
+	calls := make(map[string][]string)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Body == nil {
+					continue
+				}
+				receiver := ""
+				if fn.Recv != nil && len(fn.Recv.List) > 0 {
+					if star, ok := fn.Recv.List[0].Type.(*ast.StarExpr); ok {
+						if ident, ok := star.X.(*ast.Ident); ok {
+							receiver = ident.Name
+						}
+					}
+				}
+				key := fn.Name.Name
+				if receiver != "" {
+					key = receiver + "." + key
+				}
+
+				direct[key] = make(map[string]bool)
+				ast.Inspect(fn.Body, func(n ast.Node) bool {
+					call, ok := n.(*ast.CallExpr)
+					if !ok {
+						return true
+					}
+					switch fun := call.Fun.(type) {
+					case *ast.Ident:
+						calls[key] = append(calls[key], fun.Name)
+					case *ast.SelectorExpr:
+						switch fun.Sel.Name {
+						case "Query", "DefaultQuery", "GetQuery":
+							if len(call.Args) > 0 {
+								if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+									name, _ := strconv.Unquote(lit.Value)
+									direct[key][name] = true
+								}
+							}
+						default:
+							if receiver != "" {
+								calls[key] = append(calls[key], receiver+"."+fun.Sel.Name)
+							}
+						}
+					}
+					return true
+				})
+			}
+		}
+	}
+
+	reads := make(map[string]map[string]bool)
+	for key := range direct {
+		names := make(map[string]bool)
+		visited := make(map[string]bool)
+		var visit func(string)
+		visit = func(name string) {
+			if visited[name] {
+				return
+			}
+			visited[name] = true
+			for query := range direct[name] {
+				names[query] = true
+			}
+			for _, callee := range calls[name] {
+				visit(callee)
+			}
+		}
+		visit(key)
+		reads[key] = names
+	}
+	return reads
+}
+
+// keySet devuelve las claves de un mapa como conjunto.
+func keySet[V any](m map[string]V) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for key := range m {
+		set[key] = true
+	}
+	return set
+}
+
+// sortedKeys devuelve las claves de un conjunto ordenadas.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
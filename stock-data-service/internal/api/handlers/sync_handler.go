@@ -3,16 +3,29 @@ package handlers
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/client"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/metrics"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/problem"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/repository"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/tracing"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/webhooks"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer crea los spans de las sincronizaciones.
+var tracer = tracing.Tracer("github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/api/handlers")
+
 // SyncResponse representa la respuesta a una operación de sincronización.
 type SyncResponse struct {
 	Status  string `json:"status"`
@@ -21,15 +34,17 @@ type SyncResponse struct {
 
 // SyncHandler maneja las solicitudes de sincronización con la API externa.
 type SyncHandler struct {
-	client *client.ExternalAPIClient
-	repo   *repository.StockRepository
+	client   *client.ExternalAPIClient
+	repo     repository.StockRepository
+	webhooks *webhooks.Dispatcher
 }
 
 // NewSyncHandler crea una nueva instancia de SyncHandler.
-func NewSyncHandler(client *client.ExternalAPIClient, repo *repository.StockRepository) *SyncHandler {
+func NewSyncHandler(client *client.ExternalAPIClient, repo repository.StockRepository, dispatcher *webhooks.Dispatcher) *SyncHandler {
 	return &SyncHandler{
-		client: client,
-		repo:   repo,
+		client:   client,
+		repo:     repo,
+		webhooks: dispatcher,
 	}
 }
 
@@ -39,11 +54,7 @@ func (h *SyncHandler) SyncStocks(c *gin.Context) {
 	// Verificar que el token de autenticación esté configurado
 	apiToken := os.Getenv("STOCK_API_AUTH_TOKEN")
 	if apiToken == "" {
-		response := SyncResponse{
-			Status:  "error",
-			Message: "Error de configuración: No se encontró el token de autenticación para la API",
-		}
-		c.JSON(http.StatusInternalServerError, response)
+		problem.Abort(c, errors.New("error de configuración: no se encontró el token de autenticación para la API"))
 		return
 	}
 
@@ -56,29 +67,90 @@ func (h *SyncHandler) SyncStocks(c *gin.Context) {
 	// Enviar respuesta 202 Accepted
 	c.JSON(http.StatusAccepted, response)
 
+	// El trabajo sobrevive a la solicitud pero conserva su identificador para
+	// correlacionar los logs, el registro de la sincronización y las llamadas salientes
+	jobCtx := context.WithoutCancel(c.Request.Context())
+
 	// Ejecutar la sincronización en una goroutine separada
 	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		ctx, cancel := context.WithTimeout(jobCtx, 10*time.Minute)
 		defer cancel()
 
+		// La sincronización tiene su propia traza, enlazada con la de la solicitud,
+		// porque continúa después de que la solicitud terminó
+		ctx, span := tracer.Start(ctx, "SyncHandler.sync",
+			trace.WithNewRoot(),
+			trace.WithLinks(trace.LinkFromContext(jobCtx)))
+		defer span.End()
+
+		slog.InfoContext(ctx, "Sincronización iniciada")
+
+		run := models.SyncRun{StartedAt: time.Now(), RequestID: logging.RequestID(ctx)}
+		defer h.recordRun(ctx, &run)
+
 		// Obtener todos los stocks de la API externa
-		stocks, err := h.client.FetchAllStocks()
+		stocks, err := h.client.FetchAllStocks(ctx)
 		if err != nil {
-			log.Printf("Error al obtener stocks de la API: %v", err)
+			slog.ErrorContext(ctx, "Error al obtener stocks de la API", "error", err)
+			span.SetStatus(codes.Error, err.Error())
+			run.Status = models.SyncStatusFailed
+			run.Error = err.Error()
+			run.ErrorCode = syncErrorCode(err, models.SyncErrorUpstream)
 			return
 		}
 
 		if len(stocks) == 0 {
-			log.Printf("No se encontraron stocks para sincronizar")
+			slog.InfoContext(ctx, "No se encontraron stocks para sincronizar")
+			run.Status = models.SyncStatusCompleted
 			return
 		}
 
 		// Guardar los stocks en la base de datos
-		if err := h.repo.SaveStocks(ctx, stocks); err != nil {
-			log.Printf("Error al guardar stocks en la base de datos: %v", err)
+		newEvents, err := h.repo.SaveStocks(ctx, stocks)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error al guardar stocks en la base de datos", "error", err)
+			span.SetStatus(codes.Error, err.Error())
+			run.Status = models.SyncStatusFailed
+			run.Error = err.Error()
+			run.ErrorCode = syncErrorCode(err, models.SyncErrorStorage)
 			return
 		}
 
-		log.Printf("Sincronización completada: %d stocks guardados", len(stocks))
+		// Notificar a los suscriptores; un fallo aquí no invalida la sincronización
+		if err := h.webhooks.PublishRatingChanges(ctx, newEvents); err != nil {
+			slog.ErrorContext(ctx, "Error al encolar webhooks de calificaciones", "error", err)
+		}
+
+		run.Status = models.SyncStatusCompleted
+		run.StocksCount = len(stocks)
+		span.SetAttributes(attribute.Int("sync.stocks", len(stocks)), attribute.Int("sync.new_events", len(newEvents)))
+		slog.InfoContext(ctx, "Sincronización completada", "stocks", len(stocks), "new_events", len(newEvents))
 	}()
 }
+
+// syncErrorCode devuelve el código estable de un error de sincronización: timeout
+// si se agotó el tiempo máximo y code en otro caso.
+func syncErrorCode(err error, code string) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return models.SyncErrorTimeout
+	}
+	return code
+}
+
+// recordRun registra el resultado de la sincronización para que otros servicios lo detecten.
+// Usa su propio plazo para poder registrar también las sincronizaciones que agotaron el tiempo.
+func (h *SyncHandler) recordRun(ctx context.Context, run *models.SyncRun) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	run.FinishedAt = time.Now()
+	metrics.ObserveSync(run.Status, run.FinishedAt.Sub(run.StartedAt))
+
+	if err := h.repo.RecordSyncRun(ctx, *run); err != nil {
+		slog.ErrorContext(ctx, "Error al registrar la sincronización", "error", err)
+	}
+
+	if err := h.webhooks.PublishSyncRun(ctx, *run); err != nil {
+		slog.ErrorContext(ctx, "Error al encolar webhooks de sincronización", "error", err)
+	}
+}
@@ -0,0 +1,232 @@
+// Paquete handlers contiene los manejadores de solicitudes HTTP.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/auth"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/problem"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateWebhookRequest representa la solicitud para registrar una suscripción.
+type CreateWebhookRequest struct {
+	// URL que recibe los eventos
+	URL string `json:"url" binding:"required"`
+	// Descripción opcional
+	Description string `json:"description"`
+	// Tipos de evento; vacío suscribe a todos
+	Events []string `json:"events"`
+	// Filtros de los eventos rating.changed
+	Filters webhooks.Filters `json:"filters"`
+}
+
+// CreateWebhookResponse incluye el secreto de firma de la suscripción recién creada.
+type CreateWebhookResponse struct {
+	webhooks.Subscription
+	// Secreto para verificar la firma; solo se devuelve al crear la suscripción
+	Secret string `json:"secret"`
+}
+
+// WebhookHandler maneja las suscripciones de webhooks y su registro de entregas.
+type WebhookHandler struct {
+	store      webhooks.Storage
+	dispatcher *webhooks.Dispatcher
+}
+
+// NewWebhookHandler crea una nueva instancia de WebhookHandler.
+func NewWebhookHandler(store webhooks.Storage, dispatcher *webhooks.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{
+		store:      store,
+		dispatcher: dispatcher,
+	}
+}
+
+// CreateWebhook registra una suscripción y devuelve su secreto una única vez.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.BadRequest("Solicitud inválida: "+err.Error()))
+		return
+	}
+
+	sub := webhooks.Subscription{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Filters:     req.Filters,
+	}
+	if err := sub.Validate(h.dispatcher.AllowsPrivateNetworks()); err != nil {
+		problem.Abort(c, problem.BadRequest(err.Error()))
+		return
+	}
+	if principal, ok := auth.PrincipalFromContext(c); ok {
+		sub.CreatedBy = principal.ID
+	}
+
+	sub, secret, err := h.store.CreateSubscription(c.Request.Context(), sub)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateWebhookResponse{
+		Subscription: sub,
+		Secret:       secret,
+	})
+}
+
+// ListWebhooks lista las suscripciones activas sin sus secretos.
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	subs, err := h.store.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": subs,
+		"count":    len(subs),
+	})
+}
+
+// GetWebhook devuelve una suscripción.
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+
+	sub, err := h.store.GetSubscription(c.Request.Context(), id)
+	if err != nil {
+		respondWebhookError(c, err, "Suscripción no encontrada: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteWebhook elimina una suscripción y descarta sus entregas pendientes.
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteSubscription(c.Request.Context(), id); err != nil {
+		respondWebhookError(c, err, "Suscripción no encontrada o ya eliminada: ")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TestWebhook encola un evento ping para comprobar que el receptor responde.
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.dispatcher.Ping(c.Request.Context(), id)
+	if err != nil {
+		respondWebhookError(c, err, "Suscripción no encontrada: ")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// ListDeliveries devuelve el registro de entregas. Admite los parámetros status
+// (pending, delivered o dead), subscription_id y limit (máximo 500). Bajo
+// /webhooks/:id/deliveries se limita a la suscripción de la ruta.
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	query := webhooks.DeliveryQuery{
+		Status: c.Query("status"),
+		Limit:  100,
+	}
+
+	switch query.Status {
+	case "", webhooks.DeliveryPending, webhooks.DeliveryDelivered, webhooks.DeliveryDead:
+	default:
+		problem.Abort(c, problem.BadRequest("Estado inválido: "+query.Status+" (use pending, delivered o dead)"))
+		return
+	}
+
+	param := "subscription_id"
+	if c.Param("id") != "" {
+		param = "id"
+	}
+	if c.Param(param) != "" || c.Query(param) != "" {
+		id, ok := parseWebhookID(c, param)
+		if !ok {
+			return
+		}
+		query.SubscriptionID = id
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > 500 {
+			problem.Abort(c, problem.BadRequest("Parámetro limit inválido: "+limitStr+" (use un valor entre 1 y 500)"))
+			return
+		}
+		query.Limit = limit
+	}
+
+	deliveries, err := h.store.ListDeliveries(c.Request.Context(), query)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// RetryDelivery vuelve a poner en cola una entrega de la lista de entregas fallidas.
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.store.Redeliver(c.Request.Context(), id)
+	if err != nil {
+		respondWebhookError(c, err, "Entrega no encontrada o no está en la lista de entregas fallidas: ")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// parseWebhookID lee un identificador numérico de la ruta o de la consulta y
+// responde 400 si no es válido.
+func parseWebhookID(c *gin.Context, name string) (int64, bool) {
+	value := c.Param(name)
+	if value == "" {
+		value = c.Query(name)
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("Identificador inválido: "+value))
+		return 0, false
+	}
+	return id, true
+}
+
+// respondWebhookError responde 404 con el mensaje indicado si el recurso no
+// existe y deja los demás errores al middleware de errores.
+func respondWebhookError(c *gin.Context, err error, notFound string) {
+	if errors.Is(err, webhooks.ErrNotFound) {
+		problem.Abort(c, problem.NotFound(notFound+c.Param("id")))
+		return
+	}
+	problem.Abort(c, err)
+}
@@ -2,25 +2,232 @@
 package middlewares
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
-// CORS configura el middleware para manejo de CORS.
-func CORS() gin.HandlerFunc {
+// CORSConfig define la política CORS del servicio.
+type CORSConfig struct {
+	// Orígenes permitidos: "*", orígenes exactos ("https://app.example.com") o
+	// subdominios comodín ("https://*.example.com")
+	AllowedOrigins []string
+	// Métodos permitidos en solicitudes preflight
+	AllowedMethods []string
+	// Cabeceras permitidas en solicitudes preflight; "*" acepta cualquiera
+	AllowedHeaders []string
+	// Cabeceras de la respuesta que el navegador puede leer
+	ExposedHeaders []string
+	// Permite enviar cookies y cabeceras de autenticación
+	AllowCredentials bool
+	// Tiempo que el navegador puede guardar la respuesta preflight
+	MaxAge time.Duration
+}
+
+// Validate verifica que la política sea coherente. Debe llamarse al arrancar.
+func (cfg CORSConfig) Validate() error {
+	if len(cfg.AllowedOrigins) == 0 {
+		return errors.New("CORS: se requiere al menos un origen permitido")
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			if cfg.AllowCredentials {
+				return errors.New("CORS: no se puede permitir credenciales con el origen \"*\"")
+			}
+			continue
+		}
+		if _, err := parseOriginPattern(origin); err != nil {
+			return err
+		}
+	}
+
+	for _, method := range cfg.AllowedMethods {
+		if method == "" || method != strings.ToUpper(method) || strings.ContainsAny(method, " ,") {
+			return fmt.Errorf("CORS: método inválido %q", method)
+		}
+	}
+
+	if cfg.MaxAge < 0 {
+		return errors.New("CORS: el max-age no puede ser negativo")
+	}
+
+	return nil
+}
+
+// originPattern es un origen permitido, exacto o con subdominio comodín.
+type originPattern struct {
+	scheme string
+	// Host sin el "*." inicial en los patrones comodín
+	host     string
+	port     string
+	wildcard bool
+}
+
+// parseOriginPattern interpreta un origen de la configuración.
+func parseOriginPattern(origin string) (originPattern, error) {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		return originPattern{}, fmt.Errorf("CORS: origen inválido %q, se espera esquema://host[:puerto]", origin)
+	}
+
+	pattern := originPattern{
+		scheme: u.Scheme,
+		host:   u.Hostname(),
+		port:   u.Port(),
+	}
+
+	if strings.HasPrefix(pattern.host, "*.") {
+		pattern.wildcard = true
+		pattern.host = strings.TrimPrefix(pattern.host, "*.")
+	}
+	if pattern.host == "" || strings.Contains(pattern.host, "*") {
+		return originPattern{}, fmt.Errorf("CORS: origen inválido %q, el comodín solo se admite como primer subdominio", origin)
+	}
+
+	return pattern, nil
+}
+
+// matches indica si un origen de una solicitud coincide con el patrón.
+func (p originPattern) matches(origin string) bool {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme != p.scheme || u.Port() != p.port {
+		return false
+	}
+
+	host := u.Hostname()
+	if p.wildcard {
+		return strings.HasSuffix(host, "."+p.host)
+	}
+	return host == p.host
+}
+
+// CORS configura el middleware para manejo de CORS con la política indicada,
+// que debe haberse validado con Validate.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	allowAll := false
+	var patterns []originPattern
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		pattern, _ := parseOriginPattern(origin)
+		patterns = append(patterns, pattern)
+	}
+
+	allowedMethods := make(map[string]bool)
+	for _, method := range cfg.AllowedMethods {
+		allowedMethods[method] = true
+	}
+
+	allowAnyHeader := false
+	allowedHeaders := make(map[string]bool)
+	for _, header := range cfg.AllowedHeaders {
+		if header == "*" {
+			allowAnyHeader = true
+			continue
+		}
+		allowedHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	isAllowed := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		for _, pattern := range patterns {
+			if pattern.matches(origin) {
+				return true
+			}
+		}
+		return false
+	}
+
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token")
-		c.Writer.Header().Set("Access-Control-Expose-Headers", "Link")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Max-Age", "300")
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		// La respuesta depende del origen salvo que se permita cualquiera
+		if !allowAll {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		if !isAllowed(origin) {
+			// Sin cabeceras CORS el navegador bloquea la respuesta
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		setOrigin := func() {
+			if allowAll {
+				c.Header("Access-Control-Allow-Origin", "*")
+			} else {
+				c.Header("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if !preflight {
+			setOrigin()
+			if exposed != "" {
+				c.Header("Access-Control-Expose-Headers", exposed)
+			}
+			c.Next()
+			return
+		}
 
 		// Manejar solicitudes OPTIONS (preflight)
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		if !allowedMethods[c.GetHeader("Access-Control-Request-Method")] {
+			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
 
-		c.Next()
+		requested := c.GetHeader("Access-Control-Request-Headers")
+		allowHeaders := headers
+		if allowAnyHeader {
+			allowHeaders = requested
+		} else {
+			for _, header := range strings.Split(requested, ",") {
+				header = strings.TrimSpace(header)
+				if header != "" && !allowedHeaders[http.CanonicalHeaderKey(header)] {
+					c.AbortWithStatus(http.StatusForbidden)
+					return
+				}
+			}
+		}
+
+		setOrigin()
+		c.Header("Access-Control-Allow-Methods", methods)
+		if allowHeaders != "" {
+			c.Header("Access-Control-Allow-Headers", allowHeaders)
+		}
+		c.Header("Access-Control-Max-Age", maxAge)
+		c.AbortWithStatus(http.StatusNoContent)
 	}
 }
@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/problem"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// Errors es un middleware que convierte el error que registra un handler con
+// problem.Abort en una respuesta application/problem+json. Los errores de
+// persistencia se traducen según su categoría y los demás se responden como
+// error interno; la causa solo se escribe en los logs.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		mapped := mapError(err)
+
+		ctx := c.Request.Context()
+		if mapped.Status >= http.StatusInternalServerError {
+			slog.ErrorContext(ctx, "Error al atender la solicitud", "code", mapped.Code, "error", err)
+		} else {
+			slog.DebugContext(ctx, "Solicitud rechazada", "code", mapped.Code, "error", err)
+		}
+
+		problem.Write(c, problem.Problem{
+			Status:    mapped.Status,
+			Detail:    mapped.Detail,
+			Instance:  c.Request.URL.Path,
+			Code:      mapped.Code,
+			RequestID: logging.RequestID(ctx),
+			Errors:    mapped.Errors,
+		})
+	}
+}
+
+// mapError devuelve la respuesta que corresponde a un error.
+func mapError(err error) *problem.Error {
+	var problemErr *problem.Error
+	if errors.As(err, &problemErr) {
+		return problemErr
+	}
+
+	var repoErr *repository.Error
+	if errors.As(err, &repoErr) {
+		switch repoErr.Kind {
+		case repository.ErrNotFound:
+			return problem.New(http.StatusNotFound, problem.CodeNotFound, repoErr.Message)
+		case repository.ErrUnavailable:
+			return problem.New(http.StatusServiceUnavailable, problem.CodeUnavailable, repoErr.Message)
+		}
+	}
+
+	return problem.Internal(err)
+}
+
+// NotFound responde 404 a las rutas que no existen.
+func NotFound(c *gin.Context) {
+	problem.Abort(c, problem.NotFound("Ruta no encontrada: "+c.Request.URL.Path))
+}
@@ -2,7 +2,8 @@
 package middlewares
 
 import (
-	"log"
+	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -27,10 +28,23 @@ func Logger() gin.HandlerFunc {
 			path = path + "?" + raw
 		}
 
-		method := c.Request.Method
 		statusCode := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case statusCode >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case statusCode >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
 
 		// Registrar la información
-		log.Printf("%s %s %d %s", method, path, statusCode, duration)
+		slog.Log(c.Request.Context(), level, "Solicitud HTTP",
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", statusCode),
+			slog.Duration("duration", duration),
+			slog.String("client_ip", c.ClientIP()),
+		)
 	}
 }
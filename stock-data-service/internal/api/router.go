@@ -3,46 +3,106 @@ package api
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/api/handlers"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/api/middlewares"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/auth"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/client"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/health"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/metrics"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/openapi"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/repository"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/webhooks"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
+// ServiceName es el nombre del servicio en las trazas.
+const ServiceName = "stock-data-service"
+
 // Router maneja la configuración de rutas de la API.
 type Router struct {
-	syncHandler   *handlers.SyncHandler
-	healthHandler *health.HealthHandler
+	syncHandler    *handlers.SyncHandler
+	apiKeyHandler  *handlers.APIKeyHandler
+	webhookHandler *handlers.WebhookHandler
+	healthHandler  *health.HealthHandler
+	authenticator  *auth.Authenticator
+	cors           middlewares.CORSConfig
+	spec           *openapi.Spec
 }
 
 // NewRouter crea una nueva instancia del router.
-func NewRouter(client *client.ExternalAPIClient, repo *repository.StockRepository) *Router {
+func NewRouter(client *client.ExternalAPIClient, repo repository.StockRepository, authenticator *auth.Authenticator, apiKeys *auth.KeyStore, hooks webhooks.Storage, dispatcher *webhooks.Dispatcher, cors middlewares.CORSConfig, spec *openapi.Spec) *Router {
 	return &Router{
-		syncHandler:   handlers.NewSyncHandler(client, repo),
-		healthHandler: health.NewHealthHandler(repo),
+		syncHandler:    handlers.NewSyncHandler(client, repo, dispatcher),
+		apiKeyHandler:  handlers.NewAPIKeyHandler(apiKeys),
+		webhookHandler: handlers.NewWebhookHandler(hooks, dispatcher),
+		healthHandler:  health.NewHealthHandler(repo, client),
+		authenticator:  authenticator,
+		cors:           cors,
+		spec:           spec,
 	}
 }
 
 // SetupRoutes configura las rutas del router.
 func (r *Router) SetupRoutes(router *gin.Engine) {
 	// Middleware para todas las rutas
+	router.Use(otelgin.Middleware(ServiceName, otelgin.WithFilter(traced)))
+	router.Use(middlewares.RequestID())
+	router.Use(metrics.Middleware())
 	router.Use(middlewares.Logger())
-	router.Use(middlewares.CORS())
+	router.Use(middlewares.Errors())
+	router.Use(middlewares.CORS(r.cors))
 
-	// Rutas para la API
+	// Rutas para la API, todas requieren autenticación. Cada ruta valida la
+	// solicitud contra el documento OpenAPI después de verificar el rol
 	api := router.Group("/api/v1")
+	api.Use(r.authenticator.Authenticate())
+
+	// Ruta para sincronización
+	api.POST("/sync", auth.RequireRole(auth.RoleOperator), r.spec.Validate(), r.syncHandler.SyncStocks)
+
+	// Suscripciones de webhooks y registro de entregas
+	hooks := api.Group("/webhooks")
+	hooks.Use(auth.RequireRole(auth.RoleOperator), r.spec.Validate())
 	{
-		// Ruta para sincronización
-		api.POST("/sync", r.syncHandler.SyncStocks)
+		hooks.POST("", r.webhookHandler.CreateWebhook)
+		hooks.GET("", r.webhookHandler.ListWebhooks)
+		hooks.GET("/deliveries", r.webhookHandler.ListDeliveries)
+		hooks.POST("/deliveries/:id/retry", r.webhookHandler.RetryDelivery)
+		hooks.GET("/:id", r.webhookHandler.GetWebhook)
+		hooks.DELETE("/:id", r.webhookHandler.DeleteWebhook)
+		hooks.POST("/:id/test", r.webhookHandler.TestWebhook)
+		hooks.GET("/:id/deliveries", r.webhookHandler.ListDeliveries)
 	}
 
-	// Rutas para health checks
-	router.GET("/health", r.healthHandler.BasicHealth)
-	router.GET("/health/detailed", r.healthHandler.DetailedHealth)
+	// Rutas de administración
+	admin := api.Group("/admin")
+	admin.Use(auth.RequireRole(auth.RoleAdmin), r.spec.Validate())
+	{
+		admin.POST("/api-keys", r.apiKeyHandler.CreateAPIKey)
+		admin.GET("/api-keys", r.apiKeyHandler.ListAPIKeys)
+		admin.DELETE("/api-keys/:id", r.apiKeyHandler.RevokeAPIKey)
+	}
+
+	// Sondas de vida y disponibilidad
+	router.GET("/livez", r.healthHandler.Livez)
+	router.GET("/readyz", r.healthHandler.Readyz)
+
+	// Rutas anteriores, se mantienen por compatibilidad
+	router.GET("/health", r.healthHandler.Livez)
+	router.GET("/health/detailed", r.healthHandler.Readyz)
+
+	// Ruta para métricas de Prometheus
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Documento OpenAPI de la API
+	router.GET(openapi.Path, r.spec.Handler())
+
+	// Las rutas desconocidas también responden application/problem+json
+	router.NoRoute(middlewares.NotFound)
 }
 
 // SetupServer configura y devuelve un servidor HTTP listo para usar.
@@ -51,8 +111,9 @@ func (r *Router) SetupServer(port string) *http.Server {
 		port = "8000"
 	}
 
-	// Crear router Gin
-	router := gin.Default()
+	// Crear router Gin; los logs de solicitudes los escribe middlewares.Logger
+	router := gin.New()
+	router.Use(gin.Recovery())
 
 	// Configurar rutas
 	r.SetupRoutes(router)
@@ -68,3 +129,13 @@ func (r *Router) SetupServer(port string) *http.Server {
 
 	return server
 }
+
+// traced indica si una solicitud debe generar un span. Las sondas de salud y las
+// métricas se excluyen porque se consultan continuamente.
+func traced(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/metrics", "/livez", "/readyz":
+		return false
+	}
+	return !strings.HasPrefix(r.URL.Path, "/health")
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/api/middlewares"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/auth"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/openapi"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+// newWebhookRouter crea el router con un almacén de webhooks en memoria y un
+// despachador en segundo plano que se detiene al terminar la prueba.
+func newWebhookRouter(t *testing.T, allowPrivate bool) (*gin.Engine, *openapi.Spec) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	store := webhooks.NewMemoryStore()
+	dispatcher := webhooks.NewDispatcher(store, webhooks.Config{
+		MaxAttempts:          1,
+		Timeout:              5 * time.Second,
+		PollInterval:         10 * time.Millisecond,
+		AllowPrivateNetworks: allowPrivate,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go dispatcher.Run(ctx)
+
+	engine := gin.New()
+	authenticator := auth.NewAuthenticator(false, nil, nil, "")
+	NewRouter(nil, nil, authenticator, nil, store, dispatcher, middlewares.CORSConfig{}, spec).SetupRoutes(engine)
+	return engine, spec
+}
+
+// serve ejecuta una solicitud, valida la respuesta contra el documento y
+// decodifica el cuerpo en out si no es nil.
+func serve(t *testing.T, engine *gin.Engine, spec *openapi.Spec, method, target, body string, status int, out any) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+
+	if rec.Code != status {
+		t.Fatalf("%s %s = %d, want %d; body = %s", method, target, rec.Code, status, rec.Body.String())
+	}
+	validateResponse(t, spec, req, rec)
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("respuesta inválida de %s %s: %v", method, target, err)
+		}
+	}
+}
+
+// deliveryLog es la respuesta del registro de entregas.
+type deliveryLog struct {
+	Deliveries []webhooks.Delivery `json:"deliveries"`
+	Count      int                 `json:"count"`
+}
+
+func TestWebhookDeliveryLog(t *testing.T) {
+	var received atomic.Int32
+	var secret atomic.Value
+	rcv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhooks.Verify(secret.Load().(string), r.Header.Get(webhooks.SignatureHeader), body, time.Minute); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer rcv.Close()
+
+	engine, spec := newWebhookRouter(t, true)
+
+	var created struct {
+		ID     int64  `json:"id"`
+		Secret string `json:"secret"`
+	}
+	serve(t, engine, spec, http.MethodPost, "/api/v1/webhooks", `{"url":"`+rcv.URL+`","events":["sync.failed"]}`, http.StatusCreated, &created)
+	secret.Store(created.Secret)
+	serve(t, engine, spec, http.MethodPost, "/api/v1/webhooks/1/test", "", http.StatusAccepted, nil)
+
+	// El despachador envía el ping en segundo plano
+	var log deliveryLog
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		serve(t, engine, spec, http.MethodGet, "/api/v1/webhooks/1/deliveries", "", http.StatusOK, &log)
+		if log.Count == 1 && log.Deliveries[0].Status != webhooks.DeliveryPending {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("la entrega no se envió a tiempo: %+v", log)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	delivery := log.Deliveries[0]
+	if delivery.Status != webhooks.DeliveryDelivered || delivery.EventType != webhooks.EventPing ||
+		delivery.SubscriptionID != created.ID || received.Load() != 1 {
+		t.Errorf("entrega = %+v, recibidas = %d; want un ping entregado con firma válida", delivery, received.Load())
+	}
+
+	serve(t, engine, spec, http.MethodGet, "/api/v1/webhooks/deliveries?status=delivered&subscription_id=1", "", http.StatusOK, &log)
+	if log.Count != 1 {
+		t.Errorf("entregas con status=delivered = %d, want 1", log.Count)
+	}
+	serve(t, engine, spec, http.MethodGet, "/api/v1/webhooks/deliveries?status=dead", "", http.StatusOK, &log)
+	if log.Count != 0 {
+		t.Errorf("entregas con status=dead = %d, want 0", log.Count)
+	}
+	serve(t, engine, spec, http.MethodGet, "/api/v1/webhooks/2/deliveries", "", http.StatusOK, &log)
+	if log.Count != 0 {
+		t.Errorf("entregas de otra suscripción = %d, want 0", log.Count)
+	}
+}
+
+func TestWebhookRejectsPrivateURL(t *testing.T) {
+	engine, spec := newWebhookRouter(t, false)
+
+	serve(t, engine, spec, http.MethodPost, "/api/v1/webhooks", `{"url":"http://169.254.169.254/latest"}`, http.StatusBadRequest, nil)
+	serve(t, engine, spec, http.MethodPost, "/api/v1/webhooks", `{"url":"https://example.com/hooks"}`, http.StatusCreated, nil)
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/repository"
+)
+
+// keyPrefix identifica las API keys emitidas por el servicio.
+const keyPrefix = "sk_"
+
+// ErrInvalidKey indica que la API key no existe o fue revocada.
+var ErrInvalidKey = errors.New("API key inválida o revocada")
+
+// APIKey representa una API key registrada. El valor en claro nunca se guarda.
+type APIKey struct {
+	// Identificador de la key
+	ID int64 `json:"id"`
+	// Nombre descriptivo
+	Name string `json:"name"`
+	// Primeros caracteres de la key, para identificarla
+	Prefix string `json:"prefix"`
+	// Rol asignado
+	Role Role `json:"role"`
+	// Fecha y hora de creación
+	CreatedAt time.Time `json:"created_at"`
+	// Fecha y hora de revocación
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Principal devuelve el principal asociado a la key.
+func (k APIKey) Principal() Principal {
+	return Principal{
+		ID:     fmt.Sprintf("key:%d", k.ID),
+		Name:   k.Name,
+		Role:   k.Role,
+		Method: MethodAPIKey,
+	}
+}
+
+// KeyStore guarda las API keys en la base de datos como hashes SHA-256.
+type KeyStore struct {
+	db *sql.DB
+}
+
+// NewKeyStore crea un nuevo almacén de API keys.
+func NewKeyStore(db *sql.DB) *KeyStore {
+	return &KeyStore{
+		db: db,
+	}
+}
+
+// InitDB crea la tabla de API keys si no existe.
+func (s *KeyStore) InitDB(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+    CREATE TABLE IF NOT EXISTS api_keys (
+        id INT PRIMARY KEY DEFAULT unique_rowid(),
+        name STRING NOT NULL,
+        prefix STRING NOT NULL,
+        key_hash STRING NOT NULL UNIQUE,
+        role STRING NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT current_timestamp(),
+        revoked_at TIMESTAMP
+    )
+    `)
+	if err != nil {
+		return logging.Errorf(ctx, "error al crear la tabla de API keys: %w", repository.Classify(err))
+	}
+	return nil
+}
+
+// Create genera una nueva API key y devuelve su registro junto con el valor en
+// claro, que solo está disponible en este momento.
+func (s *KeyStore) Create(ctx context.Context, name string, role Role) (APIKey, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return APIKey{}, "", logging.Errorf(ctx, "error al generar la API key: %w", repository.Classify(err))
+	}
+	plaintext := keyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := APIKey{
+		Name:   name,
+		Prefix: plaintext[:len(keyPrefix)+6],
+		Role:   role,
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+        INSERT INTO api_keys (name, prefix, key_hash, role)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at
+    `, key.Name, key.Prefix, HashKey(plaintext), string(key.Role)).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return APIKey{}, "", logging.Errorf(ctx, "error al guardar la API key: %w", repository.Classify(err))
+	}
+
+	return key, plaintext, nil
+}
+
+// List devuelve todas las API keys registradas, incluidas las revocadas.
+func (s *KeyStore) List(ctx context.Context) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, name, prefix, role, created_at, revoked_at
+        FROM api_keys
+        ORDER BY created_at DESC
+    `)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar API keys: %w", repository.Classify(err))
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var key APIKey
+		var role string
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, &role, &key.CreatedAt, &revokedAt); err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear API key: %w", repository.Classify(err))
+		}
+		key.Role = Role(role)
+		if revokedAt.Valid {
+			key.RevokedAt = &revokedAt.Time
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logging.Errorf(ctx, "error al iterar API keys: %w", repository.Classify(err))
+	}
+
+	return keys, nil
+}
+
+// Revoke marca una API key como revocada. Devuelve ErrInvalidKey si no existe
+// o ya estaba revocada.
+func (s *KeyStore) Revoke(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `
+        UPDATE api_keys SET revoked_at = current_timestamp()
+        WHERE id = $1 AND revoked_at IS NULL
+    `, id)
+	if err != nil {
+		return logging.Errorf(ctx, "error al revocar la API key: %w", repository.Classify(err))
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return logging.Errorf(ctx, "error al revocar la API key: %w", repository.Classify(err))
+	}
+	if affected == 0 {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// Lookup busca una API key vigente a partir de su valor en claro.
+func (s *KeyStore) Lookup(ctx context.Context, plaintext string) (APIKey, error) {
+	if !strings.HasPrefix(plaintext, keyPrefix) {
+		return APIKey{}, ErrInvalidKey
+	}
+
+	var key APIKey
+	var role string
+	err := s.db.QueryRowContext(ctx, `
+        SELECT id, name, prefix, role, created_at
+        FROM api_keys
+        WHERE key_hash = $1 AND revoked_at IS NULL
+    `, HashKey(plaintext)).Scan(&key.ID, &key.Name, &key.Prefix, &role, &key.CreatedAt)
+	if err == sql.ErrNoRows {
+		return APIKey{}, ErrInvalidKey
+	}
+	if err != nil {
+		return APIKey{}, logging.Errorf(ctx, "error al consultar la API key: %w", repository.Classify(err))
+	}
+
+	key.Role = Role(role)
+	return key, nil
+}
+
+// HashKey calcula el hash SHA-256 de una API key en claro.
+func HashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
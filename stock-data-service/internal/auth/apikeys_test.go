@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// openCockroach crea una base de datos vacía en el clúster de TEST_DATABASE_URL
+// y la elimina al terminar la prueba. Sin TEST_DATABASE_URL la prueba se omite.
+func openCockroach(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL no está definida")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	name := fmt.Sprintf("auth_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("CREATE DATABASE error = %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP DATABASE " + name + " CASCADE") })
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL inválida: %v", err)
+	}
+	u.Path = "/" + name
+
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestHashKey(t *testing.T) {
+	if HashKey("sk_a") == HashKey("sk_b") {
+		t.Error("HashKey() devolvió el mismo hash para keys distintas")
+	}
+	if got := HashKey("sk_a"); got != HashKey("sk_a") || len(got) != 64 {
+		t.Errorf("HashKey() = %q, want un hash SHA-256 hexadecimal estable", got)
+	}
+}
+
+func TestKeyStore(t *testing.T) {
+	store := NewKeyStore(openCockroach(t))
+	ctx := context.Background()
+	if err := store.InitDB(ctx); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+
+	key, plaintext, err := store.Create(ctx, "dashboard", RoleReader)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.HasPrefix(plaintext, keyPrefix) || !strings.HasPrefix(plaintext, key.Prefix) {
+		t.Errorf("Create() = %q con prefijo %q", plaintext, key.Prefix)
+	}
+
+	found, err := store.Lookup(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if found.ID != key.ID || found.Role != RoleReader || found.Name != "dashboard" {
+		t.Errorf("Lookup() = %+v, want %+v", found, key)
+	}
+	if principal := found.Principal(); principal.ID != fmt.Sprintf("key:%d", key.ID) || principal.Method != MethodAPIKey {
+		t.Errorf("Principal() = %+v", principal)
+	}
+
+	for _, value := range []string{plaintext + "x", "sin-prefijo"} {
+		if _, err := store.Lookup(ctx, value); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Lookup(%q) error = %v, want %v", value, err, ErrInvalidKey)
+		}
+	}
+
+	// Las keys revocadas se listan pero dejan de autenticar
+	if err := store.Revoke(ctx, key.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := store.Revoke(ctx, key.ID); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Revoke() repetido error = %v, want %v", err, ErrInvalidKey)
+	}
+	if _, err := store.Lookup(ctx, plaintext); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Lookup() revocada error = %v, want %v", err, ErrInvalidKey)
+	}
+
+	keys, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].ID != key.ID || keys[0].RevokedAt == nil {
+		t.Errorf("List() = %+v, want la key revocada", keys)
+	}
+}
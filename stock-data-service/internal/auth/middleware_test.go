@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/problem"
+	"github.com/gin-gonic/gin"
+)
+
+const testBootstrapKey = "sk_bootstrap_test"
+
+// newTestEngine crea un router que autentica y responde con el identificador
+// del principal, o con el estado del error registrado.
+func newTestEngine(authenticator *Authenticator) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Next()
+		var p *problem.Error
+		if err := c.Errors.Last(); err != nil && errors.As(err.Err, &p) {
+			c.Status(p.Status)
+		}
+	})
+	engine.GET("/", authenticator.Authenticate(), func(c *gin.Context) {
+		principal, _ := PrincipalFromContext(c)
+		c.String(http.StatusOK, principal.ID)
+	})
+	return engine
+}
+
+func TestAuthenticate(t *testing.T) {
+	verifier, err := NewJWTVerifier(JWTConfig{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+	engine := newTestEngine(NewAuthenticator(true, nil, verifier, testBootstrapKey))
+	token := sign(t, validClaims("operator", nil))
+
+	tests := []struct {
+		name          string
+		apiKey        string
+		authorization string
+		wantStatus    int
+		wantID        string
+	}{
+		{"key de arranque", testBootstrapKey, "", http.StatusOK, "bootstrap"},
+		{"key de arranque como Bearer", "", "Bearer " + testBootstrapKey, http.StatusOK, "bootstrap"},
+		{"JWT", "", "Bearer " + token, http.StatusOK, "jwt:servicio-interno"},
+		{"esquema en minúsculas", "", "bearer " + token, http.StatusOK, "jwt:servicio-interno"},
+		{"sin credenciales", "", "", http.StatusUnauthorized, ""},
+		{"esquema desconocido", "", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, ""},
+		{"Bearer vacío", "", "Bearer ", http.StatusUnauthorized, ""},
+		{"JWT inválido", "", "Bearer no.es.valido", http.StatusUnauthorized, ""},
+		{"key sin prefijo", "otra-key", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("estado = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if rec.Code == http.StatusOK && rec.Body.String() != tt.wantID {
+				t.Errorf("principal = %q, want %q", rec.Body.String(), tt.wantID)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("falta la cabecera WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestAuthenticateDisabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestEngine(NewAuthenticator(false, nil, nil, "")).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "anonymous" {
+		t.Errorf("estado = %d, principal = %q; want 200 y anonymous", rec.Code, rec.Body.String())
+	}
+}
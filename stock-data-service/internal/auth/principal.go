@@ -0,0 +1,41 @@
+package auth
+
+import "github.com/gin-gonic/gin"
+
+// Métodos de autenticación.
+const (
+	MethodAPIKey    = "api_key"
+	MethodJWT       = "jwt"
+	MethodBootstrap = "bootstrap"
+	MethodDisabled  = "disabled"
+)
+
+// principalKey es la clave con la que se guarda el principal en el contexto de Gin.
+const principalKey = "auth.principal"
+
+// Principal identifica al cliente autenticado de una solicitud.
+type Principal struct {
+	// Identificador estable del cliente (key:<id> para API keys, jwt:<sub> para JWT)
+	ID string `json:"id"`
+	// Nombre descriptivo
+	Name string `json:"name"`
+	// Rol asignado
+	Role Role `json:"role"`
+	// Método con el que se autenticó
+	Method string `json:"method"`
+}
+
+// SetPrincipal guarda el principal autenticado en el contexto de la solicitud.
+func SetPrincipal(c *gin.Context, principal Principal) {
+	c.Set(principalKey, principal)
+}
+
+// PrincipalFromContext obtiene el principal autenticado de la solicitud.
+func PrincipalFromContext(c *gin.Context) (Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	principal, ok := value.(Principal)
+	return principal, ok
+}
@@ -0,0 +1,47 @@
+// Paquete auth proporciona autenticación por API key o JWT y autorización por roles.
+package auth
+
+import "fmt"
+
+// Role representa el nivel de acceso de un cliente.
+type Role string
+
+// Roles disponibles, de menor a mayor privilegio.
+const (
+	RoleReader   Role = "reader"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+// roleLevels asigna a cada rol su nivel de privilegio.
+var roleLevels = map[Role]int{
+	RoleReader:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ParseRole valida y convierte una cadena en un rol.
+func ParseRole(value string) (Role, error) {
+	role := Role(value)
+	if _, ok := roleLevels[role]; !ok {
+		return "", fmt.Errorf("rol desconocido: %s", value)
+	}
+	return role, nil
+}
+
+// Allows indica si el rol tiene al menos los privilegios del rol requerido.
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] >= roleLevels[required]
+}
+
+// highestRole devuelve el rol de mayor privilegio de una lista, ignorando los desconocidos.
+func highestRole(values []string) (Role, bool) {
+	var best Role
+	for _, value := range values {
+		role := Role(value)
+		if level, ok := roleLevels[role]; ok && level > roleLevels[best] {
+			best = role
+		}
+	}
+	return best, best != ""
+}
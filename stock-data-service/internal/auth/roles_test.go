@@ -0,0 +1,57 @@
+package auth
+
+import "testing"
+
+func TestParseRole(t *testing.T) {
+	for _, value := range []string{"reader", "operator", "admin"} {
+		if role, err := ParseRole(value); err != nil || string(role) != value {
+			t.Errorf("ParseRole(%q) = %q, %v", value, role, err)
+		}
+	}
+	for _, value := range []string{"", "Admin", "superusuario"} {
+		if _, err := ParseRole(value); err == nil {
+			t.Errorf("ParseRole(%q) no devolvió error", value)
+		}
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role     Role
+		required Role
+		want     bool
+	}{
+		{RoleReader, RoleReader, true},
+		{RoleReader, RoleOperator, false},
+		{RoleOperator, RoleReader, true},
+		{RoleOperator, RoleAdmin, false},
+		{RoleAdmin, RoleOperator, true},
+		{Role("desconocido"), RoleReader, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.Allows(tt.required); got != tt.want {
+			t.Errorf("%q.Allows(%q) = %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestHighestRole(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   Role
+		ok     bool
+	}{
+		{[]string{"reader"}, RoleReader, true},
+		{[]string{"admin", "reader", "operator"}, RoleAdmin, true},
+		{[]string{"desconocido", "operator", ""}, RoleOperator, true},
+		{[]string{"desconocido", ""}, "", false},
+		{nil, "", false},
+	}
+
+	for _, tt := range tests {
+		if got, ok := highestRole(tt.values); got != tt.want || ok != tt.ok {
+			t.Errorf("highestRole(%v) = %q, %v; want %q, %v", tt.values, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Paquete buildinfo expone la versión y el commit con los que se compiló el servicio.
+//
+// Los valores se inyectan al compilar:
+//
+//	go build -ldflags "-X github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/buildinfo.Version=v1.2.0 \
+//	  -X github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/buildinfo.Commit=$(git rev-parse HEAD)" ./cmd/api
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+var (
+	// Version es la versión del servicio
+	Version = "dev"
+	// Commit es el commit de git del que se compiló el servicio
+	Commit = ""
+	// BuildTime es la fecha de compilación en formato RFC 3339
+	BuildTime = ""
+)
+
+// startedAt es el momento en que arrancó el proceso.
+var startedAt = time.Now()
+
+// Info describe el binario en ejecución.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get devuelve la información de compilación. Si el commit no se inyectó, usa el
+// que registra la toolchain de Go al compilar desde un repositorio git.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+		if build, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range build.Settings {
+				if setting.Key == "vcs.revision" {
+					info.Commit = setting.Value
+				}
+			}
+		}
+	}
+
+	return info
+}
+
+// StartedAt devuelve el momento en que arrancó el proceso.
+func StartedAt() time.Time {
+	return startedAt
+}
+
+// Uptime devuelve el tiempo transcurrido desde que arrancó el proceso.
+func Uptime() time.Duration {
+	return time.Since(startedAt)
+}
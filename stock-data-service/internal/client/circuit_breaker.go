@@ -0,0 +1,125 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen indica que el circuito hacia la API externa está abierto tras
+// varios fallos consecutivos y las solicitudes se rechazan sin enviarse.
+var ErrCircuitOpen = errors.New("circuito abierto: la API externa falló repetidamente, se reintentará más tarde")
+
+// Estados del circuito.
+const (
+	// Las solicitudes se envían normalmente
+	CircuitClosed = "closed"
+	// Las solicitudes se rechazan hasta que pase el tiempo de espera
+	CircuitOpen = "open"
+	// Se permite una solicitud de prueba para decidir si cerrar el circuito
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitConfig define cuándo se abre el circuito hacia la API externa.
+type CircuitConfig struct {
+	// Fallos consecutivos que abren el circuito; 0 lo desactiva
+	Failures int
+	// Tiempo que el circuito permanece abierto antes de permitir una prueba
+	Cooldown time.Duration
+}
+
+// CircuitStatus describe el estado actual del circuito.
+type CircuitStatus struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+// circuitBreaker corta las solicitudes a la API externa tras varios fallos consecutivos.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	config   CircuitConfig
+	failures int
+	openedAt time.Time
+	// Indica si hay una solicitud de prueba en curso en estado semiabierto
+	probing bool
+	// Reloj del circuito; las pruebas lo reemplazan
+	now func() time.Time
+}
+
+// newCircuitBreaker crea un circuito cerrado.
+func newCircuitBreaker(config CircuitConfig) *circuitBreaker {
+	return &circuitBreaker{config: config, now: time.Now}
+}
+
+// state calcula el estado actual. Debe llamarse con el lock tomado.
+func (b *circuitBreaker) state(now time.Time) string {
+	if b.config.Failures <= 0 || b.failures < b.config.Failures {
+		return CircuitClosed
+	}
+	if now.Sub(b.openedAt) < b.config.Cooldown {
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
+
+// allow indica si una solicitud puede enviarse. En estado semiabierto solo se
+// permite una solicitud de prueba a la vez.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state(b.now()) {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record registra el resultado de una solicitud.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.config.Failures > 0 && b.failures >= b.config.Failures {
+		// Abrir el circuito o reiniciar la espera si falló la prueba
+		b.openedAt = b.now()
+	}
+}
+
+// release libera la solicitud de prueba sin registrar un resultado, por ejemplo
+// cuando el llamador canceló la solicitud.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// status devuelve el estado actual del circuito.
+func (b *circuitBreaker) status() CircuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := CircuitStatus{
+		State:               b.state(b.now()),
+		ConsecutiveFailures: b.failures,
+	}
+	if status.State != CircuitClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testClock es un reloj que solo avanza cuando la prueba lo indica.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// newTestBreaker crea un circuito con un reloj controlado que se abre tras dos
+// fallos y espera un minuto.
+func newTestBreaker() (*circuitBreaker, *testClock) {
+	clock := &testClock{now: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)}
+	breaker := newCircuitBreaker(CircuitConfig{Failures: 2, Cooldown: time.Minute})
+	breaker.now = clock.Now
+	return breaker, clock
+}
+
+// fail registra una solicitud fallida.
+func fail(t *testing.T, breaker *circuitBreaker) {
+	t.Helper()
+
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("allow() error = %v, want nil", err)
+	}
+	breaker.record(errors.New("502"))
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	breaker, clock := newTestBreaker()
+
+	// Cerrado: un fallo no alcanza el umbral
+	fail(t, breaker)
+	if status := breaker.status(); status.State != CircuitClosed || status.ConsecutiveFailures != 1 || status.OpenedAt != nil {
+		t.Fatalf("status() tras 1 fallo = %+v, want cerrado con 1 fallo", status)
+	}
+
+	// Abierto: el segundo fallo consecutivo rechaza las solicitudes
+	fail(t, breaker)
+	status := breaker.status()
+	if status.State != CircuitOpen || status.OpenedAt == nil || !status.OpenedAt.Equal(clock.now) {
+		t.Fatalf("status() tras 2 fallos = %+v, want abierto desde %v", status, clock.now)
+	}
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow() con el circuito abierto error = %v, want ErrCircuitOpen", err)
+	}
+
+	// Semiabierto: tras la espera se permite una sola solicitud de prueba
+	clock.now = clock.now.Add(time.Minute)
+	if state := breaker.status().State; state != CircuitHalfOpen {
+		t.Fatalf("estado tras la espera = %q, want %q", state, CircuitHalfOpen)
+	}
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("allow() de la prueba error = %v, want nil", err)
+	}
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow() con una prueba en curso error = %v, want ErrCircuitOpen", err)
+	}
+
+	// La prueba fallida vuelve a abrir el circuito y reinicia la espera
+	breaker.record(errors.New("502"))
+	status = breaker.status()
+	if status.State != CircuitOpen || !status.OpenedAt.Equal(clock.now) {
+		t.Fatalf("status() tras la prueba fallida = %+v, want abierto desde %v", status, clock.now)
+	}
+
+	// La prueba exitosa cierra el circuito y reinicia los fallos
+	clock.now = clock.now.Add(time.Minute)
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("allow() de la segunda prueba error = %v, want nil", err)
+	}
+	breaker.record(nil)
+	if status := breaker.status(); status.State != CircuitClosed || status.ConsecutiveFailures != 0 {
+		t.Errorf("status() tras la prueba exitosa = %+v, want cerrado sin fallos", status)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	breaker, _ := newTestBreaker()
+
+	fail(t, breaker)
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("allow() error = %v", err)
+	}
+	breaker.record(nil)
+	fail(t, breaker)
+
+	// Los fallos no consecutivos no abren el circuito
+	if status := breaker.status(); status.State != CircuitClosed || status.ConsecutiveFailures != 1 {
+		t.Errorf("status() = %+v, want cerrado con 1 fallo", status)
+	}
+}
+
+func TestCircuitBreakerReleaseFreesProbe(t *testing.T) {
+	breaker, clock := newTestBreaker()
+	fail(t, breaker)
+	fail(t, breaker)
+	clock.now = clock.now.Add(time.Minute)
+
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("allow() de la prueba error = %v", err)
+	}
+	// Una prueba cancelada no cuenta como resultado y libera el turno
+	breaker.release()
+	if err := breaker.allow(); err != nil {
+		t.Errorf("allow() tras release() error = %v, want nil", err)
+	}
+	if state := breaker.status().State; state != CircuitHalfOpen {
+		t.Errorf("estado tras release() = %q, want %q", state, CircuitHalfOpen)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker := newCircuitBreaker(CircuitConfig{Failures: 0, Cooldown: time.Minute})
+
+	for i := 0; i < 10; i++ {
+		fail(t, breaker)
+	}
+	if status := breaker.status(); status.State != CircuitClosed {
+		t.Errorf("status() con el circuito desactivado = %+v, want cerrado", status)
+	}
+}
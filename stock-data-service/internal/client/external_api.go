@@ -2,14 +2,24 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/metrics"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // APIError representa un error devuelto por la API externa.
@@ -24,15 +34,20 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("API retornó estado %d para URL %s: %s", e.StatusCode, e.URL, e.Body)
 }
 
+// tracer crea un span por cada página solicitada a la API externa.
+var tracer = tracing.Tracer("github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/client")
+
 // ExternalAPIClient maneja la comunicación con la API externa de stocks.
 type ExternalAPIClient struct {
 	httpClient *http.Client
 	baseURL    string
 	authToken  string
+	breaker    *circuitBreaker
 }
 
-// NewExternalAPIClient crea un nuevo cliente para la API externa.
-func NewExternalAPIClient(baseURL, authToken string) *ExternalAPIClient {
+// NewExternalAPIClient crea un nuevo cliente para la API externa. El circuito
+// deja de enviar solicitudes tras varios fallos consecutivos.
+func NewExternalAPIClient(baseURL, authToken string, circuit CircuitConfig) *ExternalAPIClient {
 	// Usar valores predeterminados si no se proporcionan
 	if baseURL == "" {
 		baseURL = "https://api.stockapi.com/v1/stocks"
@@ -41,18 +56,56 @@ func NewExternalAPIClient(baseURL, authToken string) *ExternalAPIClient {
 	return &ExternalAPIClient{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
+			// Crea un span por solicitud y propaga el contexto de trazas (traceparent)
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
 		},
 		baseURL:   baseURL,
 		authToken: authToken,
+		breaker:   newCircuitBreaker(circuit),
 	}
 }
 
-// FetchStocks obtiene una página de stocks desde la API externa.
-func (c *ExternalAPIClient) FetchStocks(nextPage string) ([]models.Stock, string, error) {
+// Configured indica si el cliente tiene las credenciales necesarias para
+// consultar la API externa.
+func (c *ExternalAPIClient) Configured() bool {
+	return c.baseURL != "" && c.authToken != ""
+}
+
+// Circuit devuelve el estado del circuito hacia la API externa.
+func (c *ExternalAPIClient) Circuit() CircuitStatus {
+	return c.breaker.status()
+}
+
+// FetchStocks obtiene una página de stocks desde la API externa. El identificador
+// de solicitud del contexto se envía en la cabecera X-Request-ID.
+func (c *ExternalAPIClient) FetchStocks(ctx context.Context, nextPage string) (stocks []models.Stock, next string, err error) {
+	ctx, span := tracer.Start(ctx, "ExternalAPIClient.FetchStocks",
+		trace.WithAttributes(attribute.Bool("stock_api.first_page", nextPage == "")))
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("stock_api.items", len(stocks)),
+			attribute.Bool("stock_api.has_next_page", next != ""),
+		)
+		tracing.End(span, err)
+	}()
+
 	if c.authToken == "" {
 		return nil, "", fmt.Errorf("no se ha configurado el token de autenticación (STOCK_API_AUTH_TOKEN)")
 	}
 
+	// Rechazar la solicitud si el circuito está abierto
+	if err := c.breaker.allow(); err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		// Una cancelación propia no indica un fallo de la API externa
+		if ctx.Err() != nil {
+			c.breaker.release()
+			return
+		}
+		c.breaker.record(err)
+	}()
+
 	// Construir URL con parámetros de paginación si es necesario
 	reqURL := c.baseURL
 	if nextPage != "" {
@@ -62,17 +115,21 @@ func (c *ExternalAPIClient) FetchStocks(nextPage string) ([]models.Stock, string
 	}
 
 	// Crear la solicitud con encabezados de autenticación
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("error al crear la solicitud: %w", err)
 	}
 
 	req.Header.Add("Authorization", "Bearer "+c.authToken)
 	req.Header.Add("Content-Type", "application/json")
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Add("X-Request-ID", id)
+	}
 
 	// Realizar la solicitud
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		metrics.AddUpstreamError("network")
 		return nil, "", fmt.Errorf("error al realizar la solicitud: %w", err)
 	}
 	defer resp.Body.Close()
@@ -80,11 +137,14 @@ func (c *ExternalAPIClient) FetchStocks(nextPage string) ([]models.Stock, string
 	// Leer el cuerpo de la respuesta
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
+		metrics.AddUpstreamError("network")
 		return nil, "", fmt.Errorf("error al leer el cuerpo de la respuesta: %w", err)
 	}
 
 	// Verificar si la respuesta tiene un código de estado exitoso
 	if resp.StatusCode != http.StatusOK {
+		metrics.AddUpstreamError(strconv.Itoa(resp.StatusCode))
+
 		if resp.StatusCode == http.StatusGone {
 			return nil, "", fmt.Errorf("el recurso de la API ya no está disponible (410 Gone). El endpoint de la API podría estar obsoleto o haber sido movido")
 		}
@@ -99,22 +159,29 @@ func (c *ExternalAPIClient) FetchStocks(nextPage string) ([]models.Stock, string
 	// Decodificar la respuesta JSON
 	var apiResp models.APIResponse
 	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
+		metrics.AddUpstreamError("decode")
 		return nil, "", fmt.Errorf("error al decodificar la respuesta: %w", err)
 	}
+	metrics.AddPage(len(apiResp.Items))
 
 	return apiResp.Items, apiResp.NextPage, nil
 }
 
 // FetchAllStocks recupera todos los stocks paginando automáticamente.
-func (c *ExternalAPIClient) FetchAllStocks() ([]models.Stock, error) {
+func (c *ExternalAPIClient) FetchAllStocks(ctx context.Context) ([]models.Stock, error) {
 	var allStocks []models.Stock
 	nextPage := ""
 	maxRetries := 3
 	retryCount := 0
 
 	for {
-		stocks, newNextPage, err := c.FetchStocks(nextPage)
+		stocks, newNextPage, err := c.FetchStocks(ctx, nextPage)
 		if err != nil {
+			// Con el circuito abierto no tiene sentido reintentar
+			if errors.Is(err, ErrCircuitOpen) {
+				return nil, err
+			}
+
 			// Manejar el caso especial de recurso no disponible
 			if err.Error() == "el recurso de la API ya no está disponible (410 Gone). El endpoint de la API podría estar obsoleto o haber sido movido" {
 				return nil, err
@@ -122,8 +189,14 @@ func (c *ExternalAPIClient) FetchAllStocks() ([]models.Stock, error) {
 
 			// Reintentar en caso de error
 			retryCount++
-			if retryCount <= maxRetries {
-				time.Sleep(2 * time.Second)
+			if retryCount <= maxRetries && ctx.Err() == nil {
+				slog.WarnContext(ctx, "Error al obtener una página de stocks, reintentando",
+					"attempt", retryCount, "max_retries", maxRetries, "error", err)
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(2 * time.Second):
+				}
 				continue
 			}
 			return nil, err
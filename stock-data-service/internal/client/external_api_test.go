@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/upstreamtest"
+)
+
+// Las pruebas con reintentos esperan el intervalo real de FetchAllStocks entre
+// intentos, por lo que se ejecutan en paralelo.
+
+const testToken = "test-token"
+
+// newTestClient arranca una API falsa con las páginas indicadas y un cliente que la usa.
+func newTestClient(t *testing.T, pages ...[]models.Stock) (*ExternalAPIClient, *upstreamtest.Server) {
+	t.Helper()
+
+	upstream := upstreamtest.NewServer(testToken)
+	t.Cleanup(upstream.Close)
+	upstream.SetPages(pages...)
+
+	return NewExternalAPIClient(upstream.URL, testToken, CircuitConfig{Failures: 5, Cooldown: time.Minute}), upstream
+}
+
+// page crea una página con una calificación por ticker.
+func page(tickers ...string) []models.Stock {
+	stocks := make([]models.Stock, len(tickers))
+	for i, ticker := range tickers {
+		stocks[i] = models.Stock{
+			Ticker:     ticker,
+			Company:    ticker + " Inc.",
+			TargetFrom: "$10.00",
+			TargetTo:   "$12.00",
+			Action:     "upgraded by",
+			Brokerage:  "Goldman Sachs",
+			RatingFrom: "Hold",
+			RatingTo:   "Buy",
+			Time:       time.Date(2024, 3, 1, 10, i, 0, 0, time.UTC),
+		}
+	}
+	return stocks
+}
+
+// tickers devuelve los tickers de los stocks, en orden.
+func tickers(stocks []models.Stock) []string {
+	result := make([]string, len(stocks))
+	for i, stock := range stocks {
+		result[i] = stock.Ticker
+	}
+	return result
+}
+
+func TestFetchAllStocksPagination(t *testing.T) {
+	client, upstream := newTestClient(t, page("AAPL", "MSFT"), page("JPM"), page("TSLA"))
+
+	ctx := logging.WithRequestID(context.Background(), "req-pagination")
+	stocks, err := client.FetchAllStocks(ctx)
+	if err != nil {
+		t.Fatalf("FetchAllStocks() error = %v", err)
+	}
+	if got, want := tickers(stocks), []string{"AAPL", "MSFT", "JPM", "TSLA"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FetchAllStocks() = %v, want %v", got, want)
+	}
+	if !stocks[0].Time.Equal(page("AAPL")[0].Time) {
+		t.Errorf("FetchAllStocks()[0].Time = %s, want %s", stocks[0].Time, page("AAPL")[0].Time)
+	}
+
+	if got, want := upstream.NextPages(), []string{"", "page-2", "page-3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("next_page solicitados = %q, want %q", got, want)
+	}
+	for _, request := range upstream.Requests() {
+		if request.Authorization != "Bearer "+testToken {
+			t.Errorf("Authorization = %q, want el token configurado", request.Authorization)
+		}
+		if request.RequestID != "req-pagination" {
+			t.Errorf("X-Request-ID = %q, want req-pagination", request.RequestID)
+		}
+	}
+}
+
+func TestFetchAllStocksRetries(t *testing.T) {
+	t.Parallel()
+
+	client, upstream := newTestClient(t, page("AAPL"), page("MSFT"))
+	upstream.Inject(2, upstreamtest.TooManyRequests, upstreamtest.InternalError, upstreamtest.MalformedJSON)
+
+	stocks, err := client.FetchAllStocks(context.Background())
+	if err != nil {
+		t.Fatalf("FetchAllStocks() error = %v", err)
+	}
+	if got, want := tickers(stocks), []string{"AAPL", "MSFT"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FetchAllStocks() = %v, want %v", got, want)
+	}
+
+	// Los reintentos piden la misma página sin repetir las anteriores
+	if got, want := upstream.NextPages(), []string{"", "page-2", "page-2", "page-2", "page-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("next_page solicitados = %q, want %q", got, want)
+	}
+	if state := client.Circuit().State; state != CircuitClosed {
+		t.Errorf("circuito = %s tras recuperarse, want %s", state, CircuitClosed)
+	}
+}
+
+func TestFetchAllStocksGivesUp(t *testing.T) {
+	t.Parallel()
+
+	client, upstream := newTestClient(t, page("AAPL"))
+	upstream.Inject(1, upstreamtest.InternalError, upstreamtest.InternalError, upstreamtest.InternalError, upstreamtest.InternalError)
+
+	_, err := client.FetchAllStocks(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 500 {
+		t.Fatalf("FetchAllStocks() error = %v, want un APIError 500", err)
+	}
+	// El intento inicial más tres reintentos
+	if got := len(upstream.Requests()); got != 4 {
+		t.Errorf("solicitudes = %d, want 4", got)
+	}
+}
+
+func TestFetchAllStocksGone(t *testing.T) {
+	client, upstream := newTestClient(t, page("AAPL"))
+	upstream.Inject(1, upstreamtest.Gone)
+
+	_, err := client.FetchAllStocks(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "410") {
+		t.Fatalf("FetchAllStocks() error = %v, want el error de 410", err)
+	}
+	// Un recurso retirado no se reintenta
+	if got := len(upstream.Requests()); got != 1 {
+		t.Errorf("solicitudes = %d, want 1", got)
+	}
+}
+
+func TestFetchStocksSlow(t *testing.T) {
+	client, upstream := newTestClient(t, page("AAPL"))
+	upstream.Inject(1, upstreamtest.Slow(50*time.Millisecond), upstreamtest.Slow(5*time.Second))
+
+	// Una respuesta lenta dentro del plazo se acepta
+	stocks, _, err := client.FetchStocks(context.Background(), "")
+	if err != nil || len(stocks) != 1 {
+		t.Fatalf("FetchStocks() lento = %v, %v; want una página", stocks, err)
+	}
+
+	// Si vence el plazo del llamador la solicitud se abandona sin contar como fallo
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err = client.FetchStocks(ctx, "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("FetchStocks() con plazo vencido error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("FetchStocks() esperó %s, want que respete el plazo", elapsed)
+	}
+	if failures := client.Circuit().ConsecutiveFailures; failures != 0 {
+		t.Errorf("fallos consecutivos = %d, want 0", failures)
+	}
+}
+
+func TestFetchStocksUnauthorized(t *testing.T) {
+	upstream := upstreamtest.NewServer(testToken)
+	t.Cleanup(upstream.Close)
+	upstream.SetPages(page("AAPL"))
+
+	client := NewExternalAPIClient(upstream.URL, "otro-token", CircuitConfig{})
+	_, _, err := client.FetchStocks(context.Background(), "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 401 {
+		t.Errorf("FetchStocks() error = %v, want un APIError 401", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Paquete companies carga los datos de referencia de las compañías desde archivos CSV.
+package companies
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+)
+
+// Rangos de capitalización de mercado.
+const (
+	BucketMega  = "mega"
+	BucketLarge = "large"
+	BucketMid   = "mid"
+	BucketSmall = "small"
+	BucketMicro = "micro"
+)
+
+// ParseCSV lee compañías desde un CSV con cabecera. Las columnas ticker y sector
+// son obligatorias; company, industry y exchange son opcionales. El rango de
+// capitalización se toma de market_cap_bucket o se calcula a partir de market_cap
+// (en dólares).
+func ParseCSV(r io.Reader) ([]models.Company, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error al leer la cabecera del CSV: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"ticker", "sector"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("el CSV no contiene la columna obligatoria %q", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var companies []models.Company
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la línea %d: %w", line, err)
+		}
+
+		ticker := strings.ToUpper(field(record, "ticker"))
+		if ticker == "" {
+			return nil, fmt.Errorf("ticker vacío en la línea %d", line)
+		}
+
+		bucket := strings.ToLower(field(record, "market_cap_bucket"))
+		if bucket == "" {
+			if marketCap := field(record, "market_cap"); marketCap != "" {
+				value, err := strconv.ParseFloat(marketCap, 64)
+				if err != nil {
+					return nil, fmt.Errorf("market_cap inválido en la línea %d: %w", line, err)
+				}
+				bucket = MarketCapBucket(value)
+			}
+		}
+
+		companies = append(companies, models.Company{
+			Ticker:          ticker,
+			Company:         field(record, "company"),
+			Sector:          field(record, "sector"),
+			Industry:        field(record, "industry"),
+			Exchange:        strings.ToUpper(field(record, "exchange")),
+			MarketCapBucket: bucket,
+		})
+	}
+
+	return companies, nil
+}
+
+// MarketCapBucket clasifica una capitalización de mercado en dólares.
+func MarketCapBucket(marketCap float64) string {
+	switch {
+	case marketCap >= 200e9:
+		return BucketMega
+	case marketCap >= 10e9:
+		return BucketLarge
+	case marketCap >= 2e9:
+		return BucketMid
+	case marketCap >= 300e6:
+		return BucketSmall
+	default:
+		return BucketMicro
+	}
+}
@@ -4,16 +4,47 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config contiene la configuración de la aplicación.
 type Config struct {
 	// Puerto del servidor
 	ServerPort string
+	// Nivel de log: debug, info, warn o error
+	LogLevel string
+	// Formato de log: json o text
+	LogFormat string
+	// Exportador de trazas: none, stdout u otlp
+	TracingExporter string
+	// URL del colector OTLP/HTTP
+	TracingOTLPEndpoint string
+	// Fracción de trazas muestreadas, entre 0 y 1
+	TracingSampleRatio float64
 	// URL base de la API externa de stocks
 	StockAPIBaseURL string
 	// Token de autenticación para la API externa
 	StockAPIToken string
+	// Fallos consecutivos de la API externa que abren el circuito; 0 lo desactiva
+	UpstreamCircuitFailures int
+	// Tiempo que el circuito permanece abierto antes de volver a intentar
+	UpstreamCircuitCooldown time.Duration
+	// Intentos de entrega de un webhook antes de moverlo a la lista de entregas fallidas
+	WebhookMaxAttempts int
+	// Espera tras el primer fallo de entrega; se duplica en cada intento
+	WebhookBackoffBase time.Duration
+	// Espera máxima entre intentos de entrega
+	WebhookBackoffMax time.Duration
+	// Tiempo máximo de respuesta del receptor de un webhook
+	WebhookTimeout time.Duration
+	// Intervalo de consulta de entregas pendientes
+	WebhookPollInterval time.Duration
+	// Entregas de webhooks enviadas en paralelo
+	WebhookConcurrency int
+	// Permite registrar y entregar webhooks a direcciones de loopback, de enlace local y privadas
+	WebhookAllowPrivateNetworks bool
 	// Configuración de la base de datos
 	DBHost     string
 	DBPort     string
@@ -21,6 +52,20 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	DBSSLMode  string
+	// Configuración de autenticación
+	AuthEnabled      bool
+	AuthBootstrapKey string
+	JWTHS256Secret   string
+	JWTJWKSFile      string
+	JWTIssuer        string
+	JWTAudience      string
+	// Configuración de CORS
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
 }
 
 // NewConfig crea una nueva instancia de configuración con valores predeterminados
@@ -28,9 +73,24 @@ type Config struct {
 func NewConfig() *Config {
 	return &Config{
 		ServerPort:      getEnv("SERVER_PORT", "8080"),
+		LogLevel:        getEnv("LOG_LEVEL", "info"),
+		LogFormat:       getEnv("LOG_FORMAT", "json"),
 		StockAPIBaseURL: getEnv("STOCK_API_BASE_URL", "https://api.stockapi.com/v1/stocks"),
 		StockAPIToken:   getEnv("STOCK_API_AUTH_TOKEN", ""),
 
+		// Circuito hacia la API externa
+		UpstreamCircuitFailures: getEnvInt("UPSTREAM_CIRCUIT_FAILURES", 5),
+		UpstreamCircuitCooldown: getEnvDuration("UPSTREAM_CIRCUIT_COOLDOWN", time.Minute),
+
+		// Entrega de webhooks
+		WebhookMaxAttempts:          getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookBackoffBase:          getEnvDuration("WEBHOOK_BACKOFF_BASE", 30*time.Second),
+		WebhookBackoffMax:           getEnvDuration("WEBHOOK_BACKOFF_MAX", time.Hour),
+		WebhookTimeout:              getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookPollInterval:         getEnvDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+		WebhookConcurrency:          getEnvInt("WEBHOOK_CONCURRENCY", 4),
+		WebhookAllowPrivateNetworks: getEnvBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+
 		// Configuración de base de datos
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "26257"),
@@ -38,6 +98,22 @@ func NewConfig() *Config {
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBName:     getEnv("DB_NAME", "stockdb"),
 		DBSSLMode:  getEnv("DB_SSL_MODE", "disable"),
+
+		// Configuración de autenticación
+		AuthEnabled:      getEnvBool("AUTH_ENABLED", true),
+		AuthBootstrapKey: getEnv("AUTH_BOOTSTRAP_ADMIN_KEY", ""),
+		JWTHS256Secret:   getEnv("AUTH_JWT_HS256_SECRET", ""),
+		JWTJWKSFile:      getEnv("AUTH_JWKS_FILE", ""),
+		JWTIssuer:        getEnv("AUTH_JWT_ISSUER", ""),
+		JWTAudience:      getEnv("AUTH_JWT_AUDIENCE", ""),
+
+		// Configuración de CORS
+		CORSAllowedOrigins:   getEnvList("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		CORSAllowedMethods:   getEnvList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+		CORSAllowedHeaders:   getEnvList("CORS_ALLOWED_HEADERS", []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-CSRF-Token", "X-Request-ID"}),
+		CORSExposedHeaders:   getEnvList("CORS_EXPOSED_HEADERS", []string{"Link", "X-Request-ID"}),
+		CORSAllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
+		CORSMaxAge:           getEnvDuration("CORS_MAX_AGE", 5*time.Minute),
 	}
 }
 
@@ -54,3 +130,61 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt obtiene el valor entero de una variable de entorno o devuelve un valor predeterminado.
+func getEnvInt(key string, defaultValue int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvBool obtiene el valor booleano de una variable de entorno o devuelve un valor predeterminado.
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDuration obtiene una duración (por ejemplo "30s" o "5m") de una variable de
+// entorno o devuelve un valor predeterminado.
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList obtiene una lista separada por comas de una variable de entorno o
+// devuelve un valor predeterminado. Los elementos vacíos se descartan.
+func getEnvList(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvFloat obtiene el valor decimal de una variable de entorno o devuelve un valor predeterminado.
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
@@ -4,7 +4,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -51,7 +51,7 @@ func Connect(connectionString string) (*sql.DB, error) {
 		return nil, fmt.Errorf("error al verificar la conexión a la base de datos: %w", err)
 	}
 
-	log.Println("Conexión exitosa a CockroachDB")
+	slog.Info("Conexión exitosa a CockroachDB")
 	return db, nil
 }
 
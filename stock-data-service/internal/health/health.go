@@ -3,72 +3,153 @@ package health
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
 	"time"
 
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/buildinfo"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/client"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
-// HealthStatus representa el estado de salud del servicio.
-type HealthStatus struct {
-	Status         string            `json:"status"`
-	Components     map[string]string `json:"components,omitempty"`
-	APICredentials bool              `json:"api_credentials_configured"`
-	Timestamp      time.Time         `json:"timestamp"`
-	Version        string            `json:"version"`
+// Estados de las verificaciones.
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degradado"
+	StatusUnavailable = "no_disponible"
+)
+
+// Mensajes de las verificaciones fallidas. /readyz no requiere autenticación,
+// así que el error original solo se registra en los logs.
+const (
+	databaseUnavailable = "la base de datos no responde"
+	schemaUnavailable   = "el esquema de la base de datos no está disponible o es anterior al de este binario"
+	upstreamMissing     = "faltan la URL o el token de la API externa (STOCK_API_BASE_URL, STOCK_API_AUTH_TOKEN)"
+	upstreamCircuitOpen = "el circuito hacia la API externa está abierto"
+)
+
+// Check es el resultado de la verificación de una dependencia.
+type Check struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Estado del circuito, solo en la verificación de la API externa
+	Circuit *client.CircuitStatus `json:"circuit,omitempty"`
+}
+
+// LivenessStatus representa el estado del proceso.
+type LivenessStatus struct {
+	Status        string         `json:"status"`
+	Build         buildinfo.Info `json:"build"`
+	StartedAt     time.Time      `json:"started_at"`
+	UptimeSeconds int64          `json:"uptime_seconds"`
+	Timestamp     time.Time      `json:"timestamp"`
+}
+
+// ReadinessStatus representa el estado de las dependencias y la frescura de los datos.
+type ReadinessStatus struct {
+	LivenessStatus
+	Checks             map[string]Check `json:"checks"`
+	LastSuccessfulSync *time.Time       `json:"last_successful_sync"`
+	NewestEventTime    *time.Time       `json:"newest_event_time"`
+	DataAgeSeconds     *int64           `json:"data_age_seconds"`
 }
 
 // HealthHandler maneja las verificaciones de salud del servicio.
 type HealthHandler struct {
-	repo *repository.StockRepository
+	repo   repository.StockRepository
+	client *client.ExternalAPIClient
 }
 
 // NewHealthHandler crea una nueva instancia de HealthHandler.
-func NewHealthHandler(repo *repository.StockRepository) *HealthHandler {
+func NewHealthHandler(repo repository.StockRepository, client *client.ExternalAPIClient) *HealthHandler {
 	return &HealthHandler{
-		repo: repo,
+		repo:   repo,
+		client: client,
 	}
 }
 
-// BasicHealth verifica el estado básico del servicio.
-func (h *HealthHandler) BasicHealth(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+// Livez indica si el proceso está vivo. No consulta dependencias para que un
+// fallo de la base de datos no provoque reinicios.
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, liveness())
 }
 
-// DetailedHealth verifica el estado detallado del servicio.
-func (h *HealthHandler) DetailedHealth(c *gin.Context) {
+// Readyz indica si el servicio puede atender tráfico. Devuelve 503 si la base de
+// datos no responde, su esquema es anterior al que crea este binario o la API
+// externa no está configurada. La disponibilidad solo depende de lo local: con
+// el circuito hacia la API externa abierto el estado es degradado, porque
+// reiniciar o sacar de balanceo el servicio no arregla la API externa.
+func (h *HealthHandler) Readyz(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	status := HealthStatus{
-		Status:     "ok",
-		Components: make(map[string]string),
-		Timestamp:  time.Now(),
-		Version:    "1.0.0",
+	status := ReadinessStatus{
+		LivenessStatus: liveness(),
+		Checks:         make(map[string]Check),
 	}
 
 	// Verificar conexión a la base de datos
-	if err := h.repo.Ping(ctx); err != nil {
-		status.Status = "degradado"
-		status.Components["database"] = "error: " + err.Error()
-	} else {
-		status.Components["database"] = "ok"
+	status.Checks["database"] = check(ctx, "database", h.repo.Ping(ctx), databaseUnavailable)
+
+	// Verificar que la base de datos tenga el esquema de este binario
+	version, err := h.repo.GetSchemaVersion(ctx)
+	if err == nil && version < repository.SchemaVersion {
+		err = fmt.Errorf("versión de esquema %d, se requiere al menos %d", version, repository.SchemaVersion)
+	}
+	status.Checks["schema"] = check(ctx, "schema", err, schemaUnavailable)
+
+	// Verificar la configuración de la API externa e informar su circuito
+	upstream := Check{Status: StatusOK}
+	circuit := h.client.Circuit()
+	if !h.client.Configured() {
+		upstream = Check{Status: StatusUnavailable, Error: upstreamMissing}
+	} else if circuit.State == client.CircuitOpen {
+		upstream = Check{Status: StatusDegraded, Error: upstreamCircuitOpen}
+		status.Status = StatusDegraded
+	}
+	upstream.Circuit = &circuit
+	status.Checks["upstream"] = upstream
+
+	// Frescura de los datos; es informativa y no afecta la disponibilidad
+	if last, err := h.repo.GetLastSuccessfulSync(ctx); err == nil {
+		status.LastSuccessfulSync = last
+	}
+	if newest, err := h.repo.GetNewestEventTime(ctx); err == nil && newest != nil {
+		status.NewestEventTime = newest
+		age := int64(status.Timestamp.Sub(*newest).Seconds())
+		status.DataAgeSeconds = &age
+	}
+
+	code := http.StatusOK
+	for _, result := range status.Checks {
+		if result.Status == StatusUnavailable {
+			status.Status = StatusUnavailable
+			code = http.StatusServiceUnavailable
+		}
 	}
 
-	// Verificar configuración de API
-	apiToken := os.Getenv("STOCK_API_AUTH_TOKEN")
-	apiURL := os.Getenv("STOCK_API_BASE_URL")
-
-	if apiToken == "" || apiURL == "" {
-		status.APICredentials = false
-		status.Components["api_config"] = "faltan credenciales"
-		status.Status = "degradado"
-	} else {
-		status.APICredentials = true
-		status.Components["api_config"] = "configurado"
+	c.JSON(code, status)
+}
+
+// liveness construye el estado básico del proceso.
+func liveness() LivenessStatus {
+	return LivenessStatus{
+		Status:        StatusOK,
+		Build:         buildinfo.Get(),
+		StartedAt:     buildinfo.StartedAt(),
+		UptimeSeconds: int64(buildinfo.Uptime().Seconds()),
+		Timestamp:     time.Now(),
 	}
+}
 
-	c.JSON(http.StatusOK, status)
+// check convierte el error de una verificación en su resultado. El error se
+// registra en los logs y la respuesta solo lleva el mensaje fijo.
+func check(ctx context.Context, name string, err error, message string) Check {
+	if err != nil {
+		slog.WarnContext(ctx, "Verificación de disponibilidad fallida", "check", name, "error", err)
+		return Check{Status: StatusUnavailable, Error: message}
+	}
+	return Check{Status: StatusOK}
 }
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/client"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/repository"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/upstreamtest"
+	"github.com/gin-gonic/gin"
+)
+
+const testToken = "test-token"
+
+// brokenRepository es un repositorio cuya base de datos no responde.
+type brokenRepository struct {
+	*repository.MemoryStockRepository
+}
+
+func (brokenRepository) Ping(ctx context.Context) error {
+	return errors.New("dial tcp 10.0.0.7:26257: connect: connection refused")
+}
+
+// readyz ejecuta /readyz y devuelve el código y el cuerpo decodificado.
+func readyz(t *testing.T, handler *HealthHandler) (int, ReadinessStatus) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", handler.Readyz)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var status ReadinessStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("cuerpo de /readyz inválido: %v", err)
+	}
+	return rec.Code, status
+}
+
+// newRepository crea un repositorio en memoria con el esquema al día.
+func newRepository(t *testing.T) *repository.MemoryStockRepository {
+	t.Helper()
+
+	repo := repository.NewMemoryStockRepository()
+	if err := repo.InitDB(context.Background()); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	return repo
+}
+
+func TestReadyzOpenCircuitIsDegraded(t *testing.T) {
+	upstream := upstreamtest.NewServer(testToken)
+	t.Cleanup(upstream.Close)
+	upstream.Inject(1, upstreamtest.InternalError)
+
+	apiClient := client.NewExternalAPIClient(upstream.URL, testToken, client.CircuitConfig{Failures: 1, Cooldown: time.Hour})
+	if _, _, err := apiClient.FetchStocks(context.Background(), ""); err == nil {
+		t.Fatal("FetchStocks() error = nil, want el 500 de la API externa")
+	}
+
+	code, status := readyz(t, NewHealthHandler(newRepository(t), apiClient))
+	if code != http.StatusOK {
+		t.Errorf("código = %d, want %d: el circuito no afecta la disponibilidad", code, http.StatusOK)
+	}
+	if status.Status != StatusDegraded {
+		t.Errorf("status = %q, want %q", status.Status, StatusDegraded)
+	}
+	upstreamCheck := status.Checks["upstream"]
+	if upstreamCheck.Status != StatusDegraded || upstreamCheck.Circuit == nil || upstreamCheck.Circuit.State != client.CircuitOpen {
+		t.Errorf("checks.upstream = %+v, want degradado con el circuito abierto", upstreamCheck)
+	}
+}
+
+func TestReadyzHidesErrorDetails(t *testing.T) {
+	apiClient := client.NewExternalAPIClient("http://127.0.0.1:1", testToken, client.CircuitConfig{})
+	handler := NewHealthHandler(brokenRepository{newRepository(t)}, apiClient)
+
+	code, status := readyz(t, handler)
+	if code != http.StatusServiceUnavailable || status.Status != StatusUnavailable {
+		t.Errorf("readyz = %d %q, want %d %q", code, status.Status, http.StatusServiceUnavailable, StatusUnavailable)
+	}
+	database := status.Checks["database"]
+	if database.Error != databaseUnavailable || strings.Contains(database.Error, "10.0.0.7") {
+		t.Errorf("checks.database.error = %q, want %q", database.Error, databaseUnavailable)
+	}
+}
+
+func TestReadyzMissingUpstreamConfig(t *testing.T) {
+	apiClient := client.NewExternalAPIClient("http://127.0.0.1:1", "", client.CircuitConfig{})
+
+	code, status := readyz(t, NewHealthHandler(newRepository(t), apiClient))
+	if code != http.StatusServiceUnavailable || status.Checks["upstream"].Error != upstreamMissing {
+		t.Errorf("readyz = %d %+v, want %d con la configuración faltante", code, status.Checks["upstream"], http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,516 @@
+// Paquete integration prueba la sincronización de punta a punta: stock-data-service
+// obtiene los stocks de una API externa falsa, los guarda en CockroachDB y
+// stock-api-service los expone. Las pruebas compilan y arrancan los binarios de
+// ambos servicios, y solo se ejecutan si TEST_DATABASE_URL apunta a un clúster;
+// cada prueba crea y elimina su propia base de datos.
+package integration
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/upstreamtest"
+	_ "github.com/lib/pq"
+)
+
+// upstreamToken es el token que los servicios envían a la API externa falsa.
+const upstreamToken = "integration-token"
+
+// Rutas de los módulos de ambos servicios, relativas a este paquete.
+const (
+	dataServiceDir = "../.."
+	apiServiceDir  = "../../../stock-api-service"
+)
+
+// binaries guarda los binarios compilados una sola vez para todas las pruebas.
+var binaries struct {
+	once sync.Once
+	dir  string
+	data string
+	api  string
+	err  error
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if binaries.dir != "" {
+		os.RemoveAll(binaries.dir)
+	}
+	os.Exit(code)
+}
+
+// buildServices compila los binarios de ambos servicios.
+func buildServices(t *testing.T) (string, string) {
+	t.Helper()
+
+	binaries.once.Do(func() {
+		binaries.dir, binaries.err = os.MkdirTemp("", "stock-integration")
+		if binaries.err != nil {
+			return
+		}
+		binaries.data = filepath.Join(binaries.dir, "stock-data-service")
+		binaries.api = filepath.Join(binaries.dir, "stock-api-service")
+
+		for _, build := range []struct{ dir, output string }{
+			{dataServiceDir, binaries.data},
+			{apiServiceDir, binaries.api},
+		} {
+			cmd := exec.Command("go", "build", "-o", build.output, "./cmd/api")
+			cmd.Dir = build.dir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				binaries.err = fmt.Errorf("go build en %s: %v\n%s", build.dir, err, output)
+				return
+			}
+		}
+	})
+	if binaries.err != nil {
+		t.Fatalf("error al compilar los servicios: %v", binaries.err)
+	}
+	return binaries.data, binaries.api
+}
+
+// harness reúne la API externa falsa, la base de datos y los dos servicios.
+type harness struct {
+	t        *testing.T
+	db       *sql.DB
+	upstream *upstreamtest.Server
+	dataURL  string
+	apiURL   string
+	syncs    int
+}
+
+// newHarness crea una base de datos vacía y arranca ambos servicios contra ella
+// y contra una API externa falsa sin páginas.
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL no está definida")
+	}
+	dataBinary, apiBinary := buildServices(t)
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL inválida: %v", err)
+	}
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	name := fmt.Sprintf("integration_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("CREATE DATABASE error = %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP DATABASE " + name + " CASCADE") })
+
+	u.Path = "/" + name
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	upstream := upstreamtest.NewServer(upstreamToken)
+	t.Cleanup(upstream.Close)
+
+	password, _ := u.User.Password()
+	sslMode := u.Query().Get("sslmode")
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	database := []string{
+		"DB_HOST=" + u.Hostname(),
+		"DB_PORT=" + u.Port(),
+		"DB_USER=" + u.User.Username(),
+		"DB_PASSWORD=" + password,
+		"DB_NAME=" + name,
+		"DB_SSL_MODE=" + sslMode,
+		"AUTH_ENABLED=false",
+		"LOG_LEVEL=debug",
+	}
+
+	h := &harness{t: t, db: db, upstream: upstream}
+	// stock-data-service crea el esquema, así que arranca primero
+	h.dataURL = startService(t, "stock-data-service", dataBinary, append(database,
+		"STOCK_API_BASE_URL="+upstream.URL,
+		"STOCK_API_AUTH_TOKEN="+upstreamToken,
+	))
+	h.apiURL = startService(t, "stock-api-service", apiBinary, append(database,
+		"GRPC_PORT=",
+		"RATE_LIMIT_ENABLED=false",
+		"SYNC_POLL_INTERVAL=200ms",
+	))
+	return h
+}
+
+// startService arranca un binario en un puerto libre con solo las variables de
+// entorno indicadas y espera a que responda en /livez. Devuelve su URL base.
+func startService(t *testing.T, name, binary string, env []string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error al reservar un puerto: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	var output bytes.Buffer
+	cmd := exec.Command(binary)
+	// Un directorio vacío evita que el servicio cargue un .env local
+	cmd.Dir = t.TempDir()
+	cmd.Env = append(env, fmt.Sprintf("SERVER_PORT=%d", port), "PATH="+os.Getenv("PATH"))
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("error al arrancar %s: %v", name, err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	t.Cleanup(func() {
+		cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-exited:
+		case <-time.After(10 * time.Second):
+			cmd.Process.Kill()
+			<-exited
+		}
+		if t.Failed() {
+			t.Logf("salida de %s:\n%s", name, output.String())
+		}
+	})
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-exited:
+			t.Fatalf("%s terminó al arrancar: %v\n%s", name, err, output.String())
+		default:
+		}
+		if resp, err := http.Get(baseURL + "/livez"); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return baseURL
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("%s no respondió en /livez\n%s", name, output.String())
+	return ""
+}
+
+// syncRun es una fila de sync_runs.
+type syncRun struct {
+	Status      string
+	StocksCount int
+	Error       string
+}
+
+// sync pide una sincronización a stock-data-service y espera a que quede
+// registrada en sync_runs. Devuelve el identificador de solicitud usado y el
+// resultado.
+func (h *harness) sync() (string, syncRun) {
+	h.t.Helper()
+
+	h.syncs++
+	requestID := fmt.Sprintf("integration-sync-%d", h.syncs)
+	req, err := http.NewRequest(http.MethodPost, h.dataURL+"/api/v1/sync", nil)
+	if err != nil {
+		h.t.Fatalf("http.NewRequest() error = %v", err)
+	}
+	req.Header.Set("X-Request-ID", requestID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatalf("POST /api/v1/sync error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		h.t.Fatalf("POST /api/v1/sync = %d, want 202", resp.StatusCode)
+	}
+
+	// Los reintentos de la sincronización esperan dos segundos cada uno
+	deadline := time.Now().Add(60 * time.Second)
+	for time.Now().Before(deadline) {
+		var run syncRun
+		err := h.db.QueryRow(`
+			SELECT status, stocks_count, COALESCE(error, '') FROM sync_runs WHERE request_id = $1
+		`, requestID).Scan(&run.Status, &run.StocksCount, &run.Error)
+		if err == nil {
+			return requestID, run
+		}
+		if err != sql.ErrNoRows {
+			h.t.Fatalf("error al consultar sync_runs: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	h.t.Fatalf("la sincronización %s no terminó", requestID)
+	return "", syncRun{}
+}
+
+// countEvents cuenta las filas del historial.
+func (h *harness) countEvents() int {
+	h.t.Helper()
+
+	var count int
+	if err := h.db.QueryRow(`SELECT COUNT(*) FROM stock_events`).Scan(&count); err != nil {
+		h.t.Fatalf("error al contar stock_events: %v", err)
+	}
+	return count
+}
+
+// get consulta stock-api-service y decodifica la respuesta JSON en dest.
+func (h *harness) get(path string, dest interface{}) int {
+	h.t.Helper()
+
+	resp, err := http.Get(h.apiURL + path)
+	if err != nil {
+		h.t.Fatalf("GET %s error = %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		h.t.Fatalf("GET %s: error al leer la respuesta: %v", path, err)
+	}
+	if resp.StatusCode == http.StatusOK && dest != nil {
+		if err := json.Unmarshal(body, dest); err != nil {
+			h.t.Fatalf("GET %s: respuesta inválida: %v\n%s", path, err, body)
+		}
+	}
+	return resp.StatusCode
+}
+
+// stockList es la parte de la respuesta de GET /api/v1/stocks que verifican las pruebas.
+type stockList struct {
+	Stocks      []models.Stock `json:"stocks"`
+	TotalStocks int            `json:"total_stocks"`
+}
+
+// tickerHistory es la parte de la respuesta de GET /api/v1/stocks/:ticker/history
+// que verifican las pruebas.
+type tickerHistory struct {
+	Events      []models.StockEvent `json:"events"`
+	TotalEvents int                 `json:"total_events"`
+}
+
+// recommendations es la parte de la respuesta de GET /api/v1/recommendations
+// que verifican las pruebas.
+type recommendations struct {
+	Recommendations []struct {
+		Stock           models.Stock `json:"stock"`
+		Score           float64      `json:"score"`
+		PotentialReturn string       `json:"potential_return"`
+	} `json:"recommendations"`
+	Count int `json:"count"`
+}
+
+// listTickers devuelve los tickers que lista stock-api-service, ordenados por ticker.
+func (h *harness) listTickers() []string {
+	h.t.Helper()
+
+	var list stockList
+	if status := h.get("/api/v1/stocks?order_by=ticker&sort=asc&page_size=100", &list); status != http.StatusOK {
+		h.t.Fatalf("GET /api/v1/stocks = %d, want 200", status)
+	}
+	tickers := []string{}
+	for _, stock := range list.Stocks {
+		tickers = append(tickers, stock.Ticker)
+	}
+	if list.TotalStocks != len(tickers) {
+		h.t.Errorf("total_stocks = %d, want %d", list.TotalStocks, len(tickers))
+	}
+	return tickers
+}
+
+// rating crea una calificación reciente, para que entre en la ventana de las recomendaciones.
+func rating(ticker, ratingFrom, ratingTo, targetFrom, targetTo string, hoursAgo int) models.Stock {
+	return models.Stock{
+		Ticker:     ticker,
+		Company:    ticker + " Inc.",
+		TargetFrom: targetFrom,
+		TargetTo:   targetTo,
+		Action:     "upgraded by",
+		Brokerage:  "Goldman Sachs",
+		RatingFrom: ratingFrom,
+		RatingTo:   ratingTo,
+		Time:       time.Now().UTC().Truncate(time.Second).Add(-time.Duration(hoursAgo) * time.Hour),
+	}
+}
+
+func TestSyncPagination(t *testing.T) {
+	h := newHarness(t)
+	h.upstream.SetPages(
+		[]models.Stock{rating("AAPL", "Hold", "Buy", "$100", "$110", 1), rating("MSFT", "Hold", "Buy", "$100", "$110", 2)},
+		[]models.Stock{rating("JPM", "Hold", "Buy", "$100", "$110", 3)},
+		[]models.Stock{rating("TSLA", "Hold", "Buy", "$100", "$110", 4), rating("NVDA", "Hold", "Buy", "$100", "$110", 5)},
+	)
+
+	requestID, run := h.sync()
+	if run.Status != models.SyncStatusCompleted || run.StocksCount != 5 {
+		t.Fatalf("sincronización = %+v, want completed con 5 stocks", run)
+	}
+
+	if got, want := h.upstream.NextPages(), []string{"", "page-2", "page-3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("next_page solicitados = %q, want %q", got, want)
+	}
+	for _, request := range h.upstream.Requests() {
+		if request.Authorization != "Bearer "+upstreamToken || request.RequestID != requestID {
+			t.Errorf("solicitud a la API externa = %+v, want el token y el X-Request-ID %s", request, requestID)
+		}
+	}
+
+	if got, want := h.listTickers(), []string{"AAPL", "JPM", "MSFT", "NVDA", "TSLA"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stocks listados = %v, want %v", got, want)
+	}
+
+	var stock models.Stock
+	if status := h.get("/api/v1/stocks/NVDA", &stock); status != http.StatusOK || stock.Brokerage != "Goldman Sachs" {
+		t.Errorf("GET /api/v1/stocks/NVDA = %d %+v, want el stock de la última página", status, stock)
+	}
+}
+
+func TestSyncRetries(t *testing.T) {
+	h := newHarness(t)
+	h.upstream.SetPages(
+		[]models.Stock{rating("AAPL", "Hold", "Buy", "$100", "$110", 1)},
+		[]models.Stock{rating("MSFT", "Hold", "Buy", "$100", "$110", 2)},
+	)
+	h.upstream.Inject(2,
+		upstreamtest.TooManyRequests,
+		upstreamtest.InternalError,
+		upstreamtest.MalformedJSON,
+		upstreamtest.Slow(500*time.Millisecond),
+	)
+
+	_, run := h.sync()
+	if run.Status != models.SyncStatusCompleted || run.StocksCount != 2 {
+		t.Fatalf("sincronización = %+v, want completed con 2 stocks", run)
+	}
+
+	// Tres fallos reintentados y la respuesta lenta que sí llega
+	if got, want := h.upstream.NextPages(), []string{"", "page-2", "page-2", "page-2", "page-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("next_page solicitados = %q, want %q", got, want)
+	}
+	if got, want := h.listTickers(), []string{"AAPL", "MSFT"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stocks listados = %v, want %v", got, want)
+	}
+}
+
+func TestSyncGone(t *testing.T) {
+	h := newHarness(t)
+	h.upstream.SetPages([]models.Stock{rating("AAPL", "Hold", "Buy", "$100", "$110", 1)})
+	h.upstream.Inject(1, upstreamtest.Gone)
+
+	_, run := h.sync()
+	if run.Status != models.SyncStatusFailed || !strings.Contains(run.Error, "410") {
+		t.Fatalf("sincronización = %+v, want failed por 410", run)
+	}
+	if got := len(h.upstream.Requests()); got != 1 {
+		t.Errorf("solicitudes a la API externa = %d, want 1 sin reintentos", got)
+	}
+	if got := h.listTickers(); len(got) != 0 {
+		t.Errorf("stocks listados = %v, want ninguno", got)
+	}
+}
+
+func TestSyncIdempotent(t *testing.T) {
+	h := newHarness(t)
+	first := rating("AAPL", "Hold", "Buy", "$100", "$110", 3)
+	pages := [][]models.Stock{
+		{first, rating("MSFT", "Hold", "Buy", "$100", "$110", 2)},
+		{rating("JPM", "Hold", "Buy", "$100", "$110", 1)},
+	}
+	h.upstream.SetPages(pages...)
+
+	for i := 0; i < 2; i++ {
+		if _, run := h.sync(); run.Status != models.SyncStatusCompleted {
+			t.Fatalf("sincronización %d = %+v, want completed", i+1, run)
+		}
+		if got := h.countEvents(); got != 3 {
+			t.Errorf("stock_events tras la sincronización %d = %d, want 3", i+1, got)
+		}
+	}
+
+	// Una calificación nueva del mismo ticker agrega un evento y reemplaza el stock
+	update := rating("AAPL", "Buy", "Strong Buy", "$110", "$140", 0)
+	pages[0] = append(pages[0], update)
+	h.upstream.SetPages(pages...)
+	if _, run := h.sync(); run.Status != models.SyncStatusCompleted {
+		t.Fatalf("sincronización con un cambio = %+v, want completed", run)
+	}
+	if got := h.countEvents(); got != 4 {
+		t.Errorf("stock_events tras el cambio = %d, want 4", got)
+	}
+
+	var history tickerHistory
+	if status := h.get("/api/v1/stocks/AAPL/history", &history); status != http.StatusOK {
+		t.Fatalf("GET /api/v1/stocks/AAPL/history = %d, want 200", status)
+	}
+	if history.TotalEvents != 2 || len(history.Events) != 2 || history.Events[0].RatingTo != "Strong Buy" {
+		t.Errorf("historial de AAPL = %+v, want 2 eventos con el más reciente primero", history)
+	}
+
+	var stock models.Stock
+	if status := h.get("/api/v1/stocks/AAPL", &stock); status != http.StatusOK || !stock.Time.Equal(update.Time) {
+		t.Errorf("GET /api/v1/stocks/AAPL = %d %+v, want la calificación de %s", status, stock, update.Time)
+	}
+}
+
+func TestRecommendations(t *testing.T) {
+	h := newHarness(t)
+	h.upstream.SetPages(
+		[]models.Stock{
+			// Mejora de calificación y precio objetivo con un alza de 30%
+			rating("AAPL", "Hold", "Strong Buy", "$100", "$130", 1),
+			// Mejora menor
+			rating("MSFT", "Hold", "Buy", "$100", "$105", 2),
+		},
+		[]models.Stock{
+			// Rebaja: no se recomienda
+			rating("JPM", "Buy", "Sell", "$100", "$80", 1),
+		},
+	)
+
+	if _, run := h.sync(); run.Status != models.SyncStatusCompleted {
+		t.Fatalf("sincronización = %+v, want completed", run)
+	}
+
+	var response recommendations
+	if status := h.get("/api/v1/recommendations", &response); status != http.StatusOK {
+		t.Fatalf("GET /api/v1/recommendations = %d, want 200", status)
+	}
+	var tickers []string
+	for _, recommendation := range response.Recommendations {
+		tickers = append(tickers, recommendation.Stock.Ticker)
+	}
+	if want := []string{"AAPL", "MSFT"}; !reflect.DeepEqual(tickers, want) || response.Count != len(want) {
+		t.Fatalf("recomendaciones = %v (count %d), want %v", tickers, response.Count, want)
+	}
+	if response.Recommendations[0].PotentialReturn != "Alto (>20%)" {
+		t.Errorf("retorno potencial de AAPL = %q, want Alto (>20%%)", response.Recommendations[0].PotentialReturn)
+	}
+	if response.Recommendations[0].Score <= response.Recommendations[1].Score {
+		t.Errorf("puntuaciones = %v, %v; want AAPL por encima de MSFT", response.Recommendations[0].Score, response.Recommendations[1].Score)
+	}
+}
@@ -0,0 +1,122 @@
+// Paquete logging configura los logs estructurados del servicio y propaga el
+// identificador de solicitud a través de los contextos.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDKey es la clave del identificador de solicitud en un context.Context.
+type requestIDKey struct{}
+
+// Setup configura el logger predeterminado con el nivel (debug, info, warn o error)
+// y el formato (json o text) indicados. Los mensajes del paquete log también pasan
+// por este logger.
+func Setup(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("nivel de log inválido %q: use debug, info, warn o error", level)
+	}
+
+	options := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("formato de log inválido %q: use json o text", format)
+	}
+
+	logger := slog.New(contextHandler{handler})
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// contextHandler añade a cada registro el identificador de solicitud y la traza del contexto.
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle implementa slog.Handler.
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs implementa slog.Handler.
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implementa slog.Handler.
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// WithRequestID devuelve un contexto que lleva el identificador de solicitud.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID devuelve el identificador de solicitud del contexto, o una cadena vacía.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID genera un identificador de solicitud aleatorio.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestError es un error asociado a la solicitud que lo originó.
+type RequestError struct {
+	RequestID string
+	Err       error
+}
+
+// Error implementa la interfaz error.
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%s (request_id=%s)", e.Err.Error(), e.RequestID)
+}
+
+// Unwrap devuelve el error original.
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// Errorf funciona como fmt.Errorf y, si el contexto lleva un identificador de
+// solicitud, lo adjunta al error.
+func Errorf(ctx context.Context, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	if id := RequestID(ctx); id != "" {
+		return &RequestError{RequestID: id, Err: err}
+	}
+	return err
+}
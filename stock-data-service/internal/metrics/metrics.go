@@ -0,0 +1,142 @@
+// Paquete metrics expone métricas del servicio en formato Prometheus.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute agrupa las solicitudes que no coinciden con ninguna ruta, para
+// que las URLs arbitrarias no generen series nuevas.
+const unmatchedRoute = "unmatched"
+
+// otherMethod agrupa los métodos HTTP no estándar, para que un cliente no pueda
+// generar series nuevas enviando métodos arbitrarios.
+const otherMethod = "other"
+
+// standardMethods son los métodos HTTP que se publican con su nombre.
+var standardMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+var (
+	// Solicitudes HTTP por método, ruta registrada y código de estado
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Solicitudes HTTP atendidas.",
+	}, []string{"method", "route", "status"})
+
+	// Latencia de las solicitudes HTTP por método, ruta registrada y código de estado
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duración de las solicitudes HTTP.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Duración de las sincronizaciones por resultado
+	syncDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "stock_sync_duration_seconds",
+		Help:    "Duración de las sincronizaciones con la API externa.",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600},
+	}, []string{"status"})
+
+	// Momento de la última sincronización completada
+	syncLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "stock_sync_last_success_timestamp_seconds",
+		Help: "Marca de tiempo Unix de la última sincronización completada.",
+	})
+
+	// Páginas obtenidas de la API externa
+	syncPages = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "stock_sync_pages_fetched_total",
+		Help: "Páginas obtenidas de la API externa.",
+	})
+
+	// Filas obtenidas de la API externa
+	syncRows = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "stock_sync_rows_fetched_total",
+		Help: "Stocks obtenidos de la API externa.",
+	})
+
+	// Errores de la API externa por código de estado
+	upstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "stock_upstream_errors_total",
+		Help: "Errores de la API externa por código de estado HTTP, o \"network\" y \"decode\" para fallos de conexión y de formato.",
+	}, []string{"code"})
+
+	// Intentos de entrega de webhooks por resultado
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "stock_webhook_deliveries_total",
+		Help: "Intentos de entrega de webhooks por resultado: delivered, retry o dead.",
+	}, []string{"result"})
+)
+
+// Middleware registra la cantidad y la duración de las solicitudes HTTP. Usa la
+// plantilla de la ruta (por ejemplo /api/v1/stocks/:ticker) y no la URL, y
+// "other" como método si no es uno estándar.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+		if !standardMethods[method] {
+			method = otherMethod
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		httpRequests.WithLabelValues(method, route, status).Inc()
+		httpDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDB publica las estadísticas del pool de conexiones de la base de datos.
+func RegisterDB(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler devuelve el handler HTTP que sirve las métricas.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveSync registra la duración y el resultado de una sincronización.
+func ObserveSync(status string, duration time.Duration) {
+	syncDuration.WithLabelValues(status).Observe(duration.Seconds())
+	if status == models.SyncStatusCompleted {
+		syncLastSuccess.SetToCurrentTime()
+	}
+}
+
+// AddPage registra una página obtenida de la API externa con la cantidad de stocks indicada.
+func AddPage(rows int) {
+	syncPages.Inc()
+	syncRows.Add(float64(rows))
+}
+
+// AddWebhookDelivery registra el resultado de un intento de entrega de webhook:
+// "delivered", "retry" o "dead".
+func AddWebhookDelivery(result string) {
+	webhookDeliveries.WithLabelValues(result).Inc()
+}
+
+// AddUpstreamError registra un error de la API externa. code es el código de estado
+// HTTP, o "network" y "decode" para fallos de conexión y de formato.
+func AddUpstreamError(code string) {
+	upstreamErrors.WithLabelValues(code).Inc()
+}
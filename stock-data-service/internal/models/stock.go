@@ -5,6 +5,23 @@ import (
 	"time"
 )
 
+// Estados posibles de una sincronización.
+const (
+	SyncStatusCompleted = "completed"
+	SyncStatusFailed    = "failed"
+)
+
+// Códigos estables de las sincronizaciones fallidas. A diferencia del mensaje de
+// error, se pueden mostrar fuera del servicio.
+const (
+	// La API externa falló o su circuito estaba abierto
+	SyncErrorUpstream = "upstream_error"
+	// No se pudieron guardar los stocks
+	SyncErrorStorage = "storage_error"
+	// La sincronización superó su tiempo máximo
+	SyncErrorTimeout = "timeout"
+)
+
 // Stock representa la información de una acción en bolsa.
 type Stock struct {
 	// Símbolo o ticker de la acción
@@ -27,6 +44,13 @@ type Stock struct {
 	Time time.Time `json:"time"`
 }
 
+// StockEvent es una actualización de calificación guardada en el historial.
+type StockEvent struct {
+	// Identificador del evento en la tabla stock_events
+	ID int64 `json:"id"`
+	Stock
+}
+
 // APIResponse representa la respuesta de la API externa.
 type APIResponse struct {
 	// Lista de stocks en la respuesta
@@ -34,3 +58,37 @@ type APIResponse struct {
 	// Token para la siguiente página de resultados
 	NextPage string `json:"next_page"`
 }
+
+// SyncRun representa el resultado de una sincronización con la API externa.
+type SyncRun struct {
+	// Estado final de la sincronización (completed o failed)
+	Status string `json:"status"`
+	// Fecha y hora de inicio
+	StartedAt time.Time `json:"started_at"`
+	// Fecha y hora de finalización
+	FinishedAt time.Time `json:"finished_at"`
+	// Cantidad de stocks guardados
+	StocksCount int `json:"stocks_count"`
+	// Mensaje de error si la sincronización falló; solo para uso interno
+	Error string `json:"error,omitempty"`
+	// Código estable del error si la sincronización falló
+	ErrorCode string `json:"error_code,omitempty"`
+	// Identificador de la solicitud que inició la sincronización
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Company representa los datos de referencia de una compañía.
+type Company struct {
+	// Símbolo o ticker de la acción
+	Ticker string `json:"ticker"`
+	// Nombre de la compañía
+	Company string `json:"company"`
+	// Sector económico
+	Sector string `json:"sector"`
+	// Industria dentro del sector
+	Industry string `json:"industry"`
+	// Bolsa en la que cotiza
+	Exchange string `json:"exchange"`
+	// Rango de capitalización de mercado (mega, large, mid, small, micro)
+	MarketCapBucket string `json:"market_cap_bucket"`
+}
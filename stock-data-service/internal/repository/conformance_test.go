@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Estas pruebas verifican que todas las implementaciones de StockRepository se
+// comporten igual. CockroachDB solo se prueba si TEST_DATABASE_URL apunta a un
+// clúster; cada prueba crea y elimina su propia base de datos.
+
+// backends crea un repositorio vacío e inicializado por cada implementación.
+var backends = []struct {
+	name string
+	open func(t *testing.T) StockRepository
+}{
+	{"memory", func(t *testing.T) StockRepository {
+		return NewMemoryStockRepository()
+	}},
+	{"sqlite", func(t *testing.T) StockRepository {
+		return NewSQLiteStockRepository(openSQLite(t))
+	}},
+	{"cockroach", func(t *testing.T) StockRepository {
+		return NewCockroachStockRepository(openCockroach(t))
+	}},
+}
+
+// openSQLite abre una base de datos SQLite en un directorio temporal.
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "stocks.db"))
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// openCockroach crea una base de datos vacía en el clúster de TEST_DATABASE_URL
+// y la elimina al terminar la prueba.
+func openCockroach(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL no está definida")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	name := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("CREATE DATABASE error = %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP DATABASE " + name + " CASCADE") })
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL inválida: %v", err)
+	}
+	u.Path = "/" + name
+
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// runBackends ejecuta una prueba contra cada implementación ya inicializada.
+func runBackends(t *testing.T, test func(t *testing.T, ctx context.Context, repo StockRepository)) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := backend.open(t)
+			if err := repo.InitDB(ctx); err != nil {
+				t.Fatalf("InitDB() error = %v", err)
+			}
+			test(t, ctx, repo)
+		})
+	}
+}
+
+// at devuelve una fecha fija del 1 de marzo de 2024 en UTC. Las fechas no usan
+// fracciones de segundo porque CockroachDB guarda microsegundos.
+func at(hour, minute int) time.Time {
+	return time.Date(2024, 3, 1, hour, minute, 0, 0, time.UTC)
+}
+
+// rating crea una calificación de prueba.
+func rating(ticker, brokerage, action string, when time.Time) models.Stock {
+	return models.Stock{
+		Ticker:     ticker,
+		Company:    ticker + " Inc.",
+		TargetFrom: "$10.00",
+		TargetTo:   "$12.00",
+		Action:     action,
+		Brokerage:  brokerage,
+		RatingFrom: "Hold",
+		RatingTo:   "Buy",
+		Time:       when,
+	}
+}
+
+func TestConformanceInitDB(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository) {
+		// Inicializar otra vez no falla ni cambia la versión
+		if err := repo.InitDB(ctx); err != nil {
+			t.Fatalf("InitDB() repetido error = %v", err)
+		}
+
+		version, err := repo.GetSchemaVersion(ctx)
+		if err != nil {
+			t.Fatalf("GetSchemaVersion() error = %v", err)
+		}
+		if version != SchemaVersion {
+			t.Errorf("GetSchemaVersion() = %d, want %d", version, SchemaVersion)
+		}
+
+		if err := repo.Ping(ctx); err != nil {
+			t.Errorf("Ping() error = %v", err)
+		}
+	})
+}
+
+func TestConformanceSaveStocks(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository) {
+		batch := []models.Stock{
+			rating("AAPL", "Goldman Sachs", "upgraded by", at(10, 0)),
+			rating("MSFT", "Morgan Stanley", "target raised by", at(11, 0)),
+			// Mismo ticker, otra casa de bolsa: es otro evento
+			rating("AAPL", "Barclays", "reiterated by", at(9, 0)),
+		}
+
+		created, err := repo.SaveStocks(ctx, batch)
+		if err != nil {
+			t.Fatalf("SaveStocks() error = %v", err)
+		}
+		if len(created) != len(batch) {
+			t.Fatalf("SaveStocks() creó %d eventos, want %d", len(created), len(batch))
+		}
+		ids := make(map[int64]bool)
+		for i, event := range created {
+			if event.ID == 0 || ids[event.ID] {
+				t.Errorf("evento %d con identificador %d repetido o vacío", i, event.ID)
+			}
+			ids[event.ID] = true
+			if event.Ticker != batch[i].Ticker || event.Brokerage != batch[i].Brokerage || !event.Time.Equal(batch[i].Time) {
+				t.Errorf("evento %d = %+v, want %+v", i, event.Stock, batch[i])
+			}
+		}
+
+		// Guardar el mismo lote es idempotente
+		again, err := repo.SaveStocks(ctx, batch)
+		if err != nil {
+			t.Fatalf("SaveStocks() repetido error = %v", err)
+		}
+		if len(again) != 0 {
+			t.Errorf("SaveStocks() repetido creó %d eventos, want 0", len(again))
+		}
+
+		// Solo la calificación nueva genera un evento
+		update := rating("MSFT", "Morgan Stanley", "target raised by", at(12, 30))
+		created, err = repo.SaveStocks(ctx, append(batch, update))
+		if err != nil {
+			t.Fatalf("SaveStocks() con un cambio error = %v", err)
+		}
+		if len(created) != 1 || !created[0].Time.Equal(update.Time) {
+			t.Fatalf("SaveStocks() con un cambio = %+v, want solo el evento de %s", created, update.Time)
+		}
+		if ids[created[0].ID] {
+			t.Errorf("el evento nuevo reutiliza el identificador %d", created[0].ID)
+		}
+
+		newest, err := repo.GetNewestEventTime(ctx)
+		if err != nil {
+			t.Fatalf("GetNewestEventTime() error = %v", err)
+		}
+		if newest == nil || !newest.Equal(update.Time) {
+			t.Errorf("GetNewestEventTime() = %v, want %s", newest, update.Time)
+		}
+	})
+}
+
+func TestConformanceEmptyHistory(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository) {
+		newest, err := repo.GetNewestEventTime(ctx)
+		if err != nil {
+			t.Fatalf("GetNewestEventTime() error = %v", err)
+		}
+		if newest != nil {
+			t.Errorf("GetNewestEventTime() = %v, want nil", newest)
+		}
+
+		last, err := repo.GetLastSuccessfulSync(ctx)
+		if err != nil {
+			t.Fatalf("GetLastSuccessfulSync() error = %v", err)
+		}
+		if last != nil {
+			t.Errorf("GetLastSuccessfulSync() = %v, want nil", last)
+		}
+
+		created, err := repo.SaveStocks(ctx, nil)
+		if err != nil || len(created) != 0 {
+			t.Errorf("SaveStocks(nil) = %v, %v; want ningún evento", created, err)
+		}
+	})
+}
+
+func TestConformanceSyncRuns(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository) {
+		runs := []models.SyncRun{
+			{Status: models.SyncStatusCompleted, StartedAt: at(8, 0), FinishedAt: at(8, 5), StocksCount: 10, RequestID: "req-1"},
+			{Status: models.SyncStatusCompleted, StartedAt: at(9, 0), FinishedAt: at(9, 5), StocksCount: 12},
+			// Una sincronización fallida posterior no cuenta como exitosa
+			{Status: models.SyncStatusFailed, StartedAt: at(10, 0), FinishedAt: at(10, 1), Error: "timeout"},
+		}
+		for _, run := range runs {
+			if err := repo.RecordSyncRun(ctx, run); err != nil {
+				t.Fatalf("RecordSyncRun() error = %v", err)
+			}
+		}
+
+		last, err := repo.GetLastSuccessfulSync(ctx)
+		if err != nil {
+			t.Fatalf("GetLastSuccessfulSync() error = %v", err)
+		}
+		if last == nil || !last.Equal(at(9, 5)) {
+			t.Errorf("GetLastSuccessfulSync() = %v, want %s", last, at(9, 5))
+		}
+	})
+}
+
+func TestConformanceSaveCompanies(t *testing.T) {
+	runBackends(t, func(t *testing.T, ctx context.Context, repo StockRepository) {
+		companies := []models.Company{
+			{Ticker: "AAPL", Company: "Apple Inc.", Sector: "Technology", Industry: "Consumer Electronics", Exchange: "NASDAQ", MarketCapBucket: "mega"},
+			{Ticker: "JPM", Company: "JPMorgan Chase & Co.", Sector: "Financials"},
+		}
+		if err := repo.SaveCompanies(ctx, companies); err != nil {
+			t.Fatalf("SaveCompanies() error = %v", err)
+		}
+
+		// Volver a guardar una compañía la reemplaza
+		companies[1].Industry = "Banks"
+		if err := repo.SaveCompanies(ctx, companies[1:]); err != nil {
+			t.Fatalf("SaveCompanies() repetido error = %v", err)
+		}
+	})
+}
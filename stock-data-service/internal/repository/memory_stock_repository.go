@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+)
+
+// eventKey identifica un evento del historial, como el índice único
+// stock_events_natural_key.
+type eventKey struct {
+	ticker    string
+	brokerage string
+	action    string
+	time      time.Time
+}
+
+// MemoryStockRepository implementa StockRepository en memoria. Sirve para
+// pruebas; los datos se pierden al terminar el proceso.
+type MemoryStockRepository struct {
+	mu            sync.RWMutex
+	stocks        map[string]models.Stock
+	events        []models.StockEvent
+	eventKeys     map[eventKey]bool
+	companies     map[string]models.Company
+	syncRuns      []models.SyncRun
+	schemaVersion int
+	nextID        int64
+}
+
+// NewMemoryStockRepository crea un repositorio de stocks en memoria vacío.
+func NewMemoryStockRepository() *MemoryStockRepository {
+	return &MemoryStockRepository{
+		stocks:    make(map[string]models.Stock),
+		eventKeys: make(map[eventKey]bool),
+		companies: make(map[string]models.Company),
+	}
+}
+
+// InitDB registra la versión del esquema; no hay tablas que crear.
+func (r *MemoryStockRepository) InitDB(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.schemaVersion < SchemaVersion {
+		r.schemaVersion = SchemaVersion
+	}
+	return nil
+}
+
+// SaveStocks guarda el último estado de cada stock y agrega al historial los
+// eventos nuevos. Devuelve solo los eventos que no existían.
+func (r *MemoryStockRepository) SaveStocks(ctx context.Context, stocks []models.Stock) ([]models.StockEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	newEvents := []models.StockEvent{}
+	for _, stock := range stocks {
+		r.stocks[stock.Ticker] = stock
+
+		key := eventKey{ticker: stock.Ticker, brokerage: stock.Brokerage, action: stock.Action, time: stock.Time.UTC()}
+		if r.eventKeys[key] {
+			continue
+		}
+		r.eventKeys[key] = true
+		r.nextID++
+		event := models.StockEvent{ID: r.nextID, Stock: stock}
+		r.events = append(r.events, event)
+		newEvents = append(newEvents, event)
+	}
+	return newEvents, nil
+}
+
+// SaveCompanies guarda o reemplaza los datos de referencia de las compañías.
+func (r *MemoryStockRepository) SaveCompanies(ctx context.Context, companies []models.Company) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, company := range companies {
+		r.companies[company.Ticker] = company
+	}
+	return nil
+}
+
+// RecordSyncRun registra el resultado de una sincronización.
+func (r *MemoryStockRepository) RecordSyncRun(ctx context.Context, run models.SyncRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.syncRuns = append(r.syncRuns, run)
+	return nil
+}
+
+// GetSchemaVersion obtiene la versión del esquema registrada por InitDB, o 0.
+func (r *MemoryStockRepository) GetSchemaVersion(ctx context.Context) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.schemaVersion, nil
+}
+
+// GetLastSuccessfulSync obtiene el fin de la última sincronización exitosa, o nil.
+func (r *MemoryStockRepository) GetLastSuccessfulSync(ctx context.Context) (*time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var last *time.Time
+	for _, run := range r.syncRuns {
+		if run.Status != models.SyncStatusCompleted {
+			continue
+		}
+		if last == nil || run.FinishedAt.After(*last) {
+			finishedAt := run.FinishedAt
+			last = &finishedAt
+		}
+	}
+	return last, nil
+}
+
+// GetNewestEventTime obtiene la fecha del evento más reciente, o nil si no hay eventos.
+func (r *MemoryStockRepository) GetNewestEventTime(ctx context.Context) (*time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var newest *time.Time
+	for _, event := range r.events {
+		if newest == nil || event.Time.After(*newest) {
+			eventTime := event.Time
+			newest = &eventTime
+		}
+	}
+	return newest, nil
+}
+
+// Ping siempre responde; el almacenamiento en memoria no puede desconectarse.
+func (r *MemoryStockRepository) Ping(ctx context.Context) error {
+	return nil
+}
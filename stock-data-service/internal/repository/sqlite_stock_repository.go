@@ -0,0 +1,352 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+)
+
+// SQLiteStockRepository implementa StockRepository sobre SQLite, para probar
+// las consultas sin un clúster de CockroachDB. El esquema tiene las mismas
+// tablas y columnas que lee SQLiteStockRepository en stock-api-service.
+// No registra ningún driver: quien abre la base de datos debe importar uno que
+// se registre como "sqlite3", por ejemplo github.com/mattn/go-sqlite3. SQLite
+// admite un solo escritor, así que conviene limitar el pool a una conexión.
+type SQLiteStockRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteStockRepository crea una nueva instancia del repositorio de stocks sobre SQLite.
+func NewSQLiteStockRepository(db *sql.DB) *SQLiteStockRepository {
+	return &SQLiteStockRepository{
+		db: db,
+	}
+}
+
+// sqliteSchema crea las tablas de InitDB con los tipos de SQLite. Las fechas se
+// guardan como texto en UTC para que compararlas como texto sea equivalente a
+// compararlas como instantes.
+var sqliteSchema = []string{
+	`
+    CREATE TABLE IF NOT EXISTS stocks (
+        ticker TEXT PRIMARY KEY,
+        company TEXT NOT NULL,
+        target_from TEXT NOT NULL,
+        target_to TEXT NOT NULL,
+        action TEXT NOT NULL,
+        brokerage TEXT NOT NULL,
+        rating_from TEXT NOT NULL,
+        rating_to TEXT NOT NULL,
+        time TIMESTAMP NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    )
+    `,
+	`
+    CREATE TABLE IF NOT EXISTS stock_events (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        ticker TEXT NOT NULL,
+        company TEXT NOT NULL,
+        target_from TEXT NOT NULL,
+        target_to TEXT NOT NULL,
+        action TEXT NOT NULL,
+        brokerage TEXT NOT NULL,
+        rating_from TEXT NOT NULL,
+        rating_to TEXT NOT NULL,
+        time TIMESTAMP NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (ticker, brokerage, action, time)
+    )
+    `,
+	`CREATE INDEX IF NOT EXISTS stock_events_time_idx ON stock_events (time)`,
+	`
+    CREATE TABLE IF NOT EXISTS sync_runs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        status TEXT NOT NULL,
+        started_at TIMESTAMP NOT NULL,
+        finished_at TIMESTAMP NOT NULL,
+        stocks_count INTEGER NOT NULL DEFAULT 0,
+        error TEXT,
+        request_id TEXT
+    )
+    `,
+	`CREATE INDEX IF NOT EXISTS sync_runs_finished_at_idx ON sync_runs (finished_at)`,
+	`
+    CREATE TABLE IF NOT EXISTS companies (
+        ticker TEXT PRIMARY KEY,
+        company TEXT NOT NULL,
+        sector TEXT NOT NULL DEFAULT '',
+        industry TEXT NOT NULL DEFAULT '',
+        exchange TEXT NOT NULL DEFAULT '',
+        market_cap_bucket TEXT NOT NULL DEFAULT '',
+        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    )
+    `,
+	`CREATE INDEX IF NOT EXISTS companies_sector_idx ON companies (sector, industry)`,
+	`
+    CREATE TABLE IF NOT EXISTS schema_versions (
+        component TEXT PRIMARY KEY,
+        version INTEGER NOT NULL,
+        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    )
+    `,
+}
+
+// InitDB crea las tablas si no existen y registra la versión del esquema.
+func (r *SQLiteStockRepository) InitDB(ctx context.Context) error {
+	for _, query := range sqliteSchema {
+		if _, err := r.db.ExecContext(ctx, query); err != nil {
+			return logging.Errorf(ctx, "error al inicializar la base de datos: %w", Classify(err))
+		}
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO schema_versions (component, version)
+        VALUES (?, ?)
+        ON CONFLICT (component) DO UPDATE
+        SET version = excluded.version, updated_at = CURRENT_TIMESTAMP
+        WHERE schema_versions.version < excluded.version
+    `, SchemaComponent, SchemaVersion)
+	if err != nil {
+		return logging.Errorf(ctx, "error al registrar la versión del esquema: %w", Classify(err))
+	}
+	return nil
+}
+
+// SaveStocks guarda múltiples stocks en una transacción. Devuelve los eventos
+// que no existían en el historial.
+func (r *SQLiteStockRepository) SaveStocks(ctx context.Context, stocks []models.Stock) ([]models.StockEvent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al iniciar la transacción: %w", Classify(err))
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+        INSERT INTO stocks (
+            ticker, company, target_from, target_to,
+            action, brokerage, rating_from, rating_to, time
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT (ticker) DO UPDATE SET
+            company = excluded.company,
+            target_from = excluded.target_from,
+            target_to = excluded.target_to,
+            action = excluded.action,
+            brokerage = excluded.brokerage,
+            rating_from = excluded.rating_from,
+            rating_to = excluded.rating_to,
+            time = excluded.time
+    `)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al preparar el statement: %w", Classify(err))
+	}
+	defer stmt.Close()
+
+	eventStmt, err := tx.PrepareContext(ctx, `
+        INSERT INTO stock_events (
+            ticker, company, target_from, target_to,
+            action, brokerage, rating_from, rating_to, time
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT DO NOTHING
+        RETURNING id
+    `)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al preparar el statement de eventos: %w", Classify(err))
+	}
+	defer eventStmt.Close()
+
+	newEvents := []models.StockEvent{}
+	for _, stock := range stocks {
+		args := []interface{}{
+			stock.Ticker,
+			stock.Company,
+			stock.TargetFrom,
+			stock.TargetTo,
+			stock.Action,
+			stock.Brokerage,
+			stock.RatingFrom,
+			stock.RatingTo,
+			stock.Time.UTC(),
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return nil, logging.Errorf(ctx, "error al guardar el stock %s: %w", stock.Ticker, Classify(err))
+		}
+
+		// Si el evento ya existía no se devuelve ninguna fila
+		var id int64
+		err := eventStmt.QueryRowContext(ctx, args...).Scan(&id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, logging.Errorf(ctx, "error al guardar el evento del stock %s: %w", stock.Ticker, Classify(err))
+		}
+		newEvents = append(newEvents, models.StockEvent{ID: id, Stock: stock})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, logging.Errorf(ctx, "error al confirmar la transacción: %w", Classify(err))
+	}
+	return newEvents, nil
+}
+
+// SaveCompanies guarda los datos de referencia de las compañías en una transacción.
+func (r *SQLiteStockRepository) SaveCompanies(ctx context.Context, companies []models.Company) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return logging.Errorf(ctx, "error al iniciar la transacción: %w", Classify(err))
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+        INSERT OR REPLACE INTO companies (
+            ticker, company, sector, industry, exchange, market_cap_bucket, updated_at
+        ) VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+    `)
+	if err != nil {
+		return logging.Errorf(ctx, "error al preparar el statement: %w", Classify(err))
+	}
+	defer stmt.Close()
+
+	for _, company := range companies {
+		_, err := stmt.ExecContext(
+			ctx,
+			company.Ticker,
+			company.Company,
+			company.Sector,
+			company.Industry,
+			company.Exchange,
+			company.MarketCapBucket,
+		)
+		if err != nil {
+			return logging.Errorf(ctx, "error al guardar la compañía %s: %w", company.Ticker, Classify(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return logging.Errorf(ctx, "error al confirmar la transacción: %w", Classify(err))
+	}
+	return nil
+}
+
+// RecordSyncRun registra el resultado de una sincronización.
+func (r *SQLiteStockRepository) RecordSyncRun(ctx context.Context, run models.SyncRun) error {
+	var errMsg, requestID interface{}
+	if run.Error != "" {
+		errMsg = run.Error
+	}
+	if run.RequestID != "" {
+		requestID = run.RequestID
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO sync_runs (status, started_at, finished_at, stocks_count, error, request_id)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, run.Status, run.StartedAt.UTC(), run.FinishedAt.UTC(), run.StocksCount, errMsg, requestID)
+	if err != nil {
+		return logging.Errorf(ctx, "error al registrar la sincronización: %w", Classify(err))
+	}
+	return nil
+}
+
+// GetSchemaVersion obtiene la versión del esquema registrada para este servicio.
+// Devuelve 0 si no hay ninguna registrada.
+func (r *SQLiteStockRepository) GetSchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT version FROM schema_versions WHERE component = ?`, SchemaComponent,
+	).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al obtener la versión del esquema: %w", Classify(err))
+	}
+	return version, nil
+}
+
+// GetLastSuccessfulSync obtiene el momento en que terminó la última sincronización
+// exitosa. Devuelve nil si aún no hay ninguna.
+func (r *SQLiteStockRepository) GetLastSuccessfulSync(ctx context.Context) (*time.Time, error) {
+	var finishedAt sqliteTime
+	err := r.db.QueryRowContext(ctx,
+		`SELECT MAX(finished_at) FROM sync_runs WHERE status = ?`, models.SyncStatusCompleted,
+	).Scan(&finishedAt)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al obtener la última sincronización: %w", Classify(err))
+	}
+	return finishedAt.ptr(), nil
+}
+
+// GetNewestEventTime obtiene la fecha del evento más reciente del historial.
+// Devuelve nil si el historial está vacío.
+func (r *SQLiteStockRepository) GetNewestEventTime(ctx context.Context) (*time.Time, error) {
+	var newest sqliteTime
+	err := r.db.QueryRowContext(ctx, `SELECT MAX(time) FROM stock_events`).Scan(&newest)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al obtener el evento más reciente: %w", Classify(err))
+	}
+	return newest.ptr(), nil
+}
+
+// Ping verifica la conexión a la base de datos.
+func (r *SQLiteStockRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// sqliteTimeFormats son los formatos en que los drivers de SQLite guardan las
+// fechas, más el de CURRENT_TIMESTAMP.
+var sqliteTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	time.RFC3339Nano,
+}
+
+// sqliteTime escanea una fecha de SQLite. El driver solo convierte a time.Time
+// las columnas declaradas como TIMESTAMP; el resultado de funciones como MAX
+// llega como texto.
+type sqliteTime struct {
+	time  time.Time
+	valid bool
+}
+
+// Scan implementa sql.Scanner.
+func (t *sqliteTime) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		t.valid = false
+		return nil
+	case time.Time:
+		t.time, t.valid = v.UTC(), true
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("no se puede convertir %T en una fecha", value)
+	}
+
+	for _, format := range sqliteTimeFormats {
+		if parsed, err := time.Parse(format, text); err == nil {
+			t.time, t.valid = parsed.UTC(), true
+			return nil
+		}
+	}
+	return fmt.Errorf("fecha inválida: %q", text)
+}
+
+// ptr devuelve la fecha, o nil si era NULL.
+func (t sqliteTime) ptr() *time.Time {
+	if !t.valid {
+		return nil
+	}
+	return &t.time
+}
@@ -4,26 +4,66 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"time"
 
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/logging"
 	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// StockRepository maneja las operaciones de base de datos para los stocks.
-type StockRepository struct {
+// SchemaComponent identifica el esquema de este servicio en la tabla schema_versions.
+const SchemaComponent = "stock-data-service"
+
+// SchemaVersion es la versión del esquema que crea InitDB. Debe incrementarse
+// cada vez que se agregue una tabla o columna de la que dependan otros servicios.
+const SchemaVersion = 1
+
+// StockRepository guarda los stocks sincronizados, su historial, los datos de
+// referencia de las compañías y el registro de sincronizaciones. La
+// implementación de producción, y la única con la que arranca el servicio, es
+// CockroachStockRepository; MemoryStockRepository y SQLiteStockRepository
+// sirven para pruebas, y todas cumplen las pruebas de conformance_test.go.
+type StockRepository interface {
+	// InitDB crea el esquema si no existe y registra su versión.
+	InitDB(ctx context.Context) error
+	// SaveStocks guarda el último estado de cada stock y agrega al historial los
+	// eventos nuevos. Devuelve solo los eventos que no existían.
+	SaveStocks(ctx context.Context, stocks []models.Stock) ([]models.StockEvent, error)
+	// SaveCompanies guarda o reemplaza los datos de referencia de las compañías.
+	SaveCompanies(ctx context.Context, companies []models.Company) error
+	// RecordSyncRun registra el resultado de una sincronización.
+	RecordSyncRun(ctx context.Context, run models.SyncRun) error
+	// GetSchemaVersion obtiene la versión del esquema registrada, o 0 si no hay ninguna.
+	GetSchemaVersion(ctx context.Context) (int, error)
+	// GetLastSuccessfulSync obtiene el fin de la última sincronización exitosa, o nil.
+	GetLastSuccessfulSync(ctx context.Context) (*time.Time, error)
+	// GetNewestEventTime obtiene la fecha del evento más reciente, o nil si no hay eventos.
+	GetNewestEventTime(ctx context.Context) (*time.Time, error)
+	// Ping verifica que el almacenamiento responda.
+	Ping(ctx context.Context) error
+}
+
+// CockroachStockRepository implementa StockRepository sobre CockroachDB.
+type CockroachStockRepository struct {
 	db *sql.DB
 }
 
-// NewStockRepository crea una nueva instancia del repositorio de stocks.
-func NewStockRepository(db *sql.DB) *StockRepository {
-	return &StockRepository{
+// NewCockroachStockRepository crea una nueva instancia del repositorio de stocks sobre CockroachDB.
+func NewCockroachStockRepository(db *sql.DB) *CockroachStockRepository {
+	return &CockroachStockRepository{
 		db: db,
 	}
 }
 
 // InitDB inicializa la base de datos creando las tablas necesarias.
-func (r *StockRepository) InitDB(ctx context.Context) error {
-	query := `
+func (r *CockroachStockRepository) InitDB(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "InitDB")
+	defer func() { tracing.End(span, err) }()
+
+	queries := []string{
+		`
     CREATE TABLE IF NOT EXISTS stocks (
         ticker STRING PRIMARY KEY,
         company STRING NOT NULL,
@@ -36,18 +76,107 @@ func (r *StockRepository) InitDB(ctx context.Context) error {
         time TIMESTAMP NOT NULL,
         created_at TIMESTAMP DEFAULT current_timestamp()
     )
-    `
+    `,
+		// Historial de eventos: la tabla stocks solo conserva la última
+		// actualización por ticker, aquí se guardan todas.
+		`
+    CREATE TABLE IF NOT EXISTS stock_events (
+        id INT PRIMARY KEY DEFAULT unique_rowid(),
+        ticker STRING NOT NULL,
+        company STRING NOT NULL,
+        target_from STRING NOT NULL,
+        target_to STRING NOT NULL,
+        action STRING NOT NULL,
+        brokerage STRING NOT NULL,
+        rating_from STRING NOT NULL,
+        rating_to STRING NOT NULL,
+        time TIMESTAMP NOT NULL,
+        created_at TIMESTAMP DEFAULT current_timestamp(),
+        UNIQUE INDEX stock_events_natural_key (ticker, brokerage, action, time),
+        INDEX stock_events_time_idx (time)
+    )
+    `,
+		// Registro de sincronizaciones; stock-api-service lo consulta para
+		// invalidar sus cachés cuando termina una sincronización.
+		`
+    CREATE TABLE IF NOT EXISTS sync_runs (
+        id INT PRIMARY KEY DEFAULT unique_rowid(),
+        status STRING NOT NULL,
+        started_at TIMESTAMP NOT NULL,
+        finished_at TIMESTAMP NOT NULL,
+        stocks_count INT NOT NULL DEFAULT 0,
+        error STRING,
+        INDEX sync_runs_finished_at_idx (finished_at)
+    )
+    `,
+		`ALTER TABLE sync_runs ADD COLUMN IF NOT EXISTS request_id STRING`,
+		// Datos de referencia de las compañías (sector, industria, bolsa y capitalización)
+		`
+    CREATE TABLE IF NOT EXISTS companies (
+        ticker STRING PRIMARY KEY,
+        company STRING NOT NULL,
+        sector STRING NOT NULL DEFAULT '',
+        industry STRING NOT NULL DEFAULT '',
+        exchange STRING NOT NULL DEFAULT '',
+        market_cap_bucket STRING NOT NULL DEFAULT '',
+        updated_at TIMESTAMP NOT NULL DEFAULT current_timestamp(),
+        INDEX companies_sector_idx (sector, industry)
+    )
+    `,
+		// Versiones de esquema de cada servicio; las sondas de disponibilidad
+		// comprueban que la base de datos tenga el esquema que esperan.
+		`
+    CREATE TABLE IF NOT EXISTS schema_versions (
+        component STRING PRIMARY KEY,
+        version INT NOT NULL,
+        updated_at TIMESTAMP NOT NULL DEFAULT current_timestamp()
+    )
+    `,
+		// Poblar el historial con los datos existentes
+		`
+    INSERT INTO stock_events (
+        ticker, company, target_from, target_to,
+        action, brokerage, rating_from, rating_to, time
+    )
+    SELECT
+        ticker, company, target_from, target_to,
+        action, brokerage, rating_from, rating_to, time
+    FROM stocks
+    ON CONFLICT DO NOTHING
+    `,
+	}
+
+	for _, query := range queries {
+		if _, err := r.db.ExecContext(ctx, query); err != nil {
+			return logging.Errorf(ctx, "error al inicializar la base de datos: %w", Classify(err))
+		}
+	}
+
+	// Registrar la versión del esquema sin retroceder si una réplica más nueva ya la subió
+	_, err = r.db.ExecContext(ctx, `
+        INSERT INTO schema_versions (component, version)
+        VALUES ($1, $2)
+        ON CONFLICT (component) DO UPDATE
+        SET version = excluded.version, updated_at = current_timestamp()
+        WHERE schema_versions.version < excluded.version
+    `, SchemaComponent, SchemaVersion)
+	if err != nil {
+		return logging.Errorf(ctx, "error al registrar la versión del esquema: %w", Classify(err))
+	}
 
-	_, err := r.db.ExecContext(ctx, query)
-	return err
+	return nil
 }
 
 // SaveStocks guarda múltiples stocks en la base de datos utilizando una transacción.
-func (r *StockRepository) SaveStocks(ctx context.Context, stocks []models.Stock) error {
+// Devuelve los eventos que no existían en el historial.
+func (r *CockroachStockRepository) SaveStocks(ctx context.Context, stocks []models.Stock) (_ []models.StockEvent, err error) {
+	ctx, span := startSpan(ctx, "SaveStocks")
+	defer func() { tracing.End(span, err) }()
+
 	// Iniciar transacción
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("error al iniciar la transacción: %w", err)
+		return nil, logging.Errorf(ctx, "error al iniciar la transacción: %w", Classify(err))
 	}
 
 	// Preparar statement para inserción/actualización
@@ -59,11 +188,27 @@ func (r *StockRepository) SaveStocks(ctx context.Context, stocks []models.Stock)
     `)
 	if err != nil {
 		tx.Rollback()
-		return fmt.Errorf("error al preparar el statement: %w", err)
+		return nil, logging.Errorf(ctx, "error al preparar el statement: %w", Classify(err))
 	}
 	defer stmt.Close()
 
+	// Preparar statement para el historial de eventos
+	eventStmt, err := tx.PrepareContext(ctx, `
+        INSERT INTO stock_events (
+            ticker, company, target_from, target_to,
+            action, brokerage, rating_from, rating_to, time
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        ON CONFLICT DO NOTHING
+        RETURNING id
+    `)
+	if err != nil {
+		tx.Rollback()
+		return nil, logging.Errorf(ctx, "error al preparar el statement de eventos: %w", Classify(err))
+	}
+	defer eventStmt.Close()
+
 	// Insertar cada stock
+	newEvents := []models.StockEvent{}
 	for _, stock := range stocks {
 		_, err := stmt.ExecContext(
 			ctx,
@@ -79,19 +224,175 @@ func (r *StockRepository) SaveStocks(ctx context.Context, stocks []models.Stock)
 		)
 		if err != nil {
 			tx.Rollback()
-			return fmt.Errorf("error al guardar el stock %s: %w", stock.Ticker, err)
+			return nil, logging.Errorf(ctx, "error al guardar el stock %s: %w", stock.Ticker, Classify(err))
+		}
+
+		// Si el evento ya existía no se devuelve ninguna fila
+		var id int64
+		err = eventStmt.QueryRowContext(
+			ctx,
+			stock.Ticker,
+			stock.Company,
+			stock.TargetFrom,
+			stock.TargetTo,
+			stock.Action,
+			stock.Brokerage,
+			stock.RatingFrom,
+			stock.RatingTo,
+			stock.Time,
+		).Scan(&id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, logging.Errorf(ctx, "error al guardar el evento del stock %s: %w", stock.Ticker, Classify(err))
 		}
+		newEvents = append(newEvents, models.StockEvent{ID: id, Stock: stock})
 	}
 
 	// Confirmar transacción
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("error al confirmar la transacción: %w", err)
+		return nil, logging.Errorf(ctx, "error al confirmar la transacción: %w", Classify(err))
+	}
+
+	return newEvents, nil
+}
+
+// SaveCompanies guarda los datos de referencia de las compañías en lotes dentro de una transacción.
+func (r *CockroachStockRepository) SaveCompanies(ctx context.Context, companies []models.Company) (err error) {
+	ctx, span := startSpan(ctx, "SaveCompanies")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return logging.Errorf(ctx, "error al iniciar la transacción: %w", Classify(err))
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+        UPSERT INTO companies (
+            ticker, company, sector, industry, exchange, market_cap_bucket, updated_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, current_timestamp())
+    `)
+	if err != nil {
+		tx.Rollback()
+		return logging.Errorf(ctx, "error al preparar el statement: %w", Classify(err))
+	}
+	defer stmt.Close()
+
+	for _, company := range companies {
+		_, err := stmt.ExecContext(
+			ctx,
+			company.Ticker,
+			company.Company,
+			company.Sector,
+			company.Industry,
+			company.Exchange,
+			company.MarketCapBucket,
+		)
+		if err != nil {
+			tx.Rollback()
+			return logging.Errorf(ctx, "error al guardar la compañía %s: %w", company.Ticker, Classify(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return logging.Errorf(ctx, "error al confirmar la transacción: %w", Classify(err))
 	}
 
 	return nil
 }
 
+// RecordSyncRun registra el resultado de una sincronización.
+func (r *CockroachStockRepository) RecordSyncRun(ctx context.Context, run models.SyncRun) (err error) {
+	ctx, span := startSpan(ctx, "RecordSyncRun")
+	defer func() { tracing.End(span, err) }()
+
+	var errMsg interface{}
+	if run.Error != "" {
+		errMsg = run.Error
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+        INSERT INTO sync_runs (status, started_at, finished_at, stocks_count, error, request_id)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+    `, run.Status, run.StartedAt, run.FinishedAt, run.StocksCount, errMsg, run.RequestID)
+	if err != nil {
+		return logging.Errorf(ctx, "error al registrar la sincronización: %w", Classify(err))
+	}
+	return nil
+}
+
+// GetSchemaVersion obtiene la versión del esquema registrada para este servicio.
+// Devuelve 0 si no hay ninguna registrada.
+func (r *CockroachStockRepository) GetSchemaVersion(ctx context.Context) (_ int, err error) {
+	ctx, span := startSpan(ctx, "GetSchemaVersion")
+	defer func() { tracing.End(span, err) }()
+
+	var version int
+	err = r.db.QueryRowContext(ctx,
+		`SELECT version FROM schema_versions WHERE component = $1`, SchemaComponent,
+	).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, logging.Errorf(ctx, "error al obtener la versión del esquema: %w", Classify(err))
+	}
+	return version, nil
+}
+
+// GetLastSuccessfulSync obtiene el momento en que terminó la última sincronización
+// exitosa. Devuelve nil si aún no hay ninguna.
+func (r *CockroachStockRepository) GetLastSuccessfulSync(ctx context.Context) (_ *time.Time, err error) {
+	ctx, span := startSpan(ctx, "GetLastSuccessfulSync")
+	defer func() { tracing.End(span, err) }()
+
+	var finishedAt sql.NullTime
+	err = r.db.QueryRowContext(ctx,
+		`SELECT MAX(finished_at) FROM sync_runs WHERE status = $1`, models.SyncStatusCompleted,
+	).Scan(&finishedAt)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al obtener la última sincronización: %w", Classify(err))
+	}
+	if !finishedAt.Valid {
+		return nil, nil
+	}
+	return &finishedAt.Time, nil
+}
+
+// GetNewestEventTime obtiene la fecha del evento más reciente del historial.
+// Devuelve nil si el historial está vacío.
+func (r *CockroachStockRepository) GetNewestEventTime(ctx context.Context) (_ *time.Time, err error) {
+	ctx, span := startSpan(ctx, "GetNewestEventTime")
+	defer func() { tracing.End(span, err) }()
+
+	var newest sql.NullTime
+	err = r.db.QueryRowContext(ctx, `SELECT MAX(time) FROM stock_events`).Scan(&newest)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al obtener el evento más reciente: %w", Classify(err))
+	}
+	if !newest.Valid {
+		return nil, nil
+	}
+	return &newest.Time, nil
+}
+
 // Ping verifica la conexión a la base de datos.
-func (r *StockRepository) Ping(ctx context.Context) error {
+func (r *CockroachStockRepository) Ping(ctx context.Context) error {
 	return r.db.PingContext(ctx)
 }
+
+// tracer crea los spans de las consultas del repositorio.
+var tracer = tracing.Tracer("github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/repository")
+
+// startSpan inicia un span hijo para una operación del repositorio. Las operaciones
+// sin una traza en curso, como la inicialización, no crean trazas nuevas.
+func startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tracer.Start(ctx, "StockRepository."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemCockroachdb, semconv.DBOperation(operation)))
+}
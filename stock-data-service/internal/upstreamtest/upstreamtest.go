@@ -0,0 +1,182 @@
+// Paquete upstreamtest proporciona una API de stocks falsa para pruebas. Sirve
+// páginas programadas enlazadas con next_page, inyecta fallos y registra las
+// solicitudes recibidas.
+package upstreamtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+)
+
+// Fault describe la respuesta de una solicitud con un fallo inyectado. Un fallo
+// sin código ni cuerpo solo retrasa la página normal.
+type Fault struct {
+	// Código de estado de la respuesta; 0 responde la página con 200
+	Status int
+	// Cuerpo de la respuesta en lugar de la página
+	Body string
+	// Espera antes de responder
+	Delay time.Duration
+}
+
+// Fallos habituales de la API externa.
+var (
+	TooManyRequests = Fault{Status: http.StatusTooManyRequests, Body: `{"error":"too many requests"}`}
+	InternalError   = Fault{Status: http.StatusInternalServerError, Body: `{"error":"internal server error"}`}
+	Gone            = Fault{Status: http.StatusGone, Body: `{"error":"gone"}`}
+	MalformedJSON   = Fault{Status: http.StatusOK, Body: `{"items": [{"ticker": `}
+)
+
+// Slow devuelve un fallo que responde la página normal tras la espera indicada.
+func Slow(delay time.Duration) Fault {
+	return Fault{Delay: delay}
+}
+
+// Request es una solicitud recibida por el servidor.
+type Request struct {
+	// Valor del parámetro next_page; vacío en la primera página
+	NextPage string
+	// Cabecera Authorization
+	Authorization string
+	// Cabecera X-Request-ID
+	RequestID string
+	// Código de estado con el que se respondió
+	Status int
+}
+
+// Server es una API de stocks falsa sobre httptest.
+type Server struct {
+	*httptest.Server
+
+	token string
+
+	mu       sync.Mutex
+	pages    map[string]models.APIResponse
+	faults   map[string][]Fault
+	requests []Request
+}
+
+// NewServer arranca un servidor sin páginas. Si token no está vacío, las
+// solicitudes deben enviarlo como "Bearer <token>" o reciben 401.
+func NewServer(token string) *Server {
+	s := &Server{
+		token:  token,
+		pages:  make(map[string]models.APIResponse),
+		faults: make(map[string][]Fault),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// PageToken devuelve el valor de next_page con el que se pide una página,
+// numerada desde 1. La primera página se pide sin next_page.
+func PageToken(page int) string {
+	if page <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("page-%d", page)
+}
+
+// SetPages reemplaza las páginas servidas. Cada página enlaza con la siguiente
+// con next_page y la última lo deja vacío.
+func (s *Server) SetPages(pages ...[]models.Stock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pages = make(map[string]models.APIResponse, len(pages))
+	for i, items := range pages {
+		response := models.APIResponse{Items: items}
+		if i < len(pages)-1 {
+			response.NextPage = PageToken(i + 2)
+		}
+		if response.Items == nil {
+			response.Items = []models.Stock{}
+		}
+		s.pages[PageToken(i+1)] = response
+	}
+}
+
+// Inject programa fallos para una página, numerada desde 1. Cada solicitud de
+// esa página consume el siguiente fallo; cuando se agotan, la página se sirve
+// normalmente.
+func (s *Server) Inject(page int, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := PageToken(page)
+	s.faults[token] = append(s.faults[token], faults...)
+}
+
+// Requests devuelve las solicitudes recibidas, en orden de llegada.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// NextPages devuelve el parámetro next_page de cada solicitud recibida.
+func (s *Server) NextPages() []string {
+	requests := s.Requests()
+	pages := make([]string, len(requests))
+	for i, request := range requests {
+		pages[i] = request.NextPage
+	}
+	return pages
+}
+
+// serve responde una solicitud. La solicitud se registra al llegar, antes de
+// cualquier espera, para que las pruebas la vean aunque el cliente se rinda.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	next := r.URL.Query().Get("next_page")
+
+	s.mu.Lock()
+	var fault Fault
+	if queue := s.faults[next]; len(queue) > 0 {
+		fault = queue[0]
+		s.faults[next] = queue[1:]
+	}
+	page, found := s.pages[next]
+
+	status, body := fault.Status, fault.Body
+	switch {
+	case s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token:
+		status, body = http.StatusUnauthorized, `{"error":"unauthorized"}`
+	case status != 0 || body != "":
+		if status == 0 {
+			status = http.StatusOK
+		}
+	case !found:
+		status, body = http.StatusBadRequest, `{"error":"unknown next_page"}`
+	default:
+		status = http.StatusOK
+		encoded, _ := json.Marshal(page)
+		body = string(encoded)
+	}
+
+	s.requests = append(s.requests, Request{
+		NextPage:      next,
+		Authorization: r.Header.Get("Authorization"),
+		RequestID:     r.Header.Get("X-Request-ID"),
+		Status:        status,
+	})
+	s.mu.Unlock()
+
+	if fault.Delay > 0 {
+		select {
+		case <-time.After(fault.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
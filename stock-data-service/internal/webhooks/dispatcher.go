@@ -0,0 +1,361 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/buildinfo"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/metrics"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// batchSize es la cantidad máxima de entregas que se reservan por consulta.
+const batchSize = 50
+
+// Config define cómo se envían y reintentan las entregas.
+type Config struct {
+	// Intentos antes de mover la entrega a la lista de entregas fallidas
+	MaxAttempts int
+	// Espera tras el primer fallo; se duplica en cada intento
+	BackoffBase time.Duration
+	// Espera máxima entre intentos
+	BackoffMax time.Duration
+	// Tiempo máximo de respuesta del receptor
+	Timeout time.Duration
+	// Intervalo de consulta de entregas pendientes
+	PollInterval time.Duration
+	// Entregas enviadas en paralelo
+	Concurrency int
+	// Permite entregar a direcciones de loopback, de enlace local y privadas;
+	// solo para desarrollo o receptores internos de confianza
+	AllowPrivateNetworks bool
+}
+
+// Dispatcher genera las entregas de cada evento y las envía en segundo plano.
+// Las entregas se guardan antes de enviarse, de modo que sobreviven a reinicios.
+type Dispatcher struct {
+	store  Storage
+	config Config
+	client *http.Client
+	// Despierta al bucle de envío cuando se encolan entregas nuevas
+	wake chan struct{}
+}
+
+// NewDispatcher crea un despachador de webhooks.
+func NewDispatcher(store Storage, config Config) *Dispatcher {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Second
+	}
+
+	return &Dispatcher{
+		store:  store,
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: otelhttp.NewTransport(newTransport(config.Timeout, config.AllowPrivateNetworks)),
+			// Una redirección cuenta como fallo: la firma corresponde a la URL registrada
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// AllowsPrivateNetworks indica si las suscripciones pueden apuntar a redes privadas.
+func (d *Dispatcher) AllowsPrivateNetworks() bool {
+	return d != nil && d.config.AllowPrivateNetworks
+}
+
+// PublishRatingChanges encola un evento rating.changed por cada evento nuevo del
+// historial, para las suscripciones cuyos filtros lo aceptan.
+func (d *Dispatcher) PublishRatingChanges(ctx context.Context, events []models.StockEvent) error {
+	if d == nil || len(events) == 0 {
+		return nil
+	}
+
+	return d.publish(ctx, EventRatingChanged, func(sub Subscription) []Event {
+		var matched []Event
+		for _, event := range events {
+			if sub.Filters.Match(event) {
+				matched = append(matched, newEvent(EventRatingChanged, newRatingChange(event)))
+			}
+		}
+		return matched
+	})
+}
+
+// PublishSyncRun encola un evento sync.completed o sync.failed con el resultado
+// de una sincronización.
+func (d *Dispatcher) PublishSyncRun(ctx context.Context, run models.SyncRun) error {
+	if d == nil {
+		return nil
+	}
+
+	eventType := EventSyncCompleted
+	if run.Status != models.SyncStatusCompleted {
+		eventType = EventSyncFailed
+	}
+	event := newEvent(eventType, newSyncResult(run))
+
+	return d.publish(ctx, eventType, func(Subscription) []Event {
+		return []Event{event}
+	})
+}
+
+// Ping encola un evento de prueba para una suscripción.
+func (d *Dispatcher) Ping(ctx context.Context, id int64) (Delivery, error) {
+	sub, err := d.store.GetSubscription(ctx, id)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	delivery, err := newDelivery(sub.ID, newEvent(EventPing, map[string]string{
+		"message": "Entrega de prueba de stock-data-service",
+	}))
+	if err != nil {
+		return Delivery{}, err
+	}
+	deliveries := []Delivery{delivery}
+	if err := d.store.enqueue(ctx, deliveries); err != nil {
+		return Delivery{}, err
+	}
+
+	d.notify()
+	deliveries[0].Status = DeliveryPending
+	return deliveries[0], nil
+}
+
+// publish encola las entregas que events genera para cada suscripción del tipo de evento.
+func (d *Dispatcher) publish(ctx context.Context, eventType string, events func(Subscription) []Event) error {
+	subs, err := d.store.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []Delivery
+	for _, sub := range subs {
+		if !sub.wants(eventType) {
+			continue
+		}
+		for _, event := range events(sub) {
+			delivery, err := newDelivery(sub.ID, event)
+			if err != nil {
+				return err
+			}
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := d.store.enqueue(ctx, deliveries); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Webhooks encolados", "event_type", eventType, "deliveries", len(deliveries))
+	d.notify()
+	return nil
+}
+
+// notify despierta al bucle de envío sin bloquear.
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run envía las entregas pendientes hasta que el contexto se cancela.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Seguir mientras haya lotes completos pendientes
+		for d.dispatchDue(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// dispatchDue envía un lote de entregas vencidas. Devuelve true si el lote
+// estaba completo y puede haber más entregas pendientes.
+func (d *Dispatcher) dispatchDue(ctx context.Context) bool {
+	// La reserva cubre el envío más lento posible del lote
+	lease := d.config.Timeout*time.Duration(batchSize/d.config.Concurrency+1) + time.Minute
+	deliveries, err := d.store.claimDue(ctx, batchSize, lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Error al consultar entregas de webhooks pendientes", "error", err)
+		}
+		return false
+	}
+	if len(deliveries) == 0 {
+		return false
+	}
+
+	subs, err := d.store.ListSubscriptions(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error al consultar suscripciones de webhooks", "error", err)
+		return false
+	}
+	byID := make(map[int64]Subscription, len(subs))
+	for _, sub := range subs {
+		byID[sub.ID] = sub
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, d.config.Concurrency)
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			d.attempt(ctx, delivery, byID)
+		}()
+	}
+	wg.Wait()
+
+	return len(deliveries) == batchSize
+}
+
+// attempt envía una entrega y registra el resultado.
+func (d *Dispatcher) attempt(ctx context.Context, delivery Delivery, subs map[int64]Subscription) {
+	// El resultado se registra aunque el servicio se esté apagando
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	sub, ok := subs[delivery.SubscriptionID]
+	if !ok {
+		metrics.AddWebhookDelivery("dead")
+		if err := d.store.markFailed(recordCtx, delivery.ID, nil, "suscripción eliminada", 0); err != nil {
+			slog.ErrorContext(ctx, "Error al registrar la entrega de webhook", "error", err)
+		}
+		return
+	}
+
+	statusCode, err := d.send(ctx, sub, delivery)
+	if err == nil {
+		metrics.AddWebhookDelivery("delivered")
+		if err := d.store.markDelivered(recordCtx, delivery.ID, statusCode); err != nil {
+			slog.ErrorContext(ctx, "Error al registrar la entrega de webhook", "error", err)
+		}
+		return
+	}
+	// Al apagar el servicio no se cuenta el intento; se reintenta al vencer la reserva
+	if ctx.Err() != nil {
+		return
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	attempts := delivery.Attempts + 1
+	retryIn := d.backoff(attempts)
+	// 410 Gone indica que el receptor no quiere más entregas en esa URL, y una
+	// dirección bloqueada no cambia al reintentar
+	if attempts >= d.config.MaxAttempts || statusCode == http.StatusGone || errors.Is(err, ErrBlockedAddress) {
+		retryIn = 0
+		metrics.AddWebhookDelivery("dead")
+		slog.WarnContext(ctx, "Entrega de webhook movida a la lista de entregas fallidas",
+			"delivery_id", delivery.ID, "subscription_id", sub.ID, "event_type", delivery.EventType,
+			"attempts", attempts, "error", err)
+	} else {
+		metrics.AddWebhookDelivery("retry")
+	}
+
+	if err := d.store.markFailed(recordCtx, delivery.ID, code, err.Error(), retryIn); err != nil {
+		slog.ErrorContext(ctx, "Error al registrar la entrega de webhook", "error", err)
+	}
+}
+
+// send envía el cuerpo firmado al receptor. Devuelve el código HTTP, o 0 si no
+// hubo respuesta, y un error si el código no es 2xx.
+func (d *Dispatcher) send(ctx context.Context, sub Subscription, delivery Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("error al crear la solicitud: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "stock-data-service-webhooks/"+buildinfo.Version)
+	req.Header.Set(SignatureHeader, Sign(sub.secret, time.Now(), delivery.Payload))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error al realizar la solicitud: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Se conserva el inicio del cuerpo para diagnosticar fallos
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("el receptor respondió %d: %s", resp.StatusCode, body)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff calcula la espera antes del siguiente intento: BackoffBase duplicado
+// por cada intento fallido, hasta BackoffMax, con hasta un 20% de variación
+// aleatoria para no reintentar todas las entregas a la vez.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.config.BackoffBase
+	for i := 1; i < attempts && wait < d.config.BackoffMax; i++ {
+		wait *= 2
+	}
+	if d.config.BackoffMax > 0 && wait > d.config.BackoffMax {
+		wait = d.config.BackoffMax
+	}
+	if wait <= 0 {
+		wait = time.Second
+	}
+	return wait + time.Duration(rand.Int64N(int64(wait)/5+1))
+}
+
+// newDelivery serializa un evento como entrega pendiente para una suscripción.
+func newDelivery(subscriptionID int64, event Event) (Delivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("error al serializar el evento: %w", err)
+	}
+
+	return Delivery{
+		SubscriptionID: subscriptionID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		Payload:        payload,
+	}, nil
+}
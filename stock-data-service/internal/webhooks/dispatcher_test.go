@@ -0,0 +1,341 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+)
+
+// testClock es un reloj que solo avanza cuando la prueba lo indica.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// received es una solicitud recibida por el receptor de prueba.
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver es un receptor de webhooks que responde con los códigos indicados,
+// en orden, y repite el último.
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []received
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		r.requests = append(r.requests, received{header: req.Header.Clone(), body: body})
+		status := r.statuses[0]
+		if len(r.statuses) > 1 {
+			r.statuses = r.statuses[1:]
+		}
+		r.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) Requests() []received {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]received(nil), r.requests...)
+}
+
+// testConfig reintenta tras un minuto, con un máximo de diez, y permite el
+// receptor de prueba en 127.0.0.1.
+var testConfig = Config{
+	MaxAttempts:          3,
+	BackoffBase:          time.Minute,
+	BackoffMax:           10 * time.Minute,
+	Timeout:              5 * time.Second,
+	AllowPrivateNetworks: true,
+}
+
+// newTestDispatcher crea un despachador sobre un almacén en memoria con un reloj controlado.
+func newTestDispatcher(config Config) (*Dispatcher, *MemoryStore, *testClock) {
+	clock := &testClock{now: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+	return NewDispatcher(store, config), store, clock
+}
+
+// subscribe registra una suscripción sin validar su URL.
+func subscribe(t *testing.T, store Storage, url string, filters Filters) (Subscription, string) {
+	t.Helper()
+
+	sub, secret, err := store.CreateSubscription(context.Background(), Subscription{URL: url, Events: eventTypes, Filters: filters})
+	if err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+	return sub, secret
+}
+
+// onlyDelivery devuelve la única entrega registrada.
+func onlyDelivery(t *testing.T, store Storage) Delivery {
+	t.Helper()
+
+	deliveries, err := store.ListDeliveries(context.Background(), DeliveryQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListDeliveries() error = %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("ListDeliveries() = %d entregas, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+func TestDispatcherSignedDelivery(t *testing.T) {
+	ctx := context.Background()
+	d, store, _ := newTestDispatcher(testConfig)
+	rcv := newReceiver(t, http.StatusNoContent)
+	sub, secret := subscribe(t, store, rcv.URL, Filters{})
+
+	if _, err := d.Ping(ctx, sub.ID); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	d.dispatchDue(ctx)
+
+	requests := rcv.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("el receptor recibió %d solicitudes, want 1", len(requests))
+	}
+	req := requests[0]
+	if err := Verify(secret, req.header.Get(SignatureHeader), req.body, 5*time.Minute); err != nil {
+		t.Errorf("Verify() error = %v, want una firma válida", err)
+	}
+
+	delivery := onlyDelivery(t, store)
+	if got := req.header.Get(EventHeader); got != EventPing {
+		t.Errorf("%s = %q, want %q", EventHeader, got, EventPing)
+	}
+	if got := req.header.Get(EventIDHeader); got != delivery.EventID {
+		t.Errorf("%s = %q, want %q", EventIDHeader, got, delivery.EventID)
+	}
+	if got := req.header.Get(DeliveryHeader); got != strconv.FormatInt(delivery.ID, 10) {
+		t.Errorf("%s = %q, want %d", DeliveryHeader, got, delivery.ID)
+	}
+	if string(req.body) != string(delivery.Payload) {
+		t.Errorf("cuerpo = %s, want el payload guardado %s", req.body, delivery.Payload)
+	}
+
+	if delivery.Status != DeliveryDelivered || delivery.Attempts != 1 || delivery.DeliveredAt == nil ||
+		delivery.LastStatusCode == nil || *delivery.LastStatusCode != http.StatusNoContent {
+		t.Errorf("entrega = %+v, want entregada en el primer intento con 204", delivery)
+	}
+}
+
+func TestDispatcherRetriesAfterServerError(t *testing.T) {
+	ctx := context.Background()
+	d, store, clock := newTestDispatcher(testConfig)
+	rcv := newReceiver(t, http.StatusInternalServerError, http.StatusOK)
+	sub, _ := subscribe(t, store, rcv.URL, Filters{})
+
+	if _, err := d.Ping(ctx, sub.ID); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	d.dispatchDue(ctx)
+
+	delivery := onlyDelivery(t, store)
+	if delivery.Status != DeliveryPending || delivery.Attempts != 1 ||
+		delivery.LastStatusCode == nil || *delivery.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("entrega tras el 500 = %+v, want pendiente con 1 intento", delivery)
+	}
+	// La espera es BackoffBase con hasta un 20% de variación
+	wait := delivery.NextAttemptAt.Sub(clock.Now())
+	if wait < time.Minute || wait > time.Minute+12*time.Second {
+		t.Errorf("próximo intento en %v, want entre 1m y 1m12s", wait)
+	}
+
+	// Antes de que venza la espera no se reintenta
+	d.dispatchDue(ctx)
+	if n := len(rcv.Requests()); n != 1 {
+		t.Fatalf("solicitudes antes de vencer la espera = %d, want 1", n)
+	}
+
+	clock.Advance(2 * time.Minute)
+	d.dispatchDue(ctx)
+
+	requests := rcv.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("solicitudes tras la espera = %d, want 2", len(requests))
+	}
+	if first, second := requests[0].header.Get(EventIDHeader), requests[1].header.Get(EventIDHeader); first != second {
+		t.Errorf("el reintento cambió el identificador del evento: %q != %q", first, second)
+	}
+	if delivery := onlyDelivery(t, store); delivery.Status != DeliveryDelivered || delivery.Attempts != 2 || delivery.LastError != "" {
+		t.Errorf("entrega tras el reintento = %+v, want entregada en 2 intentos", delivery)
+	}
+}
+
+func TestDispatcherMovesToDeadLetterAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	d, store, clock := newTestDispatcher(testConfig)
+	rcv := newReceiver(t, http.StatusBadGateway)
+	sub, _ := subscribe(t, store, rcv.URL, Filters{})
+
+	if _, err := d.Ping(ctx, sub.ID); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	for i := 0; i < testConfig.MaxAttempts+1; i++ {
+		d.dispatchDue(ctx)
+		clock.Advance(testConfig.BackoffMax * 2)
+	}
+
+	if n := len(rcv.Requests()); n != testConfig.MaxAttempts {
+		t.Errorf("solicitudes = %d, want %d", n, testConfig.MaxAttempts)
+	}
+	delivery := onlyDelivery(t, store)
+	if delivery.Status != DeliveryDead || delivery.Attempts != testConfig.MaxAttempts || delivery.NextAttemptAt != nil ||
+		!strings.Contains(delivery.LastError, "502") {
+		t.Fatalf("entrega = %+v, want en la lista de entregas fallidas tras %d intentos", delivery, testConfig.MaxAttempts)
+	}
+
+	// Desde la lista de entregas fallidas se puede reencolar
+	redelivered, err := store.Redeliver(ctx, delivery.ID)
+	if err != nil {
+		t.Fatalf("Redeliver() error = %v", err)
+	}
+	if redelivered.Status != DeliveryPending || redelivered.Attempts != 0 {
+		t.Errorf("Redeliver() = %+v, want pendiente sin intentos", redelivered)
+	}
+	d.dispatchDue(ctx)
+	if n := len(rcv.Requests()); n != testConfig.MaxAttempts+1 {
+		t.Errorf("solicitudes tras reencolar = %d, want %d", n, testConfig.MaxAttempts+1)
+	}
+}
+
+func TestDispatcherGoneIsFinal(t *testing.T) {
+	ctx := context.Background()
+	d, store, _ := newTestDispatcher(testConfig)
+	rcv := newReceiver(t, http.StatusGone)
+	sub, _ := subscribe(t, store, rcv.URL, Filters{})
+
+	if _, err := d.Ping(ctx, sub.ID); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	d.dispatchDue(ctx)
+
+	if delivery := onlyDelivery(t, store); delivery.Status != DeliveryDead || delivery.Attempts != 1 {
+		t.Errorf("entrega tras 410 = %+v, want en la lista de entregas fallidas tras 1 intento", delivery)
+	}
+}
+
+func TestDispatcherBlocksPrivateAddressesAtDial(t *testing.T) {
+	ctx := context.Background()
+	config := testConfig
+	config.AllowPrivateNetworks = false
+	d, store, _ := newTestDispatcher(config)
+	rcv := newReceiver(t, http.StatusNoContent)
+	// La URL no pasó por Validate, como un nombre que resuelve a 127.0.0.1
+	sub, _ := subscribe(t, store, rcv.URL, Filters{})
+
+	if _, err := d.Ping(ctx, sub.ID); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	d.dispatchDue(ctx)
+
+	if n := len(rcv.Requests()); n != 0 {
+		t.Errorf("el receptor recibió %d solicitudes, want 0", n)
+	}
+	delivery := onlyDelivery(t, store)
+	if delivery.Status != DeliveryDead || delivery.Attempts != 1 || !strings.Contains(delivery.LastError, ErrBlockedAddress.Error()) {
+		t.Errorf("entrega = %+v, want en la lista de entregas fallidas por la dirección bloqueada", delivery)
+	}
+}
+
+func TestDispatcherPublishRatingChangesAppliesFilters(t *testing.T) {
+	ctx := context.Background()
+	d, store, _ := newTestDispatcher(testConfig)
+	all, _ := subscribe(t, store, "https://example.com/all", Filters{})
+	apple, _ := subscribe(t, store, "https://example.com/apple", Filters{Tickers: []string{"AAPL"}})
+
+	events := []models.StockEvent{
+		ratingEvent("NVDA", "Barclays", "target raised by", "$140.00", "$160.00"),
+		ratingEvent("AAPL", "Barclays", "upgraded by", "$200.00", "$220.00"),
+	}
+	if err := d.PublishRatingChanges(ctx, events); err != nil {
+		t.Fatalf("PublishRatingChanges() error = %v", err)
+	}
+
+	counts := map[int64]int{}
+	deliveries, err := store.ListDeliveries(ctx, DeliveryQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListDeliveries() error = %v", err)
+	}
+	for _, delivery := range deliveries {
+		counts[delivery.SubscriptionID]++
+		if delivery.EventType != EventRatingChanged || delivery.Status != DeliveryPending {
+			t.Errorf("entrega = %+v, want rating.changed pendiente", delivery)
+		}
+	}
+	if counts[all.ID] != 2 || counts[apple.ID] != 1 {
+		t.Errorf("entregas por suscripción = %v, want 2 para %d y 1 para %d", counts, all.ID, apple.ID)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(NewMemoryStore(), Config{BackoffBase: 30 * time.Second, BackoffMax: time.Hour})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+
+	for _, tt := range tests {
+		// Cada espera admite hasta un 20% de variación aleatoria
+		for i := 0; i < 20; i++ {
+			if got := d.backoff(tt.attempts); got < tt.want || got > tt.want+tt.want/5 {
+				t.Errorf("backoff(%d) = %v, want entre %v y %v", tt.attempts, got, tt.want, tt.want+tt.want/5)
+				break
+			}
+		}
+	}
+
+	// Sin espera configurada se reintenta tras un segundo
+	d = NewDispatcher(NewMemoryStore(), Config{})
+	if got := d.backoff(1); got < time.Second || got > time.Second+time.Second/5 {
+		t.Errorf("backoff(1) sin configuración = %v, want alrededor de 1s", got)
+	}
+}
@@ -0,0 +1,244 @@
+package webhooks
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore implementa Storage en memoria. Sirve para pruebas y desarrollo
+// local; las entregas no sobreviven a un reinicio.
+type MemoryStore struct {
+	mu             sync.Mutex
+	subs           []Subscription
+	deliveries     []Delivery
+	nextSubID      int64
+	nextDeliveryID int64
+	// Reloj de las fechas y vencimientos; las pruebas lo reemplazan
+	now func() time.Time
+}
+
+// NewMemoryStore crea un almacén de webhooks en memoria vacío.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now}
+}
+
+// CreateSubscription registra una suscripción, que debe haberse validado, y
+// devuelve el secreto de firma, que solo está disponible en este momento.
+func (s *MemoryStore) CreateSubscription(ctx context.Context, sub Subscription) (Subscription, string, error) {
+	secret, err := newSecret(ctx)
+	if err != nil {
+		return Subscription{}, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextSubID++
+	sub.ID = s.nextSubID
+	sub.CreatedAt = s.now().UTC()
+	sub.DeletedAt = nil
+	sub.secret = secret
+	s.subs = append(s.subs, sub)
+	return sub, secret, nil
+}
+
+// ListSubscriptions devuelve las suscripciones vigentes.
+func (s *MemoryStore) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := []Subscription{}
+	for i := len(s.subs) - 1; i >= 0; i-- {
+		if s.subs[i].DeletedAt == nil {
+			subs = append(subs, s.subs[i])
+		}
+	}
+	return subs, nil
+}
+
+// GetSubscription obtiene una suscripción vigente. Devuelve ErrNotFound si no
+// existe o fue eliminada.
+func (s *MemoryStore) GetSubscription(ctx context.Context, id int64) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.subscription(id)
+	if sub == nil {
+		return Subscription{}, ErrNotFound
+	}
+	return *sub, nil
+}
+
+// DeleteSubscription elimina una suscripción y descarta sus entregas pendientes,
+// que pasan a la lista de entregas fallidas. Devuelve ErrNotFound si no existe o
+// ya fue eliminada.
+func (s *MemoryStore) DeleteSubscription(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.subscription(id)
+	if sub == nil {
+		return ErrNotFound
+	}
+	deletedAt := s.now().UTC()
+	sub.DeletedAt = &deletedAt
+
+	for i := range s.deliveries {
+		d := &s.deliveries[i]
+		if d.SubscriptionID == id && d.Status == DeliveryPending {
+			d.Status = DeliveryDead
+			d.NextAttemptAt = nil
+			d.LastError = "suscripción eliminada"
+		}
+	}
+	return nil
+}
+
+// ListDeliveries devuelve las entregas más recientes que cumplen el filtro.
+func (s *MemoryStore) ListDeliveries(ctx context.Context, query DeliveryQuery) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := []Delivery{}
+	for _, d := range s.deliveries {
+		if (query.SubscriptionID == 0 || d.SubscriptionID == query.SubscriptionID) &&
+			(query.Status == "" || d.Status == query.Status) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		a, b := deliveries[i], deliveries[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	return deliveries[:min(query.Limit, len(deliveries))], nil
+}
+
+// Redeliver vuelve a poner en cola una entrega fallida con los intentos a cero.
+// Devuelve ErrNotFound si no existe, no está en la lista de entregas fallidas o
+// su suscripción fue eliminada.
+func (s *MemoryStore) Redeliver(ctx context.Context, id int64) (Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.delivery(id)
+	if d == nil || d.Status != DeliveryDead || s.subscription(d.SubscriptionID) == nil {
+		return Delivery{}, ErrNotFound
+	}
+	now := s.now().UTC()
+	d.Status = DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = &now
+	return *d, nil
+}
+
+// enqueue guarda entregas pendientes y completa su identificador y fecha de creación.
+func (s *MemoryStore) enqueue(ctx context.Context, deliveries []Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().UTC()
+	for i := range deliveries {
+		s.nextDeliveryID++
+		deliveries[i].ID = s.nextDeliveryID
+		deliveries[i].CreatedAt = now
+
+		d := deliveries[i]
+		d.Status = DeliveryPending
+		d.NextAttemptAt = &now
+		s.deliveries = append(s.deliveries, d)
+	}
+	return nil
+}
+
+// claimDue toma hasta limit entregas pendientes cuyo intento ya venció y las
+// reserva durante lease.
+func (s *MemoryStore) claimDue(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().UTC()
+	var due []*Delivery
+	for i := range s.deliveries {
+		d := &s.deliveries[i]
+		if d.Status == DeliveryPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+	})
+
+	claimed := []Delivery{}
+	leaseEnd := now.Add(lease)
+	for _, d := range due[:min(limit, len(due))] {
+		d.NextAttemptAt = &leaseEnd
+		claimed = append(claimed, *d)
+	}
+	return claimed, nil
+}
+
+// markDelivered registra una entrega exitosa.
+func (s *MemoryStore) markDelivered(ctx context.Context, id int64, statusCode int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d := s.delivery(id); d != nil {
+		now := s.now().UTC()
+		d.Status = DeliveryDelivered
+		d.Attempts++
+		d.NextAttemptAt = nil
+		d.LastStatusCode = &statusCode
+		d.LastError = ""
+		d.DeliveredAt = &now
+	}
+	return nil
+}
+
+// markFailed registra un intento fallido. Con retryIn mayor que 0 la entrega se
+// reintenta tras ese tiempo; en otro caso pasa a la lista de entregas fallidas.
+func (s *MemoryStore) markFailed(ctx context.Context, id int64, statusCode *int, errMsg string, retryIn time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.delivery(id)
+	if d == nil {
+		return nil
+	}
+	d.Attempts++
+	d.LastStatusCode = statusCode
+	d.LastError = errMsg
+	if retryIn > 0 {
+		next := s.now().UTC().Add(retryIn)
+		d.Status = DeliveryPending
+		d.NextAttemptAt = &next
+	} else {
+		d.Status = DeliveryDead
+		d.NextAttemptAt = nil
+	}
+	return nil
+}
+
+// subscription busca una suscripción vigente. Debe llamarse con el mutex tomado.
+func (s *MemoryStore) subscription(id int64) *Subscription {
+	for i := range s.subs {
+		if s.subs[i].ID == id && s.subs[i].DeletedAt == nil {
+			return &s.subs[i]
+		}
+	}
+	return nil
+}
+
+// delivery busca una entrega. Debe llamarse con el mutex tomado.
+func (s *MemoryStore) delivery(id int64) *Delivery {
+	for i := range s.deliveries {
+		if s.deliveries[i].ID == id {
+			return &s.deliveries[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress indica que la URL de la suscripción apunta a una dirección
+// de loopback, de enlace local o privada y las redes privadas no están permitidas.
+var ErrBlockedAddress = errors.New("la dirección del receptor no está permitida")
+
+// blockedPrefixes son las redes bloqueadas que netip no clasifica: "esta red",
+// el espacio compartido de CGNAT y la difusión limitada.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("255.255.255.255/32"),
+}
+
+// blockedAddress indica si una dirección pertenece a una red a la que los
+// webhooks no deben llegar: loopback, enlace local (incluidos los metadatos de
+// la nube en 169.254.169.254), privada, sin especificar o multicast.
+func blockedAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsPrivate() || addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost rechaza los hosts que, sin resolverlos, ya se sabe que apuntan a
+// una red bloqueada: direcciones IP literales y localhost. Los demás nombres se
+// comprueban al conectarse.
+func checkHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrBlockedAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && blockedAddress(addr) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// newTransport crea el transporte de las entregas. Sin allowPrivate, cada
+// conexión comprueba la dirección ya resuelta, de modo que un nombre que
+// resuelve (o vuelve a resolver) a una red bloqueada no llega a conectarse. No
+// usa el proxy del entorno: la comprobación sería sobre la dirección del proxy.
+func newTransport(timeout time.Duration, allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+			}
+			if blockedAddress(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, addrPort.Addr())
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
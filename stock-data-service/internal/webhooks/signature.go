@@ -0,0 +1,77 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cabeceras de las entregas.
+const (
+	// Firma HMAC-SHA256 del cuerpo: "t=<unix>,v1=<hex>"
+	SignatureHeader = "X-Webhook-Signature"
+	// Tipo de evento
+	EventHeader = "X-Webhook-Event"
+	// Identificador del evento, igual en todos los reintentos
+	EventIDHeader = "X-Webhook-Event-ID"
+	// Identificador de la entrega
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+// ErrInvalidSignature indica que la firma no corresponde al cuerpo o es demasiado antigua.
+var ErrInvalidSignature = errors.New("firma de webhook inválida")
+
+// Sign calcula la cabecera X-Webhook-Signature. Se firma "<timestamp>.<cuerpo>"
+// para que un tercero no pueda reenviar un cuerpo capturado con otra fecha.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+// Verify comprueba la cabecera X-Webhook-Signature de una entrega recibida. Con
+// tolerance mayor que 0 se rechazan las firmas más antiguas que ese margen.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var ts string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)).Abs() > tolerance {
+		return ErrInvalidSignature
+	}
+
+	expected := signature(secret, ts, body)
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// signature calcula el HMAC-SHA256 en hexadecimal de "<timestamp>.<cuerpo>".
+func signature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
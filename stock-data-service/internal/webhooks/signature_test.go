@@ -0,0 +1,71 @@
+package webhooks
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+func TestSignFormat(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	header := Sign(testSecret, ts, []byte(`{"id":"evt_1"}`))
+
+	if !regexp.MustCompile(`^t=1700000000,v1=[0-9a-f]{64}$`).MatchString(header) {
+		t.Errorf("Sign() = %q, want t=<unix>,v1=<hex de 64 caracteres>", header)
+	}
+	if again := Sign(testSecret, ts, []byte(`{"id":"evt_1"}`)); again != header {
+		t.Errorf("Sign() no es determinista: %q != %q", again, header)
+	}
+	// La fecha forma parte de lo firmado
+	if other := Sign(testSecret, ts.Add(time.Second), []byte(`{"id":"evt_1"}`)); other[len(other)-64:] == header[len(header)-64:] {
+		t.Error("Sign() con otra fecha produjo la misma firma")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"ping"}`)
+	now := time.Now()
+	valid := Sign(testSecret, now, body)
+	old := Sign(testSecret, now.Add(-time.Hour), body)
+	v1 := valid[strings.Index(valid, "v1="):]
+	ts := "t=" + strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		header    string
+		body      []byte
+		tolerance time.Duration
+		wantErr   bool
+	}{
+		{"firma válida", testSecret, valid, body, 5 * time.Minute, false},
+		{"espacios entre partes", testSecret, strings.ReplaceAll(valid, ",", ", "), body, 5 * time.Minute, false},
+		{"varias firmas, una válida", testSecret, ts + ",v1=" + strings.Repeat("0", 64) + "," + v1, body, 5 * time.Minute, false},
+		{"otro secreto", "whsec_otro", valid, body, 5 * time.Minute, true},
+		{"cuerpo modificado", testSecret, valid, []byte(`{"id":"evt_2","type":"ping"}`), 5 * time.Minute, true},
+		{"firma antigua", testSecret, old, body, 5 * time.Minute, true},
+		{"firma antigua sin tolerancia", testSecret, old, body, 0, false},
+		{"fecha cambiada", testSecret, "t=" + strconv.FormatInt(now.Unix()+1, 10) + "," + v1, body, 5 * time.Minute, true},
+		{"sin fecha", testSecret, v1, body, 5 * time.Minute, true},
+		{"sin firma", testSecret, ts, body, 5 * time.Minute, true},
+		{"fecha no numérica", testSecret, "t=ayer," + v1, body, 5 * time.Minute, true},
+		{"cabecera vacía", testSecret, "", body, 5 * time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, tt.tolerance)
+			if tt.wantErr && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify() error = %v, want ErrInvalidSignature", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Verify() error = %v, want nil", err)
+			}
+		})
+	}
+}
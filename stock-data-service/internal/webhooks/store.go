@@ -0,0 +1,445 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/logging"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/repository"
+)
+
+// secretPrefix identifica los secretos de firma emitidos por el servicio.
+const secretPrefix = "whsec_"
+
+// Storage guarda las suscripciones y el registro de entregas. Store lo
+// implementa sobre CockroachDB y MemoryStore en memoria; los métodos sin
+// exportar son los que usa el despachador para enviar las entregas.
+type Storage interface {
+	// CreateSubscription registra una suscripción y devuelve su secreto de firma.
+	CreateSubscription(ctx context.Context, sub Subscription) (Subscription, string, error)
+	// ListSubscriptions devuelve las suscripciones vigentes, de la más reciente a la más antigua.
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	// GetSubscription obtiene una suscripción vigente o devuelve ErrNotFound.
+	GetSubscription(ctx context.Context, id int64) (Subscription, error)
+	// DeleteSubscription elimina una suscripción y descarta sus entregas pendientes.
+	DeleteSubscription(ctx context.Context, id int64) error
+	// ListDeliveries devuelve las entregas más recientes que cumplen el filtro.
+	ListDeliveries(ctx context.Context, query DeliveryQuery) ([]Delivery, error)
+	// Redeliver vuelve a poner en cola una entrega de la lista de entregas fallidas.
+	Redeliver(ctx context.Context, id int64) (Delivery, error)
+
+	enqueue(ctx context.Context, deliveries []Delivery) error
+	claimDue(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error)
+	markDelivered(ctx context.Context, id int64, statusCode int) error
+	markFailed(ctx context.Context, id int64, statusCode *int, errMsg string, retryIn time.Duration) error
+}
+
+// Store guarda las suscripciones y el registro de entregas en la base de datos.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea un nuevo almacén de webhooks.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// InitDB crea las tablas de suscripciones y entregas si no existen.
+func (s *Store) InitDB(ctx context.Context) error {
+	queries := []string{
+		`
+    CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+        id INT PRIMARY KEY DEFAULT unique_rowid(),
+        url STRING NOT NULL,
+        description STRING NOT NULL DEFAULT '',
+        secret STRING NOT NULL,
+        events JSONB NOT NULL,
+        filters JSONB NOT NULL,
+        created_by STRING NOT NULL DEFAULT '',
+        created_at TIMESTAMP NOT NULL DEFAULT current_timestamp(),
+        deleted_at TIMESTAMP
+    )
+    `,
+		// El cuerpo se guarda como texto para firmar y reenviar exactamente los mismos bytes
+		`
+    CREATE TABLE IF NOT EXISTS webhook_deliveries (
+        id INT PRIMARY KEY DEFAULT unique_rowid(),
+        subscription_id INT NOT NULL REFERENCES webhook_subscriptions (id),
+        event_id STRING NOT NULL,
+        event_type STRING NOT NULL,
+        payload STRING NOT NULL,
+        status STRING NOT NULL DEFAULT 'pending',
+        attempts INT NOT NULL DEFAULT 0,
+        next_attempt_at TIMESTAMP,
+        last_status_code INT,
+        last_error STRING,
+        created_at TIMESTAMP NOT NULL DEFAULT current_timestamp(),
+        delivered_at TIMESTAMP,
+        INDEX webhook_deliveries_due_idx (status, next_attempt_at),
+        INDEX webhook_deliveries_subscription_idx (subscription_id, created_at DESC)
+    )
+    `,
+	}
+
+	for _, query := range queries {
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return logging.Errorf(ctx, "error al crear las tablas de webhooks: %w", repository.Classify(err))
+		}
+	}
+	return nil
+}
+
+// CreateSubscription registra una suscripción, que debe haberse validado, y
+// devuelve el secreto de firma, que solo está disponible en este momento.
+func (s *Store) CreateSubscription(ctx context.Context, sub Subscription) (Subscription, string, error) {
+	secret, err := newSecret(ctx)
+	if err != nil {
+		return Subscription{}, "", err
+	}
+
+	events, err := json.Marshal(sub.Events)
+	if err != nil {
+		return Subscription{}, "", err
+	}
+	filters, err := json.Marshal(sub.Filters)
+	if err != nil {
+		return Subscription{}, "", err
+	}
+
+	err = s.db.QueryRowContext(ctx, `
+        INSERT INTO webhook_subscriptions (url, description, secret, events, filters, created_by)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at
+    `, sub.URL, sub.Description, secret, string(events), string(filters), sub.CreatedBy).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return Subscription{}, "", logging.Errorf(ctx, "error al guardar la suscripción: %w", repository.Classify(err))
+	}
+
+	sub.secret = secret
+	return sub, secret, nil
+}
+
+// newSecret genera un secreto de firma aleatorio.
+func newSecret(ctx context.Context) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", logging.Errorf(ctx, "error al generar el secreto del webhook: %w", repository.Classify(err))
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// subscriptionColumns son las columnas necesarias para construir una Subscription.
+const subscriptionColumns = `id, url, description, secret, events, filters, created_by, created_at, deleted_at`
+
+// ListSubscriptions devuelve las suscripciones vigentes.
+func (s *Store) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT `+subscriptionColumns+`
+        FROM webhook_subscriptions
+        WHERE deleted_at IS NULL
+        ORDER BY created_at DESC
+    `)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar suscripciones: %w", repository.Classify(err))
+	}
+	defer rows.Close()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear suscripción: %w", repository.Classify(err))
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logging.Errorf(ctx, "error al iterar suscripciones: %w", repository.Classify(err))
+	}
+
+	return subs, nil
+}
+
+// GetSubscription obtiene una suscripción vigente. Devuelve ErrNotFound si no
+// existe o fue eliminada.
+func (s *Store) GetSubscription(ctx context.Context, id int64) (Subscription, error) {
+	row := s.db.QueryRowContext(ctx, `
+        SELECT `+subscriptionColumns+`
+        FROM webhook_subscriptions
+        WHERE id = $1 AND deleted_at IS NULL
+    `, id)
+
+	sub, err := scanSubscription(row)
+	if err == sql.ErrNoRows {
+		return Subscription{}, ErrNotFound
+	}
+	if err != nil {
+		return Subscription{}, logging.Errorf(ctx, "error al consultar la suscripción: %w", repository.Classify(err))
+	}
+	return sub, nil
+}
+
+// DeleteSubscription elimina una suscripción y descarta sus entregas pendientes,
+// que pasan a la lista de entregas fallidas. Devuelve ErrNotFound si no existe o
+// ya fue eliminada.
+func (s *Store) DeleteSubscription(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return logging.Errorf(ctx, "error al iniciar la transacción: %w", repository.Classify(err))
+	}
+
+	result, err := tx.ExecContext(ctx, `
+        UPDATE webhook_subscriptions SET deleted_at = current_timestamp()
+        WHERE id = $1 AND deleted_at IS NULL
+    `, id)
+	if err != nil {
+		tx.Rollback()
+		return logging.Errorf(ctx, "error al eliminar la suscripción: %w", repository.Classify(err))
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return logging.Errorf(ctx, "error al eliminar la suscripción: %w", repository.Classify(err))
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = $2, next_attempt_at = NULL, last_error = 'suscripción eliminada'
+        WHERE subscription_id = $1 AND status = $3
+    `, id, DeliveryDead, DeliveryPending)
+	if err != nil {
+		tx.Rollback()
+		return logging.Errorf(ctx, "error al descartar las entregas pendientes: %w", repository.Classify(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return logging.Errorf(ctx, "error al confirmar la transacción: %w", repository.Classify(err))
+	}
+	return nil
+}
+
+// DeliveryQuery filtra el registro de entregas.
+type DeliveryQuery struct {
+	// Suscripción; 0 incluye todas
+	SubscriptionID int64
+	// Estado (pending, delivered o dead); vacío incluye todos
+	Status string
+	// Cantidad máxima de entregas
+	Limit int
+}
+
+// deliveryColumns son las columnas necesarias para construir una Delivery.
+const deliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+        next_attempt_at, last_status_code, COALESCE(last_error, ''), created_at, delivered_at`
+
+// ListDeliveries devuelve las entregas más recientes que cumplen el filtro.
+func (s *Store) ListDeliveries(ctx context.Context, query DeliveryQuery) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT `+deliveryColumns+`
+        FROM webhook_deliveries
+        WHERE ($1 = 0 OR subscription_id = $1) AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC, id DESC
+        LIMIT $3
+    `, query.SubscriptionID, query.Status, query.Limit)
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al consultar entregas: %w", repository.Classify(err))
+	}
+	return collectDeliveries(ctx, rows)
+}
+
+// Redeliver vuelve a poner en cola una entrega fallida con los intentos a cero.
+// Devuelve ErrNotFound si no existe, no está en la lista de entregas fallidas o
+// su suscripción fue eliminada.
+func (s *Store) Redeliver(ctx context.Context, id int64) (Delivery, error) {
+	row := s.db.QueryRowContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = $2, attempts = 0, next_attempt_at = current_timestamp()
+        WHERE id = $1 AND status = $3 AND subscription_id IN (
+            SELECT id FROM webhook_subscriptions WHERE deleted_at IS NULL
+        )
+        RETURNING `+deliveryColumns, id, DeliveryPending, DeliveryDead)
+
+	delivery, err := scanDelivery(row)
+	if err == sql.ErrNoRows {
+		return Delivery{}, ErrNotFound
+	}
+	if err != nil {
+		return Delivery{}, logging.Errorf(ctx, "error al reencolar la entrega: %w", repository.Classify(err))
+	}
+	return delivery, nil
+}
+
+// enqueue guarda entregas pendientes en una transacción y completa su
+// identificador y fecha de creación.
+func (s *Store) enqueue(ctx context.Context, deliveries []Delivery) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return logging.Errorf(ctx, "error al iniciar la transacción: %w", repository.Classify(err))
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+        INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, next_attempt_at)
+        VALUES ($1, $2, $3, $4, $5, current_timestamp())
+        RETURNING id, created_at
+    `)
+	if err != nil {
+		tx.Rollback()
+		return logging.Errorf(ctx, "error al preparar el statement: %w", repository.Classify(err))
+	}
+	defer stmt.Close()
+
+	for i, d := range deliveries {
+		err := stmt.QueryRowContext(ctx, d.SubscriptionID, d.EventID, d.EventType, string(d.Payload), DeliveryPending).
+			Scan(&deliveries[i].ID, &deliveries[i].CreatedAt)
+		if err != nil {
+			tx.Rollback()
+			return logging.Errorf(ctx, "error al guardar la entrega: %w", repository.Classify(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return logging.Errorf(ctx, "error al confirmar la transacción: %w", repository.Classify(err))
+	}
+	return nil
+}
+
+// claimDue toma hasta limit entregas pendientes cuyo intento ya venció y las
+// reserva durante lease, para que otra réplica no las envíe al mismo tiempo. Si
+// la réplica se detiene, la entrega vuelve a estar disponible al vencer la reserva.
+func (s *Store) claimDue(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        UPDATE webhook_deliveries
+        SET next_attempt_at = current_timestamp() + $3::FLOAT8 * INTERVAL '1 second'
+        WHERE id IN (
+            SELECT id FROM webhook_deliveries
+            WHERE status = $1 AND next_attempt_at <= current_timestamp()
+            ORDER BY next_attempt_at
+            LIMIT $2
+        )
+        RETURNING `+deliveryColumns, DeliveryPending, limit, lease.Seconds())
+	if err != nil {
+		return nil, logging.Errorf(ctx, "error al reservar entregas: %w", repository.Classify(err))
+	}
+	return collectDeliveries(ctx, rows)
+}
+
+// markDelivered registra una entrega exitosa.
+func (s *Store) markDelivered(ctx context.Context, id int64, statusCode int) error {
+	_, err := s.db.ExecContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = $2, attempts = attempts + 1, next_attempt_at = NULL,
+            last_status_code = $3, last_error = NULL, delivered_at = current_timestamp()
+        WHERE id = $1
+    `, id, DeliveryDelivered, statusCode)
+	if err != nil {
+		return logging.Errorf(ctx, "error al registrar la entrega: %w", repository.Classify(err))
+	}
+	return nil
+}
+
+// markFailed registra un intento fallido. Con retryIn mayor que 0 la entrega se
+// reintenta tras ese tiempo; en otro caso pasa a la lista de entregas fallidas.
+func (s *Store) markFailed(ctx context.Context, id int64, statusCode *int, errMsg string, retryIn time.Duration) error {
+	status := DeliveryDead
+	var seconds interface{}
+	if retryIn > 0 {
+		status = DeliveryPending
+		seconds = retryIn.Seconds()
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = $2, attempts = attempts + 1,
+            next_attempt_at = current_timestamp() + $3::FLOAT8 * INTERVAL '1 second',
+            last_status_code = $4, last_error = $5
+        WHERE id = $1
+    `, id, status, seconds, statusCode, errMsg)
+	if err != nil {
+		return logging.Errorf(ctx, "error al registrar el intento fallido: %w", repository.Classify(err))
+	}
+	return nil
+}
+
+// rowScanner es la interfaz común de *sql.Row y *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSubscription convierte una fila con las columnas de subscriptionColumns.
+func scanSubscription(row rowScanner) (Subscription, error) {
+	var sub Subscription
+	var events, filters []byte
+	var deletedAt sql.NullTime
+	err := row.Scan(&sub.ID, &sub.URL, &sub.Description, &sub.secret, &events, &filters,
+		&sub.CreatedBy, &sub.CreatedAt, &deletedAt)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	if err := json.Unmarshal(events, &sub.Events); err != nil {
+		return Subscription{}, err
+	}
+	if err := json.Unmarshal(filters, &sub.Filters); err != nil {
+		return Subscription{}, err
+	}
+	if deletedAt.Valid {
+		sub.DeletedAt = &deletedAt.Time
+	}
+	return sub, nil
+}
+
+// scanDelivery convierte una fila con las columnas de deliveryColumns.
+func scanDelivery(row rowScanner) (Delivery, error) {
+	var d Delivery
+	var payload string
+	var nextAttemptAt, deliveredAt sql.NullTime
+	var statusCode sql.NullInt64
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&nextAttemptAt, &statusCode, &d.LastError, &d.CreatedAt, &deliveredAt)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	d.Payload = []byte(payload)
+	if nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if statusCode.Valid {
+		code := int(statusCode.Int64)
+		d.LastStatusCode = &code
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return d, nil
+}
+
+// collectDeliveries lee todas las filas de una consulta de entregas.
+func collectDeliveries(ctx context.Context, rows *sql.Rows) ([]Delivery, error) {
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, logging.Errorf(ctx, "error al escanear entrega: %w", repository.Classify(err))
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logging.Errorf(ctx, "error al iterar entregas: %w", repository.Classify(err))
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,289 @@
+// Paquete webhooks envía notificaciones firmadas a los suscriptores cuando la
+// sincronización guarda eventos nuevos o termina.
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/repository"
+)
+
+// Tipos de evento.
+const (
+	// Una calificación nueva guardada por la sincronización
+	EventRatingChanged = "rating.changed"
+	// La sincronización terminó correctamente
+	EventSyncCompleted = "sync.completed"
+	// La sincronización falló
+	EventSyncFailed = "sync.failed"
+	// Evento de prueba enviado a pedido
+	EventPing = "ping"
+)
+
+// eventTypes son los eventos a los que se puede suscribir.
+var eventTypes = []string{EventRatingChanged, EventSyncCompleted, EventSyncFailed}
+
+// Estados de una entrega.
+const (
+	// Pendiente de enviar o de reintentar
+	DeliveryPending = "pending"
+	// El receptor respondió con un código 2xx
+	DeliveryDelivered = "delivered"
+	// Se agotaron los reintentos; forma parte de la lista de entregas fallidas
+	DeliveryDead = "dead"
+)
+
+// ErrNotFound indica que la suscripción o la entrega no existe.
+var ErrNotFound error = repository.NotFound("suscripción o entrega no encontrada")
+
+// Filters restringe los eventos rating.changed que recibe una suscripción. Los
+// filtros vacíos aceptan cualquier valor.
+type Filters struct {
+	// Tickers aceptados
+	Tickers []string `json:"tickers,omitempty"`
+	// Casas de bolsa aceptadas
+	Brokerages []string `json:"brokerages,omitempty"`
+	// Tipos de acción aceptados (upgraded by, target raised by, etc.)
+	Actions []string `json:"actions,omitempty"`
+	// Cambio mínimo del precio objetivo, en porcentaje y en valor absoluto
+	MinTargetChangePct float64 `json:"min_target_change_pct,omitempty"`
+}
+
+// Subscription es una URL registrada para recibir eventos.
+type Subscription struct {
+	// Identificador de la suscripción
+	ID int64 `json:"id"`
+	// URL que recibe los eventos
+	URL string `json:"url"`
+	// Descripción opcional
+	Description string `json:"description,omitempty"`
+	// Tipos de evento suscritos
+	Events []string `json:"events"`
+	// Filtros de los eventos rating.changed
+	Filters Filters `json:"filters"`
+	// Principal que creó la suscripción
+	CreatedBy string `json:"created_by"`
+	// Fecha y hora de creación
+	CreatedAt time.Time `json:"created_at"`
+	// Fecha y hora de eliminación
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Secreto para firmar los eventos; solo se conoce internamente
+	secret string
+}
+
+// Delivery es el envío de un evento a una suscripción.
+type Delivery struct {
+	// Identificador de la entrega
+	ID int64 `json:"id"`
+	// Suscripción destino
+	SubscriptionID int64 `json:"subscription_id"`
+	// Identificador del evento, igual para todas sus entregas
+	EventID string `json:"event_id"`
+	// Tipo de evento
+	EventType string `json:"event_type"`
+	// Cuerpo JSON enviado
+	Payload json.RawMessage `json:"payload"`
+	// Estado de la entrega (pending, delivered o dead)
+	Status string `json:"status"`
+	// Intentos realizados
+	Attempts int `json:"attempts"`
+	// Próximo intento, si está pendiente
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	// Código HTTP de la última respuesta
+	LastStatusCode *int `json:"last_status_code,omitempty"`
+	// Error del último intento
+	LastError string `json:"last_error,omitempty"`
+	// Fecha y hora de creación
+	CreatedAt time.Time `json:"created_at"`
+	// Fecha y hora de la entrega exitosa
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Event es el cuerpo JSON que recibe el suscriptor.
+type Event struct {
+	// Identificador único del evento, para descartar duplicados
+	ID string `json:"id"`
+	// Tipo de evento
+	Type string `json:"type"`
+	// Fecha y hora en que se generó
+	CreatedAt time.Time `json:"created_at"`
+	// Datos del evento: un RatingChange, un SyncResult o un mensaje de prueba
+	Data any `json:"data"`
+}
+
+// SyncResult son los datos de un evento sync.completed o sync.failed. No incluye
+// el mensaje de error de la sincronización, que puede contener direcciones o
+// detalles internos, sino su código estable.
+type SyncResult struct {
+	// Estado final (completed o failed)
+	Status string `json:"status"`
+	// Fecha y hora de inicio
+	StartedAt time.Time `json:"started_at"`
+	// Fecha y hora de finalización
+	FinishedAt time.Time `json:"finished_at"`
+	// Duración en milisegundos
+	DurationMs int64 `json:"duration_ms"`
+	// Cantidad de stocks guardados
+	StocksCount int `json:"stocks_count"`
+	// Código del error si falló (upstream_error, storage_error o timeout)
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// RatingChange son los datos de un evento rating.changed.
+type RatingChange struct {
+	models.StockEvent
+	// Cambio porcentual del precio objetivo, si ambos valores son numéricos
+	TargetChangePct *float64 `json:"target_change_pct,omitempty"`
+}
+
+// newEvent crea un evento con un identificador aleatorio.
+func newEvent(eventType string, data any) Event {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return Event{
+		ID:        "evt_" + hex.EncodeToString(id),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// newSyncResult extrae de una sincronización los datos que se envían a los suscriptores.
+func newSyncResult(run models.SyncRun) SyncResult {
+	return SyncResult{
+		Status:      run.Status,
+		StartedAt:   run.StartedAt.UTC(),
+		FinishedAt:  run.FinishedAt.UTC(),
+		DurationMs:  run.FinishedAt.Sub(run.StartedAt).Milliseconds(),
+		StocksCount: run.StocksCount,
+		ErrorCode:   run.ErrorCode,
+	}
+}
+
+// newRatingChange calcula los datos de un evento rating.changed.
+func newRatingChange(event models.StockEvent) RatingChange {
+	change := RatingChange{StockEvent: event}
+	if pct, ok := targetChangePct(event.Stock); ok {
+		change.TargetChangePct = &pct
+	}
+	return change
+}
+
+// Validate normaliza y verifica la URL, los eventos y los filtros de una
+// suscripción. Sin allowPrivate rechaza las URL cuyo host es localhost o una IP
+// de una red bloqueada; los nombres que resuelven a esas redes se rechazan al
+// conectarse.
+func (s *Subscription) Validate(allowPrivate bool) error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url inválida %q, se espera http(s)://host/ruta", s.URL)
+	}
+	if !allowPrivate {
+		if err := checkHost(u.Hostname()); err != nil {
+			return fmt.Errorf("url inválida %q: %w", s.URL, err)
+		}
+	}
+
+	if len(s.Events) == 0 {
+		s.Events = append([]string(nil), eventTypes...)
+	}
+	for i, event := range s.Events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !contains(eventTypes, event) {
+			return fmt.Errorf("evento inválido %q, se espera uno de %s", event, strings.Join(eventTypes, ", "))
+		}
+		s.Events[i] = event
+	}
+
+	for i, ticker := range s.Filters.Tickers {
+		s.Filters.Tickers[i] = strings.ToUpper(strings.TrimSpace(ticker))
+	}
+	if s.Filters.MinTargetChangePct < 0 {
+		return errors.New("min_target_change_pct no puede ser negativo")
+	}
+
+	return nil
+}
+
+// wants indica si la suscripción recibe el tipo de evento.
+func (s Subscription) wants(eventType string) bool {
+	return eventType == EventPing || contains(s.Events, eventType)
+}
+
+// Match indica si un evento de calificación cumple los filtros.
+func (f Filters) Match(event models.StockEvent) bool {
+	if len(f.Tickers) > 0 && !containsFold(f.Tickers, event.Ticker) {
+		return false
+	}
+	if len(f.Brokerages) > 0 && !containsFold(f.Brokerages, event.Brokerage) {
+		return false
+	}
+	if len(f.Actions) > 0 && !containsFold(f.Actions, event.Action) {
+		return false
+	}
+	if f.MinTargetChangePct > 0 {
+		pct, ok := targetChangePct(event.Stock)
+		if !ok || abs(pct) < f.MinTargetChangePct {
+			return false
+		}
+	}
+	return true
+}
+
+// targetChangePct calcula el cambio porcentual entre el precio objetivo anterior
+// y el actual. Los precios llegan como texto, por ejemplo "$1,250.00".
+func targetChangePct(stock models.Stock) (float64, bool) {
+	from, ok := parsePrice(stock.TargetFrom)
+	if !ok || from == 0 {
+		return 0, false
+	}
+	to, ok := parsePrice(stock.TargetTo)
+	if !ok {
+		return 0, false
+	}
+	return (to - from) / from * 100, true
+}
+
+// parsePrice convierte un precio con formato "$1,250.00" en número.
+func parsePrice(value string) (float64, bool) {
+	value = strings.NewReplacer("$", "", ",", "").Replace(strings.TrimSpace(value))
+	price, err := strconv.ParseFloat(value, 64)
+	return price, err == nil
+}
+
+// contains indica si la lista contiene el valor.
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold indica si la lista contiene el valor sin distinguir mayúsculas.
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// abs devuelve el valor absoluto.
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
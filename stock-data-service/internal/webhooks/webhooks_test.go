@@ -0,0 +1,195 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RobertCastro/stock-microservices-api/stock-data-service/internal/models"
+)
+
+// ratingEvent crea un evento de calificación de prueba.
+func ratingEvent(ticker, brokerage, action, targetFrom, targetTo string) models.StockEvent {
+	return models.StockEvent{
+		ID: 1,
+		Stock: models.Stock{
+			Ticker:     ticker,
+			Company:    ticker + " Inc.",
+			TargetFrom: targetFrom,
+			TargetTo:   targetTo,
+			Action:     action,
+			Brokerage:  brokerage,
+			RatingFrom: "Hold",
+			RatingTo:   "Buy",
+			Time:       time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func TestFiltersMatch(t *testing.T) {
+	event := ratingEvent("NVDA", "The Goldman Sachs Group", "target raised by", "$140.00", "$160.00")
+
+	tests := []struct {
+		name    string
+		filters Filters
+		event   models.StockEvent
+		want    bool
+	}{
+		{"sin filtros", Filters{}, event, true},
+		{"ticker", Filters{Tickers: []string{"AAPL", "NVDA"}}, event, true},
+		{"ticker sin mayúsculas", Filters{Tickers: []string{"nvda"}}, event, true},
+		{"otro ticker", Filters{Tickers: []string{"AAPL"}}, event, false},
+		{"casa de bolsa con espacios", Filters{Brokerages: []string{" the goldman sachs group "}}, event, true},
+		{"otra casa de bolsa", Filters{Brokerages: []string{"Barclays"}}, event, false},
+		{"acción", Filters{Actions: []string{"upgraded by", "Target Raised By"}}, event, true},
+		{"otra acción", Filters{Actions: []string{"downgraded by"}}, event, false},
+		{"cambio mínimo alcanzado", Filters{MinTargetChangePct: 14}, event, true},
+		{"cambio mínimo no alcanzado", Filters{MinTargetChangePct: 15}, event, false},
+		{"cambio negativo en valor absoluto", Filters{MinTargetChangePct: 10}, ratingEvent("NVDA", "Barclays", "target lowered by", "$1,250.00", "$1,000.00"), true},
+		{"precio no numérico", Filters{MinTargetChangePct: 1}, ratingEvent("NVDA", "Barclays", "target set by", "", "$160.00"), false},
+		{"precio anterior cero", Filters{MinTargetChangePct: 1}, ratingEvent("NVDA", "Barclays", "target set by", "$0.00", "$160.00"), false},
+		{"todos los filtros", Filters{Tickers: []string{"NVDA"}, Brokerages: []string{"The Goldman Sachs Group"}, Actions: []string{"target raised by"}, MinTargetChangePct: 10}, event, true},
+		{"un filtro falla", Filters{Tickers: []string{"NVDA"}, Brokerages: []string{"Barclays"}}, event, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filters.Match(tt.event); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		sub          Subscription
+		allowPrivate bool
+		wantErr      string
+		wantEvents   []string
+		wantTickers  []string
+	}{
+		{"todos los eventos por omisión", Subscription{URL: "https://example.com/hooks"}, false, "", eventTypes, nil},
+		{"eventos normalizados", Subscription{URL: "https://example.com/hooks", Events: []string{" Sync.Failed "}}, false, "", []string{EventSyncFailed}, nil},
+		{"tickers en mayúsculas", Subscription{URL: "https://example.com/hooks", Filters: Filters{Tickers: []string{" nvda"}}}, false, "", eventTypes, []string{"NVDA"}},
+		{"evento desconocido", Subscription{URL: "https://example.com/hooks", Events: []string{"ping"}}, false, "evento inválido", nil, nil},
+		{"esquema no http", Subscription{URL: "ftp://example.com/hooks"}, false, "url inválida", nil, nil},
+		{"sin host", Subscription{URL: "https:///hooks"}, false, "url inválida", nil, nil},
+		{"cambio mínimo negativo", Subscription{URL: "https://example.com/hooks", Filters: Filters{MinTargetChangePct: -1}}, false, "min_target_change_pct", nil, nil},
+		{"localhost", Subscription{URL: "http://localhost:9090/"}, false, "no está permitida", nil, nil},
+		{"loopback", Subscription{URL: "http://127.0.0.1:9090/"}, false, "no está permitida", nil, nil},
+		{"metadatos de la nube", Subscription{URL: "http://169.254.169.254/latest/meta-data"}, false, "no está permitida", nil, nil},
+		{"red privada", Subscription{URL: "https://10.0.0.7/hooks"}, false, "no está permitida", nil, nil},
+		{"loopback IPv6", Subscription{URL: "http://[::1]/hooks"}, false, "no está permitida", nil, nil},
+		{"IPv4 en IPv6", Subscription{URL: "http://[::ffff:192.168.1.1]/hooks"}, false, "no está permitida", nil, nil},
+		{"redes privadas permitidas", Subscription{URL: "http://localhost:9090/"}, true, "", eventTypes, nil},
+		{"IP pública", Subscription{URL: "https://93.184.216.34/hooks"}, false, "", eventTypes, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sub.Validate(tt.allowPrivate)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Validate() error = %v, want un error con %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if !reflect.DeepEqual(tt.sub.Events, tt.wantEvents) {
+				t.Errorf("Events = %v, want %v", tt.sub.Events, tt.wantEvents)
+			}
+			if tt.wantTickers != nil && !reflect.DeepEqual(tt.sub.Filters.Tickers, tt.wantTickers) {
+				t.Errorf("Filters.Tickers = %v, want %v", tt.sub.Filters.Tickers, tt.wantTickers)
+			}
+		})
+	}
+}
+
+func TestBlockedAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.0.10", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"0.0.0.0", true},
+		{"100.64.0.1", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::ffff:10.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"172.32.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := blockedAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("blockedAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestNewSyncResultOmitsErrorText(t *testing.T) {
+	run := models.SyncRun{
+		Status:     models.SyncStatusFailed,
+		StartedAt:  time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+		FinishedAt: time.Date(2024, 3, 1, 9, 0, 2, 500_000_000, time.UTC),
+		Error:      "dial tcp 10.0.0.7:26257: connect: connection refused",
+		ErrorCode:  models.SyncErrorStorage,
+		RequestID:  "req-1",
+	}
+
+	payload, err := json.Marshal(newEvent(EventSyncFailed, newSyncResult(run)))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var event struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	want := map[string]any{
+		"status":       "failed",
+		"started_at":   "2024-03-01T09:00:00Z",
+		"finished_at":  "2024-03-01T09:00:02.5Z",
+		"duration_ms":  float64(2500),
+		"stocks_count": float64(0),
+		"error_code":   "storage_error",
+	}
+	if !reflect.DeepEqual(event.Data, want) {
+		t.Errorf("data = %v, want %v", event.Data, want)
+	}
+	if strings.Contains(string(payload), "10.0.0.7") {
+		t.Errorf("el cuerpo contiene el mensaje de error interno: %s", payload)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	for _, host := range []string{"localhost", "LOCALHOST.", "api.localhost", "127.0.0.1", "::1"} {
+		if err := checkHost(host); !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("checkHost(%q) error = %v, want ErrBlockedAddress", host, err)
+		}
+	}
+	// Los nombres se comprueban al conectarse
+	for _, host := range []string{"example.com", "93.184.216.34", "internal.example.com"} {
+		if err := checkHost(host); err != nil {
+			t.Errorf("checkHost(%q) error = %v, want nil", host, err)
+		}
+	}
+}